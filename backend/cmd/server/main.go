@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"shiftplanner/backend/internal/api"
+	"shiftplanner/backend/internal/auth"
+	"shiftplanner/backend/internal/database"
+	"shiftplanner/backend/internal/notify"
+	"shiftplanner/backend/internal/oidc"
+	"shiftplanner/backend/internal/storage"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
+
+func main() {
+	// Schema migrations: server migrate status | up | down <version>
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.OpenDB(); err != nil {
+			log.Fatal("Failed to open database:", err)
+		}
+		err := database.MigrateCommand(os.Args[2:], os.Stdout)
+		database.CloseDB()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Initialize database
+	if err := database.InitDB(); err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	defer database.CloseDB()
+
+	store := storage.NewSQLiteStore(database.DB)
+	if database.Driver == database.DriverPostgres {
+		store = storage.NewPostgresStore(database.DB)
+	}
+	h := api.NewHandler(store)
+
+	// Expired sessions are also rejected on use; this keeps the table small
+	go auth.RunSessionCleanup(context.Background(), store, time.Hour)
+
+	// Single sign-on, enabled by setting OIDC_ISSUER
+	oidcConfig, err := oidc.ConfigFromEnv()
+	if err != nil {
+		log.Fatal("Invalid OIDC configuration:", err)
+	}
+	if oidcConfig != nil {
+		provider, err := oidc.NewProvider(context.Background(), *oidcConfig, nil)
+		if err != nil {
+			log.Fatal("Failed to set up OIDC:", err)
+		}
+		h.EnableOIDC(provider)
+	}
+
+	passwordPolicy, err := auth.PasswordPolicyFromEnv()
+	if err != nil {
+		log.Fatal("Invalid password policy:", err)
+	}
+	h.SetPasswordPolicy(passwordPolicy)
+
+	// Password reset tokens are emailed through SMTP_ADDR, or only logged without it
+	notifier, err := notify.SenderFromEnv()
+	if err != nil {
+		log.Fatal("Invalid SMTP configuration:", err)
+	}
+	if _, ok := notifier.(notify.LogSender); ok {
+		log.Println("SMTP_ADDR not set, notifications are only logged")
+	}
+	h.SetNotifier(notifier)
+	h.SetPasswordResetURL(os.Getenv("PASSWORD_RESET_URL"))
+
+	rateLimits, err := api.RateLimitsFromEnv()
+	if err != nil {
+		log.Fatal("Invalid rate limit:", err)
+	}
+	proxyConfig, err := api.ProxyConfigFromEnv()
+	if err != nil {
+		log.Fatal("Invalid proxy configuration:", err)
+	}
+
+	// Create Fiber app
+	appConfig := fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			code := fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				code = e.Code
+			}
+			return c.Status(code).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		},
+	}
+	// Rate limits and login lockouts count per client, which behind a proxy
+	// only the proxy can tell
+	proxyConfig.Apply(&appConfig)
+	app := fiber.New(appConfig)
+
+	// Middleware
+	app.Use(requestid.New())
+	app.Use(logger.New())
+
+	// Get allowed origins from environment variable or use default (allow all)
+	allowedOriginsStr := os.Getenv("ALLOWED_ORIGINS")
+	if allowedOriginsStr == "" {
+		allowedOriginsStr = "*" // Allow all origins by default
+	}
+
+	// Split comma-separated origins
+	allowedOrigins := []string{}
+	hasWildcard := false
+	for _, origin := range strings.Split(allowedOriginsStr, ",") {
+		trimmed := strings.TrimSpace(origin)
+		if trimmed == "*" {
+			hasWildcard = true
+		} else {
+			allowedOrigins = append(allowedOrigins, trimmed)
+		}
+	}
+
+	corsConfig := cors.Config{
+		AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
+		AllowHeaders:     "Content-Type, Authorization, X-Request-ID, X-Workspace-ID",
+		AllowCredentials: !hasWildcard, // Wildcard ile credentials çalışmaz
+	}
+
+	if hasWildcard {
+		corsConfig.AllowOrigins = "*"
+	} else {
+		corsConfig.AllowOrigins = strings.Join(allowedOrigins, ",")
+	}
+
+	app.Use(cors.New(corsConfig))
+
+	// Rate limits per route group; auth routes also count towards the API limit
+	app.Use("/api/auth", api.RateLimiter(rateLimits.Auth))
+	app.Use("/api/invites", api.RateLimiter(rateLimits.Auth))
+	app.Use("/api", api.RateLimiter(rateLimits.API))
+	app.Use("/ical", api.RateLimiter(rateLimits.Feeds))
+
+	// Auth routes (unprotected)
+	app.Post("/api/auth/register", h.Register)
+	app.Post("/api/auth/login", h.Login)
+	app.Post("/api/auth/login/2fa", h.LoginTwoFactor)
+	app.Post("/api/auth/logout", h.Logout)
+	app.Post("/api/auth/password-reset", h.RequestPasswordReset)
+	app.Post("/api/auth/password-reset/confirm", h.ResetPassword)
+	app.Get("/api/auth/oidc/login", h.OIDCLogin)
+	app.Get("/api/auth/oidc/callback", h.OIDCCallback)
+
+	// Invite route (unprotected, invitees may not have an account yet)
+	app.Post("/api/invites/:token/accept", h.AcceptWorkspaceInvite)
+
+	// Holidays route (unprotected)
+	app.Get("/api/holidays", h.GetHolidays)
+
+	// Calendar feed routes (authenticated by the feed token in the URL)
+	app.Get("/ical/:token/shifts.ics", h.GetShiftsFeed)
+	app.Get("/ical/:token/members/:id.ics", h.GetMemberShiftsFeed)
+
+	// API routes (protected)
+	apiGroup := app.Group("/api", h.AuthMiddleware)
+	apiGroup.Get("/members", h.GetMembers)
+	apiGroup.Post("/members", h.CreateMember)
+	apiGroup.Delete("/members/:id", h.DeleteMember)
+	apiGroup.Post("/members/:id/invite", h.CreateMemberInvite)
+	apiGroup.Delete("/members/:id/link", h.UnlinkMember)
+	apiGroup.Post("/me/member", h.AcceptMemberInvite)
+	apiGroup.Get("/me/shifts", h.GetMyShifts)
+	apiGroup.Get("/me/leave-days", h.GetMyLeaveDays)
+	apiGroup.Post("/me/leave-days", h.CreateMyLeaveDays)
+	apiGroup.Get("/shifts", h.GetShifts)
+	apiGroup.Get("/shifts/export", h.ExportShifts)
+	apiGroup.Get("/shifts/roster.pdf", h.GetRosterPDF)
+	apiGroup.Post("/shifts/generate", h.GenerateShifts)
+	apiGroup.Post("/shifts/import/preview", h.PreviewImport)
+	apiGroup.Post("/shifts/import", h.ImportShifts)
+	apiGroup.Delete("/shifts", h.ClearAllShifts)
+	apiGroup.Get("/stats", h.GetStats)
+	apiGroup.Get("/leave-days", h.GetLeaveDays)
+	apiGroup.Post("/leave-days", h.CreateLeaveDay)
+	apiGroup.Post("/leave-days/import", h.ImportLeaveDays)
+	apiGroup.Delete("/leave-days/:id", h.DeleteLeaveDay)
+	apiGroup.Get("/leave-requests", h.GetLeaveRequests)
+	apiGroup.Post("/leave-requests", h.CreateLeaveRequest)
+	apiGroup.Post("/leave-requests/:id/approve", h.ApproveLeaveRequest)
+	apiGroup.Post("/leave-requests/:id/reject", h.RejectLeaveRequest)
+	apiGroup.Get("/swap-requests", h.GetSwapRequests)
+	apiGroup.Post("/swap-requests", h.CreateSwapRequest)
+	apiGroup.Post("/swap-requests/:id/approve", h.ApproveSwapRequest)
+	apiGroup.Post("/swap-requests/:id/reject", h.RejectSwapRequest)
+	apiGroup.Get("/leave-allowances", h.GetLeaveAllowances)
+	apiGroup.Put("/leave-allowances", h.SetLeaveAllowance)
+	apiGroup.Get("/leave-balances", h.GetLeaveBalances)
+	apiGroup.Get("/unavailability-rules", h.GetUnavailabilityRules)
+	apiGroup.Post("/unavailability-rules", h.CreateUnavailabilityRule)
+	apiGroup.Delete("/unavailability-rules/:id", h.DeleteUnavailabilityRule)
+	apiGroup.Put("/shifts/date", h.UpdateShiftForDate)
+	apiGroup.Get("/feed-tokens", h.GetFeedTokens)
+	apiGroup.Post("/feed-tokens", h.CreateFeedToken)
+	apiGroup.Delete("/feed-tokens/:id", h.DeleteFeedToken)
+	apiGroup.Get("/backup", h.ExportBackup)
+	apiGroup.Post("/backup/restore", h.RestoreBackup)
+	apiGroup.Get("/audit", h.GetAuditLog)
+	apiGroup.Get("/snapshots", h.GetPlanSnapshots)
+	apiGroup.Post("/snapshots/:id/restore", h.RestorePlanSnapshot)
+	apiGroup.Get("/workspaces", h.GetWorkspaces)
+	apiGroup.Post("/workspaces", h.CreateWorkspace)
+	apiGroup.Get("/workspace/users", h.GetWorkspaceUsers)
+	apiGroup.Put("/workspace/users", h.SetWorkspaceUserRole)
+	apiGroup.Delete("/workspace/users/:id", h.RemoveWorkspaceUser)
+	apiGroup.Get("/workspace/invites", h.GetWorkspaceInvites)
+	apiGroup.Post("/workspace/invites", h.CreateWorkspaceInvite)
+	apiGroup.Delete("/workspace/invites/:id", h.DeleteWorkspaceInvite)
+	apiGroup.Get("/api-keys", h.GetAPIKeys)
+	apiGroup.Post("/api-keys", h.CreateAPIKey)
+	apiGroup.Delete("/api-keys/:id", h.DeleteAPIKey)
+	apiGroup.Get("/auth/sessions", h.GetSessions)
+	apiGroup.Delete("/auth/sessions/:id", h.RevokeSession)
+	apiGroup.Post("/auth/password", h.ChangePassword)
+	apiGroup.Put("/auth/email", h.SetEmail)
+	apiGroup.Delete("/auth/account", h.DeleteAccount)
+	apiGroup.Get("/auth/2fa", h.GetTwoFactor)
+	apiGroup.Post("/auth/2fa/setup", h.SetupTwoFactor)
+	apiGroup.Post("/auth/2fa/enable", h.EnableTwoFactor)
+	apiGroup.Post("/auth/2fa/disable", h.DisableTwoFactor)
+	apiGroup.Post("/auth/2fa/recovery-codes", h.RegenerateRecoveryCodes)
+	apiGroup.Put("/workspace/two-factor", h.SetWorkspaceTwoFactor)
+
+	// Start server
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	log.Printf("Starting server on port %s", port)
+	if err := app.Listen(":" + port); err != nil {
+		log.Fatal("Failed to start server:", err)
+	}
+}
@@ -0,0 +1,470 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/mail"
+	"shiftplanner/backend/internal/auth"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/notify"
+	"shiftplanner/backend/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// passwordResetExpiry how long a password reset token can be used
+const passwordResetExpiry = time.Hour
+
+// recentLoginWindow how long after logging in a user without a password counts as just authenticated
+const recentLoginWindow = 10 * time.Minute
+
+// ChangePasswordRequest password change request
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// SetEmailRequest email change request, confirmed with the current password
+type SetEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// PasswordResetRequest asks for a password reset token
+type PasswordResetRequest struct {
+	Username string `json:"username"`
+}
+
+// ResetPasswordRequest sets a new password with a reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// DeleteAccountRequest account deletion request, confirmed with the current password
+// Single sign-on users confirm with a TOTP or recovery code when two-factor is enabled.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// ChangePassword changes the password of the authenticated user and logs out their other sessions
+func (h *Handler) ChangePassword(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Current and new password are required",
+		})
+	}
+
+	user, err := h.users.GetUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := h.confirmPassword(c, user.Username, req.CurrentPassword); err != nil {
+		return err
+	}
+	if msg := h.passwordPolicy.Check(user.Username, req.NewPassword); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := h.users.UpdatePassword(userID, req.NewPassword); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	sessionID, _ := c.Locals(sessionIDKey).(int)
+	if err := h.sessions.DeleteUserSessions(userID, sessionID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Password changed, other sessions were logged out",
+	})
+}
+
+// SetEmail sets the address password resets are sent to; an empty email removes it
+func (h *Handler) SetEmail(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+
+	var req SetEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email != "" && !validEmail(req.Email) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid email",
+		})
+	}
+
+	user, err := h.users.GetUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	// Whoever controls the address can reset the password
+	if err := h.confirmPassword(c, user.Username, req.Password); err != nil {
+		return err
+	}
+
+	if err := h.users.SetUserEmail(userID, req.Email); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	user.Email = req.Email
+
+	return c.JSON(fiber.Map{
+		"user": user,
+	})
+}
+
+// RequestPasswordReset sends a password reset token to the email address of a user
+// The response is the same whether or not the user exists or has an address.
+func (h *Handler) RequestPasswordReset(c *fiber.Ctx) error {
+	var req PasswordResetRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if req.Username == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Username is required",
+		})
+	}
+
+	accepted := func() error {
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message": "If the account has an email address, a reset token was sent to it",
+		})
+	}
+
+	user, passwordHash, err := h.users.GetUserByUsername(req.Username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return accepted()
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	// Users of single sign-on have no password to reset
+	if user.Email == "" || passwordHash == "" {
+		return accepted()
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := h.users.CreatePasswordReset(user.ID, auth.HashToken(token), time.Now().Add(passwordResetExpiry)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.sendNotification(h.passwordResetMessage(user, token))
+	return accepted()
+}
+
+// ResetPassword sets a new password with a reset token and logs the user out everywhere
+func (h *Handler) ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if req.Token == "" || req.Password == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Token and password are required",
+		})
+	}
+
+	reset, err := h.users.GetPasswordReset(auth.HashToken(req.Token), time.Now())
+	if err != nil {
+		return passwordResetError(c, err)
+	}
+	user, err := h.users.GetUserByID(reset.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if msg := h.passwordPolicy.Check(user.Username, req.Password); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := h.users.ResetPassword(reset.ID, req.Password); err != nil {
+		return passwordResetError(c, err)
+	}
+	h.logins.Succeed(user.Username)
+
+	return c.JSON(fiber.Map{
+		"message": "Password reset, log in with the new password",
+	})
+}
+
+// DeleteAccount deletes the authenticated user, and the workspaces nobody else uses
+// The response is an export of everything that was stored about the user,
+// including backups of the deleted workspaces.
+func (h *Handler) DeleteAccount(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+
+	var req DeleteAccountRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	user, err := h.users.GetUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	_, passwordHash, err := h.users.GetUserByUsername(user.Username)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	// Users of single sign-on have no password to confirm with
+	if passwordHash != "" {
+		if err := h.confirmPassword(c, user.Username, req.Password); err != nil {
+			return err
+		}
+	} else if err := h.confirmRecentLogin(c, user, req.Code); err != nil {
+		return err
+	}
+
+	export, err := h.exportAccount(user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to write export",
+		})
+	}
+
+	if err := h.users.DeleteUser(userID); err != nil {
+		if errors.Is(err, storage.ErrLastOwner) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "You are the only owner of a workspace others use; make someone else owner first",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	filename := fmt.Sprintf("shiftplanner-account-%s.json", export.ExportedAt.Format("2006-01-02"))
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Send(data)
+}
+
+// exportAccount collects everything stored about a user
+// Workspaces nobody else uses are deleted with the account, so they are backed up in full.
+func (h *Handler) exportAccount(user *models.User) (*models.AccountExport, error) {
+	now := time.Now()
+	export := &models.AccountExport{
+		ExportedAt: now.UTC(),
+		User:       *user,
+		Workspaces: []models.AccountExportWorkspace{},
+		Sessions:   []models.Session{},
+	}
+
+	workspaces, err := h.workspaces.GetUserWorkspaces(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, workspace := range workspaces {
+		entry := models.AccountExportWorkspace{
+			Workspace:  workspace,
+			APIKeys:    []models.APIKey{},
+			FeedTokens: []models.FeedToken{},
+		}
+
+		member, err := h.members.GetMemberByUser(workspace.ID, user.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		entry.Member = member
+
+		keys, err := h.apiKeys.GetAPIKeys(user.ID, workspace.ID)
+		if err != nil {
+			return nil, err
+		}
+		entry.APIKeys = append(entry.APIKeys, keys...)
+
+		feedTokens, err := h.feedTokens.GetFeedTokens(workspace.ID, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		entry.FeedTokens = append(entry.FeedTokens, feedTokens...)
+
+		users, err := h.workspaces.GetWorkspaceUsers(workspace.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 1 {
+			if entry.Backup, err = h.backups.ExportBackup(workspace.ID); err != nil {
+				return nil, err
+			}
+		}
+
+		export.Workspaces = append(export.Workspaces, entry)
+	}
+
+	sessions, err := h.sessions.GetUserSessions(user.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	export.Sessions = append(export.Sessions, sessions...)
+
+	twoFactor, err := h.twoFactors.GetTwoFactor(user.ID)
+	if err != nil && !errors.Is(err, storage.ErrTwoFactorNotFound) {
+		return nil, err
+	}
+	export.TwoFactor = twoFactor.Enabled()
+
+	return export, nil
+}
+
+// confirmPassword checks the password of the logged in user before a sensitive change
+// Wrong passwords count as failed logins, so a stolen session can't be used to guess.
+func (h *Handler) confirmPassword(c *fiber.Ctx, username, password string) error {
+	user, wait := h.authenticate(username, password, strings.Clone(c.IP()))
+	if user != nil {
+		h.logins.Succeed(user.Username)
+		return nil
+	}
+	if wait > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return fiber.NewError(fiber.StatusTooManyRequests, "Too many failed attempts, try again later")
+	}
+	return fiber.NewError(fiber.StatusUnauthorized, "Invalid password")
+}
+
+// confirmRecentLogin checks that a user without a password just authenticated,
+// with a second factor when two-factor is enabled and otherwise by a login
+// less than recentLoginWindow ago. Wrong codes count as failed logins.
+func (h *Handler) confirmRecentLogin(c *fiber.Ctx, user *models.User, code string) error {
+	enabled, err := h.hasTwoFactor(user.ID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		loggedInAt, _ := c.Locals(sessionLoginKey).(time.Time)
+		if time.Since(loggedInAt) > recentLoginWindow {
+			return fiber.NewError(fiber.StatusUnauthorized, "Log in again to confirm")
+		}
+		return nil
+	}
+
+	ip := strings.Clone(c.IP())
+	if wait := h.logins.Wait(user.Username, ip); wait > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return fiber.NewError(fiber.StatusTooManyRequests, "Too many failed attempts, try again later")
+	}
+	if err := auth.VerifySecondFactor(h.twoFactors, user.ID, code); err != nil {
+		if err == auth.ErrInvalidCode {
+			h.logins.Fail(user.Username, ip)
+			return fiber.NewError(fiber.StatusUnauthorized, "Invalid code")
+		}
+		return err
+	}
+	return nil
+}
+
+// passwordResetMessage tells a user how to reset their password with token
+func (h *Handler) passwordResetMessage(user *models.User, token string) notify.Message {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Someone asked to reset the password of the Shift Planner account %q.\n\n", user.Username)
+	if h.passwordResetURL != "" {
+		fmt.Fprintf(&body, "Choose a new password at:\n%s#token=%s\n\n", h.passwordResetURL, token)
+	} else {
+		fmt.Fprintf(&body, "Reset it with this token:\n%s\n\n", token)
+	}
+	fmt.Fprintf(&body, "The token works once and expires in %d minutes. If you didn't ask for this, ignore this message.\n", int(passwordResetExpiry.Minutes()))
+
+	return notify.Message{
+		To:      user.Email,
+		Subject: "Reset your Shift Planner password",
+		Body:    body.String(),
+	}
+}
+
+// sendNotification delivers a message in the background, so responses neither
+// wait for delivery nor reveal by their timing whether a message was sent
+func (h *Handler) sendNotification(msg notify.Message) {
+	go func() {
+		if err := h.notifier.Send(msg); err != nil {
+			log.Printf("Warning: failed to send %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// passwordResetError responds to an unusable password reset token
+func passwordResetError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, storage.ErrPasswordResetNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid or expired reset token",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// validEmail reports whether email is a bare address, without a display name
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"shiftplanner/backend/internal/auth"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/notify"
+	"shiftplanner/backend/internal/storage"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// recordingSender hands sent messages to the test
+type recordingSender struct {
+	messages chan notify.Message
+}
+
+func (s *recordingSender) Send(msg notify.Message) error {
+	s.messages <- msg
+	return nil
+}
+
+// setupAccountApp serves the account routes with a test session "owner_token" for testuser
+func setupAccountApp(t *testing.T) (*Handler, storage.Store, func(method, path, token, body string) *http.Response) {
+	h, store, workspaceID := setupTestAPI(t)
+	createTestSession(t, store, workspaceID, "owner_token")
+
+	app := fiber.New()
+	app.Post("/api/auth/login", h.Login)
+	app.Post("/api/auth/password-reset", h.RequestPasswordReset)
+	app.Post("/api/auth/password-reset/confirm", h.ResetPassword)
+	app.Get("/api/auth/sessions", h.AuthMiddleware, h.GetSessions)
+	app.Post("/api/auth/password", h.AuthMiddleware, h.ChangePassword)
+	app.Put("/api/auth/email", h.AuthMiddleware, h.SetEmail)
+	app.Delete("/api/auth/account", h.AuthMiddleware, h.DeleteAccount)
+
+	send := func(method, path, token, body string) *http.Response {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		resp, _ := app.Test(req)
+		return resp
+	}
+	return h, store, send
+}
+
+func TestChangePassword(t *testing.T) {
+	_, store, send := setupAccountApp(t)
+	user, _, _ := store.GetUserByUsername("testuser")
+	store.CreateSession(user.ID, auth.HashToken("other_token"), "", "", time.Now().Add(time.Hour))
+
+	resp := send(http.MethodPost, "/api/auth/password", "owner_token", `{"current_password":"wrong","new_password":"correct horse battery"}`)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status code: %d for a wrong password, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+	resp = send(http.MethodPost, "/api/auth/password", "owner_token", `{"current_password":"testpassword","new_password":"password1"}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code: %d for a common password, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	resp = send(http.MethodPost, "/api/auth/password", "owner_token", `{"current_password":"testpassword","new_password":"correct horse battery"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	// Other sessions are logged out, the current one stays
+	if resp := send(http.MethodGet, "/api/auth/sessions", "other_token", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the other session to be revoked, got %d", resp.StatusCode)
+	}
+	if resp := send(http.MethodGet, "/api/auth/sessions", "owner_token", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the current session to stay, got %d", resp.StatusCode)
+	}
+	if resp := send(http.MethodPost, "/api/auth/login", "", `{"username":"testuser","password":"correct horse battery"}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected login with the new password, got %d", resp.StatusCode)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	h, store, send := setupAccountApp(t)
+	sender := &recordingSender{messages: make(chan notify.Message, 1)}
+	h.SetNotifier(sender)
+	h.SetPasswordResetURL("https://plan.example.com/reset")
+
+	noMessage := func(reason string) {
+		t.Helper()
+		select {
+		case msg := <-sender.messages:
+			t.Errorf("Expected no message %s, got %+v", reason, msg)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	// Without an email address nothing is sent, and nobody can tell
+	resp := send(http.MethodPost, "/api/auth/password-reset", "", `{"username":"testuser"}`)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusAccepted, resp.StatusCode)
+	}
+	noMessage("without an email address")
+	if resp := send(http.MethodPost, "/api/auth/password-reset", "", `{"username":"nobody"}`); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected status code: %d for an unknown user, got %d", http.StatusAccepted, resp.StatusCode)
+	}
+	noMessage("for an unknown user")
+
+	if resp := send(http.MethodPut, "/api/auth/email", "owner_token", `{"email":"test@example.com","password":"wrong"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status code: %d for a wrong password, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+	if resp := send(http.MethodPut, "/api/auth/email", "owner_token", `{"email":"not an address","password":"testpassword"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code: %d for an invalid email, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+	if resp := send(http.MethodPut, "/api/auth/email", "owner_token", `{"email":"test@example.com","password":"testpassword"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	send(http.MethodPost, "/api/auth/password-reset", "", `{"username":"testuser"}`)
+	var msg notify.Message
+	select {
+	case msg = <-sender.messages:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a reset message")
+	}
+	token := regexp.MustCompile(`#token=([0-9a-f]+)`).FindStringSubmatch(msg.Body)
+	if msg.To != "test@example.com" || token == nil {
+		t.Fatalf("Unexpected message: %+v", msg)
+	}
+
+	if resp := send(http.MethodPost, "/api/auth/password-reset/confirm", "", `{"token":"wrong","password":"correct horse battery"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code: %d for a wrong token, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+	if resp := send(http.MethodPost, "/api/auth/password-reset/confirm", "", `{"token":"`+token[1]+`","password":"qwerty"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code: %d for a weak password, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+	resp = send(http.MethodPost, "/api/auth/password-reset/confirm", "", `{"token":"`+token[1]+`","password":"correct horse battery"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	// The token works once, and all sessions are logged out
+	if resp := send(http.MethodPost, "/api/auth/password-reset/confirm", "", `{"token":"`+token[1]+`","password":"another good password"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code: %d for a used token, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+	if resp := send(http.MethodGet, "/api/auth/sessions", "owner_token", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected sessions to be revoked, got %d", resp.StatusCode)
+	}
+	if resp := send(http.MethodPost, "/api/auth/login", "", `{"username":"testuser","password":"correct horse battery"}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected login with the new password, got %d", resp.StatusCode)
+	}
+
+	// Users of single sign-on have no password to reset
+	sso, _ := store.CreateUserWithIdentity("sso@example.com", "https://issuer.example.com", "subject", "sso@example.com")
+	store.SetUserEmail(sso.ID, "sso@example.com")
+	send(http.MethodPost, "/api/auth/password-reset", "", `{"username":"sso@example.com"}`)
+	noMessage("for a single sign-on user")
+}
+
+func TestDeleteAccount(t *testing.T) {
+	_, store, send := setupAccountApp(t)
+	user, _, _ := store.GetUserByUsername("testuser")
+	personal := personalWorkspace(t, store, user.ID)
+	store.CreateMember(personal, "Alice")
+
+	other, _ := store.CreateUser("other", "password")
+	shared := personalWorkspace(t, store, other.ID)
+	store.SetWorkspaceUserRole(shared, user.ID, models.RoleOwner)
+	store.SetWorkspaceUserRole(shared, other.ID, models.RoleMember)
+
+	if resp := send(http.MethodDelete, "/api/auth/account", "owner_token", `{"password":"wrong"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status code: %d for a wrong password, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+	if resp := send(http.MethodDelete, "/api/auth/account", "owner_token", `{"password":"testpassword"}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status code: %d as the only owner of a shared workspace, got %d", http.StatusConflict, resp.StatusCode)
+	}
+
+	store.SetWorkspaceUserRole(shared, other.ID, models.RoleOwner)
+	resp := send(http.MethodDelete, "/api/auth/account", "owner_token", `{"password":"testpassword"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if !strings.Contains(resp.Header.Get(fiber.HeaderContentDisposition), "shiftplanner-account-") {
+		t.Errorf("Expected attachment, got %q", resp.Header.Get(fiber.HeaderContentDisposition))
+	}
+
+	var export models.AccountExport
+	if err := json.NewDecoder(resp.Body).Decode(&export); err != nil {
+		t.Fatalf("Failed to decode export: %v", err)
+	}
+	if export.User.Username != "testuser" || len(export.Workspaces) != 2 || len(export.Sessions) != 1 {
+		t.Fatalf("Unexpected export: %+v", export)
+	}
+	for _, workspace := range export.Workspaces {
+		switch workspace.ID {
+		case personal:
+			if workspace.Backup == nil || len(workspace.Backup.Members) != 1 {
+				t.Errorf("Expected a backup of the personal workspace, got %+v", workspace.Backup)
+			}
+		case shared:
+			if workspace.Backup != nil {
+				t.Error("Expected no backup of the shared workspace")
+			}
+		}
+	}
+
+	if _, err := store.GetUserByID(user.ID); err == nil {
+		t.Error("Expected the user to be deleted")
+	}
+	if resp := send(http.MethodGet, "/api/auth/sessions", "owner_token", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the session to be gone, got %d", resp.StatusCode)
+	}
+	if _, err := store.GetWorkspace(shared); err != nil {
+		t.Errorf("Expected the shared workspace to stay, got %v", err)
+	}
+}
+
+func TestDeleteAccount_SingleSignOn(t *testing.T) {
+	h, store, _ := setupTestAPI(t)
+	sso, _ := store.CreateUserWithIdentity("sso@example.com", "https://issuer.example.com", "subject", "sso@example.com")
+	store.CreateSession(sso.ID, auth.HashToken("sso_token"), "", "", time.Now().Add(time.Hour))
+
+	// Pretend the session's login was an hour ago
+	stale := func(c *fiber.Ctx) error {
+		c.Locals(sessionLoginKey, time.Now().Add(-time.Hour))
+		return c.Next()
+	}
+	app := fiber.New()
+	app.Delete("/api/auth/account/stale", h.AuthMiddleware, stale, h.DeleteAccount)
+	app.Delete("/api/auth/account", h.AuthMiddleware, h.DeleteAccount)
+
+	send := func(path, body string) int {
+		req := httptest.NewRequest(http.MethodDelete, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "sso_token")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if got := send("/api/auth/account/stale", ""); got != http.StatusUnauthorized {
+		t.Errorf("Expected status code: %d for an old login, got %d", http.StatusUnauthorized, got)
+	}
+
+	// With two-factor enabled, a code is required however recent the login
+	secret, _ := auth.GenerateTOTPSecret()
+	store.SetTOTPSecret(sso.ID, secret)
+	store.EnableTwoFactor(sso.ID, nil, time.Now())
+	if got := send("/api/auth/account", `{"code":"000000"}`); got != http.StatusUnauthorized {
+		t.Errorf("Expected status code: %d for a wrong code, got %d", http.StatusUnauthorized, got)
+	}
+	code, _ := auth.TOTPCode(secret, auth.TOTPStep(time.Now()))
+	if got := send("/api/auth/account/stale", `{"code":"`+code+`"}`); got != http.StatusOK {
+		t.Fatalf("Expected status code: %d with a valid code, got %d", http.StatusOK, got)
+	}
+	if _, err := store.GetUserByID(sso.ID); err == nil {
+		t.Error("Expected the user to be deleted")
+	}
+}
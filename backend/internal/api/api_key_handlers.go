@@ -0,0 +1,116 @@
+package api
+
+import (
+	"shiftplanner/backend/internal/auth"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/storage"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAPIKeys lists the API keys of the authenticated user for the active workspace
+func (h *Handler) GetAPIKeys(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	keys, err := h.apiKeys.GetAPIKeys(userID, workspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+
+	return c.JSON(keys)
+}
+
+// CreateAPIKey creates an API key for the active workspace
+// The key is returned once; only its prefix and hash are stored.
+func (h *Handler) CreateAPIKey(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		Name  string `json:"name"`
+		Scope string `json:"scope"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Name is required",
+		})
+	}
+	if req.Scope == "" {
+		req.Scope = models.APIKeyScopeReadOnly
+	}
+	if !models.IsValidAPIKeyScope(req.Scope) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid scope",
+		})
+	}
+	if !models.RoleAtLeast(GetRole(c), models.APIKeyScopeRole(req.Scope)) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Scope needs a role above your own",
+		})
+	}
+
+	apiKey, err := auth.CreateAPIKey(h.apiKeys, userID, workspaceID, req.Name, req.Scope)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(apiKey)
+}
+
+// DeleteAPIKey revokes an API key of the authenticated user
+func (h *Handler) DeleteAPIKey(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID",
+		})
+	}
+
+	if err := h.apiKeys.DeleteAPIKey(userID, id); err != nil {
+		if err == storage.ErrAPIKeyNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "API key not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shiftplanner/backend/internal/models"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAPIKeys(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	app := fiber.New()
+	app.Get("/api/api-keys", h.AuthMiddleware, h.GetAPIKeys)
+	app.Post("/api/api-keys", h.AuthMiddleware, h.CreateAPIKey)
+	app.Delete("/api/api-keys/:id", h.AuthMiddleware, h.DeleteAPIKey)
+	app.Get("/api/members", h.AuthMiddleware, h.GetMembers)
+	app.Post("/api/members", h.AuthMiddleware, h.CreateMember)
+	app.Get("/api/backup", h.AuthMiddleware, h.ExportBackup)
+
+	send := func(method, path, token, body string) *http.Response {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		resp, _ := app.Test(req)
+		return resp
+	}
+	createKey := func(scope string) models.APIKey {
+		resp := send(http.MethodPost, "/api/api-keys", token, `{"name":"CI","scope":"`+scope+`"}`)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status code: %d, got %d", http.StatusCreated, resp.StatusCode)
+		}
+		var apiKey models.APIKey
+		json.NewDecoder(resp.Body).Decode(&apiKey)
+		return apiKey
+	}
+
+	tests := []struct {
+		scope              string
+		read, edit, backup int
+	}{
+		{models.APIKeyScopeReadOnly, http.StatusOK, http.StatusForbidden, http.StatusForbidden},
+		{models.APIKeyScopeScheduleWrite, http.StatusOK, http.StatusCreated, http.StatusForbidden},
+		{models.APIKeyScopeAdmin, http.StatusOK, http.StatusCreated, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			key := createKey(tt.scope).Key
+			if got := send(http.MethodGet, "/api/members", key, "").StatusCode; got != tt.read {
+				t.Errorf("GET /api/members: got %d, want %d", got, tt.read)
+			}
+			if got := send(http.MethodPost, "/api/members", key, `{"name":"New `+tt.scope+`"}`).StatusCode; got != tt.edit {
+				t.Errorf("POST /api/members: got %d, want %d", got, tt.edit)
+			}
+			if got := send(http.MethodGet, "/api/backup", key, "").StatusCode; got != tt.backup {
+				t.Errorf("GET /api/backup: got %d, want %d", got, tt.backup)
+			}
+		})
+	}
+
+	apiKey := createKey(models.APIKeyScopeAdmin)
+
+	// Keys can't manage keys
+	if resp := send(http.MethodPost, "/api/api-keys", apiKey.Key, `{"name":"Nested"}`); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Creating a key with a key: expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	// Keys only work in their own workspace
+	req := httptest.NewRequest(http.MethodGet, "/api/members", nil)
+	req.Header.Set("Authorization", apiKey.Key)
+	req.Header.Set("X-Workspace-ID", strconv.Itoa(workspaceID+1))
+	if resp, _ := app.Test(req); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Other workspace: expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	resp := send(http.MethodGet, "/api/api-keys", token, "")
+	var keys []models.APIKey
+	json.NewDecoder(resp.Body).Decode(&keys)
+	if len(keys) != 4 || keys[0].Key != "" || keys[0].LastUsedAt == nil || keys[3].LastUsedAt == nil {
+		t.Errorf("Expected 4 used keys without the key itself, got %+v", keys)
+	}
+
+	if resp := send(http.MethodDelete, "/api/api-keys/"+strconv.Itoa(apiKey.ID), token, ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status code: %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if resp := send(http.MethodGet, "/api/members", apiKey.Key, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Revoked key: expected %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+}
+
+func TestAPIKeys_CappedByUserRole(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+	user, _ := store.CreateUser("scheduler", "testpassword")
+	token := "test_token_scheduler"
+	createTestSession(t, store, personalWorkspace(t, store, user.ID), token)
+	store.SetWorkspaceUserRole(workspaceID, user.ID, models.RoleScheduler)
+
+	app := fiber.New()
+	app.Post("/api/api-keys", h.AuthMiddleware, h.CreateAPIKey)
+	app.Post("/api/members", h.AuthMiddleware, h.CreateMember)
+
+	send := func(path, token, body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		req.Header.Set("X-Workspace-ID", strconv.Itoa(workspaceID))
+		resp, _ := app.Test(req)
+		return resp
+	}
+
+	if resp := send("/api/api-keys", token, `{"name":"CI","scope":"admin"}`); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Scope above own role: expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	resp := send("/api/api-keys", token, `{"name":"CI","scope":"schedule-write"}`)
+	var apiKey models.APIKey
+	json.NewDecoder(resp.Body).Decode(&apiKey)
+
+	// A key follows its user's role down
+	store.SetWorkspaceUserRole(workspaceID, user.ID, models.RoleViewer)
+	if resp := send("/api/members", apiKey.Key, `{"name":"New"}`); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Demoted user: expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+	store.RemoveWorkspaceUser(workspaceID, user.ID)
+	if resp := send("/api/members", apiKey.Key, `{"name":"New"}`); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Removed user: expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"shiftplanner/backend/internal/models"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAuditLog lists the audit log of the workspace, newest first
+// Optional filters: entity, member_id, start_date and end_date (YYYY-MM-DD, inclusive)
+func (h *Handler) GetAuditLog(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	var filter models.AuditFilter
+
+	filter.Entity = c.Query("entity")
+	if filter.Entity != "" && !models.IsValidAuditEntity(filter.Entity) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid entity",
+		})
+	}
+
+	if memberIDStr := c.Query("member_id"); memberIDStr != "" {
+		var err error
+		filter.MemberID, err = strconv.Atoi(memberIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid member_id",
+			})
+		}
+	}
+
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		var err error
+		filter.StartDate, err = parseDateParam(startDateStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid start_date format (use YYYY-MM-DD)",
+			})
+		}
+	}
+
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		var err error
+		filter.EndDate, err = parseDateParam(endDateStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid end_date format (use YYYY-MM-DD)",
+			})
+		}
+	}
+
+	entries, err := h.audit.GetAuditEntries(workspaceID, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+
+	return c.JSON(entries)
+}
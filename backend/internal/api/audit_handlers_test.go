@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shiftplanner/backend/internal/models"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestGetAuditLog(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	app := fiber.New()
+	app.Post("/api/members", h.AuthMiddleware, h.CreateMember)
+	app.Delete("/api/members/:id", h.AuthMiddleware, h.DeleteMember)
+	app.Get("/api/audit", h.AuthMiddleware, h.GetAuditLog)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/members", bytes.NewBufferString(`{"name":"Alice"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	req.Header.Set("X-Request-ID", "req-create")
+	resp, _ := app.Test(req)
+
+	var member models.Member
+	json.NewDecoder(resp.Body).Decode(&member)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/members/"+strconv.Itoa(member.ID), nil)
+	req.Header.Set("Authorization", token)
+	app.Test(req)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/audit?entity=member&member_id="+strconv.Itoa(member.ID), nil)
+	req.Header.Set("Authorization", token)
+	resp, _ = app.Test(req)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var entries []models.AuditEntry
+	json.NewDecoder(resp.Body).Decode(&entries)
+
+	user, _, _ := store.GetUserByUsername("testuser")
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %d", len(entries))
+	}
+	if entries[0].Action != models.AuditActionDelete || entries[1].Action != models.AuditActionCreate {
+		t.Errorf("Expected delete then create, got %s and %s", entries[0].Action, entries[1].Action)
+	}
+	if entries[1].RequestID != "req-create" || entries[1].ActorUserID != user.ID {
+		t.Errorf("Create entry mismatch: %+v", entries[1])
+	}
+}
+
+func TestGetAuditLog_InvalidFilter(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	app := fiber.New()
+	app.Get("/api/audit", h.AuthMiddleware, h.GetAuditLog)
+
+	for _, query := range []string{"entity=planet", "member_id=abc", "start_date=2025-13-01"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/audit?"+query, nil)
+		req.Header.Set("Authorization", token)
+		resp, _ := app.Test(req)
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", query, http.StatusBadRequest, resp.StatusCode)
+		}
+	}
+}
@@ -1,16 +1,24 @@
 package api
 
 import (
+	"log"
+	"math"
 	"shiftplanner/backend/internal/auth"
+	"shiftplanner/backend/internal/models"
 	"shiftplanner/backend/internal/storage"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // RegisterRequest registration request
+// Email is optional; without it the password can't be reset.
 type RegisterRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Email    string `json:"email"`
 }
 
 // LoginRequest login request
@@ -20,7 +28,7 @@ type LoginRequest struct {
 }
 
 // Register registers a new user
-func Register(c *fiber.Ctx) error {
+func (h *Handler) Register(c *fiber.Ctx) error {
 	var req RegisterRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -28,36 +36,37 @@ func Register(c *fiber.Ctx) error {
 		})
 	}
 
-	if req.Username == "" || req.Password == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Username and password are required",
-		})
-	}
-
-	// Username validation
-	if len(req.Username) < 3 {
+	if msg := h.validateRegistration(req.Username, req.Password); msg != "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Username must be at least 3 characters",
+			"error": msg,
 		})
 	}
-
-	if len(req.Password) < 4 {
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email != "" && !validEmail(req.Email) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Password must be at least 4 characters",
+			"error": "Invalid email",
 		})
 	}
 
 	// Create user
-	user, err := storage.CreateUser(req.Username, req.Password)
+	user, err := h.users.CreateUser(req.Username, req.Password)
 	if err != nil {
 		// Username might already be in use
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 			"error": "Username already exists",
 		})
 	}
+	if req.Email != "" {
+		if err := h.users.SetUserEmail(user.ID, req.Email); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		user.Email = req.Email
+	}
 
 	// Create session
-	session, err := auth.CreateSession(user.ID)
+	session, err := h.createSession(c, user.ID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -71,7 +80,7 @@ func Register(c *fiber.Ctx) error {
 }
 
 // Login handles user login
-func Login(c *fiber.Ctx) error {
+func (h *Handler) Login(c *fiber.Ctx) error {
 	var req LoginRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -85,40 +94,36 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
-	// Find user
-	user, passwordHash, err := storage.GetUserByUsername(req.Username)
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid username or password",
-		})
-	}
-
-	// Validate password
-	if !storage.ValidatePassword(req.Password, passwordHash) {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid username or password",
-		})
+	user, wait := h.authenticate(req.Username, req.Password, strings.Clone(c.IP()))
+	if user == nil {
+		return loginFailed(c, wait)
 	}
 
-	// Create session
-	session, err := auth.CreateSession(user.ID)
+	token, preAuthToken, err := h.logIn(c, user.ID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
+	if preAuthToken != "" {
+		return c.JSON(fiber.Map{
+			"two_factor_required": true,
+			"pre_auth_token":      preAuthToken,
+		})
+	}
+	h.logins.Succeed(user.Username)
 
 	return c.JSON(fiber.Map{
 		"user":  user,
-		"token": session.Token,
+		"token": token,
 	})
 }
 
 // Logout handles user logout
-func Logout(c *fiber.Ctx) error {
+func (h *Handler) Logout(c *fiber.Ctx) error {
 	token := c.Get("Authorization")
 	if token != "" {
-		if err := auth.DeleteSession(token); err != nil {
+		if err := auth.DeleteSession(h.sessions, token); err != nil {
 			// Return 200 even if error (idempotent)
 		}
 	}
@@ -127,3 +132,137 @@ func Logout(c *fiber.Ctx) error {
 		"message": "Logged out successfully",
 	})
 }
+
+// validateRegistration checks the credentials of a new user
+// It returns the message to show, or "" when they are acceptable.
+func (h *Handler) validateRegistration(username, password string) string {
+	if username == "" || password == "" {
+		return "Username and password are required"
+	}
+	if len(username) < 3 {
+		return "Username must be at least 3 characters"
+	}
+	return h.passwordPolicy.Check(username, password)
+}
+
+// authenticate checks a username and password, returning nil when they don't match
+// While the username or the client's IP address is locked out after failed
+// attempts, the password isn't checked and the remaining lockout is returned.
+// A correct password doesn't reset the failures; callers do that once the
+// login is complete, after the second factor if there is one.
+func (h *Handler) authenticate(username, password, ip string) (*models.User, time.Duration) {
+	if wait := h.logins.Wait(username, ip); wait > 0 {
+		return nil, wait
+	}
+
+	user, passwordHash, err := h.users.GetUserByUsername(username)
+	if err != nil {
+		// Check against no hash, which costs as much as a real one, so
+		// unknown usernames can't be told apart by the response time
+		passwordHash = ""
+	}
+	if !storage.ValidatePassword(password, passwordHash) || err != nil {
+		h.logins.Fail(username, ip)
+		return nil, 0
+	}
+
+	// Upgrade legacy and outdated hashes now that the password is known
+	if storage.PasswordNeedsRehash(passwordHash) {
+		if err := h.users.UpdatePassword(user.ID, password); err != nil {
+			log.Printf("Warning: failed to rehash password of user %d: %v", user.ID, err)
+		}
+	}
+	return user, 0
+}
+
+// loginFailed responds to a rejected password login, with 429 during a lockout
+func loginFailed(c *fiber.Ctx, wait time.Duration) error {
+	if wait > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": "Too many failed logins, try again later",
+		})
+	}
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": "Invalid username or password",
+	})
+}
+
+// GetSessions lists the active sessions of the authenticated user
+func (h *Handler) GetSessions(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := h.sessions.GetUserSessions(userID, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if sessions == nil {
+		sessions = []models.Session{}
+	}
+	currentID, _ := c.Locals(sessionIDKey).(int)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	return c.JSON(sessions)
+}
+
+// RevokeSession logs out one of the sessions of the authenticated user
+func (h *Handler) RevokeSession(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID",
+		})
+	}
+
+	if err := h.sessions.DeleteUserSession(userID, id); err != nil {
+		if err == storage.ErrSessionNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Session not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// logIn finishes a login that passed the first factor
+// Users with two-factor enabled get a pre-auth token instead of a session token,
+// to exchange together with a code at /api/auth/login/2fa.
+func (h *Handler) logIn(c *fiber.Ctx, userID int) (token, preAuthToken string, err error) {
+	enabled, err := h.hasTwoFactor(userID)
+	if err != nil {
+		return "", "", err
+	}
+	if enabled {
+		preAuthToken, err := auth.StartTwoFactorLogin(h.twoFactors, userID)
+		return "", preAuthToken, err
+	}
+
+	session, err := h.createSession(c, userID)
+	if err != nil {
+		return "", "", err
+	}
+	return session.Token, "", nil
+}
+
+// createSession logs a user in from the client of the request
+// Header values are only valid during the request, so they are copied before they are stored.
+func (h *Handler) createSession(c *fiber.Ctx, userID int) (*models.Session, error) {
+	return auth.CreateSession(h.sessions, userID, strings.Clone(c.Get(fiber.HeaderUserAgent)), strings.Clone(c.IP()))
+}
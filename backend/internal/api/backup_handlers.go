@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"shiftplanner/backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Restore modes of RestoreBackup
+const (
+	restoreModeMerge   = "merge"
+	restoreModeReplace = "replace"
+)
+
+// ExportBackup downloads the whole workspace as a JSON backup archive
+func (h *Handler) ExportBackup(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleOwner)
+	if err != nil {
+		return err
+	}
+
+	backup, err := h.backups.ExportBackup(workspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to write backup",
+		})
+	}
+
+	filename := fmt.Sprintf("shiftplanner-backup-%s.json", backup.ExportedAt.Format("2006-01-02"))
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Send(data)
+}
+
+// RestoreBackup restores a JSON backup archive into the workspace
+// The archive is read from an uploaded "file" or the request body.
+// mode=merge (default) keeps existing data, mode=replace deletes it first.
+func (h *Handler) RestoreBackup(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleOwner)
+	if err != nil {
+		return err
+	}
+
+	mode := c.Query("mode", restoreModeMerge)
+	if mode != restoreModeMerge && mode != restoreModeReplace {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid mode (use merge or replace)",
+		})
+	}
+
+	data := c.Body()
+	if file, err := c.FormFile("file"); err == nil {
+		src, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to open file",
+			})
+		}
+		defer src.Close()
+
+		data, err = io.ReadAll(src)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to read file",
+			})
+		}
+	}
+
+	var backup models.Backup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid backup archive",
+		})
+	}
+
+	result, err := h.backups.RestoreBackup(workspaceID, &backup, mode == restoreModeReplace)
+	if err != nil {
+		if errors.Is(err, models.ErrBackupTooNew) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":       err.Error(),
+				"version":     backup.Version,
+				"max_version": models.BackupVersion,
+			})
+		}
+		if errors.Is(err, models.ErrInvalidBackup) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"mode":     mode,
+		"version":  backup.Version,
+		"restored": result,
+	})
+}
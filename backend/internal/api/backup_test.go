@@ -0,0 +1,168 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"shiftplanner/backend/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestBackup_RoundTrip(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC) }
+
+	alice, _ := store.CreateMember(workspaceID, "Alice")
+	bob, _ := store.CreateMember(workspaceID, "Bob")
+	store.UpdateHiddenShiftCounts(workspaceID, alice.ID, 3, 1)
+	store.CreateShift(workspaceID, alice.ID, day(3), day(3), false)
+	store.CreateShift(workspaceID, bob.ID, day(7), day(9), true)
+	store.CreateLeaveDay(workspaceID, bob.ID, day(20), models.LeaveTypeSick)
+	request, _ := store.CreateLeaveRequest(workspaceID, alice.ID, day(10), day(11), models.LeaveTypeAnnual, "Trip")
+	store.ApproveLeaveRequest(workspaceID, request.ID)
+	store.SetLeaveAllowance(workspaceID, alice.ID, 2025, models.LeaveTypeAnnual, 25)
+	store.CreateUnavailabilityRule(workspaceID, models.UnavailabilityRule{
+		MemberID:  bob.ID,
+		Frequency: models.FrequencyWeekly,
+		Weekdays:  []time.Weekday{time.Monday, time.Friday},
+		StartDate: day(1),
+	})
+
+	app := fiber.New()
+	app.Get("/api/backup", h.AuthMiddleware, h.ExportBackup)
+	app.Post("/api/backup/restore", h.AuthMiddleware, h.RestoreBackup)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/backup", nil)
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if !strings.Contains(resp.Header.Get(fiber.HeaderContentDisposition), "shiftplanner-backup-") {
+		t.Errorf("Expected attachment, got %q", resp.Header.Get(fiber.HeaderContentDisposition))
+	}
+
+	archive, _ := io.ReadAll(resp.Body)
+	var backup models.Backup
+	if err := json.Unmarshal(archive, &backup); err != nil {
+		t.Fatalf("Failed to parse archive: %v", err)
+	}
+	if backup.Format != models.BackupFormat || backup.Version != models.BackupVersion {
+		t.Errorf("Unexpected format/version: %s %d", backup.Format, backup.Version)
+	}
+	if len(backup.Members) != 2 || len(backup.Shifts) != 2 || len(backup.LeaveRequests) != 1 ||
+		len(backup.LeaveDays) != 3 || len(backup.LeaveAllowances) != 1 || len(backup.UnavailabilityRules) != 1 {
+		t.Fatalf("Unexpected archive contents: %s", archive)
+	}
+
+	// Restore into another user's workspace which already has a member with a clashing ID
+	other, _ := store.CreateUser("otheruser", "testpassword")
+	otherID := personalWorkspace(t, store, other.ID)
+	otherToken := "test_token_456"
+	createTestSession(t, store, otherID, otherToken)
+	store.CreateMember(otherID, "Carol")
+
+	req = newUploadRequest(t, "/api/backup/restore?mode=replace", "backup.json", archive)
+	req.Header.Set("Authorization", otherToken)
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected status code: %d, got %d: %s", http.StatusOK, resp.StatusCode, body)
+	}
+
+	members, _ := store.GetAllMembers(otherID)
+	if len(members) != 2 {
+		t.Fatalf("Expected 2 members after replace, got %d", len(members))
+	}
+	restoredAlice, _ := store.GetMemberByName(otherID, "Alice")
+	restoredBob, _ := store.GetMemberByName(otherID, "Bob")
+	if restoredAlice == nil || restoredBob == nil {
+		t.Fatal("Expected Alice and Bob to be restored")
+	}
+	wantNormal, wantLong, _ := store.GetHiddenShiftCounts(workspaceID, alice.ID)
+	normal, long, _ := store.GetHiddenShiftCounts(otherID, restoredAlice.ID)
+	if normal != wantNormal || long != wantLong {
+		t.Errorf("Hidden counters mismatch: got %d/%d, want %d/%d", normal, long, wantNormal, wantLong)
+	}
+
+	shifts, _ := store.GetShiftsByDateRange(otherID, day(1), day(31))
+	if len(shifts) != 2 || shifts[1].MemberID != restoredBob.ID || !shifts[1].IsLongShift || !shifts[1].EndDate.Equal(day(9)) {
+		t.Errorf("Shifts mismatch: %+v", shifts)
+	}
+
+	requests, _ := store.GetLeaveRequests(otherID, "", 0)
+	if len(requests) != 1 || requests[0].MemberID != restoredAlice.ID || requests[0].Status != models.LeaveStatusApproved {
+		t.Errorf("Leave requests mismatch: %+v", requests)
+	}
+	rules, _ := store.GetUnavailabilityRules(otherID, restoredBob.ID)
+	if len(rules) != 1 || len(rules[0].Weekdays) != 2 {
+		t.Errorf("Unavailability rules mismatch: %+v", rules)
+	}
+
+	// Merging the same archive again doesn't duplicate anything
+	req = httptest.NewRequest(http.MethodPost, "/api/backup/restore", bytes.NewReader(archive))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", otherToken)
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var merged struct {
+		Restored models.RestoreResult `json:"restored"`
+	}
+	json.NewDecoder(resp.Body).Decode(&merged)
+	if merged.Restored.Members != 0 || merged.Restored.Shifts != 0 || merged.Restored.LeaveRequests != 0 || merged.Restored.LeaveDays != 0 {
+		t.Errorf("Merge should skip existing data, got %+v", merged.Restored)
+	}
+}
+
+func TestBackup_RestoreRejectsInvalidArchives(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+	store.CreateMember(workspaceID, "Alice")
+
+	app := fiber.New()
+	app.Post("/api/backup/restore", h.AuthMiddleware, h.RestoreBackup)
+
+	tests := []struct {
+		name    string
+		archive string
+	}{
+		{"not json", `not json`},
+		{"wrong format", `{"format":"other","version":1}`},
+		{"newer version", `{"format":"shiftplanner-backup","version":99}`},
+		{"unknown member", `{"format":"shiftplanner-backup","version":1,"shifts":[{"member_id":5,"start_date":"2025-01-01","end_date":"2025-01-01"}]}`},
+		{"invalid date", `{"format":"shiftplanner-backup","version":1,"members":[{"id":1,"name":"Bob"}],"shifts":[{"member_id":1,"start_date":"01.01.2025","end_date":"2025-01-01"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/backup/restore?mode=replace", strings.NewReader(tt.archive))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", token)
+			resp, _ := app.Test(req)
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected status code: %d, got %d", http.StatusBadRequest, resp.StatusCode)
+			}
+		})
+	}
+
+	// Failed restores leave the workspace untouched
+	members, _ := store.GetAllMembers(workspaceID)
+	if len(members) != 1 || members[0].Name != "Alice" {
+		t.Errorf("Workspace changed by failed restore: %+v", members)
+	}
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"shiftplanner/backend/internal/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+// Export formats
+const (
+	exportFormatCSV  = "csv"
+	exportFormatXLSX = "xlsx"
+)
+
+// exportShiftHeader header of exported shift rows
+// The layout matches what ImportShifts reads without an explicit mapping
+var exportShiftHeader = []string{"Date", "Name", "End Date", "Shift Type"}
+
+// exportStatsHeader header of the per-member stats sheet
+var exportStatsHeader = []string{"Name", "Shifts", "Total Days", "Normal Days", "Long Shifts", "Long Shift Days", "Leave Days"}
+
+// exportLeaveHeader header of the leave days sheet
+var exportLeaveHeader = []string{"Date", "Name", "Leave Type"}
+
+// ExportShifts exports shifts as a CSV or Excel file
+// Query: format (csv or xlsx, default csv), start_date, end_date.
+// The shift rows can be imported again with ImportShifts. The Excel file also
+// contains a sheet with per-member stats and a sheet with leave days.
+func (h *Handler) ExportShifts(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	format := c.Query("format", exportFormatCSV)
+	if format != exportFormatCSV && format != exportFormatXLSX {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid format (use csv or xlsx)",
+		})
+	}
+
+	// Same default range as GetShifts: last month to next month
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	startDate := today.AddDate(0, -1, 0)
+	endDate := today.AddDate(0, 1, 0)
+
+	if value := c.Query("start_date"); value != "" {
+		parsedDate, err := parseDateParam(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid start_date format (use YYYY-MM-DD)",
+			})
+		}
+		startDate = parsedDate
+	}
+
+	if value := c.Query("end_date"); value != "" {
+		parsedDate, err := parseDateParam(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid end_date format (use YYYY-MM-DD)",
+			})
+		}
+		endDate = parsedDate
+	}
+
+	if endDate.Before(startDate) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "end_date must be after or equal to start_date",
+		})
+	}
+
+	shifts, err := h.shifts.GetShiftsByDateRange(workspaceID, startDate, endDate)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	members, err := h.members.GetAllMembers(workspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	memberMap := make(map[int]string)
+	for _, m := range members {
+		memberMap[m.ID] = m.Name
+	}
+
+	shiftRows := exportShiftRows(shifts, memberMap)
+	filename := fmt.Sprintf("shifts_%s_%s.%s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), format)
+
+	if format == exportFormatCSV {
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		writer.Write(exportShiftHeader)
+		writer.WriteAll(shiftRows)
+		if err := writer.Error(); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to write CSV",
+			})
+		}
+
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+		return c.Send(buf.Bytes())
+	}
+
+	leaveDays, err := h.leave.GetLeaveDaysByDateRange(workspaceID, startDate, endDate)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	buf, err := writeShiftWorkbook(shiftRows, exportStatsRows(members, shifts, leaveDays), exportLeaveRows(leaveDays, memberMap))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to write Excel file",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Send(buf.Bytes())
+}
+
+// exportShiftRows converts shifts to export rows (date, name, end date, shift type)
+func exportShiftRows(shifts []models.Shift, memberMap map[int]string) [][]string {
+	rows := make([][]string, 0, len(shifts))
+	for _, shift := range shifts {
+		shiftType := models.ShiftTypeNormal
+		if shift.IsLongShift {
+			shiftType = models.ShiftTypeLong
+		}
+		rows = append(rows, []string{
+			shift.StartDate.Format("2006-01-02"),
+			memberMap[shift.MemberID],
+			shift.EndDate.Format("2006-01-02"),
+			shiftType,
+		})
+	}
+	return rows
+}
+
+// exportStatsRows builds per-member stats for the exported shifts and leave days
+func exportStatsRows(members []models.Member, shifts []models.Shift, leaveDays []models.LeaveDay) [][]interface{} {
+	type memberStats struct {
+		shifts, normalDays, longShifts, longDays, leaveDays int
+	}
+
+	stats := make(map[int]*memberStats)
+	for _, m := range members {
+		stats[m.ID] = &memberStats{}
+	}
+
+	for _, shift := range shifts {
+		s, ok := stats[shift.MemberID]
+		if !ok {
+			continue
+		}
+		shiftDays := int(shift.EndDate.Sub(shift.StartDate).Hours()/24) + 1
+		s.shifts++
+		if shift.IsLongShift {
+			s.longShifts++
+			s.longDays += shiftDays
+		} else {
+			s.normalDays += shiftDays
+		}
+	}
+
+	for _, ld := range leaveDays {
+		if s, ok := stats[ld.MemberID]; ok {
+			s.leaveDays++
+		}
+	}
+
+	rows := make([][]interface{}, 0, len(members))
+	for _, m := range members {
+		s := stats[m.ID]
+		rows = append(rows, []interface{}{m.Name, s.shifts, s.normalDays + s.longDays, s.normalDays, s.longShifts, s.longDays, s.leaveDays})
+	}
+	return rows
+}
+
+// exportLeaveRows converts leave days to export rows (date, name, leave type)
+func exportLeaveRows(leaveDays []models.LeaveDay, memberMap map[int]string) [][]string {
+	rows := make([][]string, 0, len(leaveDays))
+	for _, ld := range leaveDays {
+		rows = append(rows, []string{ld.LeaveDate.Format("2006-01-02"), memberMap[ld.MemberID], ld.LeaveType})
+	}
+	return rows
+}
+
+// writeShiftWorkbook writes the Shifts, Stats and Leave sheets to an Excel workbook
+// Shifts is the first sheet so ImportShifts reads it by default
+func writeShiftWorkbook(shiftRows [][]string, statsRows [][]interface{}, leaveRows [][]string) (*bytes.Buffer, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName("Sheet1", "Shifts"); err != nil {
+		return nil, err
+	}
+	if err := writeSheet(f, "Shifts", exportShiftHeader, stringRows(shiftRows)); err != nil {
+		return nil, err
+	}
+
+	if _, err := f.NewSheet("Stats"); err != nil {
+		return nil, err
+	}
+	if err := writeSheet(f, "Stats", exportStatsHeader, statsRows); err != nil {
+		return nil, err
+	}
+
+	if _, err := f.NewSheet("Leave"); err != nil {
+		return nil, err
+	}
+	if err := writeSheet(f, "Leave", exportLeaveHeader, stringRows(leaveRows)); err != nil {
+		return nil, err
+	}
+
+	return f.WriteToBuffer()
+}
+
+// writeSheet writes a header row followed by rows to a sheet
+func writeSheet(f *excelize.File, sheet string, header []string, rows [][]interface{}) error {
+	headerRow := make([]interface{}, len(header))
+	for i, h := range header {
+		headerRow[i] = h
+	}
+	if err := f.SetSheetRow(sheet, "A1", &headerRow); err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stringRows converts string rows to the generic rows accepted by writeSheet
+func stringRows(rows [][]string) [][]interface{} {
+	result := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		result[i] = make([]interface{}, len(row))
+		for j, value := range row {
+			result[i][j] = value
+		}
+	}
+	return result
+}
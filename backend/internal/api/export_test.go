@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExportShifts_CSVRoundTrip(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	alice, _ := store.CreateMember(workspaceID, "Alice")
+	bob, _ := store.CreateMember(workspaceID, "Bob")
+	store.CreateShift(workspaceID, alice.ID, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), false)
+	store.CreateShift(workspaceID, bob.ID, time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), true)
+
+	app := fiber.New()
+	app.Get("/api/shifts/export", h.AuthMiddleware, h.ExportShifts)
+	app.Post("/api/shifts/import", h.AuthMiddleware, h.ImportShifts)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/shifts/export?format=csv&start_date=2025-01-01&end_date=2025-01-31", nil)
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	content, _ := io.ReadAll(resp.Body)
+	expected := "Date,Name,End Date,Shift Type\n" +
+		"2025-01-02,Alice,2025-01-02,normal\n" +
+		"2025-01-03,Bob,2025-01-05,long\n"
+	if string(content) != expected {
+		t.Errorf("CSV mismatch:\ngot:\n%s\nwant:\n%s", content, expected)
+	}
+
+	// Import the export for another user without any mapping
+	other, _ := store.CreateUser("otheruser", "testpassword")
+	otherID := personalWorkspace(t, store, other.ID)
+	otherToken := "test_token_456"
+	createTestSession(t, store, otherID, otherToken)
+
+	req = newUploadRequest(t, "/api/shifts/import", "shifts.csv", content)
+	req.Header.Set("Authorization", otherToken)
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	original, _ := store.GetShiftsByDateRange(workspaceID, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC))
+	imported, _ := store.GetShiftsByDateRange(otherID, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC))
+	if len(imported) != len(original) {
+		t.Fatalf("Round trip shift count mismatch: got %d, want %d", len(imported), len(original))
+	}
+	for i := range original {
+		if !imported[i].StartDate.Equal(original[i].StartDate) || !imported[i].EndDate.Equal(original[i].EndDate) || imported[i].IsLongShift != original[i].IsLongShift {
+			t.Errorf("Round trip shift %d mismatch: got %+v, want %+v", i, imported[i], original[i])
+		}
+	}
+}
+
+func TestExportShifts_XLSX(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	alice, _ := store.CreateMember(workspaceID, "Alice")
+	store.CreateShift(workspaceID, alice.ID, time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), true)
+	store.CreateLeaveDay(workspaceID, alice.ID, time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC), "sick")
+
+	app := fiber.New()
+	app.Get("/api/shifts/export", h.AuthMiddleware, h.ExportShifts)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/shifts/export?format=xlsx&start_date=2025-01-01&end_date=2025-01-31", nil)
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if !strings.Contains(resp.Header.Get("Content-Disposition"), "shifts_2025-01-01_2025-01-31.xlsx") {
+		t.Errorf("Unexpected Content-Disposition: %s", resp.Header.Get("Content-Disposition"))
+	}
+
+	content, _ := io.ReadAll(resp.Body)
+	f, err := excelize.OpenReader(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("Failed to open exported workbook: %v", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) != 3 || sheets[0] != "Shifts" || sheets[1] != "Stats" || sheets[2] != "Leave" {
+		t.Fatalf("Sheet mismatch: %v", sheets)
+	}
+
+	stats, _ := f.GetRows("Stats")
+	if len(stats) != 2 || stats[1][0] != "Alice" || stats[1][2] != "3" || stats[1][6] != "1" {
+		t.Errorf("Stats sheet mismatch: %v", stats)
+	}
+
+	leave, _ := f.GetRows("Leave")
+	if len(leave) != 2 || leave[1][0] != "2025-01-07" || leave[1][2] != "sick" {
+		t.Errorf("Leave sheet mismatch: %v", leave)
+	}
+}
+
+func TestExportShifts_InvalidFormat(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	app := fiber.New()
+	app.Get("/api/shifts/export", h.AuthMiddleware, h.ExportShifts)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/shifts/export?format=pdf", nil)
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code: %d, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"shiftplanner/backend/internal/auth"
+	"shiftplanner/backend/internal/notify"
+	"shiftplanner/backend/internal/oidc"
+	"shiftplanner/backend/internal/scheduler"
+	"shiftplanner/backend/internal/storage"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Handler serves the HTTP API on top of the storage interfaces
+// Every handler and the auth middleware are methods of Handler.
+type Handler struct {
+	store      storage.Store
+	members    storage.MemberStore
+	shifts     storage.ShiftStore
+	leave      storage.LeaveStore
+	users      storage.UserStore
+	workspaces storage.WorkspaceStore
+	sessions   storage.SessionStore
+	feedTokens storage.FeedTokenStore
+	apiKeys    storage.APIKeyStore
+	twoFactors storage.TwoFactorStore
+	backups    storage.BackupStore
+	audit      storage.AuditStore
+	snapshots  storage.SnapshotStore
+	planner    scheduler.Store
+	// sso is nil unless single sign-on is enabled
+	sso            *oidc.Provider
+	passwordPolicy auth.PasswordPolicy
+	logins         *auth.LoginThrottle
+	notifier       notify.Sender
+	// passwordResetURL is the frontend page reset tokens are linked to, if any
+	passwordResetURL string
+}
+
+// NewHandler creates a handler that keeps all data in store
+func NewHandler(store storage.Store) *Handler {
+	return &Handler{
+		store:      store,
+		members:    store,
+		shifts:     store,
+		leave:      store,
+		users:      store,
+		workspaces: store,
+		sessions:   store,
+		feedTokens: store,
+		apiKeys:    store,
+		twoFactors: store,
+		backups:    store,
+		audit:      store,
+		snapshots:  store,
+		planner:    store,
+
+		passwordPolicy: auth.DefaultPasswordPolicy,
+		logins:         auth.NewLoginThrottle(),
+		notifier:       notify.LogSender{},
+	}
+}
+
+// EnableOIDC lets users log in through an OpenID Connect provider
+func (h *Handler) EnableOIDC(provider *oidc.Provider) {
+	h.sso = provider
+}
+
+// SetPasswordPolicy sets the rules for the passwords users choose
+func (h *Handler) SetPasswordPolicy(policy auth.PasswordPolicy) {
+	h.passwordPolicy = policy
+}
+
+// SetNotifier sets how notifications such as password reset tokens are delivered
+// They are only logged by default.
+func (h *Handler) SetNotifier(sender notify.Sender) {
+	h.notifier = sender
+}
+
+// SetPasswordResetURL links password reset messages to a frontend page, which gets the token as #token=...
+func (h *Handler) SetPasswordResetURL(url string) {
+	h.passwordResetURL = url
+}
+
+// audited returns a copy of the handler that records its changes in the audit log
+// Handlers that change data start with h = h.audited(c).
+func (h *Handler) audited(c *fiber.Ctx) *Handler {
+	store := storage.NewAuditedStore(h.store, GetUserID(c), requestID(c))
+	audited := *h
+	audited.members = store
+	audited.shifts = store
+	audited.leave = store
+	audited.backups = store
+	audited.snapshots = store
+	audited.planner = store
+	return &audited
+}
+
+// requestID returns the ID of the request, as set by the requestid middleware
+// or sent by the client in the X-Request-ID header
+func requestID(c *fiber.Ctx) string {
+	if id, ok := c.Locals("requestid").(string); ok && id != "" {
+		return id
+	}
+	return c.Get(fiber.HeaderXRequestID)
+}
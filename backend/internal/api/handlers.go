@@ -1,32 +1,23 @@
 package api
 
 import (
-	"bytes"
-	"encoding/csv"
-	"fmt"
-	"io"
 	"log"
 	"shiftplanner/backend/internal/models"
 	"shiftplanner/backend/internal/scheduler"
-	"shiftplanner/backend/internal/storage"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/xuri/excelize/v2"
 )
 
 // GetMembers returns all members
-func GetMembers(c *fiber.Ctx) error {
-	userID := GetUserID(c)
-	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+func (h *Handler) GetMembers(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
 	}
 
-	members, err := storage.GetAllMembers(userID)
+	members, err := h.members.GetAllMembers(workspaceID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -37,12 +28,11 @@ func GetMembers(c *fiber.Ctx) error {
 }
 
 // CreateMember creates a new member
-func CreateMember(c *fiber.Ctx) error {
-	userID := GetUserID(c)
-	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+func (h *Handler) CreateMember(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
 	}
 
 	var req struct {
@@ -61,7 +51,7 @@ func CreateMember(c *fiber.Ctx) error {
 		})
 	}
 
-	member, err := storage.CreateMember(userID, req.Name)
+	member, err := h.members.CreateMember(workspaceID, req.Name)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -72,12 +62,11 @@ func CreateMember(c *fiber.Ctx) error {
 }
 
 // DeleteMember deletes a member
-func DeleteMember(c *fiber.Ctx) error {
-	userID := GetUserID(c)
-	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+func (h *Handler) DeleteMember(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
 	}
 
 	// Extract ID from URL parameter
@@ -88,7 +77,7 @@ func DeleteMember(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := storage.DeleteMember(userID, memberID); err != nil {
+	if err := h.members.DeleteMember(workspaceID, memberID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -98,7 +87,7 @@ func DeleteMember(c *fiber.Ctx) error {
 }
 
 // GetShifts returns shifts
-func GetShifts(c *fiber.Ctx) error {
+func (h *Handler) GetShifts(c *fiber.Ctx) error {
 	startDateStr := c.Query("start_date")
 	endDateStr := c.Query("end_date")
 
@@ -133,14 +122,12 @@ func GetShifts(c *fiber.Ctx) error {
 		endDate = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0) // Next 1 month
 	}
 
-	userID := GetUserID(c)
-	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
 	}
 
-	shifts, err := storage.GetShiftsByDateRange(userID, startDate, endDate)
+	shifts, err := h.shifts.GetShiftsByDateRange(workspaceID, startDate, endDate)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -148,7 +135,7 @@ func GetShifts(c *fiber.Ctx) error {
 	}
 
 	// Add member names
-	members, err := storage.GetAllMembers(userID)
+	members, err := h.members.GetAllMembers(workspaceID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -168,14 +155,15 @@ func GetShifts(c *fiber.Ctx) error {
 	if len(shifts) > 0 {
 		log.Printf("Returning %d shifts", len(shifts))
 	} else {
-		log.Printf("No shifts found for user %d in range %s to %s", userID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+		log.Printf("No shifts found for workspace %d in range %s to %s", workspaceID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 	}
 
 	return c.JSON(shifts)
 }
 
 // GenerateShifts creates a new shift plan
-func GenerateShifts(c *fiber.Ctx) error {
+func (h *Handler) GenerateShifts(c *fiber.Ctx) error {
+	h = h.audited(c)
 	var req scheduler.PlanShiftRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -189,23 +177,28 @@ func GenerateShifts(c *fiber.Ctx) error {
 		})
 	}
 
-	userID := GetUserID(c)
-	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
 	}
 
 	// Create plan
-	shifts, err := scheduler.PlanShift(userID, req.StartDate, req.EndDate)
+	shifts, err := scheduler.PlanShift(h.planner, workspaceID, req.StartDate, req.EndDate)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
+	// Save the current plan of the range so the generation can be undone
+	if _, err := h.snapshots.CreatePlanSnapshot(workspaceID, models.SnapshotReasonGenerate, req.StartDate, req.EndDate); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	// Delete existing shifts (in the same date range)
-	if err := storage.DeleteShiftsByDateRange(userID, req.StartDate, req.EndDate); err != nil {
+	if err := h.shifts.DeleteShiftsByDateRange(workspaceID, req.StartDate, req.EndDate); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -213,7 +206,7 @@ func GenerateShifts(c *fiber.Ctx) error {
 
 	// Save new shifts
 	for _, shift := range shifts {
-		_, err := storage.CreateShift(userID, shift.MemberID, shift.StartDate, shift.EndDate, shift.IsLongShift)
+		_, err := h.shifts.CreateShift(workspaceID, shift.MemberID, shift.StartDate, shift.EndDate, shift.IsLongShift)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": err.Error(),
@@ -222,7 +215,7 @@ func GenerateShifts(c *fiber.Ctx) error {
 	}
 
 	// Add member names
-	members, err := storage.GetAllMembers(userID)
+	members, err := h.members.GetAllMembers(workspaceID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -242,28 +235,26 @@ func GenerateShifts(c *fiber.Ctx) error {
 }
 
 // GetHolidays returns public holidays
-func GetHolidays(c *fiber.Ctx) error {
+func (h *Handler) GetHolidays(c *fiber.Ctx) error {
 	holidays := models.GetAllHolidays()
 	return c.JSON(holidays)
 }
 
 // GetStats returns member statistics
-func GetStats(c *fiber.Ctx) error {
-	userID := GetUserID(c)
-	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+func (h *Handler) GetStats(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
 	}
 
-	members, err := storage.GetAllMembers(userID)
+	members, err := h.members.GetAllMembers(workspaceID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	stats, err := storage.GetAllMembersStats(userID)
+	stats, err := h.shifts.GetAllMembersStats(workspaceID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -292,15 +283,20 @@ func GetStats(c *fiber.Ctx) error {
 }
 
 // ClearAllShifts deletes all shifts for the authenticated user
-func ClearAllShifts(c *fiber.Ctx) error {
-	userID := GetUserID(c)
-	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
+func (h *Handler) ClearAllShifts(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.snapshots.CreatePlanSnapshot(workspaceID, models.SnapshotReasonClear, time.Time{}, time.Time{}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
-	if err := storage.DeleteAllShifts(userID); err != nil {
+	if err := h.shifts.DeleteAllShifts(workspaceID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -310,18 +306,20 @@ func ClearAllShifts(c *fiber.Ctx) error {
 }
 
 // CreateLeaveDay creates leave days for a date range
-func CreateLeaveDay(c *fiber.Ctx) error {
-	userID := GetUserID(c)
-	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+// The days skip the approval workflow, so only schedulers may add them;
+// members request leave instead.
+func (h *Handler) CreateLeaveDay(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
 	}
 
 	var req struct {
 		MemberID  int    `json:"member_id"`
 		StartDate string `json:"start_date"`
 		EndDate   string `json:"end_date"`
+		LeaveType string `json:"leave_type"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -348,6 +346,16 @@ func CreateLeaveDay(c *fiber.Ctx) error {
 		})
 	}
 
+	if req.LeaveType == "" {
+		req.LeaveType = models.LeaveTypeAnnual
+	}
+
+	if !models.IsValidLeaveType(req.LeaveType) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid leave_type",
+		})
+	}
+
 	// Parse dates
 	parsedStartDate, err := time.Parse("2006-01-02", req.StartDate)
 	if err != nil {
@@ -373,7 +381,13 @@ func CreateLeaveDay(c *fiber.Ctx) error {
 		})
 	}
 
-	leaveDays, err := storage.CreateLeaveDaysRange(userID, req.MemberID, startDate, endDate)
+	if leaveRangeTooLong(startDate, endDate) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": leaveRangeTooLongMessage,
+		})
+	}
+
+	leaveDays, err := h.leave.CreateLeaveDaysRange(workspaceID, req.MemberID, startDate, endDate, req.LeaveType)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -381,7 +395,7 @@ func CreateLeaveDay(c *fiber.Ctx) error {
 	}
 
 	// Add member name
-	member, err := storage.GetMemberByID(userID, req.MemberID)
+	member, err := h.members.GetMemberByID(workspaceID, req.MemberID)
 	if err == nil {
 		for i := range leaveDays {
 			leaveDays[i].MemberName = member.Name
@@ -392,12 +406,10 @@ func CreateLeaveDay(c *fiber.Ctx) error {
 }
 
 // GetLeaveDays returns leave days
-func GetLeaveDays(c *fiber.Ctx) error {
-	userID := GetUserID(c)
-	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+func (h *Handler) GetLeaveDays(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
 	}
 
 	memberIDStr := c.Query("member_id")
@@ -405,7 +417,6 @@ func GetLeaveDays(c *fiber.Ctx) error {
 	endDateStr := c.Query("end_date")
 
 	var leaveDays []models.LeaveDay
-	var err error
 
 	if memberIDStr != "" {
 		// Get leave days for specific member
@@ -415,7 +426,7 @@ func GetLeaveDays(c *fiber.Ctx) error {
 				"error": "Invalid member_id",
 			})
 		}
-		leaveDays, err = storage.GetLeaveDaysByMember(userID, memberID)
+		leaveDays, err = h.leave.GetLeaveDaysByMember(workspaceID, memberID)
 	} else if startDateStr != "" && endDateStr != "" {
 		// Get leave days for date range
 		startDate, err := time.Parse("2006-01-02", startDateStr)
@@ -433,13 +444,13 @@ func GetLeaveDays(c *fiber.Ctx) error {
 		// Normalize to UTC midnight
 		startDate = time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
 		endDate = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, time.UTC)
-		leaveDays, err = storage.GetLeaveDaysByDateRange(userID, startDate, endDate)
+		leaveDays, err = h.leave.GetLeaveDaysByDateRange(workspaceID, startDate, endDate)
 	} else {
 		// Get all leave days (last year to next year)
 		now := time.Now().UTC()
 		startDate := time.Date(now.Year()-1, 1, 1, 0, 0, 0, 0, time.UTC)
 		endDate := time.Date(now.Year()+1, 12, 31, 0, 0, 0, 0, time.UTC)
-		leaveDays, err = storage.GetLeaveDaysByDateRange(userID, startDate, endDate)
+		leaveDays, err = h.leave.GetLeaveDaysByDateRange(workspaceID, startDate, endDate)
 	}
 
 	if err != nil {
@@ -449,7 +460,7 @@ func GetLeaveDays(c *fiber.Ctx) error {
 	}
 
 	// Add member names
-	members, err := storage.GetAllMembers(userID)
+	members, err := h.members.GetAllMembers(workspaceID)
 	if err == nil {
 		memberMap := make(map[int]string)
 		for _, m := range members {
@@ -464,12 +475,11 @@ func GetLeaveDays(c *fiber.Ctx) error {
 }
 
 // DeleteLeaveDay deletes a leave day
-func DeleteLeaveDay(c *fiber.Ctx) error {
-	userID := GetUserID(c)
-	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+func (h *Handler) DeleteLeaveDay(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
 	}
 
 	leaveDayID, err := strconv.Atoi(c.Params("id"))
@@ -479,7 +489,7 @@ func DeleteLeaveDay(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := storage.DeleteLeaveDay(userID, leaveDayID); err != nil {
+	if err := h.leave.DeleteLeaveDay(workspaceID, leaveDayID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -489,12 +499,11 @@ func DeleteLeaveDay(c *fiber.Ctx) error {
 }
 
 // UpdateShiftForDate updates or creates a shift for a specific date
-func UpdateShiftForDate(c *fiber.Ctx) error {
-	userID := GetUserID(c)
-	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+func (h *Handler) UpdateShiftForDate(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
 	}
 
 	var req struct {
@@ -532,7 +541,7 @@ func UpdateShiftForDate(c *fiber.Ctx) error {
 	date := time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(), 0, 0, 0, 0, time.UTC)
 
 	// Create or update shift
-	shift, err := storage.CreateOrUpdateShiftForDate(userID, req.MemberID, date)
+	shift, err := h.shifts.CreateOrUpdateShiftForDate(workspaceID, req.MemberID, date)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -540,230 +549,10 @@ func UpdateShiftForDate(c *fiber.Ctx) error {
 	}
 
 	// Add member name
-	member, err := storage.GetMemberByID(userID, req.MemberID)
+	member, err := h.members.GetMemberByID(workspaceID, req.MemberID)
 	if err == nil {
 		shift.MemberName = member.Name
 	}
 
 	return c.JSON(shift)
 }
-
-// ImportShifts imports shifts from CSV or Excel file
-func ImportShifts(c *fiber.Ctx) error {
-	userID := GetUserID(c)
-	if userID == 0 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
-	}
-
-	// Get uploaded file
-	file, err := c.FormFile("file")
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "No file uploaded",
-		})
-	}
-
-	// Open file
-	src, err := file.Open()
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to open file",
-		})
-	}
-	defer src.Close()
-
-	// Determine file type by extension
-	filename := strings.ToLower(file.Filename)
-	var rows [][]string
-
-	if strings.HasSuffix(filename, ".csv") {
-		// Parse CSV
-		reader := csv.NewReader(src)
-		reader.TrimLeadingSpace = true
-		rows, err = reader.ReadAll()
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": fmt.Sprintf("Failed to parse CSV: %v", err),
-			})
-		}
-	} else if strings.HasSuffix(filename, ".xlsx") || strings.HasSuffix(filename, ".xls") {
-		// Parse Excel
-		// Read file into memory
-		fileBytes, err := io.ReadAll(src)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to read file",
-			})
-		}
-
-		// Open Excel file
-		xlFile, err := excelize.OpenReader(bytes.NewReader(fileBytes))
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": fmt.Sprintf("Failed to parse Excel: %v", err),
-			})
-		}
-		defer xlFile.Close()
-
-		// Get first sheet
-		sheetName := xlFile.GetSheetName(0)
-		if sheetName == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Excel file has no sheets",
-			})
-		}
-
-		// Read all rows from first sheet
-		rows, err = xlFile.GetRows(sheetName)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": fmt.Sprintf("Failed to read Excel sheet: %v", err),
-			})
-		}
-	} else {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Unsupported file format. Please upload CSV or Excel (.xlsx, .xls) file",
-		})
-	}
-
-	if len(rows) == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "File is empty",
-		})
-	}
-
-	// Process rows
-	type ImportResult struct {
-		MembersCreated int `json:"members_created"`
-		ShiftsCreated   int `json:"shifts_created"`
-		Errors          []string `json:"errors"`
-	}
-
-	result := ImportResult{
-		Errors: []string{},
-	}
-
-	// Map to track member names to IDs
-	memberMap := make(map[string]int)
-
-	// Process each row (skip header if exists)
-	startRow := 0
-	if len(rows) > 0 && len(rows[0]) >= 2 {
-		// Check if first row looks like a header (contains "date" or "name" keywords)
-		firstRowLower := strings.ToLower(strings.Join(rows[0], " "))
-		if strings.Contains(firstRowLower, "date") || strings.Contains(firstRowLower, "name") {
-			startRow = 1
-		}
-	}
-
-	for i := startRow; i < len(rows); i++ {
-		row := rows[i]
-		if len(row) < 2 {
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Insufficient columns (need at least 2)", i+1))
-			continue
-		}
-
-		// Parse date (first column)
-		dateStr := strings.TrimSpace(row[0])
-		if dateStr == "" {
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Empty date", i+1))
-			continue
-		}
-
-		// Try multiple date formats
-		var date time.Time
-		dateFormats := []string{
-			"2006-01-02",
-			"02/01/2006",
-			"01/02/2006",
-			"2006/01/02",
-			"02-01-2006",
-			"01-02-2006",
-			"2006-1-2",
-		}
-
-		parsed := false
-		for _, format := range dateFormats {
-			if t, err := time.Parse(format, dateStr); err == nil {
-				date = t
-				parsed = true
-				break
-			}
-		}
-
-		if !parsed {
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Invalid date format '%s'", i+1, dateStr))
-			continue
-		}
-
-		// Parse name (second column)
-		name := strings.TrimSpace(row[1])
-		if name == "" {
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Empty name", i+1))
-			continue
-		}
-
-		// Get or create member
-		memberID, exists := memberMap[name]
-		if !exists {
-			// Try to find existing member
-			member, err := storage.GetMemberByName(userID, name)
-			if err != nil {
-				// Member doesn't exist, create it
-				newMember, err := storage.CreateMember(userID, name)
-				if err != nil {
-					result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to create member '%s': %v", i+1, name, err))
-					continue
-				}
-				memberID = newMember.ID
-				result.MembersCreated++
-			} else {
-				memberID = member.ID
-			}
-			memberMap[name] = memberID
-		}
-
-		// Normalize date to UTC midnight
-		dateUTC := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
-
-		// Skip holidays and weekends - only create shifts for working days
-		if !models.IsWorkingDay(dateUTC) {
-			// Still create shift for holidays/weekends if explicitly provided in import
-			// This allows manual override of holiday shifts
-		}
-
-		// Check if shift already exists for this date
-		existingShift, err := storage.GetShiftByDate(userID, dateUTC)
-		if err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to check existing shift: %v", i+1, err))
-			continue
-		}
-
-		if existingShift != nil {
-			// Update existing shift
-			if err := storage.UpdateShiftMember(userID, existingShift.ID, memberID); err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to update shift: %v", i+1, err))
-				continue
-			}
-		} else {
-			// Create new shift
-			// Determine if it's a long shift (next day is holiday or weekend)
-			isLongShift := false
-			nextDay := dateUTC.AddDate(0, 0, 1)
-			if models.IsHoliday(nextDay) || models.IsWeekend(nextDay) {
-				isLongShift = true
-			}
-
-			_, err := storage.CreateShift(userID, memberID, dateUTC, dateUTC, isLongShift)
-			if err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to create shift: %v", i+1, err))
-				continue
-			}
-			result.ShiftsCreated++
-		}
-	}
-
-	return c.Status(fiber.StatusOK).JSON(result)
-}
@@ -2,127 +2,75 @@ package api
 
 import (
 	"bytes"
-	"database/sql"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"os"
-	"shiftplanner/backend/internal/database"
+	"shiftplanner/backend/internal/auth"
+	"shiftplanner/backend/internal/models"
 	"shiftplanner/backend/internal/storage"
 	"strconv"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	_ "modernc.org/sqlite"
 )
 
-func setupTestAPI(t *testing.T) int {
-	return setupTestDB(t)
+// setupTestAPI creates a handler on an empty in-memory store with a test user
+// Returns the test user's personal workspace.
+func setupTestAPI(t *testing.T) (*Handler, storage.Store, int) {
+	store := storage.NewMemoryStore()
+	user, err := store.CreateUser("testuser", "testpassword")
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	return NewHandler(store), store, personalWorkspace(t, store, user.ID)
 }
 
-func setupTestDB(t *testing.T) int {
-	testDBPath := "test_api.db"
-	os.Remove(testDBPath)
-
-	var err error
-	database.DB, err = sql.Open("sqlite", testDBPath)
-	if err != nil {
-		t.Fatalf("Failed to create test database: %v", err)
-	}
-
-	createUsersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		username TEXT NOT NULL UNIQUE,
-		password_hash TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	createMembersTable := `
-	CREATE TABLE IF NOT EXISTS members (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		name TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-	);`
-
-	createShiftsTable := `
-	CREATE TABLE IF NOT EXISTS shifts (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		member_id INTEGER NOT NULL,
-		start_date DATE NOT NULL,
-		end_date DATE NOT NULL,
-		is_long_shift BOOLEAN DEFAULT 0,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE
-	);`
-
-	createSessionsTable := `
-	CREATE TABLE IF NOT EXISTS sessions (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		token TEXT NOT NULL UNIQUE,
-		expires_at DATETIME NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-	);`
-
-	if _, err := database.DB.Exec(createUsersTable); err != nil {
-		t.Fatalf("Failed to create users table: %v", err)
-	}
-	if _, err := database.DB.Exec(createMembersTable); err != nil {
-		t.Fatalf("Failed to create members table: %v", err)
-	}
-	if _, err := database.DB.Exec(createShiftsTable); err != nil {
-		t.Fatalf("Failed to create shifts table: %v", err)
-	}
-	if _, err := database.DB.Exec(createSessionsTable); err != nil {
-		t.Fatalf("Failed to create sessions table: %v", err)
-	}
-
-	result, _ := database.DB.Exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", "testuser", "testhash")
-	userID, _ := result.LastInsertId()
-	return int(userID)
+// personalWorkspace returns the workspace created together with a user
+func personalWorkspace(t *testing.T, store storage.Store, userID int) int {
+	workspaces, err := store.GetUserWorkspaces(userID)
+	if err != nil || len(workspaces) == 0 {
+		t.Fatalf("Failed to get workspace of user %d: %v", userID, err)
+	}
+	return workspaces[0].ID
 }
 
-func teardownTestAPI(t *testing.T) {
-	if database.DB != nil {
-		database.DB.Close()
-		database.DB = nil
+// createTestSession stores a session for token, valid for a week, for the owner of workspaceID
+func createTestSession(t *testing.T, store storage.Store, workspaceID int, token string) {
+	users, err := store.GetWorkspaceUsers(workspaceID)
+	if err != nil || len(users) == 0 {
+		t.Fatalf("Failed to get users of workspace %d: %v", workspaceID, err)
+	}
+	if _, err := store.CreateSession(users[0].UserID, auth.HashToken(token), "", "", time.Now().Add(7*24*time.Hour)); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
 	}
-	os.Remove("test_api.db")
 }
 
-func TestGetShifts_NoDatabase(t *testing.T) {
-	database.DB = nil
+func TestGetShifts_Unauthenticated(t *testing.T) {
+	h, _, _ := setupTestAPI(t)
 
 	app := fiber.New()
-	app.Get("/api/shifts", GetShifts)
+	app.Get("/api/shifts", h.GetShifts)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/shifts?start_date=2025-01-06&end_date=2025-01-06", nil)
 	resp, _ := app.Test(req)
 
 	if resp.StatusCode == http.StatusOK {
-		t.Error("Should not succeed without database connection")
+		t.Error("Should not succeed without a session")
 	}
 }
 
 func TestGetMembers(t *testing.T) {
-	userID := setupTestDB(t)
-	defer teardownTestAPI(t)
+	h, store, workspaceID := setupTestAPI(t)
 
-	storage.CreateMember(userID, "Test Member 1")
-	storage.CreateMember(userID, "Test Member 2")
+	store.CreateMember(workspaceID, "Test Member 1")
+	store.CreateMember(workspaceID, "Test Member 2")
 
 	token := "test_token_123"
-	database.DB.Exec("INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, datetime('now', '+7 days'))", userID, token)
+	createTestSession(t, store, workspaceID, token)
 
 	app := fiber.New()
-	app.Get("/api/members", AuthMiddleware, GetMembers)
+	app.Get("/api/members", h.AuthMiddleware, h.GetMembers)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/members", nil)
 	req.Header.Set("Authorization", token)
@@ -144,14 +92,13 @@ func TestGetMembers(t *testing.T) {
 }
 
 func TestCreateMember(t *testing.T) {
-	userID := setupTestDB(t)
-	defer teardownTestAPI(t)
+	h, store, workspaceID := setupTestAPI(t)
 
 	token := "test_token_123"
-	database.DB.Exec("INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, datetime('now', '+7 days'))", userID, token)
+	createTestSession(t, store, workspaceID, token)
 
 	app := fiber.New()
-	app.Post("/api/members", AuthMiddleware, CreateMember)
+	app.Post("/api/members", h.AuthMiddleware, h.CreateMember)
 
 	body := bytes.NewBufferString(`{"name":"New Member"}`)
 	req := httptest.NewRequest(http.MethodPost, "/api/members", body)
@@ -175,14 +122,13 @@ func TestCreateMember(t *testing.T) {
 }
 
 func TestCreateMember_EmptyName(t *testing.T) {
-	userID := setupTestDB(t)
-	defer teardownTestAPI(t)
+	h, store, workspaceID := setupTestAPI(t)
 
 	token := "test_token_123"
-	database.DB.Exec("INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, datetime('now', '+7 days'))", userID, token)
+	createTestSession(t, store, workspaceID, token)
 
 	app := fiber.New()
-	app.Post("/api/members", AuthMiddleware, CreateMember)
+	app.Post("/api/members", h.AuthMiddleware, h.CreateMember)
 
 	body := bytes.NewBufferString(`{"name":""}`)
 	req := httptest.NewRequest(http.MethodPost, "/api/members", body)
@@ -196,26 +142,25 @@ func TestCreateMember_EmptyName(t *testing.T) {
 }
 
 func TestGetShifts(t *testing.T) {
-	userID := setupTestDB(t)
-	defer teardownTestAPI(t)
+	h, store, workspaceID := setupTestAPI(t)
 
 	token := "test_token_123"
-	database.DB.Exec("INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, datetime('now', '+7 days'))", userID, token)
+	createTestSession(t, store, workspaceID, token)
 
-	member, err := storage.CreateMember(userID, "Test Member")
+	member, err := store.CreateMember(workspaceID, "Test Member")
 	if err != nil {
 		t.Fatalf("Failed to create member: %v", err)
 	}
 
 	startDate := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
 	endDate := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
-	_, err = storage.CreateShift(userID, member.ID, startDate, endDate, false)
+	_, err = store.CreateShift(workspaceID, member.ID, startDate, endDate, false)
 	if err != nil {
 		t.Fatalf("Failed to create shift: %v", err)
 	}
 
 	app := fiber.New()
-	app.Get("/api/shifts", AuthMiddleware, GetShifts)
+	app.Get("/api/shifts", h.AuthMiddleware, h.GetShifts)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/shifts?start_date=2025-01-06&end_date=2025-01-06", nil)
 	req.Header.Set("Authorization", token)
@@ -227,17 +172,16 @@ func TestGetShifts(t *testing.T) {
 }
 
 func TestGetStats(t *testing.T) {
-	userID := setupTestDB(t)
-	defer teardownTestAPI(t)
+	h, store, workspaceID := setupTestAPI(t)
 
 	token := "test_token_123"
-	database.DB.Exec("INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, datetime('now', '+7 days'))", userID, token)
+	createTestSession(t, store, workspaceID, token)
 
-	storage.CreateMember(userID, "Test Member 1")
-	storage.CreateMember(userID, "Test Member 2")
+	store.CreateMember(workspaceID, "Test Member 1")
+	store.CreateMember(workspaceID, "Test Member 2")
 
 	app := fiber.New()
-	app.Get("/api/stats", AuthMiddleware, GetStats)
+	app.Get("/api/stats", h.AuthMiddleware, h.GetStats)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
 	req.Header.Set("Authorization", token)
@@ -249,16 +193,15 @@ func TestGetStats(t *testing.T) {
 }
 
 func TestDeleteMember(t *testing.T) {
-	userID := setupTestDB(t)
-	defer teardownTestAPI(t)
+	h, store, workspaceID := setupTestAPI(t)
 
 	token := "test_token_123"
-	database.DB.Exec("INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, datetime('now', '+7 days'))", userID, token)
+	createTestSession(t, store, workspaceID, token)
 
-	member, _ := storage.CreateMember(userID, "Member to Delete")
+	member, _ := store.CreateMember(workspaceID, "Member to Delete")
 
 	app := fiber.New()
-	app.Delete("/api/members/:id", AuthMiddleware, DeleteMember)
+	app.Delete("/api/members/:id", h.AuthMiddleware, h.DeleteMember)
 
 	req := httptest.NewRequest(http.MethodDelete, "/api/members/"+strconv.Itoa(member.ID), nil)
 	req.Header.Set("Authorization", token)
@@ -270,14 +213,13 @@ func TestDeleteMember(t *testing.T) {
 }
 
 func TestDeleteMember_InvalidID(t *testing.T) {
-	userID := setupTestDB(t)
-	defer teardownTestAPI(t)
+	h, store, workspaceID := setupTestAPI(t)
 
 	token := "test_token_123"
-	database.DB.Exec("INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, datetime('now', '+7 days'))", userID, token)
+	createTestSession(t, store, workspaceID, token)
 
 	app := fiber.New()
-	app.Delete("/api/members/:id", AuthMiddleware, DeleteMember)
+	app.Delete("/api/members/:id", h.AuthMiddleware, h.DeleteMember)
 
 	req := httptest.NewRequest(http.MethodDelete, "/api/members/invalid", nil)
 	req.Header.Set("Authorization", token)
@@ -289,17 +231,16 @@ func TestDeleteMember_InvalidID(t *testing.T) {
 }
 
 func TestGenerateShifts(t *testing.T) {
-	userID := setupTestDB(t)
-	defer teardownTestAPI(t)
+	h, store, workspaceID := setupTestAPI(t)
 
 	token := "test_token_123"
-	database.DB.Exec("INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, datetime('now', '+7 days'))", userID, token)
+	createTestSession(t, store, workspaceID, token)
 
-	storage.CreateMember(userID, "Member 1")
-	storage.CreateMember(userID, "Member 2")
+	store.CreateMember(workspaceID, "Member 1")
+	store.CreateMember(workspaceID, "Member 2")
 
 	app := fiber.New()
-	app.Post("/api/shifts/generate", AuthMiddleware, GenerateShifts)
+	app.Post("/api/shifts/generate", h.AuthMiddleware, h.GenerateShifts)
 
 	body := bytes.NewBufferString(`{"start_date":"2025-01-06","end_date":"2025-01-10"}`)
 	req := httptest.NewRequest(http.MethodPost, "/api/shifts/generate", body)
@@ -313,8 +254,10 @@ func TestGenerateShifts(t *testing.T) {
 }
 
 func TestGetHolidays(t *testing.T) {
+	h, _, _ := setupTestAPI(t)
+
 	app := fiber.New()
-	app.Get("/api/holidays", GetHolidays)
+	app.Get("/api/holidays", h.GetHolidays)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/holidays", nil)
 	resp, _ := app.Test(req)
@@ -323,10 +266,7 @@ func TestGetHolidays(t *testing.T) {
 		t.Errorf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
 	}
 
-	var holidays []struct {
-		Date string `json:"date"`
-		Name string `json:"name"`
-	}
+	var holidays map[string]string
 	json.NewDecoder(resp.Body).Decode(&holidays)
 
 	if len(holidays) == 0 {
@@ -335,13 +275,12 @@ func TestGetHolidays(t *testing.T) {
 }
 
 func TestRegister(t *testing.T) {
-	setupTestDB(t)
-	defer teardownTestAPI(t)
+	h, _, _ := setupTestAPI(t)
 
 	app := fiber.New()
-	app.Post("/api/auth/register", Register)
+	app.Post("/api/auth/register", h.Register)
 
-	body := bytes.NewBufferString(`{"username":"newuser","password":"password123"}`)
+	body := bytes.NewBufferString(`{"username":"newuser","password":"correct horse battery"}`)
 	req := httptest.NewRequest(http.MethodPost, "/api/auth/register", body)
 	req.Header.Set("Content-Type", "application/json")
 	resp, _ := app.Test(req)
@@ -359,15 +298,14 @@ func TestRegister(t *testing.T) {
 }
 
 func TestRegister_DuplicateUsername(t *testing.T) {
-	setupTestDB(t)
-	defer teardownTestAPI(t)
+	h, store, _ := setupTestAPI(t)
 
-	database.DB.Exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", "existinguser", "hash")
+	store.CreateUser("existinguser", "password")
 
 	app := fiber.New()
-	app.Post("/api/auth/register", Register)
+	app.Post("/api/auth/register", h.Register)
 
-	body := bytes.NewBufferString(`{"username":"existinguser","password":"password123"}`)
+	body := bytes.NewBufferString(`{"username":"existinguser","password":"correct horse battery"}`)
 	req := httptest.NewRequest(http.MethodPost, "/api/auth/register", body)
 	req.Header.Set("Content-Type", "application/json")
 	resp, _ := app.Test(req)
@@ -377,16 +315,41 @@ func TestRegister_DuplicateUsername(t *testing.T) {
 	}
 }
 
+func TestRegister_PasswordPolicy(t *testing.T) {
+	h, _, _ := setupTestAPI(t)
+
+	app := fiber.New()
+	app.Post("/api/auth/register", h.Register)
+
+	register := func(password string) int {
+		body := bytes.NewBufferString(`{"username":"newuser","password":"` + password + `"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/register", body)
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	for _, password := range []string{"abc1", "password123", "Qwerty123", "NewUser"} {
+		if status := register(password); status != http.StatusBadRequest {
+			t.Errorf("Expected %q to be rejected, got %d", password, status)
+		}
+	}
+
+	h.SetPasswordPolicy(auth.PasswordPolicy{MinLength: 4})
+	if status := register("abc1"); status != http.StatusCreated {
+		t.Errorf("Expected status code: %d with a relaxed policy, got %d", http.StatusCreated, status)
+	}
+}
+
 func TestLogin(t *testing.T) {
-	setupTestDB(t)
-	defer teardownTestAPI(t)
+	h, store, _ := setupTestAPI(t)
 
-	storage.CreateUser("testuser", "password123")
+	store.CreateUser("loginuser", "password123")
 
 	app := fiber.New()
-	app.Post("/api/auth/login", Login)
+	app.Post("/api/auth/login", h.Login)
 
-	body := bytes.NewBufferString(`{"username":"testuser","password":"password123"}`)
+	body := bytes.NewBufferString(`{"username":"loginuser","password":"password123"}`)
 	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", body)
 	req.Header.Set("Content-Type", "application/json")
 	resp, _ := app.Test(req)
@@ -403,12 +366,59 @@ func TestLogin(t *testing.T) {
 	}
 }
 
+// legacyHashUserStore serves a legacy SHA-256 hash for every user, as stored by earlier versions
+type legacyHashUserStore struct {
+	storage.UserStore
+	legacyHash string
+}
+
+func (s *legacyHashUserStore) GetUserByUsername(username string) (*models.User, string, error) {
+	user, passwordHash, err := s.UserStore.GetUserByUsername(username)
+	if err != nil || s.legacyHash == "" {
+		return user, passwordHash, err
+	}
+	return user, s.legacyHash, nil
+}
+
+func (s *legacyHashUserStore) UpdatePassword(workspaceID int, password string) error {
+	s.legacyHash = ""
+	return s.UserStore.UpdatePassword(workspaceID, password)
+}
+
+func TestLogin_RehashesLegacyPassword(t *testing.T) {
+	h, store, _ := setupTestAPI(t)
+
+	store.CreateUser("legacyuser", "unused")
+	users := &legacyHashUserStore{
+		UserStore: store,
+		// Unsalted SHA-256 of "password123"
+		legacyHash: "ef92b778bafe771e89245b89ecbc08a44a4e166c06659911881f383d4473e94f",
+	}
+	h.users = users
+
+	app := fiber.New()
+	app.Post("/api/auth/login", h.Login)
+
+	body := bytes.NewBufferString(`{"username":"legacyuser","password":"password123"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", body)
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	_, passwordHash, _ := store.GetUserByUsername("legacyuser")
+	if storage.PasswordNeedsRehash(passwordHash) || !storage.ValidatePassword("password123", passwordHash) {
+		t.Errorf("Password should have been rehashed, got %s", passwordHash)
+	}
+}
+
 func TestLogin_InvalidCredentials(t *testing.T) {
-	setupTestDB(t)
-	defer teardownTestAPI(t)
+	h, _, _ := setupTestAPI(t)
 
 	app := fiber.New()
-	app.Post("/api/auth/login", Login)
+	app.Post("/api/auth/login", h.Login)
 
 	body := bytes.NewBufferString(`{"username":"nonexistent","password":"wrong"}`)
 	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", body)
@@ -420,15 +430,76 @@ func TestLogin_InvalidCredentials(t *testing.T) {
 	}
 }
 
+func TestLogin_LockedOut(t *testing.T) {
+	h, _, _ := setupTestAPI(t)
+
+	app := fiber.New()
+	app.Post("/api/auth/login", h.Login)
+
+	login := func(password string) *http.Response {
+		body := bytes.NewBufferString(`{"username":"testuser","password":"` + password + `"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", body)
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp
+	}
+
+	for i := 0; i < 5; i++ {
+		if resp := login("wrong"); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Expected status code: %d for failure %d, got %d", http.StatusUnauthorized, i+1, resp.StatusCode)
+		}
+	}
+	login("wrong")
+
+	// Locked out, even with the right password
+	resp := login("testpassword")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+	if resp.Header.Get(fiber.HeaderRetryAfter) == "" {
+		t.Error("Expected a Retry-After header")
+	}
+}
+
+func TestLogin_LockedOutPerForwardedClient(t *testing.T) {
+	h, _, _ := setupTestAPI(t)
+
+	var config fiber.Config
+	ProxyConfig{Header: "X-Real-IP", TrustedProxies: []string{"0.0.0.0"}}.Apply(&config)
+	app := fiber.New(config)
+	app.Post("/api/auth/login", h.Login)
+
+	login := func(username, password, clientIP string) *http.Response {
+		body := bytes.NewBufferString(`{"username":"` + username + `","password":"` + password + `"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Real-IP", clientIP)
+		resp, _ := app.Test(req)
+		return resp
+	}
+
+	// Guessing from one client behind the proxy locks out its address...
+	for i := 0; i < 21; i++ {
+		login("guess"+strconv.Itoa(i), "wrong", "203.0.113.1")
+	}
+	if resp := login("testuser", "testpassword", "203.0.113.1"); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Guessing client: expected status code %d, got %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+
+	// ...but not the other clients of the same proxy
+	if resp := login("testuser", "testpassword", "203.0.113.2"); resp.StatusCode != http.StatusOK {
+		t.Errorf("Other client: expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
 func TestLogout(t *testing.T) {
-	userID := setupTestDB(t)
-	defer teardownTestAPI(t)
+	h, store, workspaceID := setupTestAPI(t)
 
 	token := "test_token_123"
-	database.DB.Exec("INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, datetime('now', '+7 days'))", userID, token)
+	createTestSession(t, store, workspaceID, token)
 
 	app := fiber.New()
-	app.Post("/api/auth/logout", AuthMiddleware, Logout)
+	app.Post("/api/auth/logout", h.AuthMiddleware, h.Logout)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/auth/logout", nil)
 	req.Header.Set("Authorization", token)
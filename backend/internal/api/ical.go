@@ -0,0 +1,252 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"shiftplanner/backend/internal/auth"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Calendar feeds cover shifts from icalPastMonths ago to icalFutureMonths ahead
+const (
+	icalPastMonths   = 3
+	icalFutureMonths = 12
+)
+
+// icalMaxLineOctets maximum length of an iCalendar content line before folding (RFC 5545)
+const icalMaxLineOctets = 75
+
+// GetFeedTokens gets the calendar feed tokens of the authenticated user for the active workspace
+func (h *Handler) GetFeedTokens(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := h.feedTokens.GetFeedTokens(workspaceID, GetUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if tokens == nil {
+		tokens = []models.FeedToken{}
+	}
+
+	return c.JSON(tokens)
+}
+
+// CreateFeedToken creates a calendar feed token for the active workspace
+// The response is the only time the token can be read.
+func (h *Handler) CreateFeedToken(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	feedToken, err := auth.CreateFeedToken(h.feedTokens, workspaceID, GetUserID(c), strings.TrimSpace(req.Name))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(feedToken)
+}
+
+// DeleteFeedToken revokes a calendar feed token of the active workspace
+func (h *Handler) DeleteFeedToken(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID",
+		})
+	}
+
+	if err := h.feedTokens.DeleteFeedToken(workspaceID, GetUserID(c), id); err != nil {
+		if err == storage.ErrFeedTokenNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Feed token not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetShiftsFeed serves all shifts as an iCalendar feed
+// The feed token in the URL replaces the session, so calendar clients can subscribe
+func (h *Handler) GetShiftsFeed(c *fiber.Ctx) error {
+	workspaceID, err := h.feedTokens.GetWorkspaceIDByFeedToken(auth.HashToken(c.Params("token")))
+	if err != nil {
+		return feedTokenError(c, err)
+	}
+
+	return h.sendShiftsFeed(c, workspaceID, 0, "On-call shifts")
+}
+
+// GetMemberShiftsFeed serves the shifts of a single member as an iCalendar feed
+func (h *Handler) GetMemberShiftsFeed(c *fiber.Ctx) error {
+	workspaceID, err := h.feedTokens.GetWorkspaceIDByFeedToken(auth.HashToken(c.Params("token")))
+	if err != nil {
+		return feedTokenError(c, err)
+	}
+
+	memberID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid member ID",
+		})
+	}
+
+	member, err := h.members.GetMemberByID(workspaceID, memberID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Member not found",
+		})
+	}
+
+	return h.sendShiftsFeed(c, workspaceID, member.ID, "On-call shifts - "+member.Name)
+}
+
+// feedTokenError responds to an unknown feed token or a lookup failure
+func feedTokenError(c *fiber.Ctx, err error) error {
+	if err == storage.ErrFeedTokenNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Feed not found",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// sendShiftsFeed writes the shifts of a workspace (only memberID's if not 0) as an iCalendar feed
+func (h *Handler) sendShiftsFeed(c *fiber.Ctx, workspaceID, memberID int, calendarName string) error {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	shifts, err := h.shifts.GetShiftsByDateRange(workspaceID, today.AddDate(0, -icalPastMonths, 0), today.AddDate(0, icalFutureMonths, 0))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	members, err := h.members.GetAllMembers(workspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	memberMap := make(map[int]string)
+	for _, m := range members {
+		memberMap[m.ID] = m.Name
+	}
+
+	var feedShifts []models.Shift
+	for _, shift := range shifts {
+		if memberID != 0 && shift.MemberID != memberID {
+			continue
+		}
+		shift.MemberName = memberMap[shift.MemberID]
+		feedShifts = append(feedShifts, shift)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	return c.Send(buildShiftsCalendar(feedShifts, calendarName, now))
+}
+
+// buildShiftsCalendar builds an iCalendar document with an all-day event per shift
+// UIDs are derived from shift IDs so clients update events instead of duplicating
+// them, and SEQUENCE follows the shift's sequence, which is bumped on reassignment.
+func buildShiftsCalendar(shifts []models.Shift, calendarName string, stamp time.Time) []byte {
+	var buf bytes.Buffer
+	writeICalLine(&buf, "BEGIN", "VCALENDAR")
+	writeICalLine(&buf, "VERSION", "2.0")
+	writeICalLine(&buf, "PRODID", "-//Shift Planner//Shift Planner//EN")
+	writeICalLine(&buf, "CALSCALE", "GREGORIAN")
+	writeICalLine(&buf, "METHOD", "PUBLISH")
+	writeICalLine(&buf, "X-WR-CALNAME", escapeICalText(calendarName))
+
+	for _, shift := range shifts {
+		summary := "On call: " + shift.MemberName
+		if shift.IsLongShift {
+			summary += " (long shift)"
+		}
+
+		writeICalLine(&buf, "BEGIN", "VEVENT")
+		writeICalLine(&buf, "UID", fmt.Sprintf("shift-%d@shiftplanner", shift.ID))
+		writeICalLine(&buf, "DTSTAMP", stamp.UTC().Format("20060102T150405Z"))
+		writeICalLine(&buf, "DTSTART;VALUE=DATE", shift.StartDate.Format("20060102"))
+		// DTEND of all-day events is exclusive
+		writeICalLine(&buf, "DTEND;VALUE=DATE", shift.EndDate.AddDate(0, 0, 1).Format("20060102"))
+		writeICalLine(&buf, "SEQUENCE", strconv.Itoa(shift.Sequence))
+		writeICalLine(&buf, "SUMMARY", escapeICalText(summary))
+		writeICalLine(&buf, "TRANSP", "TRANSPARENT")
+		writeICalLine(&buf, "END", "VEVENT")
+	}
+
+	writeICalLine(&buf, "END", "VCALENDAR")
+	return buf.Bytes()
+}
+
+// writeICalLine writes a content line, folded at icalMaxLineOctets and ended with CRLF
+func writeICalLine(buf *bytes.Buffer, name, value string) {
+	line := name + ":" + value
+	limit := icalMaxLineOctets
+	for len(line) > limit {
+		// Don't split multi-byte characters
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space
+		limit = icalMaxLineOctets - 1
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
+
+// escapeICalText escapes a TEXT property value (RFC 5545 section 3.3.11)
+func escapeICalText(s string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return replacer.Replace(s)
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"shiftplanner/backend/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestShiftsFeed(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	alice, _ := store.CreateMember(workspaceID, "Alice")
+	bob, _ := store.CreateMember(workspaceID, "Bob")
+	longShift, _ := store.CreateShift(workspaceID, alice.ID, today, today.AddDate(0, 0, 2), true)
+	store.CreateShift(workspaceID, bob.ID, today.AddDate(0, 0, 3), today.AddDate(0, 0, 3), false)
+
+	app := fiber.New()
+	app.Get("/ical/:token/shifts.ics", h.GetShiftsFeed)
+	app.Get("/ical/:token/members/:id.ics", h.GetMemberShiftsFeed)
+	app.Get("/api/feed-tokens", h.AuthMiddleware, h.GetFeedTokens)
+	app.Post("/api/feed-tokens", h.AuthMiddleware, h.CreateFeedToken)
+	app.Delete("/api/feed-tokens/:id", h.AuthMiddleware, h.DeleteFeedToken)
+
+	// Create a feed token
+	req := httptest.NewRequest(http.MethodPost, "/api/feed-tokens", bytes.NewBufferString(`{"name":"Google Calendar"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	var feedToken models.FeedToken
+	json.NewDecoder(resp.Body).Decode(&feedToken)
+	if feedToken.Token == "" || feedToken.Token == token {
+		t.Fatalf("Feed token must be a new token, got %q", feedToken.Token)
+	}
+
+	// Listed tokens only show their prefix
+	req = httptest.NewRequest(http.MethodGet, "/api/feed-tokens", nil)
+	req.Header.Set("Authorization", token)
+	resp, _ = app.Test(req)
+	var listed []models.FeedToken
+	json.NewDecoder(resp.Body).Decode(&listed)
+	if len(listed) != 1 || listed[0].Token != "" || !strings.HasPrefix(feedToken.Token, listed[0].Prefix) || listed[0].Prefix == "" {
+		t.Errorf("Listed feed tokens should show the prefix only: %+v", listed)
+	}
+
+	getFeed := func(url string) (int, string) {
+		resp, _ := app.Test(httptest.NewRequest(http.MethodGet, url, nil))
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	// Workspace feed contains both shifts, long shifts span until the day after they end
+	status, body := getFeed("/ical/" + feedToken.Token + "/shifts.ics")
+	if status != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, status)
+	}
+	if strings.Count(body, "BEGIN:VEVENT") != 2 {
+		t.Errorf("Expected 2 events, got:\n%s", body)
+	}
+	uid := fmt.Sprintf("UID:shift-%d@shiftplanner\r\n", longShift.ID)
+	if !strings.Contains(body, uid) {
+		t.Errorf("Feed should contain %q", uid)
+	}
+	if !strings.Contains(body, "DTSTART;VALUE=DATE:"+today.Format("20060102")+"\r\n") ||
+		!strings.Contains(body, "DTEND;VALUE=DATE:"+today.AddDate(0, 0, 3).Format("20060102")+"\r\n") {
+		t.Errorf("Long shift dates mismatch:\n%s", body)
+	}
+	if !strings.Contains(body, "SUMMARY:On call: Alice (long shift)\r\n") {
+		t.Errorf("Summary mismatch:\n%s", body)
+	}
+
+	// Member feed only contains the member's shifts
+	status, body = getFeed(fmt.Sprintf("/ical/%s/members/%d.ics", feedToken.Token, bob.ID))
+	if status != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, status)
+	}
+	if strings.Count(body, "BEGIN:VEVENT") != 1 || !strings.Contains(body, "On call: Bob") {
+		t.Errorf("Member feed mismatch:\n%s", body)
+	}
+
+	// Reassignment bumps the sequence
+	store.UpdateShiftMember(workspaceID, longShift.ID, bob.ID)
+	_, body = getFeed(fmt.Sprintf("/ical/%s/members/%d.ics", feedToken.Token, bob.ID))
+	if strings.Count(body, "BEGIN:VEVENT") != 2 || !strings.Contains(body, "SEQUENCE:1\r\n") {
+		t.Errorf("Reassigned shift should have SEQUENCE:1:\n%s", body)
+	}
+
+	// Session tokens are not feed tokens
+	if status, _ := getFeed("/ical/" + token + "/shifts.ics"); status != http.StatusNotFound {
+		t.Errorf("Expected status code: %d for a session token, got %d", http.StatusNotFound, status)
+	}
+
+	// Revoked tokens stop working
+	req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/feed-tokens/%d", feedToken.ID), nil)
+	req.Header.Set("Authorization", token)
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if status, _ := getFeed("/ical/" + feedToken.Token + "/shifts.ics"); status != http.StatusNotFound {
+		t.Errorf("Expected status code: %d for a revoked token, got %d", http.StatusNotFound, status)
+	}
+}
+
+func TestWriteICalLine(t *testing.T) {
+	var buf bytes.Buffer
+	writeICalLine(&buf, "SUMMARY", strings.Repeat("a", 100))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 folded lines, got %d: %q", len(lines), buf.String())
+	}
+	if len(lines[0]) != 75 || !strings.HasPrefix(lines[1], " ") {
+		t.Errorf("Folding mismatch: %q", lines)
+	}
+
+	if got := escapeICalText("a,b;c\\d\ne"); got != `a\,b\;c\\d\ne` {
+		t.Errorf("escapeICalText mismatch: got %s", got)
+	}
+}
@@ -0,0 +1,870 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"shiftplanner/backend/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+// importDateFormat a date format accepted in import files
+// Label is the format clients see and submit, Layout the Go time layout.
+// Unpadded layouts also accept zero-padded values, so Padded names the
+// zero-padded twin that makes them redundant as a candidate.
+type importDateFormat struct {
+	Label  string
+	Layout string
+	Padded string
+}
+
+// importDateFormats date formats accepted in import files, tried in order when
+// no explicit format is given (so 02/01/2006 wins over 01/02/2006)
+var importDateFormats = []importDateFormat{
+	{Label: "YYYY-MM-DD", Layout: "2006-01-02"},
+	{Label: "DD/MM/YYYY", Layout: "02/01/2006"},
+	{Label: "MM/DD/YYYY", Layout: "01/02/2006"},
+	{Label: "YYYY/MM/DD", Layout: "2006/01/02"},
+	{Label: "DD-MM-YYYY", Layout: "02-01-2006"},
+	{Label: "MM-DD-YYYY", Layout: "01-02-2006"},
+	{Label: "DD.MM.YYYY", Layout: "02.01.2006"},
+	{Label: "YYYY-M-D", Layout: "2006-1-2", Padded: "YYYY-MM-DD"},
+	{Label: "D/M/YYYY", Layout: "2/1/2006", Padded: "DD/MM/YYYY"},
+	{Label: "M/D/YYYY", Layout: "1/2/2006", Padded: "MM/DD/YYYY"},
+}
+
+// previewSampleRows number of rows returned by the import preview
+const previewSampleRows = 10
+
+// importFile rows read from an uploaded CSV or Excel file
+// Hash is the hex encoded SHA-256 hash of the file content
+type importFile struct {
+	Filename string
+	Hash     string
+	Sheets   []string
+	Sheet    string
+	Rows     [][]string
+}
+
+// importMapping describes where shift data is found in an import file
+// EndDateColumn and TypeColumn are optional and -1 when the file has no such column.
+// ExpandLongShifts extends rows without an end date that start a long shift
+// to the full long-shift span.
+type importMapping struct {
+	DateColumn       int
+	NameColumn       int
+	EndDateColumn    int
+	TypeColumn       int
+	DateFormat       string // Label of an importDateFormats entry, empty when no value is a date
+	HasHeader        bool
+	ExpandLongShifts bool
+}
+
+// importLayout default columns of an import kind
+// They apply to the mapping fields a client doesn't send. TypeField names the
+// form field that selects the type column.
+type importLayout struct {
+	DateColumn    int
+	NameColumn    int
+	EndDateColumn int
+	TypeColumn    int
+	TypeField     string
+}
+
+// shiftImportLayout historical layout of shift files: date, name
+var shiftImportLayout = importLayout{DateColumn: 0, NameColumn: 1, EndDateColumn: -1, TypeColumn: -1, TypeField: "shift_type_column"}
+
+// leaveImportLayout historical layout of leave files: member name, start date, end date, leave type
+var leaveImportLayout = importLayout{DateColumn: 1, NameColumn: 0, EndDateColumn: 2, TypeColumn: 3, TypeField: "leave_type_column"}
+
+// readUploadedFile reads all rows of the uploaded "file" form field
+// CSV files are read as a whole, Excel files from the named sheet (first sheet if empty)
+func readUploadedFile(c *fiber.Ctx, sheet string) (*importFile, *fiber.Error) {
+	// Get uploaded file
+	file, err := c.FormFile("file")
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "No file uploaded")
+	}
+
+	// Open file
+	src, err := file.Open()
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to open file")
+	}
+	defer src.Close()
+
+	// Read file into memory
+	fileBytes, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to read file")
+	}
+
+	// Determine file type by extension
+	filename := strings.ToLower(file.Filename)
+	hash := sha256.Sum256(fileBytes)
+	result := &importFile{Filename: file.Filename, Hash: hex.EncodeToString(hash[:])}
+
+	if strings.HasSuffix(filename, ".csv") {
+		// Parse CSV
+		reader := csv.NewReader(bytes.NewReader(fileBytes))
+		reader.TrimLeadingSpace = true
+		reader.FieldsPerRecord = -1
+		result.Rows, err = reader.ReadAll()
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Failed to parse CSV: %v", err))
+		}
+	} else if strings.HasSuffix(filename, ".xlsx") || strings.HasSuffix(filename, ".xls") {
+		// Parse Excel
+		// Open Excel file
+		xlFile, err := excelize.OpenReader(bytes.NewReader(fileBytes))
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Failed to parse Excel: %v", err))
+		}
+		defer xlFile.Close()
+
+		result.Sheets = xlFile.GetSheetList()
+		if len(result.Sheets) == 0 {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Excel file has no sheets")
+		}
+
+		// Use requested sheet or the first one
+		result.Sheet = result.Sheets[0]
+		if sheet != "" {
+			if index, err := xlFile.GetSheetIndex(sheet); err != nil || index == -1 {
+				return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Sheet '%s' not found", sheet))
+			}
+			result.Sheet = sheet
+		}
+
+		// Read all rows from the sheet
+		result.Rows, err = xlFile.GetRows(result.Sheet)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Failed to read Excel sheet: %v", err))
+		}
+	} else {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Unsupported file format. Please upload CSV or Excel (.xlsx, .xls) file")
+	}
+
+	if len(result.Rows) == 0 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "File is empty")
+	}
+
+	return result, nil
+}
+
+// hasHeaderRow checks if the first row looks like a header (contains "date" or "name" keywords)
+func hasHeaderRow(rows [][]string) bool {
+	if len(rows) == 0 || len(rows[0]) < 2 {
+		return false
+	}
+	firstRowLower := strings.ToLower(strings.Join(rows[0], " "))
+	return strings.Contains(firstRowLower, "date") || strings.Contains(firstRowLower, "name")
+}
+
+// parseImportDate parses a date from an import file using importDateFormats
+func parseImportDate(value string) (time.Time, bool) {
+	for _, format := range importDateFormats {
+		if t, err := time.Parse(format.Layout, value); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseImportDateWithFormat parses a date using the format with the given label
+// An empty label falls back to trying all formats
+func parseImportDateWithFormat(value, label string) (time.Time, bool) {
+	if label == "" {
+		return parseImportDate(value)
+	}
+	for _, format := range importDateFormats {
+		if format.Label == label {
+			t, err := time.Parse(format.Layout, value)
+			if err != nil {
+				return time.Time{}, false
+			}
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// isKnownDateFormat checks if label names one of importDateFormats
+func isKnownDateFormat(label string) bool {
+	for _, format := range importDateFormats {
+		if format.Label == label {
+			return true
+		}
+	}
+	return false
+}
+
+// candidateDateFormats returns the labels of all formats that parse every non-empty value
+func candidateDateFormats(values []string) []string {
+	candidates := []string{}
+	matchedLabels := make(map[string]bool)
+	for _, format := range importDateFormats {
+		if format.Padded != "" && matchedLabels[format.Padded] {
+			continue
+		}
+		matched := 0
+		ok := true
+		for _, value := range values {
+			if value == "" {
+				continue
+			}
+			if _, err := time.Parse(format.Layout, value); err != nil {
+				ok = false
+				break
+			}
+			matched++
+		}
+		if ok && matched > 0 {
+			candidates = append(candidates, format.Label)
+			matchedLabels[format.Label] = true
+		}
+	}
+	return candidates
+}
+
+// detectFileDateFormat picks the one date format used by the date and end date
+// columns of a file, so rows can't be read with different formats
+// Values no format parses are left to fail on their row. The file is refused
+// when its dates fit several formats or no single format fits them all.
+func detectFileDateFormat(rows [][]string, mapping importMapping) (string, *fiber.Error) {
+	var values []string
+	for _, column := range []int{mapping.DateColumn, mapping.EndDateColumn} {
+		if column == -1 {
+			continue
+		}
+		for _, value := range columnValues(rows, column, mapping.HasHeader) {
+			if _, ok := parseImportDate(value); ok {
+				values = append(values, value)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	formats := candidateDateFormats(values)
+	switch len(formats) {
+	case 0:
+		return "", fiber.NewError(fiber.StatusBadRequest, "Dates in the file use different formats; send date_format")
+	case 1:
+		return formats[0], nil
+	default:
+		return "", fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Ambiguous dates, send date_format (one of %s)", strings.Join(formats, ", ")))
+	}
+}
+
+// columnValues returns the trimmed values of a column, skipping the header row if present
+func columnValues(rows [][]string, column int, hasHeader bool) []string {
+	start := 0
+	if hasHeader {
+		start = 1
+	}
+	var values []string
+	for i := start; i < len(rows); i++ {
+		if column < len(rows[i]) {
+			values = append(values, strings.TrimSpace(rows[i][column]))
+		} else {
+			values = append(values, "")
+		}
+	}
+	return values
+}
+
+// columnCount returns the number of columns of the widest row
+func columnCount(rows [][]string) int {
+	count := 0
+	for _, row := range rows {
+		if len(row) > count {
+			count = len(row)
+		}
+	}
+	return count
+}
+
+// detectImportMapping guesses the date and name columns of an import file
+// Header names are used when present, otherwise the column whose values parse
+// as dates is the date column and the first other non-empty column the name column
+func detectImportMapping(rows [][]string) importMapping {
+	mapping := importMapping{DateColumn: -1, NameColumn: -1, EndDateColumn: -1, TypeColumn: -1, HasHeader: hasHeaderRow(rows)}
+	columns := columnCount(rows)
+
+	if mapping.HasHeader {
+		for i, header := range rows[0] {
+			header = strings.ToLower(header)
+			if mapping.EndDateColumn == -1 && strings.Contains(header, "end") && strings.Contains(header, "date") {
+				mapping.EndDateColumn = i
+			} else if mapping.DateColumn == -1 && strings.Contains(header, "date") {
+				mapping.DateColumn = i
+			} else if mapping.NameColumn == -1 && (strings.Contains(header, "name") || strings.Contains(header, "member")) {
+				mapping.NameColumn = i
+			} else if mapping.TypeColumn == -1 && strings.Contains(header, "type") {
+				mapping.TypeColumn = i
+			}
+		}
+	}
+
+	if mapping.DateColumn == -1 {
+		for i := 0; i < columns; i++ {
+			if i != mapping.NameColumn && i != mapping.EndDateColumn && len(candidateDateFormats(columnValues(rows, i, mapping.HasHeader))) > 0 {
+				mapping.DateColumn = i
+				break
+			}
+		}
+	}
+
+	if mapping.NameColumn == -1 {
+		for i := 0; i < columns; i++ {
+			if i == mapping.DateColumn || i == mapping.EndDateColumn || i == mapping.TypeColumn {
+				continue
+			}
+			for _, value := range columnValues(rows, i, mapping.HasHeader) {
+				if value != "" {
+					mapping.NameColumn = i
+					break
+				}
+			}
+			if mapping.NameColumn != -1 {
+				break
+			}
+		}
+	}
+
+	// Fall back to the historical layout: date in column 0, name in column 1
+	if mapping.DateColumn == -1 {
+		mapping.DateColumn = 0
+	}
+	if mapping.NameColumn == -1 {
+		mapping.NameColumn = 1
+	}
+
+	if formats := candidateDateFormats(columnValues(rows, mapping.DateColumn, mapping.HasHeader)); len(formats) > 0 {
+		mapping.DateFormat = formats[0]
+	}
+
+	return mapping
+}
+
+// parseImportMapping reads an explicit mapping from the form fields
+// date_column, name_column, end_date_column, the layout's type field,
+// date_format, has_header and expand_long_shifts. Fields that are not sent
+// keep the defaults of the layout (one date format detected for the whole
+// file, header detected from keywords). End date and type columns found in
+// the header take precedence over the layout.
+func parseImportMapping(c *fiber.Ctx, rows [][]string, layout importLayout) (importMapping, *fiber.Error) {
+	mapping := importMapping{DateColumn: layout.DateColumn, NameColumn: layout.NameColumn, EndDateColumn: layout.EndDateColumn, TypeColumn: layout.TypeColumn, HasHeader: hasHeaderRow(rows)}
+
+	if value := c.FormValue("has_header"); value != "" {
+		hasHeader, err := strconv.ParseBool(value)
+		if err != nil {
+			return mapping, fiber.NewError(fiber.StatusBadRequest, "Invalid has_header")
+		}
+		mapping.HasHeader = hasHeader
+	}
+
+	if value := c.FormValue("date_column"); value != "" {
+		column, err := strconv.Atoi(value)
+		if err != nil || column < 0 {
+			return mapping, fiber.NewError(fiber.StatusBadRequest, "Invalid date_column")
+		}
+		mapping.DateColumn = column
+	}
+
+	if value := c.FormValue("name_column"); value != "" {
+		column, err := strconv.Atoi(value)
+		if err != nil || column < 0 {
+			return mapping, fiber.NewError(fiber.StatusBadRequest, "Invalid name_column")
+		}
+		mapping.NameColumn = column
+	}
+
+	// Optional columns default to the "end date" and "type" headers, -1 disables them
+	if mapping.HasHeader {
+		detected := detectImportMapping(rows)
+		if detected.EndDateColumn != -1 {
+			mapping.EndDateColumn = detected.EndDateColumn
+		}
+		if detected.TypeColumn != -1 {
+			mapping.TypeColumn = detected.TypeColumn
+		}
+	}
+	for _, column := range []*int{&mapping.EndDateColumn, &mapping.TypeColumn} {
+		if *column == mapping.DateColumn || *column == mapping.NameColumn {
+			*column = -1
+		}
+	}
+
+	optionalColumns := []struct {
+		field  string
+		column *int
+	}{
+		{"end_date_column", &mapping.EndDateColumn},
+		{layout.TypeField, &mapping.TypeColumn},
+	}
+	for _, optional := range optionalColumns {
+		if value := c.FormValue(optional.field); value != "" {
+			column, err := strconv.Atoi(value)
+			if err != nil || column < -1 {
+				return mapping, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Invalid %s", optional.field))
+			}
+			*optional.column = column
+		}
+	}
+
+	// All used columns must be different
+	used := make(map[int]bool)
+	for _, column := range []int{mapping.DateColumn, mapping.NameColumn, mapping.EndDateColumn, mapping.TypeColumn} {
+		if column == -1 {
+			continue
+		}
+		if used[column] {
+			return mapping, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("date_column, name_column, end_date_column and %s must be different", layout.TypeField))
+		}
+		used[column] = true
+	}
+
+	if value := c.FormValue("date_format"); value != "" {
+		if !isKnownDateFormat(value) {
+			return mapping, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Unsupported date_format '%s'", value))
+		}
+		mapping.DateFormat = value
+	} else {
+		format, fiberErr := detectFileDateFormat(rows, mapping)
+		if fiberErr != nil {
+			return mapping, fiberErr
+		}
+		mapping.DateFormat = format
+	}
+
+	if value := c.FormValue("expand_long_shifts"); value != "" {
+		expand, err := strconv.ParseBool(value)
+		if err != nil {
+			return mapping, fiber.NewError(fiber.StatusBadRequest, "Invalid expand_long_shifts")
+		}
+		mapping.ExpandLongShifts = expand
+	}
+
+	return mapping, nil
+}
+
+// PreviewImport inspects an uploaded shift file without importing it
+// Returns the sheets, detected columns, sample rows, candidate date formats and
+// a suggested mapping the client can adjust and send back to ImportShifts
+func (h *Handler) PreviewImport(c *fiber.Ctx) error {
+	if _, err := authorize(c, models.RoleScheduler); err != nil {
+		return err
+	}
+
+	file, fiberErr := readUploadedFile(c, c.FormValue("sheet"))
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	type ColumnPreview struct {
+		Index       int      `json:"index"`
+		Header      string   `json:"header,omitempty"`
+		Samples     []string `json:"samples"`
+		DateFormats []string `json:"date_formats"`
+	}
+
+	type MappingPreview struct {
+		DateColumn      int    `json:"date_column"`
+		NameColumn      int    `json:"name_column"`
+		EndDateColumn   int    `json:"end_date_column"`
+		ShiftTypeColumn int    `json:"shift_type_column"`
+		DateFormat      string `json:"date_format,omitempty"`
+		HasHeader       bool   `json:"has_header"`
+		Sheet           string `json:"sheet,omitempty"`
+	}
+
+	mapping := detectImportMapping(file.Rows)
+
+	columns := []ColumnPreview{}
+	for i := 0; i < columnCount(file.Rows); i++ {
+		values := columnValues(file.Rows, i, mapping.HasHeader)
+		column := ColumnPreview{
+			Index:       i,
+			Samples:     values,
+			DateFormats: candidateDateFormats(values),
+		}
+		if len(column.Samples) > previewSampleRows {
+			column.Samples = column.Samples[:previewSampleRows]
+		}
+		if mapping.HasHeader && i < len(file.Rows[0]) {
+			column.Header = strings.TrimSpace(file.Rows[0][i])
+		}
+		columns = append(columns, column)
+	}
+
+	sampleRows := file.Rows
+	if len(sampleRows) > previewSampleRows {
+		sampleRows = sampleRows[:previewSampleRows]
+	}
+
+	allFormats := make([]string, len(importDateFormats))
+	for i, format := range importDateFormats {
+		allFormats[i] = format.Label
+	}
+
+	dateFormats := candidateDateFormats(columnValues(file.Rows, mapping.DateColumn, mapping.HasHeader))
+
+	return c.JSON(fiber.Map{
+		"filename":       file.Filename,
+		"sheets":         file.Sheets,
+		"sheet":          file.Sheet,
+		"row_count":      len(file.Rows),
+		"columns":        columns,
+		"sample_rows":    sampleRows,
+		"date_formats":   dateFormats,
+		"ambiguous_date": len(dateFormats) > 1,
+		"all_formats":    allFormats,
+		"suggested_mapping": MappingPreview{
+			DateColumn:      mapping.DateColumn,
+			NameColumn:      mapping.NameColumn,
+			EndDateColumn:   mapping.EndDateColumn,
+			ShiftTypeColumn: mapping.TypeColumn,
+			DateFormat:      mapping.DateFormat,
+			HasHeader:       mapping.HasHeader,
+			Sheet:           file.Sheet,
+		},
+	})
+}
+
+// optionalImportValue returns the trimmed value of an optional column
+// Optional columns may be missing or empty in a row, or not mapped at all (-1).
+func optionalImportValue(row []string, column int) string {
+	if column == -1 || column >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[column])
+}
+
+// parseBoolOption reads a boolean option from the form or the query string
+// Options that are not sent are false
+func parseBoolOption(c *fiber.Ctx, name string) (bool, *fiber.Error) {
+	value := c.FormValue(name)
+	if value == "" {
+		value = c.Query(name)
+	}
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Invalid %s", name))
+	}
+	return b, nil
+}
+
+// ImportShifts imports shifts from CSV or Excel file
+// Optional form fields (sheet, date_column, name_column, end_date_column,
+// shift_type_column, date_format, has_header, expand_long_shifts) select an
+// explicit mapping, usually the one returned by PreviewImport.
+// Valid rows are applied in a single transaction. Options:
+//   - dry_run: report what would be created or updated without writing anything
+//   - all_or_nothing: apply nothing if any row is invalid
+//   - force: import a file whose content was already imported before
+func (h *Handler) ImportShifts(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	// Read rows from the uploaded CSV or Excel file
+	file, fiberErr := readUploadedFile(c, c.FormValue("sheet"))
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	rows := file.Rows
+
+	mapping, fiberErr := parseImportMapping(c, rows, shiftImportLayout)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	options := make(map[string]bool)
+	for _, name := range []string{"dry_run", "all_or_nothing", "force"} {
+		value, fiberErr := parseBoolOption(c, name)
+		if fiberErr != nil {
+			return c.Status(fiberErr.Code).JSON(fiber.Map{
+				"error": fiberErr.Message,
+			})
+		}
+		options[name] = value
+	}
+	dryRun := options["dry_run"]
+
+	// Detect repeated uploads of the same file
+	previous, err := h.shifts.GetImportByHash(workspaceID, models.ImportKindShifts, file.Hash)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check previous imports",
+		})
+	}
+	if previous != nil && !dryRun && !options["force"] {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":           "This file has already been imported. Send force=true to import it again",
+			"content_hash":    file.Hash,
+			"previous_import": previous,
+		})
+	}
+
+	type ImportResult struct {
+		models.ShiftImportResult
+		DryRun      bool                 `json:"dry_run"`
+		Applied     bool                 `json:"applied"`
+		ContentHash string               `json:"content_hash"`
+		DuplicateOf *models.ImportRecord `json:"duplicate_of,omitempty"`
+		Errors      []string             `json:"errors"`
+	}
+
+	result := ImportResult{
+		DryRun:      dryRun,
+		ContentHash: file.Hash,
+		DuplicateOf: previous,
+		Errors:      []string{},
+	}
+
+	// Process each row (skip header if exists)
+	startRow := 0
+	if mapping.HasHeader {
+		startRow = 1
+	}
+
+	minColumns := max(mapping.DateColumn, mapping.NameColumn) + 1
+
+	var importRows []models.ShiftImportRow
+	for i := startRow; i < len(rows); i++ {
+		row := rows[i]
+		if len(row) < minColumns {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Insufficient columns (need at least %d)", i+1, minColumns))
+			continue
+		}
+
+		// Parse date
+		dateStr := strings.TrimSpace(row[mapping.DateColumn])
+		if dateStr == "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Empty date", i+1))
+			continue
+		}
+
+		date, parsed := parseImportDateWithFormat(dateStr, mapping.DateFormat)
+		if !parsed {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Invalid date format '%s'", i+1, dateStr))
+			continue
+		}
+
+		// Parse name
+		name := strings.TrimSpace(row[mapping.NameColumn])
+		if name == "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Empty name", i+1))
+			continue
+		}
+
+		// Parse shift type, defaults to a long shift if the next day is a holiday or weekend
+		isLongShift := models.WillBeLongShift(date)
+		if shiftType := strings.ToLower(optionalImportValue(row, mapping.TypeColumn)); shiftType != "" {
+			switch shiftType {
+			case models.ShiftTypeLong:
+				isLongShift = true
+			case models.ShiftTypeNormal:
+				isLongShift = false
+			default:
+				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Invalid shift type '%s'", i+1, shiftType))
+				continue
+			}
+		}
+
+		importRow := models.ShiftImportRow{
+			Row:         i + 1,
+			Name:        name,
+			StartDate:   date,
+			EndDate:     date,
+			IsLongShift: isLongShift,
+		}
+
+		// Parse end date, or expand a long shift the same way the planner does
+		if endDateStr := optionalImportValue(row, mapping.EndDateColumn); endDateStr != "" {
+			endDate, parsed := parseImportDateWithFormat(endDateStr, mapping.DateFormat)
+			if !parsed {
+				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Invalid end date format '%s'", i+1, endDateStr))
+				continue
+			}
+			if endDate.Before(date) {
+				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: End date is before start date", i+1))
+				continue
+			}
+			importRow.EndDate = endDate
+			importRow.HasRange = true
+		} else if mapping.ExpandLongShifts && isLongShift {
+			importRow.EndDate = models.LongShiftEndDate(date)
+			importRow.HasRange = true
+		}
+
+		importRows = append(importRows, importRow)
+	}
+
+	// With all_or_nothing an invalid row rejects the whole file; the rows are
+	// still evaluated so the response shows what the import would have done
+	rejected := options["all_or_nothing"] && len(result.Errors) > 0
+
+	importResult, err := h.shifts.ImportShifts(workspaceID, file.Filename, file.Hash, importRows, dryRun || rejected)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to import shifts: %v", err),
+		})
+	}
+	result.ShiftImportResult = *importResult
+	result.Applied = !dryRun && !rejected
+
+	if rejected {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(result)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// ImportLeaveDays imports leave days from CSV or Excel file
+// Columns default to member name, start date, end date (optional, defaults to
+// the start date) and leave type (optional, defaults to annual); the same form
+// fields as ImportShifts select another mapping, with leave_type_column for the
+// leave type. Members are resolved by name and are never created by the leave import.
+func (h *Handler) ImportLeaveDays(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	// Read rows from the uploaded CSV or Excel file
+	file, fiberErr := readUploadedFile(c, c.FormValue("sheet"))
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	rows := file.Rows
+
+	mapping, fiberErr := parseImportMapping(c, rows, leaveImportLayout)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	// Process rows
+	type ImportResult struct {
+		LeaveDaysImported int      `json:"leave_days_imported"`
+		RowsImported      int      `json:"rows_imported"`
+		Errors            []string `json:"errors"`
+	}
+
+	result := ImportResult{
+		Errors: []string{},
+	}
+
+	// Map to track member names to IDs
+	memberMap := make(map[string]int)
+
+	// Process each row (skip header if exists)
+	startRow := 0
+	if mapping.HasHeader {
+		startRow = 1
+	}
+
+	minColumns := max(mapping.DateColumn, mapping.NameColumn) + 1
+
+	for i := startRow; i < len(rows); i++ {
+		row := rows[i]
+		if len(row) < minColumns {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Insufficient columns (need at least %d)", i+1, minColumns))
+			continue
+		}
+
+		// Parse name
+		name := strings.TrimSpace(row[mapping.NameColumn])
+		if name == "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Empty name", i+1))
+			continue
+		}
+
+		// Parse start and end date
+		startDateStr := strings.TrimSpace(row[mapping.DateColumn])
+		startDate, parsed := parseImportDateWithFormat(startDateStr, mapping.DateFormat)
+		if !parsed {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Invalid start date format '%s'", i+1, startDateStr))
+			continue
+		}
+
+		endDate := startDate
+		if endDateStr := optionalImportValue(row, mapping.EndDateColumn); endDateStr != "" {
+			endDate, parsed = parseImportDateWithFormat(endDateStr, mapping.DateFormat)
+			if !parsed {
+				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Invalid end date format '%s'", i+1, endDateStr))
+				continue
+			}
+		}
+
+		if startDate.After(endDate) {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Start date is after end date", i+1))
+			continue
+		}
+
+		if leaveRangeTooLong(startDate, endDate) {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: %s", i+1, leaveRangeTooLongMessage))
+			continue
+		}
+
+		// Parse leave type
+		leaveType := models.LeaveTypeAnnual
+		if value := optionalImportValue(row, mapping.TypeColumn); value != "" {
+			leaveType = strings.ToLower(value)
+		}
+		if !models.IsValidLeaveType(leaveType) {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Invalid leave type '%s'", i+1, leaveType))
+			continue
+		}
+
+		// Resolve member by name
+		memberID, exists := memberMap[name]
+		if !exists {
+			member, err := h.members.GetMemberByName(workspaceID, name)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Member '%s' not found", i+1, name))
+				continue
+			}
+			memberID = member.ID
+			memberMap[name] = memberID
+		}
+
+		leaveDays, err := h.leave.CreateLeaveDaysRange(workspaceID, memberID, startDate, endDate, leaveType)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to create leave days: %v", i+1, err))
+			continue
+		}
+
+		result.LeaveDaysImported += len(leaveDays)
+		result.RowsImported++
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
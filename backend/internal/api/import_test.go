@@ -0,0 +1,495 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// newUploadRequest creates a multipart request uploading content as the "file" field
+func newUploadRequest(t *testing.T, url, filename string, content []byte) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, url, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestParseImportDate(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+		ok       bool
+	}{
+		{"2025-01-06", "2025-01-06", true},
+		{"06/01/2025", "2025-01-06", true},
+		{"2025/01/06", "2025-01-06", true},
+		{"not a date", "", false},
+	}
+
+	for _, tt := range tests {
+		result, ok := parseImportDate(tt.value)
+		if ok != tt.ok {
+			t.Errorf("parseImportDate(%q) ok = %v, want %v", tt.value, ok, tt.ok)
+			continue
+		}
+		if ok && result.Format("2006-01-02") != tt.expected {
+			t.Errorf("parseImportDate(%q) = %s, want %s", tt.value, result.Format("2006-01-02"), tt.expected)
+		}
+	}
+}
+
+func TestCandidateDateFormats(t *testing.T) {
+	// Day <= 12 is ambiguous between day-first and month-first formats
+	formats := candidateDateFormats([]string{"03/04/2025", "05/06/2025"})
+	if len(formats) < 2 || formats[0] != "DD/MM/YYYY" || formats[1] != "MM/DD/YYYY" {
+		t.Errorf("Expected DD/MM/YYYY and MM/DD/YYYY candidates, got %v", formats)
+	}
+
+	// 25 can only be a day
+	formats = candidateDateFormats([]string{"03/04/2025", "04/25/2025"})
+	if len(formats) != 1 || formats[0] != "MM/DD/YYYY" {
+		t.Errorf("Expected only MM/DD/YYYY, got %v", formats)
+	}
+}
+
+func TestDetectImportMapping(t *testing.T) {
+	// No header, name first and date second
+	rows := [][]string{
+		{"Alice", "2025-01-06"},
+		{"Bob", "2025-01-07"},
+	}
+
+	mapping := detectImportMapping(rows)
+	if mapping.HasHeader {
+		t.Error("Mapping should not detect a header")
+	}
+	if mapping.DateColumn != 1 || mapping.NameColumn != 0 {
+		t.Errorf("Column mismatch: got date=%d name=%d, want date=1 name=0", mapping.DateColumn, mapping.NameColumn)
+	}
+	if mapping.DateFormat != "YYYY-MM-DD" {
+		t.Errorf("Date format mismatch: got %s, want YYYY-MM-DD", mapping.DateFormat)
+	}
+	if mapping.EndDateColumn != -1 || mapping.TypeColumn != -1 {
+		t.Errorf("Optional columns should be disabled: got end=%d type=%d", mapping.EndDateColumn, mapping.TypeColumn)
+	}
+
+	// Header with end date and shift type
+	rows = [][]string{
+		{"Member", "Start Date", "End Date", "Shift Type"},
+		{"Alice", "2025-01-03", "2025-01-05", "long"},
+	}
+
+	mapping = detectImportMapping(rows)
+	if mapping.NameColumn != 0 || mapping.DateColumn != 1 || mapping.EndDateColumn != 2 || mapping.TypeColumn != 3 {
+		t.Errorf("Column mismatch: got %+v", mapping)
+	}
+}
+
+func TestImportShifts_ExplicitMapping(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	app := fiber.New()
+	app.Post("/api/shifts/import", h.AuthMiddleware, h.ImportShifts)
+
+	// Month-first dates would be read day-first without an explicit format
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "shifts.csv")
+	part.Write([]byte("Alice,01/06/2025\nBob,01/07/2025\n"))
+	writer.WriteField("date_column", "1")
+	writer.WriteField("name_column", "0")
+	writer.WriteField("date_format", "MM/DD/YYYY")
+	writer.WriteField("has_header", "false")
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shifts/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	shift, err := store.GetShiftByDate(workspaceID, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	if err != nil || shift == nil {
+		t.Fatalf("Expected a shift on 2025-01-06: %v", err)
+	}
+
+	member, _ := store.GetMemberByName(workspaceID, "Alice")
+	if member == nil || shift.MemberID != member.ID {
+		t.Error("Shift on 2025-01-06 should belong to Alice")
+	}
+}
+
+func TestImportShifts_FileDateFormat(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	app := fiber.New()
+	app.Post("/api/shifts/import", h.AuthMiddleware, h.ImportShifts)
+
+	send := func(content string) int {
+		req := newUploadRequest(t, "/api/shifts/import", "shifts.csv", []byte(content))
+		req.Header.Set("Authorization", token)
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	// Every date fits both day-first and month-first
+	if got := send("01/06/2025,Alice\n02/06/2025,Bob\n"); got != http.StatusBadRequest {
+		t.Errorf("Ambiguous dates: expected %d, got %d", http.StatusBadRequest, got)
+	}
+	// No single format reads all dates
+	if got := send("13/01/2025,Alice\n01/14/2025,Bob\n"); got != http.StatusBadRequest {
+		t.Errorf("Mixed formats: expected %d, got %d", http.StatusBadRequest, got)
+	}
+
+	// 01/13/2025 settles month-first for the whole file, so 01/06/2025 is January 6
+	if got := send("01/13/2025,Alice\n01/06/2025,Bob\n"); got != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, got)
+	}
+	shift, err := store.GetShiftByDate(workspaceID, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	if err != nil || shift == nil {
+		t.Fatalf("Expected a shift on 2025-01-06: %v", err)
+	}
+}
+
+func TestImportShifts_DryRunAndDuplicates(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	app := fiber.New()
+	app.Post("/api/shifts/import", h.AuthMiddleware, h.ImportShifts)
+
+	csvContent := []byte("date,name\n2025-01-06,Alice\n2025-01-07,Bob\n")
+
+	type importResponse struct {
+		MembersCreated  int      `json:"members_created"`
+		ShiftsCreated   int      `json:"shifts_created"`
+		ShiftsUnchanged int      `json:"shifts_unchanged"`
+		Applied         bool     `json:"applied"`
+		Errors          []string `json:"errors"`
+		Changes         []struct {
+			Action string `json:"action"`
+		} `json:"changes"`
+	}
+
+	// Dry run reports the changes without writing anything
+	req := newUploadRequest(t, "/api/shifts/import?dry_run=true", "shifts.csv", csvContent)
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var result importResponse
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result.Applied || result.MembersCreated != 2 || result.ShiftsCreated != 2 || len(result.Changes) != 2 {
+		t.Errorf("Dry run result mismatch: %+v", result)
+	}
+	if members, _ := store.GetAllMembers(workspaceID); len(members) != 0 {
+		t.Errorf("Dry run must not create members, got %d", len(members))
+	}
+
+	// Real import
+	req = newUploadRequest(t, "/api/shifts/import", "shifts.csv", csvContent)
+	req.Header.Set("Authorization", token)
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if !result.Applied || result.ShiftsCreated != 2 {
+		t.Errorf("Import result mismatch: %+v", result)
+	}
+
+	// Uploading the same file again is detected
+	req = newUploadRequest(t, "/api/shifts/import", "shifts.csv", csvContent)
+	req.Header.Set("Authorization", token)
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusConflict, resp.StatusCode)
+	}
+
+	// Forcing it is idempotent
+	req = newUploadRequest(t, "/api/shifts/import?force=true", "shifts.csv", csvContent)
+	req.Header.Set("Authorization", token)
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	result = importResponse{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result.ShiftsCreated != 0 || result.ShiftsUnchanged != 2 || result.MembersCreated != 0 {
+		t.Errorf("Forced re-import should change nothing: %+v", result)
+	}
+}
+
+func TestImportShifts_AllOrNothing(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	app := fiber.New()
+	app.Post("/api/shifts/import", h.AuthMiddleware, h.ImportShifts)
+
+	csvContent := []byte("date,name\n2025-01-06,Alice\nnot a date,Bob\n")
+
+	req := newUploadRequest(t, "/api/shifts/import?all_or_nothing=true", "shifts.csv", csvContent)
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+
+	if members, _ := store.GetAllMembers(workspaceID); len(members) != 0 {
+		t.Errorf("Rejected import must not create members, got %d", len(members))
+	}
+
+	// Without the option the valid row is imported
+	req = newUploadRequest(t, "/api/shifts/import", "shifts.csv", csvContent)
+	req.Header.Set("Authorization", token)
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	shift, _ := store.GetShiftByDate(workspaceID, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	if shift == nil {
+		t.Error("Expected a shift on 2025-01-06")
+	}
+}
+
+func TestImportShifts_Ranges(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	app := fiber.New()
+	app.Post("/api/shifts/import", h.AuthMiddleware, h.ImportShifts)
+
+	// A single-day Saturday shift that the Friday-Sunday range replaces
+	bob, _ := store.CreateMember(workspaceID, "Bob")
+	store.CreateShift(workspaceID, bob.ID, time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC), false)
+
+	csvContent := "start date,name,end date,shift type\n" +
+		"2025-01-03,Alice,2025-01-05,long\n" +
+		"2025-01-06,Bob,,\n" +
+		"2025-01-10,Carol,,\n" +
+		"2025-01-07,Dave,,weekly\n"
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "shifts.csv")
+	part.Write([]byte(csvContent))
+	writer.WriteField("expand_long_shifts", "true")
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shifts/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var result struct {
+		ShiftsCreated  int      `json:"shifts_created"`
+		ShiftsReplaced int      `json:"shifts_replaced"`
+		Errors         []string `json:"errors"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+
+	if result.ShiftsCreated != 3 || result.ShiftsReplaced != 1 {
+		t.Errorf("Import mismatch: got %d created / %d replaced, want 3 / 1", result.ShiftsCreated, result.ShiftsReplaced)
+	}
+	if len(result.Errors) != 1 {
+		t.Errorf("Expected 1 row error for the invalid shift type, got %v", result.Errors)
+	}
+
+	tests := []struct {
+		date        time.Time
+		start       time.Time
+		end         time.Time
+		isLongShift bool
+	}{
+		// Explicit range
+		{time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), true},
+		// Working day stays a single day
+		{time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), false},
+		// Friday expanded to the weekend
+		{time.Date(2025, 1, 12, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 12, 0, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		shift, err := store.GetShiftByDate(workspaceID, tt.date)
+		if err != nil || shift == nil {
+			t.Fatalf("Expected a shift on %s: %v", tt.date.Format("2006-01-02"), err)
+		}
+		if !shift.StartDate.Equal(tt.start) || !shift.EndDate.Equal(tt.end) || shift.IsLongShift != tt.isLongShift {
+			t.Errorf("Shift on %s mismatch: got %s - %s long=%v", tt.date.Format("2006-01-02"), shift.StartDate.Format("2006-01-02"), shift.EndDate.Format("2006-01-02"), shift.IsLongShift)
+		}
+	}
+
+	// Bob lost the replaced Saturday and gained Monday
+	normalShifts, longShifts, _ := store.GetHiddenShiftCounts(workspaceID, bob.ID)
+	if normalShifts != 1 || longShifts != 0 {
+		t.Errorf("Bob hidden counters mismatch: got %d/%d, want 1/0", normalShifts, longShifts)
+	}
+}
+
+func TestPreviewImport(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	app := fiber.New()
+	app.Post("/api/shifts/import/preview", h.AuthMiddleware, h.PreviewImport)
+
+	req := newUploadRequest(t, "/api/shifts/import/preview", "shifts.csv", []byte("Member Name,Shift Date\nAlice,03/04/2025\n"))
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var preview struct {
+		AmbiguousDate    bool `json:"ambiguous_date"`
+		SuggestedMapping struct {
+			DateColumn int  `json:"date_column"`
+			NameColumn int  `json:"name_column"`
+			HasHeader  bool `json:"has_header"`
+		} `json:"suggested_mapping"`
+	}
+	json.NewDecoder(resp.Body).Decode(&preview)
+
+	if !preview.SuggestedMapping.HasHeader || preview.SuggestedMapping.DateColumn != 1 || preview.SuggestedMapping.NameColumn != 0 {
+		t.Errorf("Suggested mapping mismatch: %+v", preview.SuggestedMapping)
+	}
+
+	if !preview.AmbiguousDate {
+		t.Error("03/04/2025 should be reported as ambiguous")
+	}
+
+	// Preview must not import anything
+	members, _ := store.GetAllMembers(workspaceID)
+	if len(members) != 0 {
+		t.Errorf("Preview should not create members, got %d", len(members))
+	}
+}
+
+func TestImportLeaveDays(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	member, _ := store.CreateMember(workspaceID, "Alice")
+
+	app := fiber.New()
+	app.Post("/api/leave-days/import", h.AuthMiddleware, h.ImportLeaveDays)
+
+	csvContent := "name,start date,end date,type\n" +
+		"alice,2025-01-06,2025-01-08,sick\n" +
+		"Bob,2025-01-06,2025-01-06,annual\n" +
+		"Alice,2025-01-10,2025-01-09,annual\n" +
+		"Alice,2025-01-01,2026-12-31,unpaid\n"
+	req := newUploadRequest(t, "/api/leave-days/import", "leave.csv", []byte(csvContent))
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var result struct {
+		LeaveDaysImported int      `json:"leave_days_imported"`
+		RowsImported      int      `json:"rows_imported"`
+		Errors            []string `json:"errors"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+
+	if result.RowsImported != 1 || result.LeaveDaysImported != 3 {
+		t.Errorf("Import mismatch: got %d rows / %d days, want 1 / 3", result.RowsImported, result.LeaveDaysImported)
+	}
+
+	// Unknown member, reversed and overlong ranges are reported per row
+	if len(result.Errors) != 3 {
+		t.Errorf("Expected 3 row errors, got %v", result.Errors)
+	}
+
+	leaveDays, _ := store.GetLeaveDaysByMember(workspaceID, member.ID)
+	if len(leaveDays) != 3 || leaveDays[0].LeaveType != "sick" {
+		t.Errorf("Imported leave days mismatch: %+v", leaveDays)
+	}
+
+	if _, err := store.GetMemberByName(workspaceID, "Bob"); err == nil {
+		t.Error("Leave import must not create members")
+	}
+}
+
+func TestImportLeaveDays_ExplicitMapping(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	member, _ := store.CreateMember(workspaceID, "Alice")
+
+	app := fiber.New()
+	app.Post("/api/leave-days/import", h.AuthMiddleware, h.ImportLeaveDays)
+
+	// Leave files use the same mapping fields as shift files; rows without an end date cover one day
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "leave.csv")
+	part.Write([]byte("sick,01/06/2025,Alice\n,01/08/2025,Alice\n"))
+	writer.WriteField("date_column", "1")
+	writer.WriteField("name_column", "2")
+	writer.WriteField("end_date_column", "-1")
+	writer.WriteField("leave_type_column", "0")
+	writer.WriteField("date_format", "MM/DD/YYYY")
+	writer.WriteField("has_header", "false")
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/leave-days/import", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	leaveDays, _ := store.GetLeaveDaysByMember(workspaceID, member.ID)
+	if len(leaveDays) != 2 || !leaveDays[0].LeaveDate.Equal(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)) || leaveDays[0].LeaveType != "sick" || leaveDays[1].LeaveType != "annual" {
+		t.Errorf("Imported leave days mismatch: %+v", leaveDays)
+	}
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/mail"
+	"shiftplanner/backend/internal/auth"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// workspaceInviteExpiry how long a workspace invite can be accepted
+const workspaceInviteExpiry = 7 * 24 * time.Hour
+
+// CreateWorkspaceInvite invites someone to the active workspace
+// The token is returned once; only its hash is stored.
+func (h *Handler) CreateWorkspaceInvite(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	if _, err := mail.ParseAddress(req.Email); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid email",
+		})
+	}
+	if req.Role == "" {
+		req.Role = models.RoleMember
+	}
+	if !models.IsValidRole(req.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid role",
+		})
+	}
+	// Schedulers can't hand out more rights than they have
+	if !models.RoleAtLeast(GetRole(c), req.Role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Cannot invite with a role above your own",
+		})
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate token",
+		})
+	}
+
+	invite, err := h.workspaces.CreateWorkspaceInvite(workspaceID, GetUserID(c), req.Email, req.Role, auth.HashToken(token), time.Now().UTC().Add(workspaceInviteExpiry))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	invite.Token = token
+
+	return c.Status(fiber.StatusCreated).JSON(invite)
+}
+
+// GetWorkspaceInvites lists the pending invites of the active workspace
+func (h *Handler) GetWorkspaceInvites(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	invites, err := h.workspaces.GetWorkspaceInvites(workspaceID, time.Now().UTC())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if invites == nil {
+		invites = []models.WorkspaceInvite{}
+	}
+
+	return c.JSON(invites)
+}
+
+// DeleteWorkspaceInvite revokes a pending invite
+func (h *Handler) DeleteWorkspaceInvite(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	inviteID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid invite ID",
+		})
+	}
+
+	if err := h.workspaces.DeleteWorkspaceInvite(workspaceID, inviteID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Invite not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// AcceptInviteRequest credentials of the invitee when not already logged in
+// With Register set an account is created, otherwise the user logs in.
+type AcceptInviteRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Register bool   `json:"register"`
+}
+
+// AcceptWorkspaceInvite joins the workspace of an invite
+// Logged in users accept with their session; others register or log in with the request.
+func (h *Handler) AcceptWorkspaceInvite(c *fiber.Ctx) error {
+	tokenHash := auth.HashToken(c.Params("token"))
+	now := time.Now().UTC()
+
+	if token := c.Get("Authorization"); token != "" {
+		if userID, err := auth.ValidateToken(h.sessions, token); err == nil {
+			workspace, err := h.workspaces.AcceptWorkspaceInvite(tokenHash, userID, now)
+			if err != nil {
+				return workspaceInviteError(c, err)
+			}
+			return c.JSON(fiber.Map{
+				"workspace": workspace,
+			})
+		}
+	}
+
+	var req AcceptInviteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var user *models.User
+	if req.Register {
+		if msg := h.validateRegistration(req.Username, req.Password); msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": msg,
+			})
+		}
+		// Don't leave an account behind for an invite that can't be used
+		if _, err := h.workspaces.GetWorkspaceInvite(tokenHash, now); err != nil {
+			return workspaceInviteError(c, err)
+		}
+		var err error
+		if user, err = h.users.CreateUser(req.Username, req.Password); err != nil {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Username already exists",
+			})
+		}
+	} else {
+		if req.Username == "" || req.Password == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Username and password are required",
+			})
+		}
+		var wait time.Duration
+		if user, wait = h.authenticate(req.Username, req.Password, strings.Clone(c.IP())); user == nil {
+			return loginFailed(c, wait)
+		}
+		// A password alone mustn't get around the second factor
+		enabled, err := h.hasTwoFactor(user.ID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if enabled {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Two-factor authentication is enabled; log in first and accept the invite with your session",
+			})
+		}
+		h.logins.Succeed(user.Username)
+	}
+
+	workspace, err := h.workspaces.AcceptWorkspaceInvite(tokenHash, user.ID, now)
+	if err != nil {
+		return workspaceInviteError(c, err)
+	}
+
+	session, err := h.createSession(c, user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"workspace": workspace,
+		"user":      user,
+		"token":     session.Token,
+	})
+}
+
+// workspaceInviteError maps storage errors of invites to responses
+func workspaceInviteError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, storage.ErrWorkspaceInviteNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Invite not found or expired",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shiftplanner/backend/internal/models"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestWorkspaceInvites(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	ownerToken := "test_token_123"
+	createTestSession(t, store, workspaceID, ownerToken)
+	scheduler, _ := store.CreateUser("scheduler", "testpassword")
+	schedulerToken := "test_token_scheduler"
+	createTestSession(t, store, personalWorkspace(t, store, scheduler.ID), schedulerToken)
+	store.SetWorkspaceUserRole(workspaceID, scheduler.ID, models.RoleScheduler)
+
+	app := fiber.New()
+	app.Post("/api/invites/:token/accept", h.AcceptWorkspaceInvite)
+	app.Get("/api/workspace/invites", h.AuthMiddleware, h.GetWorkspaceInvites)
+	app.Post("/api/workspace/invites", h.AuthMiddleware, h.CreateWorkspaceInvite)
+	app.Delete("/api/workspace/invites/:id", h.AuthMiddleware, h.DeleteWorkspaceInvite)
+
+	send := func(method, path, token, body string) *http.Response {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+			req.Header.Set("X-Workspace-ID", strconv.Itoa(workspaceID))
+		}
+		resp, _ := app.Test(req)
+		return resp
+	}
+	invite := func(role string) models.WorkspaceInvite {
+		resp := send(http.MethodPost, "/api/workspace/invites", schedulerToken, `{"email":"new@example.com","role":"`+role+`"}`)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status code: %d, got %d", http.StatusCreated, resp.StatusCode)
+		}
+		var invite models.WorkspaceInvite
+		json.NewDecoder(resp.Body).Decode(&invite)
+		return invite
+	}
+
+	if resp := send(http.MethodPost, "/api/workspace/invites", schedulerToken, `{"email":"not an email"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Invalid email: expected %d, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+	if resp := send(http.MethodPost, "/api/workspace/invites", schedulerToken, `{"email":"new@example.com","role":"owner"}`); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Role above own: expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	registerInvite := invite(models.RoleMember)
+	if registerInvite.Token == "" {
+		t.Fatal("Expected the token in the create response")
+	}
+
+	resp := send(http.MethodGet, "/api/workspace/invites", ownerToken, "")
+	var invites []models.WorkspaceInvite
+	json.NewDecoder(resp.Body).Decode(&invites)
+	if len(invites) != 1 || invites[0].Token != "" {
+		t.Errorf("Expected 1 invite without its token, got %+v", invites)
+	}
+
+	// Invitees without an account register while accepting
+	resp = send(http.MethodPost, "/api/invites/"+registerInvite.Token+"/accept", "", `{"username":"newuser","password":"testpassword","register":true}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var accepted struct {
+		Workspace models.Workspace `json:"workspace"`
+		User      models.User      `json:"user"`
+		Token     string           `json:"token"`
+	}
+	json.NewDecoder(resp.Body).Decode(&accepted)
+	if accepted.Workspace.ID != workspaceID || accepted.Workspace.Role != models.RoleMember || accepted.Token == "" {
+		t.Errorf("Unexpected accept response: %+v", accepted)
+	}
+	if role, _ := store.GetWorkspaceRole(workspaceID, accepted.User.ID); role != models.RoleMember {
+		t.Errorf("Role mismatch: got %q, want member", role)
+	}
+
+	// The token can't be used twice, and no account is created for it
+	resp = send(http.MethodPost, "/api/invites/"+registerInvite.Token+"/accept", "", `{"username":"another","password":"testpassword","register":true}`)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Used invite: expected %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+	if _, _, err := store.GetUserByUsername("another"); err == nil {
+		t.Error("No account should be created for a used invite")
+	}
+
+	// Existing users log in while accepting
+	store.CreateUser("existing", "testpassword")
+	loginInvite := invite(models.RoleViewer)
+	resp = send(http.MethodPost, "/api/invites/"+loginInvite.Token+"/accept", "", `{"username":"existing","password":"wrong"}`)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Wrong password: expected %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+	resp = send(http.MethodPost, "/api/invites/"+loginInvite.Token+"/accept", "", `{"username":"existing","password":"testpassword"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	// Logged in users accept with their session
+	sessionInvite := invite(models.RoleViewer)
+	req := httptest.NewRequest(http.MethodPost, "/api/invites/"+sessionInvite.Token+"/accept", nil)
+	req.Header.Set("Authorization", accepted.Token)
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if role, _ := store.GetWorkspaceRole(workspaceID, accepted.User.ID); role != models.RoleMember {
+		t.Errorf("Accepting a lower role should keep member, got %q", role)
+	}
+
+	revoked := invite(models.RoleViewer)
+	if resp := send(http.MethodDelete, "/api/workspace/invites/"+strconv.Itoa(revoked.ID), schedulerToken, ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status code: %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	resp = send(http.MethodPost, "/api/invites/"+revoked.Token+"/accept", "", `{"username":"existing","password":"testpassword"}`)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Revoked invite: expected %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
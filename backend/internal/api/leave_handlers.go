@@ -0,0 +1,380 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/storage"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxLeaveRangeDays longest range of leave one request, import row or direct entry may cover
+const maxLeaveRangeDays = 366
+
+// leaveRangeTooLong checks if a leave range covers more than maxLeaveRangeDays
+func leaveRangeTooLong(startDate, endDate time.Time) bool {
+	return endDate.Sub(startDate) >= maxLeaveRangeDays*24*time.Hour
+}
+
+// leaveRangeTooLongMessage error for ranges rejected by leaveRangeTooLong
+var leaveRangeTooLongMessage = fmt.Sprintf("Leave can cover at most %d days at once", maxLeaveRangeDays)
+
+// CreateLeaveRequest creates a pending leave request
+// Requested days are not visible to the planner until the request is approved.
+// Below the scheduler role users can only request leave for their linked member.
+func (h *Handler) CreateLeaveRequest(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleMember)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		MemberID  int    `json:"member_id"`
+		StartDate string `json:"start_date"`
+		EndDate   string `json:"end_date"`
+		LeaveType string `json:"leave_type"`
+		Note      string `json:"note"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.MemberID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "member_id is required",
+		})
+	}
+
+	if req.LeaveType == "" {
+		req.LeaveType = models.LeaveTypeAnnual
+	}
+
+	if !models.IsValidLeaveType(req.LeaveType) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid leave_type",
+		})
+	}
+
+	startDate, err := parseDateParam(req.StartDate)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid start_date format (use YYYY-MM-DD)",
+		})
+	}
+
+	endDate, err := parseDateParam(req.EndDate)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid end_date format (use YYYY-MM-DD)",
+		})
+	}
+
+	if startDate.After(endDate) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "start_date must be before or equal to end_date",
+		})
+	}
+
+	if leaveRangeTooLong(startDate, endDate) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": leaveRangeTooLongMessage,
+		})
+	}
+
+	member, err := h.members.GetMemberByID(workspaceID, req.MemberID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Member not found",
+		})
+	}
+	if !models.RoleAtLeast(GetRole(c), models.RoleScheduler) && member.UserID != GetUserID(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You can only request leave for your own member",
+		})
+	}
+
+	leaveRequest, err := h.leave.CreateLeaveRequest(workspaceID, req.MemberID, startDate, endDate, req.LeaveType, req.Note)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	leaveRequest.MemberName = member.Name
+
+	return c.Status(fiber.StatusCreated).JSON(leaveRequest)
+}
+
+// GetLeaveRequests returns leave requests, optionally filtered by status and member
+func (h *Handler) GetLeaveRequests(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	status := c.Query("status")
+	if status != "" && status != models.LeaveStatusPending && status != models.LeaveStatusApproved && status != models.LeaveStatusRejected {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid status",
+		})
+	}
+
+	memberID := 0
+	if memberIDStr := c.Query("member_id"); memberIDStr != "" {
+		var err error
+		memberID, err = strconv.Atoi(memberIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid member_id",
+			})
+		}
+	}
+
+	requests, err := h.leave.GetLeaveRequests(workspaceID, status, memberID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Add member names
+	members, err := h.members.GetAllMembers(workspaceID)
+	if err == nil {
+		memberMap := make(map[int]string)
+		for _, m := range members {
+			memberMap[m.ID] = m.Name
+		}
+		for i := range requests {
+			requests[i].MemberName = memberMap[requests[i].MemberID]
+		}
+	}
+
+	if requests == nil {
+		requests = []models.LeaveRequest{}
+	}
+
+	return c.JSON(requests)
+}
+
+// ApproveLeaveRequest approves a pending leave request
+func (h *Handler) ApproveLeaveRequest(c *fiber.Ctx) error {
+	h = h.audited(c)
+	return h.decideLeaveRequest(c, h.leave.ApproveLeaveRequest)
+}
+
+// RejectLeaveRequest rejects a pending leave request
+func (h *Handler) RejectLeaveRequest(c *fiber.Ctx) error {
+	h = h.audited(c)
+	return h.decideLeaveRequest(c, h.leave.RejectLeaveRequest)
+}
+
+// decideLeaveRequest applies an approve or reject decision to the request in the URL
+func (h *Handler) decideLeaveRequest(c *fiber.Ctx, decide func(workspaceID, requestID int) (*models.LeaveRequest, error)) error {
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	requestID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid leave request ID",
+		})
+	}
+
+	leaveRequest, err := decide(workspaceID, requestID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Leave request not found",
+			})
+		}
+		if errors.Is(err, storage.ErrLeaveRequestNotPending) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Add member name
+	member, err := h.members.GetMemberByID(workspaceID, leaveRequest.MemberID)
+	if err == nil {
+		leaveRequest.MemberName = member.Name
+	}
+
+	return c.JSON(leaveRequest)
+}
+
+// GetLeaveAllowances returns leave allowances for a year (defaults to current year)
+func (h *Handler) GetLeaveAllowances(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	year, err := parseYearParam(c.Query("year"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid year",
+		})
+	}
+
+	allowances, err := h.leave.GetLeaveAllowances(workspaceID, year)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Add member names
+	members, err := h.members.GetAllMembers(workspaceID)
+	if err == nil {
+		memberMap := make(map[int]string)
+		for _, m := range members {
+			memberMap[m.ID] = m.Name
+		}
+		for i := range allowances {
+			allowances[i].MemberName = memberMap[allowances[i].MemberID]
+		}
+	}
+
+	if allowances == nil {
+		allowances = []models.LeaveAllowance{}
+	}
+
+	return c.JSON(allowances)
+}
+
+// SetLeaveAllowance sets a member's yearly allowance for a leave type
+func (h *Handler) SetLeaveAllowance(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		MemberID  int    `json:"member_id"`
+		Year      int    `json:"year"`
+		LeaveType string `json:"leave_type"`
+		Days      int    `json:"days"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.MemberID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "member_id is required",
+		})
+	}
+
+	if req.Year == 0 {
+		req.Year = time.Now().UTC().Year()
+	}
+
+	if req.LeaveType == "" {
+		req.LeaveType = models.LeaveTypeAnnual
+	}
+
+	if !models.IsValidLeaveType(req.LeaveType) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid leave_type",
+		})
+	}
+
+	if req.Days < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "days cannot be negative",
+		})
+	}
+
+	member, err := h.members.GetMemberByID(workspaceID, req.MemberID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Member not found",
+		})
+	}
+
+	allowance, err := h.leave.SetLeaveAllowance(workspaceID, req.MemberID, req.Year, req.LeaveType, req.Days)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	allowance.MemberName = member.Name
+
+	return c.JSON(allowance)
+}
+
+// GetLeaveBalances returns used and remaining leave days per member for a year
+func (h *Handler) GetLeaveBalances(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	year, err := parseYearParam(c.Query("year"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid year",
+		})
+	}
+
+	memberID := 0
+	if memberIDStr := c.Query("member_id"); memberIDStr != "" {
+		memberID, err = strconv.Atoi(memberIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid member_id",
+			})
+		}
+	}
+
+	balances, err := h.leave.GetLeaveBalances(workspaceID, year, memberID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if balances == nil {
+		balances = []models.LeaveBalance{}
+	}
+
+	return c.JSON(balances)
+}
+
+// parseDateParam parses a "YYYY-MM-DD" date and normalizes it to UTC midnight
+func parseDateParam(value string) (time.Time, error) {
+	parsedDate, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+// parseYearParam parses a year query parameter, defaulting to the current year
+func parseYearParam(value string) (int, error) {
+	if value == "" {
+		return time.Now().UTC().Year(), nil
+	}
+	year, err := strconv.Atoi(value)
+	if err != nil || year < 1900 || year > 9999 {
+		return 0, errors.New("invalid year")
+	}
+	return year, nil
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shiftplanner/backend/internal/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLeaveRequestApprovalFlow(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	member, _ := store.CreateMember(workspaceID, "Test Member")
+
+	app := fiber.New()
+	app.Post("/api/leave-requests", h.AuthMiddleware, h.CreateLeaveRequest)
+	app.Post("/api/leave-requests/:id/approve", h.AuthMiddleware, h.ApproveLeaveRequest)
+
+	body := bytes.NewBufferString(`{"member_id":` + strconv.Itoa(member.ID) + `,"start_date":"2025-01-06","end_date":"2025-01-07","leave_type":"annual"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/leave-requests", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	var leaveRequest models.LeaveRequest
+	json.NewDecoder(resp.Body).Decode(&leaveRequest)
+
+	if leaveRequest.Status != models.LeaveStatusPending {
+		t.Errorf("Status mismatch: got %s, want %s", leaveRequest.Status, models.LeaveStatusPending)
+	}
+
+	approveURL := "/api/leave-requests/" + strconv.Itoa(leaveRequest.ID) + "/approve"
+	req = httptest.NewRequest(http.MethodPost, approveURL, nil)
+	req.Header.Set("Authorization", token)
+	resp, _ = app.Test(req)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	// Approving twice is a conflict
+	req = httptest.NewRequest(http.MethodPost, approveURL, nil)
+	req.Header.Set("Authorization", token)
+	resp, _ = app.Test(req)
+
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status code: %d, got %d", http.StatusConflict, resp.StatusCode)
+	}
+}
+
+func TestCreateLeaveRequest_OtherMember(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+	alice, _ := store.CreateMember(workspaceID, "Alice")
+	bob, _ := store.CreateMember(workspaceID, "Bob")
+
+	user, _ := store.CreateUser("alice", "testpassword")
+	token := "test_token_alice"
+	createTestSession(t, store, personalWorkspace(t, store, user.ID), token)
+	store.SetWorkspaceUserRole(workspaceID, user.ID, models.RoleMember)
+	store.CreateMemberInvite(workspaceID, alice.ID, "invite", time.Now().Add(time.Hour))
+	store.AcceptMemberInvite(user.ID, "invite", time.Now())
+
+	app := fiber.New()
+	app.Post("/api/leave-requests", h.AuthMiddleware, h.CreateLeaveRequest)
+
+	send := func(memberID int) int {
+		body := bytes.NewBufferString(`{"member_id":` + strconv.Itoa(memberID) + `,"start_date":"2025-01-06","end_date":"2025-01-07"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/leave-requests", body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		req.Header.Set("X-Workspace-ID", strconv.Itoa(workspaceID))
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	if got := send(alice.ID); got != http.StatusCreated {
+		t.Errorf("Own leave: expected status code %d, got %d", http.StatusCreated, got)
+	}
+	if got := send(bob.ID); got != http.StatusForbidden {
+		t.Errorf("A colleague's leave: expected status code %d, got %d", http.StatusForbidden, got)
+	}
+	if requests, _ := store.GetLeaveRequests(workspaceID, "", bob.ID); len(requests) != 0 {
+		t.Errorf("Expected no request for Bob, got %+v", requests)
+	}
+}
+
+func TestCreateLeaveRequest_InvalidLeaveType(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	member, _ := store.CreateMember(workspaceID, "Test Member")
+
+	app := fiber.New()
+	app.Post("/api/leave-requests", h.AuthMiddleware, h.CreateLeaveRequest)
+
+	body := bytes.NewBufferString(`{"member_id":` + strconv.Itoa(member.ID) + `,"start_date":"2025-01-06","end_date":"2025-01-07","leave_type":"holiday"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/leave-requests", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code: %d, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+func TestCreateLeaveRequest_RangeTooLong(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	member, _ := store.CreateMember(workspaceID, "Test Member")
+
+	app := fiber.New()
+	app.Post("/api/leave-requests", h.AuthMiddleware, h.CreateLeaveRequest)
+
+	send := func(endDate string) int {
+		body := bytes.NewBufferString(`{"member_id":` + strconv.Itoa(member.ID) + `,"start_date":"2025-01-01","end_date":"` + endDate + `"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/leave-requests", body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	// 366 days are allowed, one more is not
+	if got := send("2026-01-01"); got != http.StatusCreated {
+		t.Errorf("Expected status code: %d, got %d", http.StatusCreated, got)
+	}
+	if got := send("2026-01-02"); got != http.StatusBadRequest {
+		t.Errorf("Expected status code: %d, got %d", http.StatusBadRequest, got)
+	}
+}
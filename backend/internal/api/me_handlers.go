@@ -0,0 +1,274 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"shiftplanner/backend/internal/auth"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/storage"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// memberInviteExpiry how long a member invite can be accepted
+const memberInviteExpiry = 7 * 24 * time.Hour
+
+// CreateMemberInvite creates a single-use token that links the account accepting it to a member
+func (h *Handler) CreateMemberInvite(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	memberID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid member ID",
+		})
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate token",
+		})
+	}
+
+	invite, err := h.members.CreateMemberInvite(workspaceID, memberID, auth.HashToken(token), time.Now().UTC().Add(memberInviteExpiry))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Member not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	invite.Token = token
+
+	return c.Status(fiber.StatusCreated).JSON(invite)
+}
+
+// UnlinkMember removes the link between a member and their user account
+func (h *Handler) UnlinkMember(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	memberID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid member ID",
+		})
+	}
+
+	if err := h.members.UnlinkMember(workspaceID, memberID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Member not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// AcceptMemberInvite links the authenticated user to the member of an invite
+// Users without access to the member's workspace join it with the member role.
+func (h *Handler) AcceptMemberInvite(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "token is required",
+		})
+	}
+
+	workspaceID, member, err := h.members.AcceptMemberInvite(userID, auth.HashToken(req.Token), time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, storage.ErrMemberInviteNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Invite not found or expired",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"workspace_id": workspaceID,
+		"member":       member,
+	})
+}
+
+// GetMyShifts returns the shifts of the member linked to the authenticated user
+// Defaults to the range of GetShifts: one month back to one month ahead.
+func (h *Handler) GetMyShifts(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	member, err := h.linkedMember(c, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	startDate, endDate := today.AddDate(0, -1, 0), today.AddDate(0, 1, 0)
+	if value := c.Query("start_date"); value != "" {
+		if startDate, err = parseDateParam(value); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid start_date format (use YYYY-MM-DD)",
+			})
+		}
+	}
+	if value := c.Query("end_date"); value != "" {
+		if endDate, err = parseDateParam(value); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid end_date format (use YYYY-MM-DD)",
+			})
+		}
+	}
+
+	shifts, err := h.shifts.GetShiftsByDateRange(workspaceID, startDate, endDate)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	myShifts := []models.Shift{}
+	for _, shift := range shifts {
+		if shift.MemberID == member.ID {
+			shift.MemberName = member.Name
+			myShifts = append(myShifts, shift)
+		}
+	}
+
+	return c.JSON(myShifts)
+}
+
+// GetMyLeaveDays returns the leave days of the member linked to the authenticated user
+func (h *Handler) GetMyLeaveDays(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	member, err := h.linkedMember(c, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	leaveDays, err := h.leave.GetLeaveDaysByMember(workspaceID, member.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if leaveDays == nil {
+		leaveDays = []models.LeaveDay{}
+	}
+	for i := range leaveDays {
+		leaveDays[i].MemberName = member.Name
+	}
+
+	return c.JSON(leaveDays)
+}
+
+// CreateMyLeaveDays requests leave days for the member linked to the authenticated user
+// The days are added as a leave request, which stays pending until a scheduler approves it.
+func (h *Handler) CreateMyLeaveDays(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleMember)
+	if err != nil {
+		return err
+	}
+
+	member, err := h.linkedMember(c, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		StartDate string `json:"start_date"`
+		EndDate   string `json:"end_date"`
+		LeaveType string `json:"leave_type"`
+		Note      string `json:"note"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.LeaveType == "" {
+		req.LeaveType = models.LeaveTypeAnnual
+	}
+	if !models.IsValidLeaveType(req.LeaveType) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid leave_type",
+		})
+	}
+
+	startDate, err := parseDateParam(req.StartDate)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid start_date format (use YYYY-MM-DD)",
+		})
+	}
+	endDate, err := parseDateParam(req.EndDate)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid end_date format (use YYYY-MM-DD)",
+		})
+	}
+	if startDate.After(endDate) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "start_date must be before or equal to end_date",
+		})
+	}
+
+	if leaveRangeTooLong(startDate, endDate) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": leaveRangeTooLongMessage,
+		})
+	}
+
+	leaveRequest, err := h.leave.CreateLeaveRequest(workspaceID, member.ID, startDate, endDate, req.LeaveType, req.Note)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	leaveRequest.MemberName = member.Name
+
+	return c.Status(fiber.StatusCreated).JSON(leaveRequest)
+}
+
+// linkedMember gets the member linked to the authenticated user in the workspace
+// Handlers return the error as is; it carries the 404 status when no member is linked.
+func (h *Handler) linkedMember(c *fiber.Ctx, workspaceID int) (*models.Member, error) {
+	member, err := h.members.GetMemberByUser(workspaceID, GetUserID(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "No member is linked to your account")
+	}
+	return member, err
+}
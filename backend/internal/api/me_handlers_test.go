@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shiftplanner/backend/internal/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMemberSelfService(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+	alice, _ := store.CreateMember(workspaceID, "Alice")
+	bob, _ := store.CreateMember(workspaceID, "Bob")
+	day := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	store.CreateShift(workspaceID, alice.ID, day, day, false)
+	store.CreateShift(workspaceID, bob.ID, day.AddDate(0, 0, 1), day.AddDate(0, 0, 1), false)
+
+	schedulerToken := "test_token_123"
+	createTestSession(t, store, workspaceID, schedulerToken)
+	user, _ := store.CreateUser("alice", "testpassword")
+	aliceToken := "test_token_alice"
+	createTestSession(t, store, personalWorkspace(t, store, user.ID), aliceToken)
+
+	app := fiber.New()
+	app.Post("/api/members/:id/invite", h.AuthMiddleware, h.CreateMemberInvite)
+	app.Post("/api/me/member", h.AuthMiddleware, h.AcceptMemberInvite)
+	app.Get("/api/me/shifts", h.AuthMiddleware, h.GetMyShifts)
+	app.Get("/api/me/leave-days", h.AuthMiddleware, h.GetMyLeaveDays)
+	app.Post("/api/me/leave-days", h.AuthMiddleware, h.CreateMyLeaveDays)
+
+	send := func(method, path, token, body string) *http.Response {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		req.Header.Set("X-Workspace-ID", strconv.Itoa(workspaceID))
+		resp, _ := app.Test(req)
+		return resp
+	}
+
+	resp := send(http.MethodPost, "/api/members/"+strconv.Itoa(alice.ID)+"/invite", schedulerToken, "")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+	var invite models.MemberInvite
+	json.NewDecoder(resp.Body).Decode(&invite)
+
+	// Before accepting, Alice's account has no access to the workspace
+	if resp := send(http.MethodGet, "/api/me/shifts", aliceToken, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status code: %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/me/member", bytes.NewBufferString(`{"token":"`+invite.Token+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", aliceToken)
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	resp = send(http.MethodGet, "/api/me/shifts?start_date=2025-03-01&end_date=2025-03-31", aliceToken, "")
+	var shifts []models.Shift
+	json.NewDecoder(resp.Body).Decode(&shifts)
+	if len(shifts) != 1 || shifts[0].MemberID != alice.ID || shifts[0].MemberName != "Alice" {
+		t.Errorf("Expected only Alice's shift, got %+v", shifts)
+	}
+
+	// Requested leave waits for a scheduler like any other leave request
+	resp = send(http.MethodPost, "/api/me/leave-days", aliceToken, `{"start_date":"2025-04-01","end_date":"2025-04-02"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+	var leaveRequest models.LeaveRequest
+	json.NewDecoder(resp.Body).Decode(&leaveRequest)
+	if leaveRequest.MemberID != alice.ID || leaveRequest.Status != models.LeaveStatusPending {
+		t.Errorf("Expected a pending request for Alice, got %+v", leaveRequest)
+	}
+	resp = send(http.MethodGet, "/api/me/leave-days", aliceToken, "")
+	var leaveDays []models.LeaveDay
+	json.NewDecoder(resp.Body).Decode(&leaveDays)
+	if len(leaveDays) != 0 {
+		t.Errorf("Expected no leave days before approval, got %+v", leaveDays)
+	}
+
+	store.ApproveLeaveRequest(workspaceID, leaveRequest.ID)
+	store.CreateLeaveDay(workspaceID, bob.ID, day, models.LeaveTypeAnnual)
+
+	resp = send(http.MethodGet, "/api/me/leave-days", aliceToken, "")
+	leaveDays = nil
+	json.NewDecoder(resp.Body).Decode(&leaveDays)
+	if len(leaveDays) != 2 || leaveDays[0].MemberID != alice.ID {
+		t.Errorf("Expected Alice's 2 leave days, got %+v", leaveDays)
+	}
+}
+
+func TestMemberSelfService_NoLinkedMember(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	app := fiber.New()
+	app.Get("/api/me/shifts", h.AuthMiddleware, h.GetMyShifts)
+	app.Post("/api/me/member", h.AuthMiddleware, h.AcceptMemberInvite)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me/shifts", nil)
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code: %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/me/member", bytes.NewBufferString(`{"token":"unknown"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code: %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
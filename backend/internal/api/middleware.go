@@ -2,14 +2,31 @@ package api
 
 import (
 	"shiftplanner/backend/internal/auth"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/storage"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-const userIDKey = "userID"
+const (
+	userIDKey      = "userID"
+	workspaceIDKey = "workspaceID"
+	roleKey        = "role"
+	apiKeyIDKey    = "apiKeyID"
+	sessionIDKey   = "sessionID"
+	// sessionLoginKey holds when the session's login happened
+	sessionLoginKey = "sessionLogin"
+	// cappedRoleKey holds the role a user would have if they enabled two-factor
+	cappedRoleKey = "cappedRole"
+)
+
+// workspaceHeader selects the active workspace; without it the user's first workspace is used
+const workspaceHeader = "X-Workspace-ID"
 
 // AuthMiddleware authentication middleware
-func AuthMiddleware(c *fiber.Ctx) error {
+// It accepts session tokens and API keys, and resolves the active workspace and the user's role in it.
+func (h *Handler) AuthMiddleware(c *fiber.Ctx) error {
 	token := c.Get("Authorization")
 	if token == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -17,18 +34,136 @@ func AuthMiddleware(c *fiber.Ctx) error {
 		})
 	}
 
-	userID, err := auth.ValidateToken(token)
+	if auth.IsAPIKey(token) {
+		return h.authenticateAPIKey(c, token)
+	}
+
+	session, err := auth.ValidateSession(h.sessions, token)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Unauthorized",
 		})
 	}
+	userID := session.UserID
 
 	// Add UserID to locals
 	c.Locals(userIDKey, userID)
+	c.Locals(sessionIDKey, session.ID)
+	c.Locals(sessionLoginKey, session.CreatedAt)
+
+	if header := c.Get(workspaceHeader); header != "" {
+		workspaceID, err := strconv.Atoi(header)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid workspace ID",
+			})
+		}
+		role, err := h.workspaces.GetWorkspaceRole(workspaceID, userID)
+		if err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "No access to this workspace",
+			})
+		}
+		if role, err = h.twoFactorRole(c, workspaceID, userID, role); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		c.Locals(workspaceIDKey, workspaceID)
+		c.Locals(roleKey, role)
+		return c.Next()
+	}
+
+	workspaces, err := h.workspaces.GetUserWorkspaces(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	// Users without a workspace can still list and create workspaces
+	if len(workspaces) > 0 {
+		role, err := h.twoFactorRole(c, workspaces[0].ID, userID, workspaces[0].Role)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		c.Locals(workspaceIDKey, workspaces[0].ID)
+		c.Locals(roleKey, role)
+	}
+	return c.Next()
+}
+
+// authenticateAPIKey authenticates a request made with an API key
+// The key's workspace is always the active one, and the role is capped by the key's scope.
+func (h *Handler) authenticateAPIKey(c *fiber.Ctx, key string) error {
+	apiKey, err := auth.ValidateAPIKey(h.apiKeys, key)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	if header := c.Get(workspaceHeader); header != "" && header != strconv.Itoa(apiKey.WorkspaceID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "API key is for another workspace",
+		})
+	}
+
+	// Keys lose their rights together with their user
+	role, err := h.workspaces.GetWorkspaceRole(apiKey.WorkspaceID, apiKey.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "No access to this workspace",
+		})
+	}
+	if scopeRole := models.APIKeyScopeRole(apiKey.Scope); !models.RoleAtLeast(scopeRole, role) {
+		role = scopeRole
+	}
+	if role, err = h.twoFactorRole(c, apiKey.WorkspaceID, apiKey.UserID, role); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Locals(userIDKey, apiKey.UserID)
+	c.Locals(workspaceIDKey, apiKey.WorkspaceID)
+	c.Locals(roleKey, role)
+	c.Locals(apiKeyIDKey, apiKey.ID)
 	return c.Next()
 }
 
+// twoFactorRole returns the role a user acts with in a workspace
+// Where the workspace requires two-factor authentication, schedulers and owners
+// without it act as members, so they can still see the plan and enable it.
+func (h *Handler) twoFactorRole(c *fiber.Ctx, workspaceID, userID int, role string) (string, error) {
+	if !models.RoleAtLeast(role, models.RoleScheduler) {
+		return role, nil
+	}
+	workspace, err := h.workspaces.GetWorkspace(workspaceID)
+	if err != nil {
+		return "", err
+	}
+	if !workspace.RequireTwoFactor {
+		return role, nil
+	}
+	enabled, err := h.hasTwoFactor(userID)
+	if err != nil || enabled {
+		return role, err
+	}
+	c.Locals(cappedRoleKey, role)
+	return models.RoleMember, nil
+}
+
+// hasTwoFactor reports whether a user logs in with a second factor
+func (h *Handler) hasTwoFactor(userID int) (bool, error) {
+	twoFactor, err := h.twoFactors.GetTwoFactor(userID)
+	if err != nil && err != storage.ErrTwoFactorNotFound {
+		return false, err
+	}
+	return twoFactor.Enabled(), nil
+}
+
 // GetUserID gets user ID from Fiber context
 func GetUserID(c *fiber.Ctx) int {
 	if userID, ok := c.Locals(userIDKey).(int); ok {
@@ -36,3 +171,51 @@ func GetUserID(c *fiber.Ctx) int {
 	}
 	return 0
 }
+
+// GetWorkspaceID gets the active workspace ID from Fiber context
+func GetWorkspaceID(c *fiber.Ctx) int {
+	if workspaceID, ok := c.Locals(workspaceIDKey).(int); ok {
+		return workspaceID
+	}
+	return 0
+}
+
+// GetRole gets the user's role in the active workspace from Fiber context
+func GetRole(c *fiber.Ctx) string {
+	if role, ok := c.Locals(roleKey).(string); ok {
+		return role
+	}
+	return ""
+}
+
+// authorize returns the active workspace if the user has at least the required role in it
+// Handlers return the error as is; it carries the 401 or 403 status.
+func authorize(c *fiber.Ctx, required string) (int, error) {
+	if GetUserID(c) == 0 {
+		return 0, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+	workspaceID := GetWorkspaceID(c)
+	if workspaceID == 0 {
+		return 0, fiber.NewError(fiber.StatusForbidden, "No workspace")
+	}
+	if !models.RoleAtLeast(GetRole(c), required) {
+		if capped, ok := c.Locals(cappedRoleKey).(string); ok && models.RoleAtLeast(capped, required) {
+			return 0, fiber.NewError(fiber.StatusForbidden, "This workspace requires two-factor authentication for the "+required+" role")
+		}
+		return 0, fiber.NewError(fiber.StatusForbidden, "Requires the "+required+" role")
+	}
+	return workspaceID, nil
+}
+
+// requireSession returns the user of a request authenticated with a session
+// Changes to the account itself, like managing API keys, can't be made with an API key.
+func requireSession(c *fiber.Ctx) (int, error) {
+	userID := GetUserID(c)
+	if userID == 0 {
+		return 0, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+	if c.Locals(apiKeyIDKey) != nil {
+		return 0, fiber.NewError(fiber.StatusForbidden, "Not allowed with an API key")
+	}
+	return userID, nil
+}
@@ -1,175 +1,195 @@
 package api
 
 import (
-	"context"
-	"database/sql"
 	"net/http"
 	"net/http/httptest"
-	"os"
-	"path/filepath"
 	"shiftplanner/backend/internal/auth"
-	"shiftplanner/backend/internal/database"
+	"shiftplanner/backend/internal/models"
+	"strconv"
 	"testing"
+	"time"
 
-	_ "modernc.org/sqlite"
+	"github.com/gofiber/fiber/v2"
 )
 
-func setupMiddlewareTestDB(t *testing.T) int {
-	testDBPath := filepath.Join(os.TempDir(), "test_middleware.db")
-	os.Remove(testDBPath)
-
-	var err error
-	database.DB, err = sql.Open("sqlite", testDBPath)
-	if err != nil {
-		t.Fatalf("Failed to create test database: %v", err)
-	}
-
-	createUsersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		username TEXT NOT NULL UNIQUE,
-		password_hash TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	createSessionsTable := `
-	CREATE TABLE IF NOT EXISTS sessions (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		token TEXT NOT NULL UNIQUE,
-		expires_at DATETIME NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-	);`
-
-	if _, err := database.DB.Exec(createUsersTable); err != nil {
-		t.Fatalf("Failed to create users table: %v", err)
-	}
-	if _, err := database.DB.Exec(createSessionsTable); err != nil {
-		t.Fatalf("Failed to create sessions table: %v", err)
-	}
-
-	// Create test user
-	result, _ := database.DB.Exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", "testuser", "testhash")
-	userID, _ := result.LastInsertId()
-	return int(userID)
-}
-
-func teardownMiddlewareTestDB(t *testing.T) {
-	if database.DB != nil {
-		database.DB.Close()
-		database.DB = nil
-	}
-	testDBPath := filepath.Join(os.TempDir(), "test_middleware.db")
-	os.Remove(testDBPath)
-}
-
 func TestAuthMiddleware_ValidToken(t *testing.T) {
-	userID := setupMiddlewareTestDB(t)
-	defer teardownMiddlewareTestDB(t)
+	h, store, workspaceID := setupTestAPI(t)
+	user, _, _ := store.GetUserByUsername("testuser")
+	userID := user.ID
 
 	// Create valid session
-	session, _ := auth.CreateSession(userID)
+	session, _ := auth.CreateSession(store, userID, "", "")
 
 	// Test handler
 	handlerCalled := false
-	testHandler := func(w http.ResponseWriter, r *http.Request) {
+	app := fiber.New()
+	app.Get("/test", h.AuthMiddleware, func(c *fiber.Ctx) error {
 		handlerCalled = true
-		// Check userID from context
-		ctxUserID := GetUserID(r)
+		// Check userID from locals
+		ctxUserID := GetUserID(c)
 		if ctxUserID != userID {
 			t.Errorf("User ID in context mismatch: got %d, want %d", ctxUserID, userID)
 		}
-		w.WriteHeader(http.StatusOK)
-	}
+		// Without a workspace header the personal workspace is active
+		if GetWorkspaceID(c) != workspaceID || GetRole(c) != models.RoleOwner {
+			t.Errorf("Workspace in context mismatch: got %d as %s", GetWorkspaceID(c), GetRole(c))
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
 
 	// Create request
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	req.Header.Set("Authorization", session.Token)
-	w := httptest.NewRecorder()
-
-	// Call handler with middleware
-	middleware := AuthMiddleware(testHandler)
-	middleware(w, req)
+	resp, _ := app.Test(req)
 
 	if !handlerCalled {
 		t.Error("Handler was not called")
 	}
 
-	if w.Code != http.StatusOK {
-		t.Errorf("Status code mismatch: got %d, want %d", w.Code, http.StatusOK)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Status code mismatch: got %d, want %d", resp.StatusCode, http.StatusOK)
 	}
 }
 
 func TestAuthMiddleware_NoToken(t *testing.T) {
-	setupMiddlewareTestDB(t)
-	defer teardownMiddlewareTestDB(t)
+	h, _, _ := setupTestAPI(t)
 
 	handlerCalled := false
-	testHandler := func(w http.ResponseWriter, r *http.Request) {
+	app := fiber.New()
+	app.Get("/test", h.AuthMiddleware, func(c *fiber.Ctx) error {
 		handlerCalled = true
-	}
+		return nil
+	})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
-	w := httptest.NewRecorder()
-
-	middleware := AuthMiddleware(testHandler)
-	middleware(w, req)
+	resp, _ := app.Test(req)
 
 	if handlerCalled {
 		t.Error("Handler should not be called")
 	}
 
-	if w.Code != http.StatusUnauthorized {
-		t.Errorf("Status code mismatch: got %d, want %d", w.Code, http.StatusUnauthorized)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Status code mismatch: got %d, want %d", resp.StatusCode, http.StatusUnauthorized)
 	}
 }
 
 func TestAuthMiddleware_InvalidToken(t *testing.T) {
-	setupMiddlewareTestDB(t)
-	defer teardownMiddlewareTestDB(t)
+	h, _, _ := setupTestAPI(t)
 
 	handlerCalled := false
-	testHandler := func(w http.ResponseWriter, r *http.Request) {
+	app := fiber.New()
+	app.Get("/test", h.AuthMiddleware, func(c *fiber.Ctx) error {
 		handlerCalled = true
-	}
+		return nil
+	})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	req.Header.Set("Authorization", "invalid_token")
-	w := httptest.NewRecorder()
+	resp, _ := app.Test(req)
+
+	if handlerCalled {
+		t.Error("Handler should not be called")
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Status code mismatch: got %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestAuthMiddleware_ExpiredToken(t *testing.T) {
+	h, store, _ := setupTestAPI(t)
+	user, _, _ := store.GetUserByUsername("testuser")
 
-	middleware := AuthMiddleware(testHandler)
-	middleware(w, req)
+	store.CreateSession(user.ID, auth.HashToken("expired_token"), "", "", time.Now().Add(-time.Hour))
+
+	handlerCalled := false
+	app := fiber.New()
+	app.Get("/test", h.AuthMiddleware, func(c *fiber.Ctx) error {
+		handlerCalled = true
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "expired_token")
+	resp, _ := app.Test(req)
 
 	if handlerCalled {
 		t.Error("Handler should not be called")
 	}
 
-	if w.Code != http.StatusUnauthorized {
-		t.Errorf("Status code mismatch: got %d, want %d", w.Code, http.StatusUnauthorized)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Status code mismatch: got %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestAuthMiddleware_WorkspaceHeader(t *testing.T) {
+	h, store, _ := setupTestAPI(t)
+	user, _, _ := store.GetUserByUsername("testuser")
+	team, _ := store.CreateWorkspace(user.ID, "Team")
+	other, _ := store.CreateUser("otheruser", "testpassword")
+	createTestSession(t, store, personalWorkspace(t, store, other.ID), "other_token")
+	store.SetWorkspaceUserRole(team.ID, other.ID, models.RoleViewer)
+
+	var workspaceID int
+	var role string
+	app := fiber.New()
+	app.Get("/test", h.AuthMiddleware, func(c *fiber.Ctx) error {
+		workspaceID, role = GetWorkspaceID(c), GetRole(c)
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "other_token")
+	req.Header.Set("X-Workspace-ID", strconv.Itoa(team.ID))
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Status code mismatch: got %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if workspaceID != team.ID || role != models.RoleViewer {
+		t.Errorf("Workspace in context mismatch: got %d as %s", workspaceID, role)
+	}
+
+	// Workspaces the user doesn't belong to are refused
+	req = httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "other_token")
+	req.Header.Set("X-Workspace-ID", strconv.Itoa(personalWorkspace(t, store, user.ID)))
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Status code mismatch: got %d, want %d", resp.StatusCode, http.StatusForbidden)
 	}
 }
 
 func TestGetUserID(t *testing.T) {
-	userID := setupMiddlewareTestDB(t)
-	defer teardownMiddlewareTestDB(t)
+	userID := 1
+
+	// Add userID to locals and test GetUserID
+	var result int
+	app := fiber.New()
+	app.Get("/test", func(c *fiber.Ctx) error {
+		c.Locals(userIDKey, userID)
+		result = GetUserID(c)
+		return nil
+	})
 
-	// Add userID to context
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
-	ctx := context.WithValue(req.Context(), userIDContextKey, userID)
-	req = req.WithContext(ctx)
+	app.Test(req)
 
-	// Test GetUserID
-	result := GetUserID(req)
 	if result != userID {
 		t.Errorf("User ID mismatch: got %d, want %d", result, userID)
 	}
 }
 
 func TestGetUserID_NoContext(t *testing.T) {
+	result := -1
+	app := fiber.New()
+	app.Get("/test", func(c *fiber.Ctx) error {
+		result = GetUserID(c)
+		return nil
+	})
+
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	app.Test(req)
 
-	result := GetUserID(req)
 	if result != 0 {
 		t.Errorf("User ID should be 0: got %d", result)
 	}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/url"
+	"shiftplanner/backend/internal/oidc"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// oidcLoginCookie binds a started login to the browser that started it
+const oidcLoginCookie = "oidc_login"
+
+// OIDCLogin sends the browser to the OpenID Connect provider to log in
+// The login's state is also set in a short-lived cookie, which the callback checks against the state it gets.
+func (h *Handler) OIDCLogin(c *fiber.Ctx) error {
+	if h.sso == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Single sign-on is not configured",
+		})
+	}
+
+	authURL, state, err := h.sso.StartLogin()
+	if err != nil {
+		if errors.Is(err, oidc.ErrTooManyLogins) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Too many logins in progress, try again later",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Cookie(h.oidcCookie(state, time.Now().Add(oidc.LoginExpiry)))
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// OIDCCallback finishes a login at the OpenID Connect provider
+// Unknown users are created on their first login, and the provider's groups
+// set their roles in the configured workspaces (see syncSSOWorkspaceRoles).
+func (h *Handler) OIDCCallback(c *fiber.Ctx) error {
+	if h.sso == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Single sign-on is not configured",
+		})
+	}
+	if providerError := c.Query("error"); providerError != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Login failed: " + providerError,
+		})
+	}
+
+	boundState := c.Cookies(oidcLoginCookie)
+	c.Cookie(h.oidcCookie("", time.Unix(0, 0)))
+	claims, err := h.sso.FinishLogin(c.UserContext(), boundState, c.Query("state"), c.Query("code"))
+	if err != nil {
+		if errors.Is(err, oidc.ErrUnknownLogin) || errors.Is(err, oidc.ErrInvalidIDToken) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	config := h.sso.Config()
+	// A domain restriction is only as good as the provider's check of the address
+	if len(config.AllowedDomains) > 0 && (!claims.EmailVerified || !config.EmailAllowed(claims.Email)) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Email domain is not allowed",
+		})
+	}
+
+	user, err := h.users.GetUserByIdentity(config.Issuer, claims.Subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		user, err = h.users.CreateUserWithIdentity(ssoUsername(claims), config.Issuer, claims.Subject, claims.Email)
+		if err != nil {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Username already exists",
+			})
+		}
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.syncSSOWorkspaceRoles(user.ID, config.WorkspaceRoles(claims.Groups))
+
+	token, preAuthToken, err := h.logIn(c, user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Browsers are sent back to the frontend with the token in the fragment, which isn't sent to servers
+	if config.PostLoginURL != "" {
+		fragment := "#token=" + url.QueryEscape(token)
+		if preAuthToken != "" {
+			fragment = "#pre_auth_token=" + url.QueryEscape(preAuthToken)
+		}
+		return c.Redirect(config.PostLoginURL+fragment, fiber.StatusFound)
+	}
+
+	if preAuthToken != "" {
+		return c.JSON(fiber.Map{
+			"two_factor_required": true,
+			"pre_auth_token":      preAuthToken,
+		})
+	}
+	return c.JSON(fiber.Map{
+		"user":  user,
+		"token": token,
+	})
+}
+
+// syncSSOWorkspaceRoles brings the roles single sign-on granted a user in line with their groups
+// Workspaces the groups no longer map to are left, and roles granted by hand are never changed.
+// A change that would leave a workspace without an owner is skipped.
+func (h *Handler) syncSSOWorkspaceRoles(userID int, roles map[int]string) {
+	granted, err := h.workspaces.GetSSOWorkspaceRoles(userID)
+	if err != nil {
+		log.Printf("Warning: failed to get the single sign-on roles of user %d: %v", userID, err)
+		return
+	}
+
+	for workspaceID, role := range roles {
+		if err := h.workspaces.SetSSOWorkspaceRole(workspaceID, userID, role); err != nil {
+			log.Printf("Warning: failed to give user %d the %s role in workspace %d: %v", userID, role, workspaceID, err)
+		}
+	}
+	for workspaceID := range granted {
+		if _, ok := roles[workspaceID]; ok {
+			continue
+		}
+		if err := h.workspaces.RemoveSSOWorkspaceRole(workspaceID, userID); err != nil {
+			log.Printf("Warning: failed to remove user %d from workspace %d: %v", userID, workspaceID, err)
+		}
+	}
+}
+
+// oidcCookie builds the login cookie; it is only sent back on the callback's top-level redirect
+func (h *Handler) oidcCookie(value string, expires time.Time) *fiber.Cookie {
+	return &fiber.Cookie{
+		Name:     oidcLoginCookie,
+		Value:    value,
+		Path:     "/api/auth/oidc",
+		Expires:  expires,
+		Secure:   strings.HasPrefix(h.sso.Config().RedirectURL, "https://"),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	}
+}
+
+// ssoUsername picks the username of a user created through single sign-on
+func ssoUsername(claims *oidc.Claims) string {
+	switch {
+	case claims.Email != "":
+		return claims.Email
+	case claims.PreferredUsername != "":
+		return claims.PreferredUsername
+	}
+	return "sso-" + claims.Subject
+}
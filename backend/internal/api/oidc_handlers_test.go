@@ -0,0 +1,227 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/oidc"
+	"shiftplanner/backend/internal/oidc/oidctest"
+	"shiftplanner/backend/internal/storage"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// setupTestOIDC enables single sign-on against a mock issuer
+// configure gets the test user's workspace and returns the provider settings.
+func setupTestOIDC(t *testing.T, configure func(workspaceID int) oidc.Config) (*fiber.App, storage.Store, int, *oidctest.Issuer) {
+	h, store, workspaceID := setupTestAPI(t)
+	issuer := oidctest.NewIssuer(t, "shiftplanner", "secret")
+
+	config := configure(workspaceID)
+	config.Issuer = issuer.URL
+	config.ClientID = "shiftplanner"
+	config.ClientSecret = "secret"
+	config.RedirectURL = "http://localhost:8080/api/auth/oidc/callback"
+	provider, err := oidc.NewProvider(context.Background(), config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	h.EnableOIDC(provider)
+
+	app := fiber.New()
+	app.Get("/api/auth/oidc/login", h.OIDCLogin)
+	app.Get("/api/auth/oidc/callback", h.OIDCCallback)
+	app.Post("/api/auth/login", h.Login)
+	return app, store, workspaceID, issuer
+}
+
+// ssoLogin follows the redirects of a login and returns the callback response
+func ssoLogin(t *testing.T, app *fiber.App, issuer *oidctest.Issuer) *http.Response {
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusFound, resp.StatusCode)
+	}
+	callback, err := issuer.Authorize(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Failed to authorize: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	for _, cookie := range resp.Cookies() {
+		req.AddCookie(cookie)
+	}
+	resp, _ = app.Test(req)
+	return resp
+}
+
+func TestOIDCLogin(t *testing.T) {
+	app, store, workspaceID, issuer := setupTestOIDC(t, func(workspaceID int) oidc.Config {
+		return oidc.Config{
+			GroupRoles: []oidc.GroupRole{{Group: "planners", WorkspaceID: workspaceID, Role: models.RoleScheduler}},
+		}
+	})
+	issuer.SetClaims(map[string]interface{}{
+		"sub":    "alice-id",
+		"email":  "alice@example.com",
+		"groups": []string{"planners"},
+	})
+
+	resp := ssoLogin(t, app, issuer)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var result struct {
+		User  models.User `json:"user"`
+		Token string      `json:"token"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result.User.Username != "alice@example.com" || result.Token == "" {
+		t.Errorf("Unexpected login response: %+v", result)
+	}
+	if role, _ := store.GetWorkspaceRole(workspaceID, result.User.ID); role != models.RoleScheduler {
+		t.Errorf("Group should map to scheduler, got %q", role)
+	}
+
+	// The second login finds the same user
+	resp = ssoLogin(t, app, issuer)
+	var second struct {
+		User models.User `json:"user"`
+	}
+	json.NewDecoder(resp.Body).Decode(&second)
+	if second.User.ID != result.User.ID {
+		t.Errorf("Expected user %d again, got %d", result.User.ID, second.User.ID)
+	}
+
+	// Users from the provider can't log in with a password
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewBufferString(`{"username":"alice@example.com","password":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if resp, _ := app.Test(req); resp.StatusCode == http.StatusOK {
+		t.Error("Password login should fail for single sign-on users")
+	}
+}
+
+func TestOIDCLogin_AllowedDomains(t *testing.T) {
+	app, _, _, issuer := setupTestOIDC(t, func(int) oidc.Config {
+		return oidc.Config{AllowedDomains: []string{"example.com"}}
+	})
+
+	tests := []struct {
+		claims map[string]interface{}
+		want   int
+	}{
+		{map[string]interface{}{"sub": "1", "email": "bob@other.com", "email_verified": true}, http.StatusForbidden},
+		{map[string]interface{}{"sub": "2", "email": "bob@example.com", "email_verified": false}, http.StatusForbidden},
+		{map[string]interface{}{"sub": "3", "email": "bob@example.com", "email_verified": true}, http.StatusOK},
+	}
+	for i, tt := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			issuer.SetClaims(tt.claims)
+			if resp := ssoLogin(t, app, issuer); resp.StatusCode != tt.want {
+				t.Errorf("Expected status code: %d, got %d", tt.want, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestOIDCCallback_UnknownState(t *testing.T) {
+	app, _, _, _ := setupTestOIDC(t, func(int) oidc.Config { return oidc.Config{} })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?state=forged&code=x", nil)
+	req.AddCookie(&http.Cookie{Name: oidcLoginCookie, Value: "forged"})
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status code: %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+}
+
+func TestOIDCCallback_RequiresLoginCookie(t *testing.T) {
+	app, _, _, issuer := setupTestOIDC(t, func(int) oidc.Config { return oidc.Config{} })
+	issuer.SetClaims(map[string]interface{}{"sub": "alice-id", "email": "alice@example.com"})
+
+	// A callback for a login started elsewhere, e.g. a victim sent to the attacker's callback URL
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	cookies := resp.Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oidcLoginCookie || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("Expected an HttpOnly SameSite=Lax login cookie, got %+v", cookies)
+	}
+	callback, err := issuer.Authorize(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Failed to authorize: %v", err)
+	}
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil))
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status code: %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+}
+
+func TestOIDCLogin_SyncsGrantedRoles(t *testing.T) {
+	app, store, workspaceID, issuer := setupTestOIDC(t, func(workspaceID int) oidc.Config {
+		return oidc.Config{GroupRoles: []oidc.GroupRole{
+			{Group: "admins", WorkspaceID: workspaceID, Role: models.RoleOwner},
+			{Group: "planners", WorkspaceID: workspaceID, Role: models.RoleScheduler},
+		}}
+	})
+	login := func(groups ...string) int {
+		issuer.SetClaims(map[string]interface{}{"sub": "alice-id", "email": "alice@example.com", "groups": groups})
+		resp := ssoLogin(t, app, issuer)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		var result struct {
+			User models.User `json:"user"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return result.User.ID
+	}
+
+	// Leaving the group takes the granted role away again
+	userID := login("planners")
+	login()
+	if _, err := store.GetWorkspaceRole(workspaceID, userID); err == nil {
+		t.Error("Role should be removed when the user leaves the group")
+	}
+
+	// A role granted by hand stays, whatever the groups say
+	if err := store.SetWorkspaceUserRole(workspaceID, userID, models.RoleViewer); err != nil {
+		t.Fatalf("Failed to set role: %v", err)
+	}
+	login("planners")
+	login()
+	if role, _ := store.GetWorkspaceRole(workspaceID, userID); role != models.RoleViewer {
+		t.Errorf("Role granted by hand should stay viewer, got %q", role)
+	}
+
+	// The last owner keeps the role when they leave the group
+	if err := store.RemoveWorkspaceUser(workspaceID, userID); err != nil {
+		t.Fatalf("Failed to remove user: %v", err)
+	}
+	login("admins")
+	users, _ := store.GetWorkspaceUsers(workspaceID)
+	for _, wu := range users {
+		if wu.UserID != userID {
+			if err := store.RemoveWorkspaceUser(workspaceID, wu.UserID); err != nil {
+				t.Fatalf("Failed to remove the other owner: %v", err)
+			}
+		}
+	}
+	login("planners")
+	login()
+	if role, _ := store.GetWorkspaceRole(workspaceID, userID); role != models.RoleOwner {
+		t.Errorf("Last owner should stay owner, got %q", role)
+	}
+}
+
+func TestOIDCLogin_NotConfigured(t *testing.T) {
+	h, _, _ := setupTestAPI(t)
+	app := fiber.New()
+	app.Get("/api/auth/oidc/login", h.OIDCLogin)
+
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code: %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
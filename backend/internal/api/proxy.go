@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// defaultProxyHeader is the header the bundled nginx sets to the address of its client
+const defaultProxyHeader = "X-Real-IP"
+
+// ProxyConfig names the reverse proxies trusted to report the client IP address
+// Rate limits and login lockouts count per client IP address, so behind a
+// proxy without this every client would share the address of the proxy.
+type ProxyConfig struct {
+	// Header carries the client IP address; the proxy must overwrite what clients send in it
+	Header string
+	// TrustedProxies IP addresses and CIDR ranges of the proxies
+	TrustedProxies []string
+}
+
+// ProxyConfigFromEnv reads TRUSTED_PROXIES, a comma-separated list of IP
+// addresses and CIDR ranges, and PROXY_HEADER (default X-Real-IP)
+// Without trusted proxies the address of the connection is the client's.
+func ProxyConfigFromEnv() (ProxyConfig, error) {
+	var config ProxyConfig
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return ProxyConfig{}, fmt.Errorf("TRUSTED_PROXIES: invalid IP address or CIDR range %q", proxy)
+		}
+		config.TrustedProxies = append(config.TrustedProxies, proxy)
+	}
+
+	config.Header = strings.TrimSpace(os.Getenv("PROXY_HEADER"))
+	if len(config.TrustedProxies) == 0 {
+		if config.Header != "" {
+			return ProxyConfig{}, fmt.Errorf("PROXY_HEADER is set without TRUSTED_PROXIES, which would let any client choose its IP address")
+		}
+		return config, nil
+	}
+	if config.Header == "" {
+		config.Header = defaultProxyHeader
+	}
+	return config, nil
+}
+
+// Apply makes c.IP() return the address reported by a trusted proxy
+// The header is ignored on connections from other addresses, and when it
+// doesn't hold a valid IP address.
+func (p ProxyConfig) Apply(config *fiber.Config) {
+	if len(p.TrustedProxies) == 0 {
+		return
+	}
+	config.ProxyHeader = p.Header
+	config.EnableTrustedProxyCheck = true
+	config.TrustedProxies = p.TrustedProxies
+	config.EnableIPValidation = true
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestProxyConfigFromEnv(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "")
+	t.Setenv("PROXY_HEADER", "")
+	config, err := ProxyConfigFromEnv()
+	if err != nil || config.Header != "" || len(config.TrustedProxies) != 0 {
+		t.Errorf("Expected no proxy by default, got %+v, %v", config, err)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1, 172.16.0.0/12")
+	config, err = ProxyConfigFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to read proxy config: %v", err)
+	}
+	if config.Header != "X-Real-IP" || len(config.TrustedProxies) != 2 || config.TrustedProxies[1] != "172.16.0.0/12" {
+		t.Errorf("Proxy config mismatch: %+v", config)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1,proxy")
+	if _, err := ProxyConfigFromEnv(); err == nil {
+		t.Error("Expected an error for an invalid proxy address")
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "")
+	t.Setenv("PROXY_HEADER", "X-Forwarded-For")
+	if _, err := ProxyConfigFromEnv(); err == nil {
+		t.Error("Expected an error for a proxy header without trusted proxies")
+	}
+}
+
+func TestRateLimiter_BehindProxy(t *testing.T) {
+	newApp := func(proxy ProxyConfig) *fiber.App {
+		var config fiber.Config
+		proxy.Apply(&config)
+		app := fiber.New(config)
+		app.Use(RateLimiter(RateLimit{Max: 1, Window: time.Minute}))
+		app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+		return app
+	}
+	get := func(app *fiber.App, clientIP string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Real-IP", clientIP)
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	// Test requests come from 0.0.0.0, the proxy here
+	app := newApp(ProxyConfig{Header: "X-Real-IP", TrustedProxies: []string{"0.0.0.0"}})
+	if got := get(app, "203.0.113.1"); got != http.StatusOK {
+		t.Fatalf("First client: expected status code %d, got %d", http.StatusOK, got)
+	}
+	if got := get(app, "203.0.113.2"); got != http.StatusOK {
+		t.Errorf("Second client: expected its own limit, got %d", got)
+	}
+	if got := get(app, "203.0.113.1"); got != http.StatusTooManyRequests {
+		t.Errorf("First client again: expected status code %d, got %d", http.StatusTooManyRequests, got)
+	}
+
+	// The header of an untrusted connection doesn't pick the client
+	app = newApp(ProxyConfig{Header: "X-Real-IP", TrustedProxies: []string{"10.0.0.1"}})
+	get(app, "203.0.113.1")
+	if got := get(app, "203.0.113.2"); got != http.StatusTooManyRequests {
+		t.Errorf("Untrusted header: expected status code %d, got %d", http.StatusTooManyRequests, got)
+	}
+}
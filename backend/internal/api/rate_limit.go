@@ -0,0 +1,104 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+// RateLimit allows Max requests from each client IP address in every Window
+// A zero Max turns limiting off.
+type RateLimit struct {
+	Max    int
+	Window time.Duration
+}
+
+// RateLimits holds the limit of each route group
+type RateLimits struct {
+	// Auth limits login, registration and invite acceptance
+	Auth RateLimit
+	// API limits the protected API
+	API RateLimit
+	// Feeds limits the calendar feeds
+	Feeds RateLimit
+}
+
+// DefaultRateLimits are used for route groups without a configured limit
+var DefaultRateLimits = RateLimits{
+	Auth:  RateLimit{Max: 20, Window: time.Minute},
+	API:   RateLimit{Max: 600, Window: time.Minute},
+	Feeds: RateLimit{Max: 60, Window: time.Minute},
+}
+
+// RateLimitsFromEnv reads the limits from RATE_LIMIT_AUTH, RATE_LIMIT_API and
+// RATE_LIMIT_FEEDS, keeping the defaults for unset variables
+func RateLimitsFromEnv() (RateLimits, error) {
+	limits := DefaultRateLimits
+	for name, limit := range map[string]*RateLimit{
+		"RATE_LIMIT_AUTH":  &limits.Auth,
+		"RATE_LIMIT_API":   &limits.API,
+		"RATE_LIMIT_FEEDS": &limits.Feeds,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := ParseRateLimit(value)
+		if err != nil {
+			return limits, fmt.Errorf("%s: %w", name, err)
+		}
+		*limit = parsed
+	}
+	return limits, nil
+}
+
+// ParseRateLimit parses a limit like "60/1m", requests per window; "off" turns limiting off
+func ParseRateLimit(value string) (RateLimit, error) {
+	value = strings.TrimSpace(value)
+	if value == "off" || value == "0" {
+		return RateLimit{}, nil
+	}
+
+	count, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected requests/window like 60/1m", value)
+	}
+	requests, err := strconv.Atoi(count)
+	if err != nil || requests < 0 {
+		return RateLimit{}, fmt.Errorf("invalid request count in rate limit %q", value)
+	}
+	duration, err := time.ParseDuration(window)
+	if err != nil || duration < time.Second {
+		return RateLimit{}, fmt.Errorf("invalid window in rate limit %q, expected a duration of at least 1s", value)
+	}
+	return RateLimit{Max: requests, Window: duration}, nil
+}
+
+// RateLimiter limits the requests of each client IP address to limit
+// The client IP address is c.IP(), reported by a trusted proxy when the app
+// is configured with ProxyConfig.Apply. The counts only live in this process.
+// Rejected requests get a 429 with a Retry-After header.
+func RateLimiter(limit RateLimit) fiber.Handler {
+	if limit.Max <= 0 {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+	return limiter.New(limiter.Config{
+		Max:        limit.Max,
+		Expiration: limit.Window,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			return c.IP()
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many requests, try again later",
+			})
+		},
+	})
+}
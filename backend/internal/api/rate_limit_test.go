@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		value string
+		want  RateLimit
+		ok    bool
+	}{
+		{"60/1m", RateLimit{Max: 60, Window: time.Minute}, true},
+		{" 5/10s ", RateLimit{Max: 5, Window: 10 * time.Second}, true},
+		{"off", RateLimit{}, true},
+		{"60", RateLimit{}, false},
+		{"x/1m", RateLimit{}, false},
+		{"60/1ms", RateLimit{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseRateLimit(tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseRateLimit(%q) = %+v, %v", tt.value, got, err)
+		}
+	}
+}
+
+func TestRateLimitsFromEnv(t *testing.T) {
+	t.Setenv("RATE_LIMIT_AUTH", "5/1m")
+	t.Setenv("RATE_LIMIT_FEEDS", "off")
+	limits, err := RateLimitsFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to read limits: %v", err)
+	}
+	if limits.Auth != (RateLimit{Max: 5, Window: time.Minute}) || limits.API != DefaultRateLimits.API || limits.Feeds.Max != 0 {
+		t.Errorf("Limits mismatch: %+v", limits)
+	}
+
+	t.Setenv("RATE_LIMIT_API", "lots")
+	if _, err := RateLimitsFromEnv(); err == nil {
+		t.Error("Expected an error for an invalid limit")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	app := fiber.New()
+	app.Use("/limited", RateLimiter(RateLimit{Max: 2, Window: time.Minute}))
+	app.Use("/unlimited", RateLimiter(RateLimit{}))
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/limited", ok)
+	app.Get("/unlimited", ok)
+
+	get := func(path string) *http.Response {
+		resp, _ := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		return resp
+	}
+
+	for i := 0; i < 2; i++ {
+		if resp := get("/limited"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code: %d for request %d, got %d", http.StatusOK, i+1, resp.StatusCode)
+		}
+	}
+	resp := get("/limited")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get(fiber.HeaderRetryAfter) == "" {
+		t.Errorf("Expected a 429 with Retry-After, got %d", resp.StatusCode)
+	}
+
+	for i := 0; i < 5; i++ {
+		if resp := get("/unlimited"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected no limit, got %d", resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"shiftplanner/backend/internal/models"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Roster page layout in millimetres (A4 landscape)
+const (
+	rosterMargin       = 10.0
+	rosterTitleHeight  = 10.0
+	rosterHeaderHeight = 7.0
+	rosterLegendHeight = 12.0
+	rosterBarHeight    = 5.0
+	rosterLeaveLine    = 3.2
+)
+
+// rosterColor RGB fill color used on the roster
+type rosterColor struct {
+	R, G, B int
+}
+
+// Roster colors
+var (
+	rosterNormalShiftColor = rosterColor{198, 219, 239}
+	rosterLongShiftColor   = rosterColor{253, 208, 162}
+	rosterWeekendColor     = rosterColor{245, 245, 245}
+	rosterHolidayColor     = rosterColor{254, 230, 230}
+	rosterOutsideColor     = rosterColor{230, 230, 230}
+	rosterHolidayTextColor = rosterColor{192, 0, 0}
+)
+
+// rosterLeaveColors legend colors per leave type, in legend order
+var rosterLeaveColors = []struct {
+	LeaveType string
+	Color     rosterColor
+}{
+	{models.LeaveTypeAnnual, rosterColor{116, 196, 118}},
+	{models.LeaveTypeSick, rosterColor{251, 106, 74}},
+	{models.LeaveTypeUnpaid, rosterColor{158, 154, 200}},
+	{models.LeaveTypeOther, rosterColor{231, 186, 82}},
+	{models.LeaveTypeUnavailable, rosterColor{150, 150, 150}},
+}
+
+// GetRosterPDF renders a printable monthly roster as PDF
+// Query: month (YYYY-MM, default current month)
+func (h *Handler) GetRosterPDF(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if value := c.Query("month"); value != "" {
+		parsed, err := time.Parse("2006-01", value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid month format (use YYYY-MM)",
+			})
+		}
+		month = time.Date(parsed.Year(), parsed.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	gridStart, gridEnd := rosterGridRange(month)
+
+	shifts, err := h.shifts.GetShiftsByDateRange(workspaceID, gridStart, gridEnd)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	leaveDays, err := h.leave.GetLeaveDaysByDateRange(workspaceID, gridStart, gridEnd)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	members, err := h.members.GetAllMembers(workspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	memberMap := make(map[int]string)
+	for _, m := range members {
+		memberMap[m.ID] = m.Name
+	}
+
+	content, err := buildRosterPDF(month, shifts, leaveDays, memberMap)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to render roster",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", "roster_"+month.Format("2006-01")+".pdf"))
+	return c.Send(content)
+}
+
+// rosterGridRange returns the first and last day of the calendar grid of a month
+// The grid starts on the Monday on or before the 1st and ends on the Sunday on or after the last day
+func rosterGridRange(month time.Time) (time.Time, time.Time) {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1)
+	start := first.AddDate(0, 0, -((int(first.Weekday()) + 6) % 7))
+	end := last.AddDate(0, 0, (7-int(last.Weekday()))%7)
+	return start, end
+}
+
+// buildRosterPDF renders the roster of a month as a calendar grid
+// Shifts are drawn as bars so long shifts visibly span weekends and holidays,
+// holidays are named in their cell and leave days are listed with a color legend.
+func buildRosterPDF(month time.Time, shifts []models.Shift, leaveDays []models.LeaveDay, memberMap map[int]string) ([]byte, error) {
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.SetMargins(rosterMargin, rosterMargin, rosterMargin)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle("Shift Roster "+month.Format("January 2006"), true)
+	pdf.AddPage()
+
+	// Core fonts only cover cp1252
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pageWidth, pageHeight := pdf.GetPageSize()
+	gridStart, gridEnd := rosterGridRange(month)
+	weeks := int(gridEnd.Sub(gridStart).Hours()/24)/7 + 1
+
+	gridTop := rosterMargin + rosterTitleHeight + rosterHeaderHeight
+	cellWidth := (pageWidth - 2*rosterMargin) / 7
+	cellHeight := (pageHeight - gridTop - rosterLegendHeight - rosterMargin) / float64(weeks)
+
+	cellPosition := func(date time.Time) (float64, float64) {
+		index := int(date.Sub(gridStart).Hours() / 24)
+		return rosterMargin + float64(index%7)*cellWidth, gridTop + float64(index/7)*cellHeight
+	}
+
+	// Title
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.SetXY(rosterMargin, rosterMargin)
+	pdf.CellFormat(pageWidth-2*rosterMargin, rosterTitleHeight-2, tr("Shift Roster - "+month.Format("January 2006")), "", 0, "L", false, 0, "")
+
+	// Weekday header
+	pdf.SetFont("Helvetica", "B", 9)
+	for i := 0; i < 7; i++ {
+		pdf.SetXY(rosterMargin+float64(i)*cellWidth, rosterMargin+rosterTitleHeight)
+		pdf.CellFormat(cellWidth, rosterHeaderHeight, gridStart.AddDate(0, 0, i).Weekday().String(), "1", 0, "C", false, 0, "")
+	}
+
+	// Day cells
+	pdf.SetDrawColor(160, 160, 160)
+	for day := gridStart; !day.After(gridEnd); day = day.AddDate(0, 0, 1) {
+		x, y := cellPosition(day)
+		inMonth := day.Month() == month.Month()
+
+		fill := rosterColor{255, 255, 255}
+		switch {
+		case !inMonth:
+			fill = rosterOutsideColor
+		case models.IsHoliday(day):
+			fill = rosterHolidayColor
+		case models.IsWeekend(day):
+			fill = rosterWeekendColor
+		}
+		pdf.SetFillColor(fill.R, fill.G, fill.B)
+		pdf.Rect(x, y, cellWidth, cellHeight, "FD")
+
+		pdf.SetFont("Helvetica", "B", 9)
+		if inMonth {
+			pdf.SetTextColor(0, 0, 0)
+		} else {
+			pdf.SetTextColor(140, 140, 140)
+		}
+		pdf.Text(x+1.5, y+4, fmt.Sprintf("%d", day.Day()))
+
+		if name := models.GetHolidayName(day); name != "" {
+			pdf.SetFont("Helvetica", "", 6)
+			pdf.SetTextColor(rosterHolidayTextColor.R, rosterHolidayTextColor.G, rosterHolidayTextColor.B)
+			pdf.Text(x+1.5, y+7.5, fitRosterText(pdf, tr(name), cellWidth-3))
+		}
+	}
+
+	// Shift bars, split at the end of each week row
+	pdf.SetFont("Helvetica", "B", 7)
+	pdf.SetTextColor(0, 0, 0)
+	for _, shift := range shifts {
+		start, end := shift.StartDate, shift.EndDate
+		if start.Before(gridStart) {
+			start = gridStart
+		}
+		if end.After(gridEnd) {
+			end = gridEnd
+		}
+
+		color := rosterNormalShiftColor
+		if shift.IsLongShift {
+			color = rosterLongShiftColor
+		}
+
+		for segmentStart := start; !segmentStart.After(end); {
+			// Last day of the week row (Sunday)
+			segmentEnd := segmentStart.AddDate(0, 0, (7-int(segmentStart.Weekday()))%7)
+			if segmentEnd.After(end) {
+				segmentEnd = end
+			}
+
+			x, y := cellPosition(segmentStart)
+			endX, _ := cellPosition(segmentEnd)
+			width := endX + cellWidth - x - 2
+
+			pdf.SetFillColor(color.R, color.G, color.B)
+			pdf.RoundedRect(x+1, y+9, width, rosterBarHeight, 1, "1234", "F")
+			pdf.SetXY(x+1, y+9)
+			pdf.CellFormat(width, rosterBarHeight, fitRosterText(pdf, tr(memberMap[shift.MemberID]), width-2), "", 0, "L", false, 0, "")
+
+			segmentStart = segmentEnd.AddDate(0, 0, 1)
+		}
+	}
+
+	// Leave days per cell
+	leaveColors := make(map[string]rosterColor)
+	for _, lc := range rosterLeaveColors {
+		leaveColors[lc.LeaveType] = lc.Color
+	}
+
+	leaveByDate := make(map[string][]models.LeaveDay)
+	for _, ld := range leaveDays {
+		dateStr := ld.LeaveDate.Format("2006-01-02")
+		leaveByDate[dateStr] = append(leaveByDate[dateStr], ld)
+	}
+
+	leaveTop := 9 + rosterBarHeight + 3
+	capacity := int((cellHeight - leaveTop) / rosterLeaveLine)
+	pdf.SetFont("Helvetica", "", 6)
+	for day := gridStart; !day.After(gridEnd); day = day.AddDate(0, 0, 1) {
+		entries := leaveByDate[day.Format("2006-01-02")]
+		if len(entries) == 0 || capacity <= 0 {
+			continue
+		}
+
+		x, y := cellPosition(day)
+		shown := entries
+		if len(entries) > capacity {
+			shown = entries[:capacity-1]
+		}
+
+		for i, ld := range shown {
+			lineY := y + leaveTop + float64(i)*rosterLeaveLine
+			color := leaveColors[ld.LeaveType]
+			pdf.SetFillColor(color.R, color.G, color.B)
+			pdf.Rect(x+1.5, lineY-2, 2, 2, "F")
+			pdf.SetTextColor(0, 0, 0)
+			pdf.Text(x+4.5, lineY, fitRosterText(pdf, tr(memberMap[ld.MemberID]), cellWidth-6))
+		}
+
+		if len(shown) < len(entries) {
+			pdf.SetTextColor(90, 90, 90)
+			pdf.Text(x+4.5, y+leaveTop+float64(len(shown))*rosterLeaveLine, fmt.Sprintf("+%d more", len(entries)-len(shown)))
+		}
+	}
+
+	// Legend
+	legendY := pageHeight - rosterMargin - rosterLegendHeight/2
+	legendX := rosterMargin
+	drawLegendItem := func(color rosterColor, label string) {
+		pdf.SetFillColor(color.R, color.G, color.B)
+		pdf.SetDrawColor(160, 160, 160)
+		pdf.Rect(legendX, legendY-3, 4, 4, "FD")
+		pdf.SetTextColor(0, 0, 0)
+		pdf.Text(legendX+5.5, legendY, label)
+		legendX += 5.5 + pdf.GetStringWidth(label) + 6
+	}
+
+	pdf.SetFont("Helvetica", "", 8)
+	drawLegendItem(rosterNormalShiftColor, "Shift")
+	drawLegendItem(rosterLongShiftColor, "Long shift")
+	drawLegendItem(rosterHolidayColor, "Public holiday")
+	drawLegendItem(rosterWeekendColor, "Weekend")
+	for _, lc := range rosterLeaveColors {
+		drawLegendItem(lc.Color, "Leave: "+lc.LeaveType)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fitRosterText shortens text with an ellipsis so it fits into width with the current font
+func fitRosterText(pdf *fpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	// Text is already cp1252 encoded, so bytes are characters
+	for len(text) > 0 && pdf.GetStringWidth(text+"...") > width {
+		text = text[:len(text)-1]
+	}
+	return text + "..."
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRosterGridRange(t *testing.T) {
+	start, end := rosterGridRange(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if !start.Equal(time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Grid range mismatch: got %s - %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+
+	// Month starting on Monday and ending on Sunday
+	start, end = rosterGridRange(time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC))
+	if !start.Equal(time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 10, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Grid range mismatch: got %s - %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+}
+
+func TestGetRosterPDF(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	alice, _ := store.CreateMember(workspaceID, "Alice")
+	bob, _ := store.CreateMember(workspaceID, "Bob")
+	store.CreateShift(workspaceID, alice.ID, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), false)
+	store.CreateShift(workspaceID, bob.ID, time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), true)
+	store.CreateLeaveDay(workspaceID, alice.ID, time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC), "sick")
+
+	app := fiber.New()
+	app.Get("/api/shifts/roster.pdf", h.AuthMiddleware, h.GetRosterPDF)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/shifts/roster.pdf?month=2025-01", nil)
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp.Header.Get("Content-Type") != "application/pdf" {
+		t.Errorf("Unexpected Content-Type: %s", resp.Header.Get("Content-Type"))
+	}
+
+	content, _ := io.ReadAll(resp.Body)
+	if !bytes.HasPrefix(content, []byte("%PDF-")) {
+		t.Error("Response is not a PDF document")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/shifts/roster.pdf?month=2025-13", nil)
+	req.Header.Set("Authorization", token)
+	resp, _ = app.Test(req)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code: %d, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
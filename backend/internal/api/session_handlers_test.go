@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shiftplanner/backend/internal/models"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSessions(t *testing.T) {
+	h, _, _ := setupTestAPI(t)
+
+	app := fiber.New()
+	app.Post("/api/auth/login", h.Login)
+	app.Get("/api/auth/sessions", h.AuthMiddleware, h.GetSessions)
+	app.Delete("/api/auth/sessions/:id", h.AuthMiddleware, h.RevokeSession)
+
+	login := func(userAgent string) string {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewBufferString(`{"username":"testuser","password":"testpassword"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		resp, _ := app.Test(req)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Login: expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		var result struct {
+			Token string `json:"token"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return result.Token
+	}
+	send := func(method, path, token string) *http.Response {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", token)
+		resp, _ := app.Test(req)
+		return resp
+	}
+
+	laptop := login("Laptop")
+	phone := login("Phone")
+
+	resp := send(http.MethodGet, "/api/auth/sessions", laptop)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var sessions []models.Session
+	json.NewDecoder(resp.Body).Decode(&sessions)
+	var current, other *models.Session
+	for i := range sessions {
+		if sessions[i].Current {
+			current = &sessions[i]
+		} else {
+			other = &sessions[i]
+		}
+	}
+	if len(sessions) != 2 || current == nil || current.UserAgent != "Laptop" || other.UserAgent != "Phone" {
+		t.Fatalf("Expected the laptop session marked current and the phone session, got %+v", sessions)
+	}
+	if sessions[0].Token != "" || sessions[0].IPAddress == "" {
+		t.Errorf("Expected sessions with client address and without token, got %+v", sessions[0])
+	}
+
+	if resp := send(http.MethodDelete, "/api/auth/sessions/"+strconv.Itoa(other.ID), laptop); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status code: %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if resp := send(http.MethodGet, "/api/auth/sessions", phone); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Revoked session: expected %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+	if resp := send(http.MethodDelete, "/api/auth/sessions/"+strconv.Itoa(other.ID), laptop); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Revoking twice: expected %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"shiftplanner/backend/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetPlanSnapshots lists the plan snapshots taken before bulk changes, newest first
+func (h *Handler) GetPlanSnapshots(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := h.snapshots.GetPlanSnapshots(workspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if snapshots == nil {
+		snapshots = []models.PlanSnapshot{}
+	}
+
+	return c.JSON(snapshots)
+}
+
+// RestorePlanSnapshot undoes a bulk change by putting a snapshot's shifts and counters back
+// The current plan of the snapshot's range is snapshotted first, so the restore can be undone too.
+func (h *Handler) RestorePlanSnapshot(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	snapshotID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid snapshot ID",
+		})
+	}
+
+	snapshot, err := h.snapshots.GetPlanSnapshot(workspaceID, snapshotID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Snapshot not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	startDate, endDate, err := snapshotRange(snapshot)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if _, err := h.snapshots.CreatePlanSnapshot(workspaceID, models.SnapshotReasonRestore, startDate, endDate); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	result, err := h.snapshots.RestorePlanSnapshot(workspaceID, snapshot)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// snapshotRange parses the date range of a snapshot, zero dates when it covers all shifts
+func snapshotRange(snapshot *models.PlanSnapshot) (startDate, endDate time.Time, err error) {
+	if snapshot.StartDate == "" {
+		return time.Time{}, time.Time{}, nil
+	}
+	if startDate, err = parseDateParam(snapshot.StartDate); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if endDate, err = parseDateParam(snapshot.EndDate); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return startDate, endDate, nil
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shiftplanner/backend/internal/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestClearAllShifts_CanBeUndone(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	member, _ := store.CreateMember(workspaceID, "Alice")
+	day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	store.CreateShift(workspaceID, member.ID, day, day, true)
+
+	app := fiber.New()
+	app.Delete("/api/shifts", h.AuthMiddleware, h.ClearAllShifts)
+	app.Get("/api/snapshots", h.AuthMiddleware, h.GetPlanSnapshots)
+	app.Post("/api/snapshots/:id/restore", h.AuthMiddleware, h.RestorePlanSnapshot)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/shifts", nil)
+	req.Header.Set("Authorization", token)
+	app.Test(req)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/snapshots", nil)
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+
+	var snapshots []models.PlanSnapshot
+	json.NewDecoder(resp.Body).Decode(&snapshots)
+	if len(snapshots) != 1 || snapshots[0].Reason != models.SnapshotReasonClear || snapshots[0].ShiftCount != 1 {
+		t.Fatalf("Expected one clear snapshot with 1 shift, got %+v", snapshots)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/snapshots/"+strconv.Itoa(snapshots[0].ID)+"/restore", nil)
+	req.Header.Set("Authorization", token)
+	resp, _ = app.Test(req)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	shift, _ := store.GetShiftByDate(workspaceID, day)
+	if shift == nil || shift.MemberID != member.ID || !shift.IsLongShift {
+		t.Errorf("Shift was not restored: %+v", shift)
+	}
+	if _, longShifts, _ := store.GetHiddenShiftCounts(workspaceID, member.ID); longShifts != 1 {
+		t.Errorf("Expected long shift counter 1, got %d", longShifts)
+	}
+
+	// The restore took a snapshot of its own
+	if snapshots, _ := store.GetPlanSnapshots(workspaceID); len(snapshots) != 2 || snapshots[0].Reason != models.SnapshotReasonRestore {
+		t.Errorf("Expected a restore snapshot, got %+v", snapshots)
+	}
+}
+
+func TestRestorePlanSnapshot_NotFound(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	app := fiber.New()
+	app.Post("/api/snapshots/:id/restore", h.AuthMiddleware, h.RestorePlanSnapshot)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/snapshots/999/restore", nil)
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code: %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
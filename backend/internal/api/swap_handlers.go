@@ -0,0 +1,186 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/storage"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateSwapRequest creates a pending request to swap the members of two shifts
+// The shifts only change hands once a scheduler approves the request. Below
+// the scheduler role users can only offer shifts of their linked member.
+func (h *Handler) CreateSwapRequest(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleMember)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		ShiftID     int    `json:"shift_id"`
+		WithShiftID int    `json:"with_shift_id"`
+		Note        string `json:"note"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.ShiftID == 0 || req.WithShiftID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "shift_id and with_shift_id are required",
+		})
+	}
+
+	shift, err := h.shifts.GetShiftByID(workspaceID, req.ShiftID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Shift not found",
+		})
+	}
+	withShift, err := h.shifts.GetShiftByID(workspaceID, req.WithShiftID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Shift not found",
+		})
+	}
+
+	if shift.MemberID == withShift.MemberID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Both shifts belong to the same member",
+		})
+	}
+
+	member, err := h.members.GetMemberByID(workspaceID, shift.MemberID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Member not found",
+		})
+	}
+	if !models.RoleAtLeast(GetRole(c), models.RoleScheduler) && member.UserID != GetUserID(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You can only offer shifts of your own member",
+		})
+	}
+
+	swapRequest, err := h.shifts.CreateSwapRequest(workspaceID, req.ShiftID, req.WithShiftID, req.Note)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	created := []models.SwapRequest{*swapRequest}
+	h.addSwapMemberNames(workspaceID, created)
+
+	return c.Status(fiber.StatusCreated).JSON(created[0])
+}
+
+// GetSwapRequests returns swap requests, optionally filtered by status and member
+// A member filter matches both sides of a swap.
+func (h *Handler) GetSwapRequests(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	status := c.Query("status")
+	if status != "" && status != models.LeaveStatusPending && status != models.LeaveStatusApproved && status != models.LeaveStatusRejected {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid status",
+		})
+	}
+
+	memberID := 0
+	if memberIDStr := c.Query("member_id"); memberIDStr != "" {
+		memberID, err = strconv.Atoi(memberIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid member_id",
+			})
+		}
+	}
+
+	requests, err := h.shifts.GetSwapRequests(workspaceID, status, memberID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if requests == nil {
+		requests = []models.SwapRequest{}
+	}
+	h.addSwapMemberNames(workspaceID, requests)
+
+	return c.JSON(requests)
+}
+
+// ApproveSwapRequest approves a pending swap request and swaps the members of its shifts
+func (h *Handler) ApproveSwapRequest(c *fiber.Ctx) error {
+	h = h.audited(c)
+	return h.decideSwapRequest(c, h.shifts.ApproveSwapRequest)
+}
+
+// RejectSwapRequest rejects a pending swap request
+func (h *Handler) RejectSwapRequest(c *fiber.Ctx) error {
+	h = h.audited(c)
+	return h.decideSwapRequest(c, h.shifts.RejectSwapRequest)
+}
+
+// decideSwapRequest applies an approve or reject decision to the request in the URL
+func (h *Handler) decideSwapRequest(c *fiber.Ctx, decide func(workspaceID, requestID int) (*models.SwapRequest, error)) error {
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	requestID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid swap request ID",
+		})
+	}
+
+	swapRequest, err := decide(workspaceID, requestID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Swap request not found",
+			})
+		}
+		if errors.Is(err, storage.ErrSwapRequestNotPending) || errors.Is(err, storage.ErrSwapRequestOutdated) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	decided := []models.SwapRequest{*swapRequest}
+	h.addSwapMemberNames(workspaceID, decided)
+
+	return c.JSON(decided[0])
+}
+
+// addSwapMemberNames fills in the member names of swap requests
+func (h *Handler) addSwapMemberNames(workspaceID int, requests []models.SwapRequest) {
+	members, err := h.members.GetAllMembers(workspaceID)
+	if err != nil {
+		return
+	}
+	memberMap := make(map[int]string)
+	for _, m := range members {
+		memberMap[m.ID] = m.Name
+	}
+	for i := range requests {
+		requests[i].MemberName = memberMap[requests[i].MemberID]
+		requests[i].WithMemberName = memberMap[requests[i].WithMemberID]
+	}
+}
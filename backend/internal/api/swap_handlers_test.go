@@ -0,0 +1,79 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shiftplanner/backend/internal/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSwapRequestFlow(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+	alice, _ := store.CreateMember(workspaceID, "Alice")
+	bob, _ := store.CreateMember(workspaceID, "Bob")
+	day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	aliceShift, _ := store.CreateShift(workspaceID, alice.ID, day, day, false)
+	bobShift, _ := store.CreateShift(workspaceID, bob.ID, day.AddDate(0, 0, 1), day.AddDate(0, 0, 1), false)
+
+	ownerToken := "test_token_123"
+	createTestSession(t, store, workspaceID, ownerToken)
+
+	user, _ := store.CreateUser("alice", "testpassword")
+	memberToken := "test_token_alice"
+	createTestSession(t, store, personalWorkspace(t, store, user.ID), memberToken)
+	store.SetWorkspaceUserRole(workspaceID, user.ID, models.RoleMember)
+	store.CreateMemberInvite(workspaceID, alice.ID, "invite", time.Now().Add(time.Hour))
+	store.AcceptMemberInvite(user.ID, "invite", time.Now())
+
+	app := fiber.New()
+	app.Post("/api/swap-requests", h.AuthMiddleware, h.CreateSwapRequest)
+	app.Post("/api/swap-requests/:id/approve", h.AuthMiddleware, h.ApproveSwapRequest)
+
+	send := func(url, token, body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		req.Header.Set("X-Workspace-ID", strconv.Itoa(workspaceID))
+		resp, _ := app.Test(req)
+		return resp
+	}
+
+	// Members can only offer shifts of their own member
+	resp := send("/api/swap-requests", memberToken, `{"shift_id":`+strconv.Itoa(bobShift.ID)+`,"with_shift_id":`+strconv.Itoa(aliceShift.ID)+`}`)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("A colleague's shift: expected status code %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	resp = send("/api/swap-requests", memberToken, `{"shift_id":`+strconv.Itoa(aliceShift.ID)+`,"with_shift_id":`+strconv.Itoa(bobShift.ID)+`,"note":"Dentist"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+	var swapRequest models.SwapRequest
+	json.NewDecoder(resp.Body).Decode(&swapRequest)
+	if swapRequest.Status != models.LeaveStatusPending || swapRequest.WithMemberName != "Bob" {
+		t.Errorf("Unexpected swap request: %+v", swapRequest)
+	}
+
+	// Only schedulers decide on swaps
+	approveURL := "/api/swap-requests/" + strconv.Itoa(swapRequest.ID) + "/approve"
+	if resp := send(approveURL, memberToken, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Member approval: expected status code %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+	if resp := send(approveURL, ownerToken, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if shift, _ := store.GetShiftByID(workspaceID, aliceShift.ID); shift.MemberID != bob.ID {
+		t.Errorf("Shift member mismatch: got %d, want %d", shift.MemberID, bob.ID)
+	}
+
+	// Approving twice is a conflict
+	if resp := send(approveURL, ownerToken, ""); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status code: %d, got %d", http.StatusConflict, resp.StatusCode)
+	}
+}
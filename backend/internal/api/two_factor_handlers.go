@@ -0,0 +1,308 @@
+package api
+
+import (
+	"shiftplanner/backend/internal/auth"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/storage"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TwoFactorCodeRequest TOTP or recovery code confirming a two-factor change
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// LoginTwoFactorRequest second step of a login with two-factor enabled
+type LoginTwoFactorRequest struct {
+	PreAuthToken string `json:"pre_auth_token"`
+	Code         string `json:"code"`
+}
+
+// GetTwoFactor shows whether the authenticated user has two-factor enabled
+func (h *Handler) GetTwoFactor(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+
+	twoFactor, err := h.twoFactors.GetTwoFactor(userID)
+	if err != nil && err != storage.ErrTwoFactorNotFound {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if !twoFactor.Enabled() {
+		return c.JSON(fiber.Map{
+			"enabled": false,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"enabled":        true,
+		"enabled_at":     twoFactor.EnabledAt,
+		"recovery_codes": twoFactor.RecoveryCodes,
+	})
+}
+
+// SetupTwoFactor starts enrollment with a new TOTP secret
+// The secret and its otpauth URI are returned once; two-factor is on after EnableTwoFactor.
+func (h *Handler) SetupTwoFactor(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+
+	if enabled, err := h.hasTwoFactor(userID); err != nil || enabled {
+		return twoFactorEnabledError(c, err)
+	}
+	user, err := h.users.GetUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	secret, err := auth.StartTwoFactorEnrollment(h.twoFactors, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"secret":      secret,
+		"otpauth_uri": auth.TOTPURI(secret, user.Username),
+	})
+}
+
+// EnableTwoFactor turns two-factor on with a code from the authenticator app
+// The recovery codes are returned once; only their hashes are stored.
+func (h *Handler) EnableTwoFactor(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if enabled, err := h.hasTwoFactor(userID); err != nil || enabled {
+		return twoFactorEnabledError(c, err)
+	}
+
+	codes, err := auth.EnableTwoFactor(h.twoFactors, userID, req.Code)
+	if err != nil {
+		if err == storage.ErrTwoFactorNotFound {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Start two-factor setup first",
+			})
+		}
+		return twoFactorCodeError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns two-factor off with a TOTP or recovery code
+func (h *Handler) DisableTwoFactor(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := auth.VerifySecondFactor(h.twoFactors, userID, req.Code); err != nil {
+		return twoFactorCodeError(c, err)
+	}
+	if err := h.twoFactors.DisableTwoFactor(userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, confirmed with a TOTP or recovery code
+func (h *Handler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := auth.VerifySecondFactor(h.twoFactors, userID, req.Code); err != nil {
+		return twoFactorCodeError(c, err)
+	}
+	codes, hashes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := h.twoFactors.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"recovery_codes": codes,
+	})
+}
+
+// LoginTwoFactor exchanges the pre-auth token of a login and a TOTP or recovery code for a session
+func (h *Handler) LoginTwoFactor(c *fiber.Ctx) error {
+	var req LoginTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.PreAuthToken == "" || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Pre-auth token and code are required",
+		})
+	}
+
+	// Wrong codes count as failed logins of the user, like wrong passwords
+	userID, err := auth.LoginChallengeUser(h.twoFactors, req.PreAuthToken)
+	if err != nil {
+		return twoFactorLoginError(c, err)
+	}
+	user, err := h.users.GetUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	ip := strings.Clone(c.IP())
+	if wait := h.logins.Wait(user.Username, ip); wait > 0 {
+		return loginFailed(c, wait)
+	}
+
+	if _, err := auth.FinishTwoFactorLogin(h.twoFactors, req.PreAuthToken, req.Code); err != nil {
+		if err == auth.ErrInvalidCode {
+			h.logins.Fail(user.Username, ip)
+		}
+		return twoFactorLoginError(c, err)
+	}
+	h.logins.Succeed(user.Username)
+
+	session, err := h.createSession(c, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"user":  user,
+		"token": session.Token,
+	})
+}
+
+// twoFactorLoginError responds to a rejected second login step
+func twoFactorLoginError(c *fiber.Ctx, err error) error {
+	switch err {
+	case auth.ErrInvalidToken:
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Login expired, log in again",
+		})
+	case auth.ErrInvalidCode:
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid code",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// SetWorkspaceTwoFactor sets whether schedulers and owners of the active workspace need two-factor
+func (h *Handler) SetWorkspaceTwoFactor(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleOwner)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		Required bool `json:"required"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Owners can't lock themselves out of the setting
+	if req.Required {
+		enabled, err := h.hasTwoFactor(GetUserID(c))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if !enabled {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Enable two-factor authentication for your own account first",
+			})
+		}
+	}
+
+	if err := h.workspaces.SetWorkspaceRequireTwoFactor(workspaceID, req.Required); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"require_two_factor": req.Required,
+	})
+}
+
+// twoFactorEnabledError responds to enrollment of a user who already has two-factor,
+// or to the error of looking that up
+func twoFactorEnabledError(c *fiber.Ctx, err error) error {
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error": "Two-factor authentication is already enabled",
+	})
+}
+
+// twoFactorCodeError maps errors of checking a second factor to responses
+func twoFactorCodeError(c *fiber.Ctx, err error) error {
+	if err == auth.ErrInvalidCode {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid code",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
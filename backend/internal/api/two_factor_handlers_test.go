@@ -0,0 +1,200 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"shiftplanner/backend/internal/auth"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/storage"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// setupTwoFactorApp serves the login and two-factor routes with a test session for the owner
+// Requests are sent to the owner's workspace.
+func setupTwoFactorApp(t *testing.T) (storage.Store, func(method, path, token, body string) *http.Response, int) {
+	h, store, workspaceID := setupTestAPI(t)
+	createTestSession(t, store, workspaceID, "owner_token")
+
+	app := fiber.New()
+	app.Post("/api/auth/login", h.Login)
+	app.Post("/api/auth/login/2fa", h.LoginTwoFactor)
+	app.Get("/api/auth/2fa", h.AuthMiddleware, h.GetTwoFactor)
+	app.Post("/api/auth/2fa/setup", h.AuthMiddleware, h.SetupTwoFactor)
+	app.Post("/api/auth/2fa/enable", h.AuthMiddleware, h.EnableTwoFactor)
+	app.Post("/api/auth/2fa/disable", h.AuthMiddleware, h.DisableTwoFactor)
+	app.Put("/api/workspace/two-factor", h.AuthMiddleware, h.SetWorkspaceTwoFactor)
+	app.Get("/api/members", h.AuthMiddleware, h.GetMembers)
+	app.Post("/api/members", h.AuthMiddleware, h.CreateMember)
+
+	send := func(method, path, token, body string) *http.Response {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		req.Header.Set(workspaceHeader, strconv.Itoa(workspaceID))
+		resp, _ := app.Test(req)
+		return resp
+	}
+	return store, send, workspaceID
+}
+
+// enableTwoFactorForTest enrolls the session's user and returns the TOTP secret and recovery codes
+func enableTwoFactorForTest(t *testing.T, send func(method, path, token, body string) *http.Response, token string) (string, []string) {
+	t.Helper()
+	resp := send(http.MethodPost, "/api/auth/2fa/setup", token, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Setup: expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var setup struct {
+		Secret     string `json:"secret"`
+		OTPAuthURI string `json:"otpauth_uri"`
+	}
+	json.NewDecoder(resp.Body).Decode(&setup)
+	if setup.Secret == "" || !strings.HasPrefix(setup.OTPAuthURI, "otpauth://totp/") {
+		t.Fatalf("Unexpected setup response: %+v", setup)
+	}
+
+	code, _ := auth.TOTPCode(setup.Secret, auth.TOTPStep(time.Now()))
+	resp = send(http.MethodPost, "/api/auth/2fa/enable", token, `{"code":"`+code+`"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Enable: expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var enabled struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	json.NewDecoder(resp.Body).Decode(&enabled)
+	return setup.Secret, enabled.RecoveryCodes
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	_, send, _ := setupTwoFactorApp(t)
+
+	secret, recoveryCodes := enableTwoFactorForTest(t, send, "owner_token")
+	if len(recoveryCodes) != auth.RecoveryCodeCount {
+		t.Fatalf("Expected %d recovery codes, got %d", auth.RecoveryCodeCount, len(recoveryCodes))
+	}
+	if resp := send(http.MethodPost, "/api/auth/2fa/setup", "owner_token", ""); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Setup with two-factor enabled: expected %d, got %d", http.StatusConflict, resp.StatusCode)
+	}
+
+	// The password alone only gets a pre-auth token
+	resp := send(http.MethodPost, "/api/auth/login", "", `{"username":"testuser","password":"testpassword"}`)
+	var login struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		PreAuthToken      string `json:"pre_auth_token"`
+		Token             string `json:"token"`
+	}
+	json.NewDecoder(resp.Body).Decode(&login)
+	if resp.StatusCode != http.StatusOK || !login.TwoFactorRequired || login.PreAuthToken == "" || login.Token != "" {
+		t.Fatalf("Expected a pre-auth token, got %d %+v", resp.StatusCode, login)
+	}
+	if resp := send(http.MethodGet, "/api/members", login.PreAuthToken, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Pre-auth token as session: expected %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	if resp := send(http.MethodPost, "/api/auth/login/2fa", "", `{"pre_auth_token":"`+login.PreAuthToken+`","code":"000000"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Wrong code: expected %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+	code, _ := auth.TOTPCode(secret, auth.TOTPStep(time.Now())+1)
+	resp = send(http.MethodPost, "/api/auth/login/2fa", "", `{"pre_auth_token":"`+login.PreAuthToken+`","code":"`+code+`"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Second step: expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var session struct {
+		User  models.User `json:"user"`
+		Token string      `json:"token"`
+	}
+	json.NewDecoder(resp.Body).Decode(&session)
+	if session.User.Username != "testuser" {
+		t.Errorf("Expected testuser, got %+v", session.User)
+	}
+	if resp := send(http.MethodGet, "/api/members", session.Token, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Session after second step: expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	// Recovery codes turn two-factor off, and logins go back to one step
+	if resp := send(http.MethodPost, "/api/auth/2fa/disable", session.Token, `{"code":"`+recoveryCodes[0]+`"}`); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Disable: expected status code %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	resp = send(http.MethodPost, "/api/auth/login", "", `{"username":"testuser","password":"testpassword"}`)
+	var relogin map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&relogin)
+	if relogin["two_factor_required"] != nil || relogin["token"] == nil {
+		t.Errorf("Expected a session token after disabling two-factor, got %v", relogin)
+	}
+}
+
+func TestTwoFactorLogin_LockedOut(t *testing.T) {
+	_, send, _ := setupTwoFactorApp(t)
+	secret, _ := enableTwoFactorForTest(t, send, "owner_token")
+
+	preAuthToken := func() string {
+		resp := send(http.MethodPost, "/api/auth/login", "", `{"username":"testuser","password":"testpassword"}`)
+		var login struct {
+			PreAuthToken string `json:"pre_auth_token"`
+		}
+		json.NewDecoder(resp.Body).Decode(&login)
+		return login.PreAuthToken
+	}
+
+	// A correct password doesn't reset the count, so each fresh challenge adds to it
+	var token string
+	for i := 0; i < 6; i++ {
+		token = preAuthToken()
+		if token == "" {
+			t.Fatalf("Expected a pre-auth token for attempt %d", i+1)
+		}
+		if resp := send(http.MethodPost, "/api/auth/login/2fa", "", `{"pre_auth_token":"`+token+`","code":"000000"}`); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Wrong code %d: expected %d, got %d", i+1, http.StatusUnauthorized, resp.StatusCode)
+		}
+	}
+
+	if resp := send(http.MethodPost, "/api/auth/login", "", `{"username":"testuser","password":"testpassword"}`); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Password after wrong codes: expected %d, got %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+	code, _ := auth.TOTPCode(secret, auth.TOTPStep(time.Now()))
+	if resp := send(http.MethodPost, "/api/auth/login/2fa", "", `{"pre_auth_token":"`+token+`","code":"`+code+`"}`); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Right code during the lockout: expected %d, got %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+}
+
+func TestWorkspaceRequireTwoFactor(t *testing.T) {
+	store, send, workspaceID := setupTwoFactorApp(t)
+
+	scheduler, _ := store.CreateUser("scheduler", "password")
+	store.SetWorkspaceUserRole(workspaceID, scheduler.ID, models.RoleScheduler)
+	store.CreateSession(scheduler.ID, auth.HashToken("scheduler_token"), "", "", time.Now().Add(time.Hour))
+	if resp := send(http.MethodPut, "/api/workspace/two-factor", "owner_token", `{"required":true}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Requiring two-factor without it: expected %d, got %d", http.StatusConflict, resp.StatusCode)
+	}
+	enableTwoFactorForTest(t, send, "owner_token")
+	if resp := send(http.MethodPut, "/api/workspace/two-factor", "owner_token", `{"required":true}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Requiring two-factor: expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp := send(http.MethodPost, "/api/members", "owner_token", `{"name":"Alice"}`); resp.StatusCode != http.StatusCreated {
+		t.Errorf("Owner with two-factor: expected %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	// Schedulers without two-factor keep read access until they enable it
+	resp := send(http.MethodPost, "/api/members", "scheduler_token", `{"name":"Bob"}`)
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusForbidden || !strings.Contains(string(body), "two-factor") {
+		t.Errorf("Scheduler without two-factor: expected %d asking for two-factor, got %d %s", http.StatusForbidden, resp.StatusCode, body)
+	}
+	if resp := send(http.MethodGet, "/api/members", "scheduler_token", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Scheduler reading without two-factor: expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	enableTwoFactorForTest(t, send, "scheduler_token")
+	if resp := send(http.MethodPost, "/api/members", "scheduler_token", `{"name":"Bob"}`); resp.StatusCode != http.StatusCreated {
+		t.Errorf("Scheduler with two-factor: expected %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"shiftplanner/backend/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetUnavailabilityRules returns recurring unavailability rules, optionally for one member
+func (h *Handler) GetUnavailabilityRules(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	memberID := 0
+	if memberIDStr := c.Query("member_id"); memberIDStr != "" {
+		var err error
+		memberID, err = strconv.Atoi(memberIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid member_id",
+			})
+		}
+	}
+
+	rules, err := h.leave.GetUnavailabilityRules(workspaceID, memberID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Add member names
+	members, err := h.members.GetAllMembers(workspaceID)
+	if err == nil {
+		memberMap := make(map[int]string)
+		for _, m := range members {
+			memberMap[m.ID] = m.Name
+		}
+		for i := range rules {
+			rules[i].MemberName = memberMap[rules[i].MemberID]
+		}
+	}
+
+	if rules == nil {
+		rules = []models.UnavailabilityRule{}
+	}
+
+	return c.JSON(rules)
+}
+
+// CreateUnavailabilityRule creates a recurring unavailability rule
+func (h *Handler) CreateUnavailabilityRule(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		MemberID  int    `json:"member_id"`
+		Frequency string `json:"frequency"`
+		Weekdays  []int  `json:"weekdays"`
+		Nth       int    `json:"nth"`
+		StartDate string `json:"start_date"`
+		EndDate   string `json:"end_date"`
+		Note      string `json:"note"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.MemberID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "member_id is required",
+		})
+	}
+
+	if !models.IsValidFrequency(req.Frequency) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "frequency must be one of weekly, biweekly, monthly",
+		})
+	}
+
+	if len(req.Weekdays) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "weekdays is required",
+		})
+	}
+
+	rule := models.UnavailabilityRule{
+		MemberID:  req.MemberID,
+		Frequency: req.Frequency,
+		Nth:       req.Nth,
+		Note:      req.Note,
+	}
+
+	for _, wd := range req.Weekdays {
+		if wd < 0 || wd > 6 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "weekdays must be between 0 (Sunday) and 6 (Saturday)",
+			})
+		}
+		rule.Weekdays = append(rule.Weekdays, time.Weekday(wd))
+	}
+
+	if req.StartDate == "" {
+		now := time.Now().UTC()
+		rule.StartDate = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	} else if rule.StartDate, err = parseDateParam(req.StartDate); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid start_date format (use YYYY-MM-DD)",
+		})
+	}
+
+	if req.EndDate != "" {
+		endDate, err := parseDateParam(req.EndDate)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid end_date format (use YYYY-MM-DD)",
+			})
+		}
+		rule.EndDate = &endDate
+	}
+
+	member, err := h.members.GetMemberByID(workspaceID, req.MemberID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Member not found",
+		})
+	}
+
+	created, err := h.leave.CreateUnavailabilityRule(workspaceID, rule)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	created.MemberName = member.Name
+
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// DeleteUnavailabilityRule deletes a recurring unavailability rule
+func (h *Handler) DeleteUnavailabilityRule(c *fiber.Ctx) error {
+	h = h.audited(c)
+	workspaceID, err := authorize(c, models.RoleScheduler)
+	if err != nil {
+		return err
+	}
+
+	ruleID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid rule ID",
+		})
+	}
+
+	if err := h.leave.DeleteUnavailabilityRule(workspaceID, ruleID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
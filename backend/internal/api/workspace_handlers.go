@@ -0,0 +1,173 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/storage"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetWorkspaces lists the workspaces of the authenticated user with their role
+func (h *Handler) GetWorkspaces(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	workspaces, err := h.workspaces.GetUserWorkspaces(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if workspaces == nil {
+		workspaces = []models.Workspace{}
+	}
+
+	return c.JSON(workspaces)
+}
+
+// CreateWorkspace creates a workspace owned by the authenticated user
+func (h *Handler) CreateWorkspace(c *fiber.Ctx) error {
+	userID, err := requireSession(c)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Name is required",
+		})
+	}
+
+	workspace, err := h.workspaces.CreateWorkspace(userID, name)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(workspace)
+}
+
+// GetWorkspaceUsers lists the users of the active workspace and their roles
+func (h *Handler) GetWorkspaceUsers(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+
+	users, err := h.workspaces.GetWorkspaceUsers(workspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if users == nil {
+		users = []models.WorkspaceUser{}
+	}
+
+	return c.JSON(users)
+}
+
+// SetWorkspaceUserRole adds a user to the active workspace by username or changes their role
+func (h *Handler) SetWorkspaceUserRole(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleOwner)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if !models.IsValidRole(req.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "role must be owner, scheduler, member or viewer",
+		})
+	}
+
+	user, _, err := h.users.GetUserByUsername(strings.TrimSpace(req.Username))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "User not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.workspaces.SetWorkspaceUserRole(workspaceID, user.ID, req.Role); err != nil {
+		return workspaceUserError(c, err)
+	}
+
+	return c.JSON(models.WorkspaceUser{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     req.Role,
+	})
+}
+
+// RemoveWorkspaceUser takes a user's access to the active workspace away
+func (h *Handler) RemoveWorkspaceUser(c *fiber.Ctx) error {
+	workspaceID, err := authorize(c, models.RoleOwner)
+	if err != nil {
+		return err
+	}
+
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	if err := h.workspaces.RemoveWorkspaceUser(workspaceID, userID); err != nil {
+		return workspaceUserError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// workspaceUserError responds to a failed change of a workspace user
+func workspaceUserError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User is not in this workspace",
+		})
+	case errors.Is(err, storage.ErrLastOwner):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shiftplanner/backend/internal/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestWorkspaceRoles(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+	member, _ := store.CreateMember(workspaceID, "Alice")
+
+	app := fiber.New()
+	app.Get("/api/members", h.AuthMiddleware, h.GetMembers)
+	app.Post("/api/members", h.AuthMiddleware, h.CreateMember)
+	app.Post("/api/leave-requests", h.AuthMiddleware, h.CreateLeaveRequest)
+	app.Post("/api/leave-days", h.AuthMiddleware, h.CreateLeaveDay)
+	app.Get("/api/backup", h.AuthMiddleware, h.ExportBackup)
+
+	tests := []struct {
+		role                        string
+		read, request, edit, backup int
+	}{
+		{models.RoleViewer, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden},
+		{models.RoleMember, http.StatusOK, http.StatusCreated, http.StatusForbidden, http.StatusForbidden},
+		{models.RoleScheduler, http.StatusOK, http.StatusCreated, http.StatusCreated, http.StatusForbidden},
+		{models.RoleOwner, http.StatusOK, http.StatusCreated, http.StatusCreated, http.StatusOK},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			user, _ := store.CreateUser("user"+strconv.Itoa(i), "testpassword")
+			token := "token_" + tt.role
+			createTestSession(t, store, personalWorkspace(t, store, user.ID), token)
+			store.SetWorkspaceUserRole(workspaceID, user.ID, tt.role)
+			// Members request leave for the member linked to their account
+			own, _ := store.CreateMember(workspaceID, "Member "+tt.role)
+			store.CreateMemberInvite(workspaceID, own.ID, "invite_"+tt.role, time.Now().Add(time.Hour))
+			store.AcceptMemberInvite(user.ID, "invite_"+tt.role, time.Now())
+
+			send := func(method, path, body string) int {
+				req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", token)
+				req.Header.Set("X-Workspace-ID", strconv.Itoa(workspaceID))
+				resp, _ := app.Test(req)
+				return resp.StatusCode
+			}
+
+			if got := send(http.MethodGet, "/api/members", ""); got != tt.read {
+				t.Errorf("GET /api/members: got %d, want %d", got, tt.read)
+			}
+			leaveRequest := `{"member_id":` + strconv.Itoa(own.ID) + `,"start_date":"2025-03-0` + strconv.Itoa(i+1) + `","end_date":"2025-03-0` + strconv.Itoa(i+1) + `"}`
+			if got := send(http.MethodPost, "/api/leave-requests", leaveRequest); got != tt.request {
+				t.Errorf("POST /api/leave-requests: got %d, want %d", got, tt.request)
+			}
+			if got := send(http.MethodPost, "/api/members", `{"name":"New"}`); got != tt.edit {
+				t.Errorf("POST /api/members: got %d, want %d", got, tt.edit)
+			}
+			// Leave days added directly skip approval
+			leaveDay := `{"member_id":` + strconv.Itoa(member.ID) + `,"start_date":"2025-04-0` + strconv.Itoa(i+1) + `","end_date":"2025-04-0` + strconv.Itoa(i+1) + `"}`
+			if got := send(http.MethodPost, "/api/leave-days", leaveDay); got != tt.edit {
+				t.Errorf("POST /api/leave-days: got %d, want %d", got, tt.edit)
+			}
+			if got := send(http.MethodGet, "/api/backup", ""); got != tt.backup {
+				t.Errorf("GET /api/backup: got %d, want %d", got, tt.backup)
+			}
+		})
+	}
+}
+
+func TestWorkspaceUsers(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+	owner, _, _ := store.GetUserByUsername("testuser")
+	other, _ := store.CreateUser("otheruser", "testpassword")
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	app := fiber.New()
+	app.Get("/api/workspace/users", h.AuthMiddleware, h.GetWorkspaceUsers)
+	app.Put("/api/workspace/users", h.AuthMiddleware, h.SetWorkspaceUserRole)
+	app.Delete("/api/workspace/users/:id", h.AuthMiddleware, h.RemoveWorkspaceUser)
+
+	send := func(method, path, body string) *http.Response {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		resp, _ := app.Test(req)
+		return resp
+	}
+
+	resp := send(http.MethodPut, "/api/workspace/users", `{"username":"otheruser","role":"scheduler"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if role, _ := store.GetWorkspaceRole(workspaceID, other.ID); role != models.RoleScheduler {
+		t.Errorf("Role mismatch: got %q, want scheduler", role)
+	}
+
+	if resp := send(http.MethodPut, "/api/workspace/users", `{"username":"otheruser","role":"admin"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Invalid role: expected %d, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+	if resp := send(http.MethodPut, "/api/workspace/users", `{"username":"nobody","role":"viewer"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Unknown user: expected %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	// The only owner can neither step down nor leave
+	if resp := send(http.MethodPut, "/api/workspace/users", `{"username":"testuser","role":"viewer"}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Demoting last owner: expected %d, got %d", http.StatusConflict, resp.StatusCode)
+	}
+	if resp := send(http.MethodDelete, "/api/workspace/users/"+strconv.Itoa(owner.ID), ""); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Removing last owner: expected %d, got %d", http.StatusConflict, resp.StatusCode)
+	}
+
+	resp = send(http.MethodGet, "/api/workspace/users", "")
+	var users []models.WorkspaceUser
+	json.NewDecoder(resp.Body).Decode(&users)
+	if len(users) != 2 || users[0].Username != "otheruser" || users[1].Role != models.RoleOwner {
+		t.Errorf("Unexpected workspace users: %+v", users)
+	}
+
+	if resp := send(http.MethodDelete, "/api/workspace/users/"+strconv.Itoa(other.ID), ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status code: %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if _, err := store.GetWorkspaceRole(workspaceID, other.ID); err == nil {
+		t.Error("Removed user should lose access")
+	}
+}
+
+func TestCreateWorkspace(t *testing.T) {
+	h, store, workspaceID := setupTestAPI(t)
+
+	token := "test_token_123"
+	createTestSession(t, store, workspaceID, token)
+
+	app := fiber.New()
+	app.Get("/api/workspaces", h.AuthMiddleware, h.GetWorkspaces)
+	app.Post("/api/workspaces", h.AuthMiddleware, h.CreateWorkspace)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/workspaces", bytes.NewBufferString(`{"name":"Night team"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code: %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/workspaces", nil)
+	req.Header.Set("Authorization", token)
+	resp, _ = app.Test(req)
+
+	var workspaces []models.Workspace
+	json.NewDecoder(resp.Body).Decode(&workspaces)
+	if len(workspaces) != 2 || workspaces[1].Name != "Night team" || workspaces[1].Role != models.RoleOwner {
+		t.Errorf("Unexpected workspaces: %+v", workspaces)
+	}
+}
@@ -1,17 +1,34 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
-	"database/sql"
+	"crypto/sha256"
 	"encoding/hex"
-	"shiftplanner/backend/internal/database"
+	"log"
 	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/storage"
+	"strings"
 	"time"
 )
 
 const (
-	TokenLength   = 32
+	TokenLength = 32
+	// SessionExpiry how long a session lasts without being used; every use extends it
 	SessionExpiry = 7 * 24 * time.Hour // 7 days
+	// SessionMaxLifetime how long a session lasts at most, however often it is used
+	SessionMaxLifetime = 30 * 24 * time.Hour // 30 days
+	// sessionTouchInterval how often the use of a session is recorded, to save a write on every request
+	sessionTouchInterval = time.Minute
+	// maxUserAgentLength longest user agent stored with a session
+	maxUserAgentLength = 256
+
+	// APIKeyPrefix starts every API key, telling keys apart from session tokens
+	APIKeyPrefix = "sp_"
+	// apiKeyVisibleLength characters at the start of a key that are stored in the clear
+	apiKeyVisibleLength = len(APIKeyPrefix) + 8
+	// feedTokenVisibleLength characters at the start of a feed token that are stored in the clear
+	feedTokenVisibleLength = 8
 )
 
 // GenerateToken generates a random token
@@ -23,79 +40,156 @@ func GenerateToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-// CreateSession creates a new session
-func CreateSession(userID int) (*models.Session, error) {
+// HashToken hashes a token for storage, so a leaked database doesn't leak usable tokens
+// Tokens are random, so a plain SHA-256 is enough.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateSession creates a new session for a client
+// The returned session carries the token; only its hash is stored.
+func CreateSession(sessions storage.SessionStore, userID int, userAgent, ipAddress string) (*models.Session, error) {
 	token, err := GenerateToken()
 	if err != nil {
 		return nil, err
 	}
 
-	expiresAt := time.Now().Add(SessionExpiry)
-
-	result, err := database.DB.Exec(
-		"INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, ?)",
-		userID, token, expiresAt,
-	)
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	session, err := sessions.CreateSession(userID, HashToken(token), userAgent, ipAddress, time.Now().Add(SessionExpiry))
 	if err != nil {
 		return nil, err
 	}
+	session.Token = token
+	return session, nil
+}
 
-	id, err := result.LastInsertId()
+// ValidateSession validates the token and returns its session
+// Using a session extends it by SessionExpiry, up to SessionMaxLifetime after it was created.
+func ValidateSession(sessions storage.SessionStore, token string) (*models.Session, error) {
+	tokenHash := HashToken(token)
+	session, err := sessions.GetSession(tokenHash)
 	if err != nil {
+		if err == storage.ErrSessionNotFound {
+			return nil, ErrInvalidToken
+		}
 		return nil, err
 	}
 
-	return &models.Session{
-		ID:        int(id),
-		UserID:    userID,
-		Token:     token,
-		ExpiresAt: expiresAt,
-		CreatedAt: time.Now(),
-	}, nil
+	now := time.Now()
+	maxExpiresAt := session.CreatedAt.Add(SessionMaxLifetime)
+	if now.After(session.ExpiresAt) || now.After(maxExpiresAt) {
+		// Delete expired session
+		sessions.DeleteSession(tokenHash)
+		return nil, ErrExpiredToken
+	}
+
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		expiresAt := now.Add(SessionExpiry)
+		if expiresAt.After(maxExpiresAt) {
+			expiresAt = maxExpiresAt
+		}
+		if err := sessions.TouchSession(session.ID, now, expiresAt); err != nil {
+			return nil, err
+		}
+		session.LastSeenAt, session.ExpiresAt = now, expiresAt
+	}
+
+	return session, nil
 }
 
 // ValidateToken validates the token and returns the user ID
-func ValidateToken(token string) (int, error) {
-	var userID int
-	var expiresAt time.Time
+func ValidateToken(sessions storage.SessionStore, token string) (int, error) {
+	session, err := ValidateSession(sessions, token)
+	if err != nil {
+		return 0, err
+	}
+	return session.UserID, nil
+}
+
+// IsAPIKey reports whether a token from the Authorization header is an API key
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// CreateAPIKey creates an API key; the returned key is the only time it is readable
+func CreateAPIKey(apiKeys storage.APIKeyStore, userID, workspaceID int, name, scope string) (*models.APIKey, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
 
-	err := database.DB.QueryRow(
-		"SELECT user_id, expires_at FROM sessions WHERE token = ?",
-		token,
-	).Scan(&userID, &expiresAt)
+	key := APIKeyPrefix + token
+	apiKey, err := apiKeys.CreateAPIKey(userID, workspaceID, name, key[:apiKeyVisibleLength], scope, HashToken(key))
+	if err != nil {
+		return nil, err
+	}
+	apiKey.Key = key
+	return apiKey, nil
+}
 
+// ValidateAPIKey resolves an API key and records its use, at most once per sessionTouchInterval
+func ValidateAPIKey(apiKeys storage.APIKeyStore, key string) (*models.APIKey, error) {
+	apiKey, err := apiKeys.UseAPIKey(HashToken(key), time.Now(), sessionTouchInterval)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return 0, ErrInvalidToken
+		if err == storage.ErrAPIKeyNotFound {
+			return nil, ErrInvalidToken
 		}
-		return 0, err
+		return nil, err
 	}
+	return apiKey, nil
+}
 
-	if time.Now().After(expiresAt) {
-		// Delete expired session
-		database.DB.Exec("DELETE FROM sessions WHERE token = ?", token)
-		return 0, ErrExpiredToken
+// CreateFeedToken creates a calendar feed token; the returned token is the only time it is readable
+func CreateFeedToken(feedTokens storage.FeedTokenStore, workspaceID, userID int, name string) (*models.FeedToken, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return nil, err
 	}
 
-	return userID, nil
+	feedToken, err := feedTokens.CreateFeedToken(workspaceID, userID, name, token[:feedTokenVisibleLength], HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	feedToken.Token = token
+	return feedToken, nil
 }
 
 // DeleteSession deletes a session
-func DeleteSession(token string) error {
-	_, err := database.DB.Exec("DELETE FROM sessions WHERE token = ?", token)
-	return err
+func DeleteSession(sessions storage.SessionStore, token string) error {
+	return sessions.DeleteSession(HashToken(token))
 }
 
 // CleanExpiredSessions cleans expired sessions
-func CleanExpiredSessions() error {
-	_, err := database.DB.Exec("DELETE FROM sessions WHERE expires_at < ?", time.Now())
-	return err
+func CleanExpiredSessions(sessions storage.SessionStore) error {
+	return sessions.DeleteExpiredSessions(time.Now())
+}
+
+// RunSessionCleanup cleans expired sessions every interval until ctx is done
+// The server runs it in a background goroutine.
+func RunSessionCleanup(ctx context.Context, sessions storage.SessionStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := CleanExpiredSessions(sessions); err != nil {
+				log.Printf("Warning: failed to clean expired sessions: %v", err)
+			}
+		}
+	}
 }
 
 // Error definitions
 var (
 	ErrInvalidToken = &AuthError{Message: "Invalid token"}
 	ErrExpiredToken = &AuthError{Message: "Session expired"}
+	ErrInvalidCode  = &AuthError{Message: "Invalid code"}
 )
 
 // AuthError authentication error
@@ -1,65 +1,22 @@
 package auth
 
 import (
-	"database/sql"
-	"os"
-	"path/filepath"
-	"shiftplanner/backend/internal/database"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/storage"
+	"strings"
 	"testing"
 	"time"
-
-	_ "modernc.org/sqlite"
 )
 
-func setupAuthTestDB(t *testing.T) int {
-	testDBPath := filepath.Join(os.TempDir(), "test_auth.db")
-	os.Remove(testDBPath)
-
-	var err error
-	database.DB, err = sql.Open("sqlite", testDBPath)
-	if err != nil {
-		t.Fatalf("Failed to create test database: %v", err)
-	}
-
-	// Create schema
-	createUsersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		username TEXT NOT NULL UNIQUE,
-		password_hash TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	createSessionsTable := `
-	CREATE TABLE IF NOT EXISTS sessions (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		token TEXT NOT NULL UNIQUE,
-		expires_at DATETIME NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-	);`
-
-	if _, err := database.DB.Exec(createUsersTable); err != nil {
-		t.Fatalf("Failed to create users table: %v", err)
-	}
-	if _, err := database.DB.Exec(createSessionsTable); err != nil {
-		t.Fatalf("Failed to create sessions table: %v", err)
-	}
+func setupAuthTestStore(t *testing.T) (*storage.MemoryStore, int) {
+	store := storage.NewMemoryStore()
 
 	// Create test user
-	result, _ := database.DB.Exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", "testuser", "testhash")
-	userID, _ := result.LastInsertId()
-	return int(userID)
-}
-
-func teardownAuthTestDB(t *testing.T) {
-	if database.DB != nil {
-		database.DB.Close()
-		database.DB = nil
+	user, err := store.CreateUser("testuser", "testpassword")
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
 	}
-	testDBPath := filepath.Join(os.TempDir(), "test_auth.db")
-	os.Remove(testDBPath)
+	return store, user.ID
 }
 
 func TestGenerateToken(t *testing.T) {
@@ -79,11 +36,23 @@ func TestGenerateToken(t *testing.T) {
 	}
 }
 
+func TestHashToken(t *testing.T) {
+	hash := HashToken("token")
+	if hash == "token" || len(hash) != 64 {
+		t.Errorf("Unexpected hash: %q", hash)
+	}
+	if HashToken("token") != hash {
+		t.Error("Hashing should be deterministic")
+	}
+	if HashToken("other") == hash {
+		t.Error("Different tokens should have different hashes")
+	}
+}
+
 func TestCreateSession(t *testing.T) {
-	userID := setupAuthTestDB(t)
-	defer teardownAuthTestDB(t)
+	store, userID := setupAuthTestStore(t)
 
-	session, err := CreateSession(userID)
+	session, err := CreateSession(store, userID, "test-agent", "127.0.0.1")
 	if err != nil {
 		t.Fatalf("Failed to create session: %v", err)
 	}
@@ -99,17 +68,79 @@ func TestCreateSession(t *testing.T) {
 	if session.ExpiresAt.Before(time.Now()) {
 		t.Error("ExpiresAt should be in the future")
 	}
+
+	// Only the hash of the token is stored
+	if _, err := store.GetSession(session.Token); err != storage.ErrSessionNotFound {
+		t.Errorf("Session should not be stored under its token, got %v", err)
+	}
+	stored, err := store.GetSession(HashToken(session.Token))
+	if err != nil {
+		t.Fatalf("Failed to get session by hash: %v", err)
+	}
+	if stored.UserAgent != "test-agent" || stored.IPAddress != "127.0.0.1" {
+		t.Errorf("Client mismatch: %+v", stored)
+	}
+}
+
+func TestValidateSession_SlidingExpiry(t *testing.T) {
+	store, userID := setupAuthTestStore(t)
+
+	// Session about to expire that wasn't used for a while
+	token, _ := GenerateToken()
+	session, _ := store.CreateSession(userID, HashToken(token), "", "", time.Now().Add(time.Minute))
+	store.TouchSession(session.ID, time.Now().Add(-time.Hour), session.ExpiresAt)
+
+	validated, err := ValidateSession(store, token)
+	if err != nil {
+		t.Fatalf("Failed to validate session: %v", err)
+	}
+	if validated.ExpiresAt.Before(time.Now().Add(SessionExpiry - time.Minute)) {
+		t.Errorf("Expiry should be extended, got %v", validated.ExpiresAt)
+	}
+	stored, _ := store.GetSession(HashToken(token))
+	if !stored.ExpiresAt.Equal(validated.ExpiresAt) || time.Since(stored.LastSeenAt) > time.Minute {
+		t.Errorf("Extension should be stored: %+v", stored)
+	}
+}
+
+func TestValidateSession_MaxLifetime(t *testing.T) {
+	store, userID := setupAuthTestStore(t)
+
+	// Kept in use, but created longer ago than the maximum lifetime
+	token, _ := GenerateToken()
+	store.CreateSession(userID, HashToken(token), "", "", time.Now().Add(time.Hour))
+	sessions := backdatedSessions{store, SessionMaxLifetime + time.Hour}
+
+	if _, err := ValidateSession(sessions, token); err != ErrExpiredToken {
+		t.Errorf("Expected ErrExpiredToken past the maximum lifetime, got %v", err)
+	}
+	if _, err := store.GetSession(HashToken(token)); err != storage.ErrSessionNotFound {
+		t.Errorf("Session past its maximum lifetime should be deleted, got %v", err)
+	}
+}
+
+// backdatedSessions reports sessions as created age earlier than they were
+type backdatedSessions struct {
+	storage.SessionStore
+	age time.Duration
+}
+
+func (s backdatedSessions) GetSession(tokenHash string) (*models.Session, error) {
+	session, err := s.SessionStore.GetSession(tokenHash)
+	if err == nil {
+		session.CreatedAt = session.CreatedAt.Add(-s.age)
+	}
+	return session, err
 }
 
 func TestValidateToken(t *testing.T) {
-	userID := setupAuthTestDB(t)
-	defer teardownAuthTestDB(t)
+	store, userID := setupAuthTestStore(t)
 
 	// Create valid session
-	session, _ := CreateSession(userID)
+	session, _ := CreateSession(store, userID, "test-agent", "127.0.0.1")
 
 	// Test valid token
-	validatedUserID, err := ValidateToken(session.Token)
+	validatedUserID, err := ValidateToken(store, session.Token)
 	if err != nil {
 		t.Fatalf("Failed to validate token: %v", err)
 	}
@@ -119,73 +150,89 @@ func TestValidateToken(t *testing.T) {
 	}
 
 	// Test invalid token
-	_, err = ValidateToken("invalid_token")
+	_, err = ValidateToken(store, "invalid_token")
 	if err != ErrInvalidToken {
 		t.Errorf("Expected error for invalid token: got %v", err)
 	}
 }
 
 func TestValidateToken_Expired(t *testing.T) {
-	userID := setupAuthTestDB(t)
-	defer teardownAuthTestDB(t)
+	store, userID := setupAuthTestStore(t)
 
 	// Create expired session
 	token, _ := GenerateToken()
 	expiredTime := time.Now().Add(-1 * time.Hour)
-	database.DB.Exec(
-		"INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, ?)",
-		userID, token, expiredTime,
-	)
+	store.CreateSession(userID, HashToken(token), "", "", expiredTime)
 
-	_, err := ValidateToken(token)
+	_, err := ValidateToken(store, token)
 	if err != ErrExpiredToken {
 		t.Errorf("Expected error for expired token: got %v", err)
 	}
 }
 
 func TestDeleteSession(t *testing.T) {
-	userID := setupAuthTestDB(t)
-	defer teardownAuthTestDB(t)
+	store, userID := setupAuthTestStore(t)
 
-	session, _ := CreateSession(userID)
+	session, _ := CreateSession(store, userID, "test-agent", "127.0.0.1")
 
 	// Delete session
-	err := DeleteSession(session.Token)
+	err := DeleteSession(store, session.Token)
 	if err != nil {
 		t.Fatalf("Failed to delete session: %v", err)
 	}
 
 	// Session should now be invalid
-	_, err = ValidateToken(session.Token)
+	_, err = ValidateToken(store, session.Token)
 	if err != ErrInvalidToken {
 		t.Error("Deleted session should still be valid")
 	}
 }
 
 func TestCleanExpiredSessions(t *testing.T) {
-	userID := setupAuthTestDB(t)
-	defer teardownAuthTestDB(t)
+	store, userID := setupAuthTestStore(t)
 
 	// Valid session
-	CreateSession(userID)
+	CreateSession(store, userID, "test-agent", "127.0.0.1")
 
 	// Expired session
 	token, _ := GenerateToken()
 	expiredTime := time.Now().Add(-1 * time.Hour)
-	database.DB.Exec(
-		"INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, ?)",
-		userID, token, expiredTime,
-	)
+	store.CreateSession(userID, HashToken(token), "", "", expiredTime)
 
 	// Clean
-	err := CleanExpiredSessions()
+	err := CleanExpiredSessions(store)
 	if err != nil {
 		t.Fatalf("Failed to clean expired sessions: %v", err)
 	}
 
 	// Expired session should be deleted
-	_, err = ValidateToken(token)
+	_, err = ValidateToken(store, token)
 	if err != ErrInvalidToken {
 		t.Error("Expired session was not cleaned")
 	}
 }
+
+func TestAPIKey(t *testing.T) {
+	store, userID := setupAuthTestStore(t)
+	workspaces, _ := store.GetUserWorkspaces(userID)
+
+	apiKey, err := CreateAPIKey(store, userID, workspaces[0].ID, "CI", "read-only")
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	if !IsAPIKey(apiKey.Key) || !strings.HasPrefix(apiKey.Key, apiKey.Prefix) || len(apiKey.Prefix) >= len(apiKey.Key) {
+		t.Errorf("Unexpected key %q with prefix %q", apiKey.Key, apiKey.Prefix)
+	}
+
+	validated, err := ValidateAPIKey(store, apiKey.Key)
+	if err != nil {
+		t.Fatalf("Failed to validate API key: %v", err)
+	}
+	if validated.ID != apiKey.ID || validated.LastUsedAt == nil {
+		t.Errorf("Unexpected validated key: %+v", validated)
+	}
+
+	if _, err := ValidateAPIKey(store, APIKeyPrefix+"invalid"); err != ErrInvalidToken {
+		t.Errorf("Expected ErrInvalidToken, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxPasswordLength longest password accepted, so hashing stays cheap
+const maxPasswordLength = 256
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var (
+	commonPasswords     map[string]bool
+	commonPasswordsOnce sync.Once
+)
+
+// PasswordPolicy decides which passwords users may choose
+type PasswordPolicy struct {
+	// MinLength shortest password, in characters
+	MinLength int
+	// RejectCommon rejects passwords on the bundled list of common and breached passwords
+	RejectCommon bool
+}
+
+// DefaultPasswordPolicy is used unless configured otherwise
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:    8,
+	RejectCommon: true,
+}
+
+// PasswordPolicyFromEnv reads the password policy from PASSWORD_MIN_LENGTH and
+// PASSWORD_REJECT_COMMON, keeping the defaults for unset variables
+func PasswordPolicyFromEnv() (PasswordPolicy, error) {
+	policy := DefaultPasswordPolicy
+	if value := os.Getenv("PASSWORD_MIN_LENGTH"); value != "" {
+		minLength, err := strconv.Atoi(value)
+		if err != nil || minLength < 1 || minLength > maxPasswordLength {
+			return policy, fmt.Errorf("PASSWORD_MIN_LENGTH must be a number from 1 to %d", maxPasswordLength)
+		}
+		policy.MinLength = minLength
+	}
+	if value := os.Getenv("PASSWORD_REJECT_COMMON"); value != "" {
+		reject, err := strconv.ParseBool(value)
+		if err != nil {
+			return policy, fmt.Errorf("PASSWORD_REJECT_COMMON must be true or false")
+		}
+		policy.RejectCommon = reject
+	}
+	return policy, nil
+}
+
+// Check checks a password a user wants to use
+// It returns the message to show, or "" when the password is acceptable.
+func (p PasswordPolicy) Check(username, password string) string {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return fmt.Sprintf("Password must be at least %d characters", p.MinLength)
+	}
+	if length > maxPasswordLength {
+		return fmt.Sprintf("Password must be at most %d characters", maxPasswordLength)
+	}
+	if strings.EqualFold(password, username) {
+		return "Password must not be the username"
+	}
+	if p.RejectCommon && IsCommonPassword(password) {
+		return "Password is too common, choose another one"
+	}
+	return ""
+}
+
+// IsCommonPassword reports whether password is on the bundled list of common passwords
+func IsCommonPassword(password string) bool {
+	commonPasswordsOnce.Do(func() {
+		commonPasswords = make(map[string]bool)
+		for _, line := range strings.Split(commonPasswordList, "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				commonPasswords[line] = true
+			}
+		}
+	})
+	return commonPasswords[strings.ToLower(password)]
+}
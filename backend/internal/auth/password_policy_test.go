@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	tests := []struct {
+		password string
+		ok       bool
+	}{
+		{"correct horse battery", true},
+		{"short", false},
+		{"password123", false},
+		{"PassWord123", false},
+		{"alice-smith", false},
+		{strings.Repeat("x", maxPasswordLength+1), false},
+		// Length counts characters, not bytes
+		{"ääääääää", true},
+	}
+	for _, tt := range tests {
+		msg := DefaultPasswordPolicy.Check("Alice-Smith", tt.password)
+		if (msg == "") != tt.ok {
+			t.Errorf("Check(%q) = %q, want ok=%v", tt.password, msg, tt.ok)
+		}
+	}
+
+	relaxed := PasswordPolicy{MinLength: 4}
+	if msg := relaxed.Check("alice", "password"); msg != "" {
+		t.Errorf("Expected common passwords to be allowed when not rejected, got %q", msg)
+	}
+}
+
+func TestPasswordPolicyFromEnv(t *testing.T) {
+	t.Setenv("PASSWORD_MIN_LENGTH", "12")
+	t.Setenv("PASSWORD_REJECT_COMMON", "false")
+	policy, err := PasswordPolicyFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to read policy: %v", err)
+	}
+	if policy.MinLength != 12 || policy.RejectCommon {
+		t.Errorf("Policy mismatch: %+v", policy)
+	}
+
+	t.Setenv("PASSWORD_MIN_LENGTH", "zero")
+	if _, err := PasswordPolicyFromEnv(); err == nil {
+		t.Error("Expected an error for an invalid length")
+	}
+}
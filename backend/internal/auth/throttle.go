@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// usernameFreeAttempts failed logins for a username before it is locked out
+	usernameFreeAttempts = 5
+	// ipFreeAttempts failed logins from an IP address before it is locked out;
+	// higher than per username since offices share an address
+	ipFreeAttempts = 20
+	// loginLockout first lockout once the free attempts are used up; every further failure doubles it
+	loginLockout = 30 * time.Second
+	// maxLoginLockout longest lockout
+	maxLoginLockout = 15 * time.Minute
+	// loginFailuresForgotten how long after its last failure a username or IP address starts over
+	loginFailuresForgotten = time.Hour
+)
+
+// Throttle counts failed attempts per key and locks a key out once it has used
+// up its free attempts, for a lockout that doubles with every further failure
+// It is safe for concurrent use; the counts only live in this process.
+type Throttle struct {
+	freeAttempts int
+	lockout      time.Duration
+	maxLockout   time.Duration
+	forget       time.Duration
+	now          func() time.Time
+
+	mu        sync.Mutex
+	failures  map[string]*failures
+	lastPrune time.Time
+}
+
+type failures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// NewThrottle creates a throttle that forgets a key's failures after forget without a new one
+func NewThrottle(freeAttempts int, lockout, maxLockout, forget time.Duration) *Throttle {
+	return &Throttle{
+		freeAttempts: freeAttempts,
+		lockout:      lockout,
+		maxLockout:   maxLockout,
+		forget:       forget,
+		now:          time.Now,
+		failures:     make(map[string]*failures),
+	}
+}
+
+// Wait returns how long key is still locked out, or 0 when it may try again
+func (t *Throttle) Wait(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f, ok := t.failures[key]
+	if !ok {
+		return 0
+	}
+	if wait := f.lockedUntil.Sub(t.now()); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Fail records a failed attempt of key
+func (t *Throttle) Fail(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.prune(now)
+
+	f, ok := t.failures[key]
+	if !ok || now.Sub(f.last) > t.forget {
+		f = &failures{}
+		t.failures[key] = f
+	}
+	f.count++
+	f.last = now
+
+	if over := f.count - t.freeAttempts; over > 0 {
+		lockout := t.maxLockout
+		// Stop doubling before the shift overflows
+		if over < 32 {
+			lockout = min(t.lockout<<(over-1), t.maxLockout)
+		}
+		f.lockedUntil = now.Add(lockout)
+	}
+}
+
+// Reset forgets the failures of key, after a successful attempt
+func (t *Throttle) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, key)
+}
+
+// prune drops forgotten keys, at most once per forget period so failing stays cheap
+func (t *Throttle) prune(now time.Time) {
+	if now.Sub(t.lastPrune) < t.forget {
+		return
+	}
+	t.lastPrune = now
+	for key, f := range t.failures {
+		if now.Sub(f.last) > t.forget && !now.Before(f.lockedUntil) {
+			delete(t.failures, key)
+		}
+	}
+}
+
+// LoginThrottle slows down password guessing, per username and per client IP address
+type LoginThrottle struct {
+	usernames *Throttle
+	ips       *Throttle
+}
+
+// NewLoginThrottle creates a login throttle with the default limits
+func NewLoginThrottle() *LoginThrottle {
+	return &LoginThrottle{
+		usernames: NewThrottle(usernameFreeAttempts, loginLockout, maxLoginLockout, loginFailuresForgotten),
+		ips:       NewThrottle(ipFreeAttempts, loginLockout, maxLoginLockout, loginFailuresForgotten),
+	}
+}
+
+// Wait returns how long logins for username or from ip are still locked out, or 0
+func (t *LoginThrottle) Wait(username, ip string) time.Duration {
+	return max(t.usernames.Wait(usernameKey(username)), t.ips.Wait(ip))
+}
+
+// Fail records a failed login
+func (t *LoginThrottle) Fail(username, ip string) {
+	t.usernames.Fail(usernameKey(username))
+	t.ips.Fail(ip)
+}
+
+// Succeed forgets the failures of username after a successful login
+// Failures of the IP address are kept, so one known password doesn't reset
+// the guessing of others from the same address.
+func (t *LoginThrottle) Succeed(username string) {
+	t.usernames.Reset(usernameKey(username))
+}
+
+func usernameKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottle_Backoff(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttle := NewThrottle(3, time.Second, 5*time.Second, time.Hour)
+	throttle.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		throttle.Fail("key")
+		if wait := throttle.Wait("key"); wait != 0 {
+			t.Fatalf("Expected no lockout after %d failures, got %v", i+1, wait)
+		}
+	}
+
+	// Every failure after the free ones doubles the lockout, up to the maximum
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		throttle.Fail("key")
+		if wait := throttle.Wait("key"); wait != want {
+			t.Errorf("Expected a lockout of %v, got %v", want, wait)
+		}
+	}
+	if wait := throttle.Wait("other"); wait != 0 {
+		t.Errorf("Expected other keys to be unaffected, got %v", wait)
+	}
+
+	now = now.Add(5 * time.Second)
+	if wait := throttle.Wait("key"); wait != 0 {
+		t.Errorf("Expected the lockout to be over, got %v", wait)
+	}
+
+	// Failures are forgotten after a quiet period
+	now = now.Add(2 * time.Hour)
+	throttle.Fail("key")
+	if wait := throttle.Wait("key"); wait != 0 {
+		t.Errorf("Expected the count to start over, got %v", wait)
+	}
+}
+
+func TestThrottle_Reset(t *testing.T) {
+	throttle := NewThrottle(1, time.Minute, time.Hour, time.Hour)
+	throttle.Fail("key")
+	throttle.Fail("key")
+	if throttle.Wait("key") == 0 {
+		t.Fatal("Expected a lockout")
+	}
+
+	throttle.Reset("key")
+	if wait := throttle.Wait("key"); wait != 0 {
+		t.Errorf("Expected no lockout after a reset, got %v", wait)
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	throttle := NewLoginThrottle()
+	for i := 0; i <= usernameFreeAttempts; i++ {
+		throttle.Fail("Alice", "192.0.2.1")
+	}
+	if throttle.Wait("alice", "192.0.2.2") == 0 {
+		t.Error("Expected the username to be locked out from any address")
+	}
+	if wait := throttle.Wait("bob", "192.0.2.1"); wait != 0 {
+		t.Errorf("Expected other usernames from the address to be allowed, got %v", wait)
+	}
+
+	// Guessing across usernames locks out the address
+	for i := 0; i <= ipFreeAttempts; i++ {
+		throttle.Fail("user"+string(rune('a'+i)), "192.0.2.3")
+	}
+	if throttle.Wait("bob", "192.0.2.3") == 0 {
+		t.Error("Expected the address to be locked out")
+	}
+
+	throttle.Succeed("ALICE")
+	if wait := throttle.Wait("alice", "192.0.2.2"); wait != 0 {
+		t.Errorf("Expected a successful login to clear the username, got %v", wait)
+	}
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors, base32 encoded
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		code, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Failed to compute code: %v", err)
+		}
+		if code != tt.code {
+			t.Errorf("Code at %d: got %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := TOTPStep(now)
+	previous, _ := TOTPCode(rfcSecret, step-1)
+	old, _ := TOTPCode(rfcSecret, step-2)
+
+	if matched, ok := MatchTOTP(rfcSecret, "005924", now); !ok || matched != step {
+		t.Errorf("Current code should match step %d, got %d %v", step, matched, ok)
+	}
+	if matched, ok := MatchTOTP(rfcSecret, previous, now); !ok || matched != step-1 {
+		t.Errorf("Previous code should match for clock drift, got %d %v", matched, ok)
+	}
+	if _, ok := MatchTOTP(rfcSecret, old, now); ok {
+		t.Error("Codes older than the allowed drift should not match")
+	}
+	if _, ok := MatchTOTP(rfcSecret, "00592", now); ok {
+		t.Error("Short codes should not match")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("Failed to generate secret: %v", err)
+	}
+	if _, err := TOTPCode(secret, 1); err != nil {
+		t.Errorf("Generated secret should decode: %v", err)
+	}
+
+	uri, err := url.Parse(TOTPURI(secret, "alice"))
+	if err != nil {
+		t.Fatalf("Invalid URI: %v", err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/"+TOTPIssuer+":alice" {
+		t.Errorf("Unexpected URI: %s", uri)
+	}
+	if uri.Query().Get("secret") != secret || uri.Query().Get("issuer") != TOTPIssuer {
+		t.Errorf("Unexpected URI parameters: %s", uri.RawQuery)
+	}
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"shiftplanner/backend/internal/storage"
+	"strings"
+	"time"
+)
+
+const (
+	// RecoveryCodeCount recovery codes handed out when two-factor is enabled
+	RecoveryCodeCount = 10
+	// LoginChallengeExpiry how long the pre-auth token of a login can be exchanged for a session
+	LoginChallengeExpiry = 5 * time.Minute
+	// maxLoginChallengeFailures wrong codes after which a pre-auth token is revoked
+	maxLoginChallengeFailures = 5
+)
+
+// StartTwoFactorEnrollment gives a user a new TOTP secret to add to their authenticator app
+// Two-factor stays off until a code of the secret is confirmed with EnableTwoFactor.
+func StartTwoFactorEnrollment(twoFactors storage.TwoFactorStore, userID int) (string, error) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := twoFactors.SetTOTPSecret(userID, secret); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// EnableTwoFactor turns two-factor on once the user proves their app has the secret
+// It returns the recovery codes; only their hashes are stored.
+func EnableTwoFactor(twoFactors storage.TwoFactorStore, userID int, code string) ([]string, error) {
+	twoFactor, err := twoFactors.GetTwoFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := useTOTP(twoFactors, userID, twoFactor.Secret, code, time.Now()); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := twoFactors.EnableTwoFactor(userID, hashes, time.Now()); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifySecondFactor checks a TOTP code or uses up a recovery code of a user with two-factor enabled
+func VerifySecondFactor(twoFactors storage.TwoFactorStore, userID int, code string) error {
+	twoFactor, err := twoFactors.GetTwoFactor(userID)
+	if err == storage.ErrTwoFactorNotFound || (err == nil && !twoFactor.Enabled()) {
+		return ErrInvalidCode
+	}
+	if err != nil {
+		return err
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totpDigits {
+		return useTOTP(twoFactors, userID, twoFactor.Secret, code, time.Now())
+	}
+	if err := twoFactors.UseRecoveryCode(userID, HashRecoveryCode(code)); err != nil {
+		if err == storage.ErrRecoveryCodeNotFound {
+			return ErrInvalidCode
+		}
+		return err
+	}
+	return nil
+}
+
+// useTOTP checks a TOTP code and records its step, so it can't be used twice
+func useTOTP(twoFactors storage.TwoFactorStore, userID int, secret, code string, now time.Time) error {
+	step, ok := MatchTOTP(secret, strings.TrimSpace(code), now)
+	if !ok {
+		return ErrInvalidCode
+	}
+	if err := twoFactors.UseTOTPStep(userID, step); err != nil {
+		if err == storage.ErrTOTPCodeUsed {
+			return ErrInvalidCode
+		}
+		return err
+	}
+	return nil
+}
+
+// GenerateRecoveryCodes generates recovery codes and their hashes for storage
+func GenerateRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < RecoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(b)
+		code = code[:5] + "-" + code[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode hashes a recovery code, ignoring case, spaces and dashes
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return HashToken(code)
+}
+
+// StartTwoFactorLogin creates the pre-auth token of a login waiting for a second factor
+func StartTwoFactorLogin(twoFactors storage.TwoFactorStore, userID int) (string, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	if err := twoFactors.CreateLoginChallenge(userID, HashToken(token), time.Now().Add(LoginChallengeExpiry)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// LoginChallengeUser returns the user of a login waiting for a second factor,
+// so the login can be throttled before the code is checked
+func LoginChallengeUser(twoFactors storage.TwoFactorStore, token string) (int, error) {
+	challenge, err := twoFactors.GetLoginChallenge(HashToken(token), time.Now())
+	if err != nil {
+		if err == storage.ErrLoginChallengeNotFound {
+			return 0, ErrInvalidToken
+		}
+		return 0, err
+	}
+	return challenge.UserID, nil
+}
+
+// FinishTwoFactorLogin checks the second factor of a login and returns its user
+// The pre-auth token can be used once, and is revoked after too many wrong codes.
+func FinishTwoFactorLogin(twoFactors storage.TwoFactorStore, token, code string) (int, error) {
+	challenge, err := twoFactors.GetLoginChallenge(HashToken(token), time.Now())
+	if err != nil {
+		if err == storage.ErrLoginChallengeNotFound {
+			return 0, ErrInvalidToken
+		}
+		return 0, err
+	}
+
+	if err := VerifySecondFactor(twoFactors, challenge.UserID, code); err != nil {
+		if err != ErrInvalidCode {
+			return 0, err
+		}
+		failures, failErr := twoFactors.FailLoginChallenge(challenge.ID)
+		if failErr != nil {
+			return 0, failErr
+		}
+		if failures >= maxLoginChallengeFailures {
+			if err := twoFactors.DeleteLoginChallenge(challenge.ID); err != nil {
+				return 0, err
+			}
+		}
+		return 0, ErrInvalidCode
+	}
+
+	if err := twoFactors.DeleteLoginChallenge(challenge.ID); err != nil {
+		return 0, err
+	}
+	return challenge.UserID, nil
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"shiftplanner/backend/internal/storage"
+	"testing"
+	"time"
+)
+
+// enableTestTwoFactor enrolls a user and returns the secret and recovery codes
+// The code of the current step is used up by the enrollment.
+func enableTestTwoFactor(t *testing.T, twoFactors storage.TwoFactorStore, userID int) (string, []string) {
+	t.Helper()
+	secret, err := StartTwoFactorEnrollment(twoFactors, userID)
+	if err != nil {
+		t.Fatalf("Failed to start enrollment: %v", err)
+	}
+	code, _ := TOTPCode(secret, TOTPStep(time.Now()))
+	codes, err := EnableTwoFactor(twoFactors, userID, code)
+	if err != nil {
+		t.Fatalf("Failed to enable two-factor: %v", err)
+	}
+	return secret, codes
+}
+
+func TestEnableTwoFactor(t *testing.T) {
+	store, userID := setupAuthTestStore(t)
+
+	secret, err := StartTwoFactorEnrollment(store, userID)
+	if err != nil {
+		t.Fatalf("Failed to start enrollment: %v", err)
+	}
+	if _, err := EnableTwoFactor(store, userID, "000000"); err != ErrInvalidCode {
+		t.Errorf("Expected ErrInvalidCode for a wrong code, got %v", err)
+	}
+	if err := VerifySecondFactor(store, userID, "000000"); err != ErrInvalidCode {
+		t.Errorf("Second factor should not verify before enrollment is finished, got %v", err)
+	}
+
+	code, _ := TOTPCode(secret, TOTPStep(time.Now()))
+	codes, err := EnableTwoFactor(store, userID, code)
+	if err != nil {
+		t.Fatalf("Failed to enable two-factor: %v", err)
+	}
+	if len(codes) != RecoveryCodeCount {
+		t.Errorf("Expected %d recovery codes, got %d", RecoveryCodeCount, len(codes))
+	}
+	twoFactor, _ := store.GetTwoFactor(userID)
+	if !twoFactor.Enabled() || twoFactor.RecoveryCodes != RecoveryCodeCount {
+		t.Errorf("Two-factor should be enabled with recovery codes: %+v", twoFactor)
+	}
+
+	// The code used for enrollment can't be replayed
+	if err := VerifySecondFactor(store, userID, code); err != ErrInvalidCode {
+		t.Errorf("Expected ErrInvalidCode for a used code, got %v", err)
+	}
+	next, _ := TOTPCode(secret, TOTPStep(time.Now())+1)
+	if err := VerifySecondFactor(store, userID, next); err != nil {
+		t.Errorf("Failed to verify the next code: %v", err)
+	}
+}
+
+func TestVerifySecondFactor_RecoveryCode(t *testing.T) {
+	store, userID := setupAuthTestStore(t)
+	_, codes := enableTestTwoFactor(t, store, userID)
+
+	// Recovery codes ignore case and dashes, and work once
+	code := codes[0]
+	if err := VerifySecondFactor(store, userID, " "+code[:5]+code[6:]+" "); err != nil {
+		t.Fatalf("Failed to verify recovery code: %v", err)
+	}
+	if err := VerifySecondFactor(store, userID, code); err != ErrInvalidCode {
+		t.Errorf("Expected ErrInvalidCode for a used recovery code, got %v", err)
+	}
+	if twoFactor, _ := store.GetTwoFactor(userID); twoFactor.RecoveryCodes != RecoveryCodeCount-1 {
+		t.Errorf("Expected %d recovery codes left, got %d", RecoveryCodeCount-1, twoFactor.RecoveryCodes)
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	store, userID := setupAuthTestStore(t)
+	_, codes := enableTestTwoFactor(t, store, userID)
+
+	token, err := StartTwoFactorLogin(store, userID)
+	if err != nil {
+		t.Fatalf("Failed to start login: %v", err)
+	}
+	if _, err := FinishTwoFactorLogin(store, token, "wrong"); err != ErrInvalidCode {
+		t.Errorf("Expected ErrInvalidCode, got %v", err)
+	}
+	loggedIn, err := FinishTwoFactorLogin(store, token, codes[0])
+	if err != nil || loggedIn != userID {
+		t.Fatalf("Expected login of user %d, got %d, %v", userID, loggedIn, err)
+	}
+	if _, err := FinishTwoFactorLogin(store, token, codes[1]); err != ErrInvalidToken {
+		t.Errorf("Pre-auth tokens should work once, got %v", err)
+	}
+}
+
+func TestTwoFactorLogin_TooManyFailures(t *testing.T) {
+	store, userID := setupAuthTestStore(t)
+	_, codes := enableTestTwoFactor(t, store, userID)
+
+	token, _ := StartTwoFactorLogin(store, userID)
+	for i := 0; i < maxLoginChallengeFailures; i++ {
+		if _, err := FinishTwoFactorLogin(store, token, "wrong"); err != ErrInvalidCode {
+			t.Fatalf("Expected ErrInvalidCode, got %v", err)
+		}
+	}
+	if _, err := FinishTwoFactorLogin(store, token, codes[0]); err != ErrInvalidToken {
+		t.Errorf("Pre-auth token should be revoked after %d failures, got %v", maxLoginChallengeFailures, err)
+	}
+}
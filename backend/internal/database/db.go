@@ -2,16 +2,41 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "modernc.org/sqlite"
 )
 
+// Database drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
 var DB *sql.DB
 
-// InitDB initializes the database and creates the schema
+// Driver is the driver of DB
+var Driver = DriverSQLite
+
+// InitDB opens the database and migrates it to the latest schema version
 func InitDB() error {
+	if err := OpenDB(); err != nil {
+		return err
+	}
+	return CreateSchema()
+}
+
+// OpenDB opens the database without touching its schema
+// A Postgres database is used when DATABASE_URL is set, SQLite in DATA_DIR otherwise
+func OpenDB() error {
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+		return openPostgres(databaseURL)
+	}
+
 	// Get data directory from environment variable or use default
 	dataDir := os.Getenv("DATA_DIR")
 	if dataDir == "" {
@@ -30,7 +55,7 @@ func InitDB() error {
 				break
 			}
 		}
-		
+
 		// If still not found, use current directory
 		if dataDir == "" {
 			dataDir = "."
@@ -54,123 +79,33 @@ func InitDB() error {
 		return err
 	}
 
+	Driver = DriverSQLite
+
 	// Connection pool settings
 	DB.SetMaxOpenConns(25)
 	DB.SetMaxIdleConns(5)
 
-	if err := DB.Ping(); err != nil {
-		return err
-	}
-
-	return createSchema()
+	return DB.Ping()
 }
 
-// createSchema creates database tables
-func createSchema() error {
-	// Users table
-	createUsersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		username TEXT NOT NULL UNIQUE,
-		password_hash TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	// Members table
-	createMembersTable := `
-	CREATE TABLE IF NOT EXISTS members (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		name TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		hidden_normal_shifts INTEGER DEFAULT 0,
-		hidden_long_shifts INTEGER DEFAULT 0,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-	);`
-
-	// Shifts table
-	createShiftsTable := `
-	CREATE TABLE IF NOT EXISTS shifts (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		member_id INTEGER NOT NULL,
-		start_date DATE NOT NULL,
-		end_date DATE NOT NULL,
-		is_long_shift BOOLEAN DEFAULT 0,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE
-	);`
-
-	// Sessions table
-	createSessionsTable := `
-	CREATE TABLE IF NOT EXISTS sessions (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		token TEXT NOT NULL UNIQUE,
-		expires_at DATETIME NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-	);`
-
-	// Leave days table
-	createLeaveDaysTable := `
-	CREATE TABLE IF NOT EXISTS leave_days (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		member_id INTEGER NOT NULL,
-		leave_date DATE NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE,
-		UNIQUE(user_id, member_id, leave_date)
-	);`
-
-	// Indexes
-	createIndexes := `
-	CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
-	CREATE INDEX IF NOT EXISTS idx_members_user_id ON members(user_id);
-	CREATE INDEX IF NOT EXISTS idx_shifts_user_id ON shifts(user_id);
-	CREATE INDEX IF NOT EXISTS idx_shifts_member_id ON shifts(member_id);
-	CREATE INDEX IF NOT EXISTS idx_shifts_start_date ON shifts(start_date);
-	CREATE INDEX IF NOT EXISTS idx_shifts_end_date ON shifts(end_date);
-	CREATE INDEX IF NOT EXISTS idx_sessions_token ON sessions(token);
-	CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
-	CREATE INDEX IF NOT EXISTS idx_leave_days_user_id ON leave_days(user_id);
-	CREATE INDEX IF NOT EXISTS idx_leave_days_member_id ON leave_days(member_id);
-	CREATE INDEX IF NOT EXISTS idx_leave_days_date ON leave_days(leave_date);
-	`
-
-	if _, err := DB.Exec(createUsersTable); err != nil {
-		return err
+// openPostgres connects to the Postgres database at databaseURL
+func openPostgres(databaseURL string) error {
+	if !strings.HasPrefix(databaseURL, "postgres://") && !strings.HasPrefix(databaseURL, "postgresql://") {
+		return fmt.Errorf("unsupported DATABASE_URL scheme, expected postgres://")
 	}
 
-	if _, err := DB.Exec(createMembersTable); err != nil {
-		return err
-	}
-
-	// Migration: Add hidden shift columns if they don't exist
-	// Try to add columns (will fail silently if they already exist)
-	DB.Exec("ALTER TABLE members ADD COLUMN hidden_normal_shifts INTEGER DEFAULT 0")
-	DB.Exec("ALTER TABLE members ADD COLUMN hidden_long_shifts INTEGER DEFAULT 0")
-
-	if _, err := DB.Exec(createShiftsTable); err != nil {
-		return err
-	}
-
-	if _, err := DB.Exec(createSessionsTable); err != nil {
-		return err
-	}
-
-	if _, err := DB.Exec(createLeaveDaysTable); err != nil {
+	var err error
+	DB, err = sql.Open("pgx", databaseURL)
+	if err != nil {
 		return err
 	}
+	Driver = DriverPostgres
 
-	if _, err := DB.Exec(createIndexes); err != nil {
-		return err
-	}
+	// Connection pool settings
+	DB.SetMaxOpenConns(25)
+	DB.SetMaxIdleConns(5)
 
-	return nil
+	return DB.Ping()
 }
 
 // CloseDB closes the database connection
@@ -0,0 +1,212 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer build
+var ErrSchemaTooNew = errors.New("database schema is newer than this build")
+
+// MigrationState is a migration and when it was applied to the database
+type MigrationState struct {
+	Version   int
+	Name      string
+	AppliedAt string // Empty while the migration is pending
+}
+
+// createMigrationsTable records the applied migrations, one row per version
+const createMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TEXT NOT NULL
+)`
+
+// LatestVersion returns the schema version this build migrates to
+func LatestVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// CreateSchema migrates the database to the latest schema version
+// Databases migrated by a newer build are refused with ErrSchemaTooNew
+func CreateSchema() error {
+	version, err := SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version > LatestVersion() {
+		return schemaTooNew(version)
+	}
+	return MigrateUp()
+}
+
+// SchemaVersion returns the version of the latest applied migration, 0 for an empty database
+func SchemaVersion() (int, error) {
+	if _, err := DB.Exec(createMigrationsTable); err != nil {
+		return 0, err
+	}
+
+	var version sql.NullInt64
+	if err := DB.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// MigrationStatus lists all migrations of this build and whether they are applied
+// Applied migrations unknown to this build are listed too
+func MigrationStatus() ([]MigrationState, error) {
+	if _, err := DB.Exec(createMigrationsTable); err != nil {
+		return nil, err
+	}
+
+	rows, err := DB.Query("SELECT version, name, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]MigrationState)
+	for rows.Next() {
+		var s MigrationState
+		if err := rows.Scan(&s.Version, &s.Name, &s.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[s.Version] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var states []MigrationState
+	for _, m := range migrations {
+		state := MigrationState{Version: m.version, Name: m.name}
+		if s, ok := applied[m.version]; ok {
+			state.AppliedAt = s.AppliedAt
+			delete(applied, m.version)
+		}
+		states = append(states, state)
+	}
+	for _, s := range applied {
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+
+	return states, nil
+}
+
+// MigrateUp applies all pending migrations in version order
+func MigrateUp() error {
+	version, err := SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		if err := applyMigration(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateDown reverts applied migrations newer than version, latest first
+func MigrateDown(version int) error {
+	if version < 0 {
+		return fmt.Errorf("invalid target version %d", version)
+	}
+
+	current, err := SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > LatestVersion() {
+		return schemaTooNew(current)
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.version > current || m.version <= version {
+			continue
+		}
+		if err := revertMigration(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyMigration runs a migration and records it in one transaction
+func applyMigration(m migration) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.up.forDriver()); err != nil {
+		return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+	}
+	if m.upgrade != nil {
+		if err := m.upgrade(tx); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+
+	insert := driverSQL{
+		sqlite:   "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+		postgres: "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+	}
+	appliedAt := time.Now().UTC().Format("2006-01-02 15:04:05")
+	if _, err := tx.Exec(insert.forDriver(), m.version, m.name, appliedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// revertMigration reverts a migration and removes its record in one transaction
+func revertMigration(m migration) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.down.forDriver()); err != nil {
+		return fmt.Errorf("reverting migration %d (%s): %w", m.version, m.name, err)
+	}
+
+	remove := driverSQL{
+		sqlite:   "DELETE FROM schema_migrations WHERE version = ?",
+		postgres: "DELETE FROM schema_migrations WHERE version = $1",
+	}
+	if _, err := tx.Exec(remove.forDriver(), m.version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// columnExists checks whether a table has a column
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	query := driverSQL{
+		sqlite:   "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?",
+		postgres: "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 AND column_name = $2",
+	}
+	var count int
+	err := tx.QueryRow(query.forDriver(), table, column).Scan(&count)
+	return count > 0, err
+}
+
+// schemaTooNew formats ErrSchemaTooNew with the versions involved
+func schemaTooNew(version int) error {
+	return fmt.Errorf("%w: database is at version %d, this build supports up to %d", ErrSchemaTooNew, version, LatestVersion())
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// errMigrateUsage is returned for unknown migrate subcommands
+var errMigrateUsage = errors.New("usage: migrate status | up | down <version>")
+
+// MigrateCommand runs the migrate subcommand on the open database
+// args are the arguments after "migrate": status, up, or down <version>
+func MigrateCommand(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	switch args[0] {
+	case "status":
+		if len(args) != 1 {
+			return errMigrateUsage
+		}
+		return printMigrationStatus(out)
+
+	case "up":
+		if len(args) != 1 {
+			return errMigrateUsage
+		}
+		if err := CreateSchema(); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Database is at version %d\n", LatestVersion())
+		return nil
+
+	case "down":
+		if len(args) != 2 {
+			return errMigrateUsage
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return errMigrateUsage
+		}
+		if err := MigrateDown(version); err != nil {
+			return err
+		}
+		current, err := SchemaVersion()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Database is at version %d\n", current)
+		return nil
+	}
+
+	return errMigrateUsage
+}
+
+// printMigrationStatus writes a table of all migrations and when they were applied
+func printMigrationStatus(out io.Writer) error {
+	states, err := MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, s := range states {
+		applied := s.AppliedAt
+		if applied == "" {
+			applied = "pending"
+		}
+		if s.Version > LatestVersion() {
+			applied += " (unknown to this build)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	return w.Flush()
+}
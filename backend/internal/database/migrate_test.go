@@ -0,0 +1,323 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openTestDB opens an empty SQLite database as DB
+func openTestDB(t *testing.T) {
+	var err error
+	DB, err = sql.Open("sqlite", filepath.Join(t.TempDir(), "test_shifts.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	Driver = DriverSQLite
+	t.Cleanup(func() {
+		DB.Close()
+		DB = nil
+	})
+}
+
+// tableExists checks whether the SQLite test database has a table
+func tableExists(t *testing.T, table string) bool {
+	var count int
+	if err := DB.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count); err != nil {
+		t.Fatalf("Failed to look up table %s: %v", table, err)
+	}
+	return count > 0
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	openTestDB(t)
+
+	if err := CreateSchema(); err != nil {
+		t.Fatalf("Failed to migrate up: %v", err)
+	}
+	version, err := SchemaVersion()
+	if err != nil {
+		t.Fatalf("Failed to get schema version: %v", err)
+	}
+	if version != LatestVersion() {
+		t.Errorf("Schema version mismatch: got %d, want %d", version, LatestVersion())
+	}
+	if !tableExists(t, "shifts") {
+		t.Error("shifts table should exist after migrating up")
+	}
+
+	// Migrating again is a no-op
+	if err := CreateSchema(); err != nil {
+		t.Fatalf("Failed to migrate up twice: %v", err)
+	}
+
+	if err := MigrateDown(0); err != nil {
+		t.Fatalf("Failed to migrate down: %v", err)
+	}
+	version, _ = SchemaVersion()
+	if version != 0 {
+		t.Errorf("Schema version after down: got %d, want 0", version)
+	}
+	if tableExists(t, "shifts") {
+		t.Error("shifts table should be dropped after migrating down")
+	}
+
+	if err := MigrateUp(); err != nil {
+		t.Fatalf("Failed to migrate up after down: %v", err)
+	}
+	version, _ = SchemaVersion()
+	if version != LatestVersion() {
+		t.Errorf("Schema version mismatch: got %d, want %d", version, LatestVersion())
+	}
+}
+
+func TestCreateSchema_RefusesNewerDatabase(t *testing.T) {
+	openTestDB(t)
+
+	if err := CreateSchema(); err != nil {
+		t.Fatalf("Failed to migrate up: %v", err)
+	}
+	if _, err := DB.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'from_the_future', '2030-01-01 00:00:00')", LatestVersion()+1); err != nil {
+		t.Fatalf("Failed to record newer migration: %v", err)
+	}
+
+	if err := CreateSchema(); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew, got %v", err)
+	}
+	if err := MigrateDown(0); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew when migrating down, got %v", err)
+	}
+}
+
+func TestCreateSchema_UpgradesLegacyDatabase(t *testing.T) {
+	openTestDB(t)
+
+	// Tables as created before the hidden shift and sequence columns existed
+	legacy := `
+	CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT NOT NULL UNIQUE, password_hash TEXT NOT NULL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+	CREATE TABLE members (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, name TEXT NOT NULL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+	CREATE TABLE shifts (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, member_id INTEGER NOT NULL, start_date DATE NOT NULL, end_date DATE NOT NULL, is_long_shift BOOLEAN DEFAULT 0, created_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+	INSERT INTO users (username, password_hash) VALUES ('legacy', 'hash');
+	INSERT INTO members (user_id, name) VALUES (1, 'Alice');
+	`
+	if _, err := DB.Exec(legacy); err != nil {
+		t.Fatalf("Failed to create legacy tables: %v", err)
+	}
+
+	if err := CreateSchema(); err != nil {
+		t.Fatalf("Failed to migrate legacy database: %v", err)
+	}
+
+	var name string
+	var hiddenNormal, sequenceCount int
+	if err := DB.QueryRow("SELECT name, hidden_normal_shifts FROM members WHERE id = 1").Scan(&name, &hiddenNormal); err != nil {
+		t.Fatalf("Failed to read migrated member: %v", err)
+	}
+	if name != "Alice" || hiddenNormal != 0 {
+		t.Errorf("Migrated member mismatch: got %s with %d hidden shifts", name, hiddenNormal)
+	}
+	if err := DB.QueryRow("SELECT COUNT(*) FROM pragma_table_info('shifts') WHERE name = 'sequence'").Scan(&sequenceCount); err != nil || sequenceCount != 1 {
+		t.Errorf("shifts.sequence should be added, got %d (%v)", sequenceCount, err)
+	}
+	if !tableExists(t, "feed_tokens") {
+		t.Error("Missing tables should be created")
+	}
+}
+
+func TestMigrateWorkspaces_KeepsUserData(t *testing.T) {
+	openTestDB(t)
+
+	if err := CreateSchema(); err != nil {
+		t.Fatalf("Failed to migrate up: %v", err)
+	}
+	if err := MigrateDown(3); err != nil {
+		t.Fatalf("Failed to migrate down to version 3: %v", err)
+	}
+	data := `
+	INSERT INTO users (username, password_hash) VALUES ('alice', 'hash');
+	INSERT INTO members (user_id, name) VALUES (1, 'Alice');
+	INSERT INTO feed_tokens (user_id, token) VALUES (1, 'feed');
+	`
+	if _, err := DB.Exec(data); err != nil {
+		t.Fatalf("Failed to insert data: %v", err)
+	}
+
+	if err := MigrateUp(); err != nil {
+		t.Fatalf("Failed to migrate to workspaces: %v", err)
+	}
+
+	var name, role string
+	if err := DB.QueryRow("SELECT w.name, wu.role FROM workspaces w JOIN workspace_users wu ON wu.workspace_id = w.id WHERE wu.user_id = 1").Scan(&name, &role); err != nil {
+		t.Fatalf("Failed to read personal workspace: %v", err)
+	}
+	if name != "alice" || role != "owner" {
+		t.Errorf("Personal workspace mismatch: %s as %s", name, role)
+	}
+
+	var members, tokens int
+	DB.QueryRow("SELECT COUNT(*) FROM members WHERE workspace_id = 1").Scan(&members)
+	DB.QueryRow("SELECT COUNT(*) FROM feed_tokens WHERE workspace_id = 1 AND user_id = 1").Scan(&tokens)
+	if members != 1 || tokens != 1 {
+		t.Errorf("Rows should move to the personal workspace, got %d members and %d feed tokens", members, tokens)
+	}
+
+	var definition string
+	DB.QueryRow("SELECT sql FROM sqlite_master WHERE name = 'members'").Scan(&definition)
+	if !strings.Contains(definition, "REFERENCES workspaces(id)") {
+		t.Errorf("members should reference workspaces, got %s", definition)
+	}
+}
+
+func TestMigrateSessionDetails_HashesTokens(t *testing.T) {
+	openTestDB(t)
+
+	if err := CreateSchema(); err != nil {
+		t.Fatalf("Failed to migrate up: %v", err)
+	}
+	if err := MigrateDown(8); err != nil {
+		t.Fatalf("Failed to migrate down to version 8: %v", err)
+	}
+	data := `
+	INSERT INTO users (username, password_hash) VALUES ('alice', 'hash');
+	INSERT INTO sessions (user_id, token, expires_at) VALUES (1, 'token', '2030-01-01 00:00:00');
+	`
+	if _, err := DB.Exec(data); err != nil {
+		t.Fatalf("Failed to insert data: %v", err)
+	}
+
+	if err := MigrateUp(); err != nil {
+		t.Fatalf("Failed to migrate to session details: %v", err)
+	}
+
+	// Existing sessions stay valid under the hash of their token
+	var count int
+	DB.QueryRow("SELECT COUNT(*) FROM sessions WHERE token_hash = ? AND last_seen_at IS NOT NULL",
+		"3c469e9d6c5875d37a43f353d4f88e61fcf812c66eee3457465a40b0da4153e0").Scan(&count)
+	if count != 1 {
+		t.Errorf("Session token should be replaced by its SHA-256 hash")
+	}
+}
+
+func TestMigrateFeedTokenHashes_HashesTokens(t *testing.T) {
+	openTestDB(t)
+
+	if err := CreateSchema(); err != nil {
+		t.Fatalf("Failed to migrate up: %v", err)
+	}
+	if err := MigrateDown(11); err != nil {
+		t.Fatalf("Failed to migrate down to version 11: %v", err)
+	}
+	data := `
+	INSERT INTO users (username, password_hash) VALUES ('alice', 'hash');
+	INSERT INTO feed_tokens (workspace_id, user_id, token) VALUES (1, 1, 'token');
+	`
+	if _, err := DB.Exec(data); err != nil {
+		t.Fatalf("Failed to insert data: %v", err)
+	}
+
+	if err := MigrateUp(); err != nil {
+		t.Fatalf("Failed to migrate to feed token hashes: %v", err)
+	}
+
+	// Existing feed URLs keep working under the hash of their token
+	var count int
+	DB.QueryRow("SELECT COUNT(*) FROM feed_tokens WHERE token_hash = ? AND prefix = 'token'",
+		"3c469e9d6c5875d37a43f353d4f88e61fcf812c66eee3457465a40b0da4153e0").Scan(&count)
+	if count != 1 {
+		t.Errorf("Feed token should be replaced by its SHA-256 hash")
+	}
+}
+
+func TestMigrateMemberInviteTokenHashes_HashesTokens(t *testing.T) {
+	openTestDB(t)
+
+	if err := CreateSchema(); err != nil {
+		t.Fatalf("Failed to migrate up: %v", err)
+	}
+	if err := MigrateDown(12); err != nil {
+		t.Fatalf("Failed to migrate down to version 12: %v", err)
+	}
+	data := `
+	INSERT INTO member_invites (workspace_id, member_id, token, expires_at, created_at) VALUES (1, 1, 'token', '2030-01-01 00:00:00', '2025-01-01 00:00:00');
+	`
+	if _, err := DB.Exec(data); err != nil {
+		t.Fatalf("Failed to insert data: %v", err)
+	}
+
+	if err := MigrateUp(); err != nil {
+		t.Fatalf("Failed to migrate to member invite token hashes: %v", err)
+	}
+
+	// Pending invites can still be accepted with their token
+	var count int
+	DB.QueryRow("SELECT COUNT(*) FROM member_invites WHERE token_hash = ?",
+		"3c469e9d6c5875d37a43f353d4f88e61fcf812c66eee3457465a40b0da4153e0").Scan(&count)
+	if count != 1 {
+		t.Errorf("Member invite token should be replaced by its SHA-256 hash")
+	}
+}
+
+func TestMigrateSessionTimesAsText(t *testing.T) {
+	openTestDB(t)
+
+	if err := CreateSchema(); err != nil {
+		t.Fatalf("Failed to migrate up: %v", err)
+	}
+	if err := MigrateDown(13); err != nil {
+		t.Fatalf("Failed to migrate down to version 13: %v", err)
+	}
+	// Sessions used to be written as time.Time values, in local time with fractional seconds
+	data := `
+	INSERT INTO sessions (user_id, token_hash, expires_at, last_seen_at, created_at)
+	VALUES (1, 'hash', '2030-01-01 02:00:00.123456789+02:00', '2025-01-01 02:00:00.5+02:00', '2025-01-01 00:00:00');
+	`
+	if _, err := DB.Exec(data); err != nil {
+		t.Fatalf("Failed to insert data: %v", err)
+	}
+
+	if err := MigrateUp(); err != nil {
+		t.Fatalf("Failed to migrate to session times as text: %v", err)
+	}
+
+	var expiresAt, lastSeenAt, createdAt string
+	DB.QueryRow("SELECT CAST(expires_at AS TEXT), CAST(last_seen_at AS TEXT), CAST(created_at AS TEXT) FROM sessions").Scan(&expiresAt, &lastSeenAt, &createdAt)
+	if expiresAt != "2030-01-01 00:00:00" || lastSeenAt != "2025-01-01 00:00:00" || createdAt != "2025-01-01 00:00:00" {
+		t.Errorf("Session times should be UTC text, got %s, %s and %s", expiresAt, lastSeenAt, createdAt)
+	}
+}
+
+func TestMigrateCommand(t *testing.T) {
+	openTestDB(t)
+
+	var out bytes.Buffer
+	if err := MigrateCommand([]string{"status"}, &out); err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "initial_schema") || !strings.Contains(out.String(), "pending") {
+		t.Errorf("status should list the pending initial migration, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := MigrateCommand([]string{"up"}, &out); err != nil {
+		t.Fatalf("up failed: %v", err)
+	}
+	if strings.TrimSpace(out.String()) != fmt.Sprintf("Database is at version %d", LatestVersion()) {
+		t.Errorf("Unexpected up output: %s", out.String())
+	}
+
+	out.Reset()
+	if err := MigrateCommand([]string{"down", "0"}, &out); err != nil {
+		t.Fatalf("down failed: %v", err)
+	}
+	if strings.TrimSpace(out.String()) != "Database is at version 0" {
+		t.Errorf("Unexpected down output: %s", out.String())
+	}
+
+	if err := MigrateCommand([]string{"sideways"}, &out); err == nil {
+		t.Error("Unknown subcommand should fail")
+	}
+}
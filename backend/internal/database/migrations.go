@@ -0,0 +1,1148 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// driverSQL is a statement list written for each database driver
+type driverSQL struct {
+	sqlite   string
+	postgres string
+}
+
+// forDriver returns the statements for the current driver
+func (d driverSQL) forDriver() string {
+	if Driver == DriverPostgres {
+		return d.postgres
+	}
+	return d.sqlite
+}
+
+// migration is a numbered schema change that can be applied and reverted
+type migration struct {
+	version int
+	name    string
+	up      driverSQL
+	down    driverSQL
+	// upgrade runs after up, in the same transaction
+	upgrade func(tx *sql.Tx) error
+}
+
+// migrations are all schema changes in version order
+// Released migrations must never be edited; add a new one instead.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "initial_schema",
+		up: driverSQL{
+			sqlite:   initialSchemaSQLite + initialSchemaIndexes,
+			postgres: initialSchemaPostgres + initialSchemaIndexes,
+		},
+		down: driverSQL{
+			sqlite:   initialSchemaDown,
+			postgres: initialSchemaDown,
+		},
+		upgrade: addLegacyColumns,
+	},
+	// Audit entries reference the workspace owner only, so they outlive deleted members
+	{
+		version: 2,
+		name:    "audit_log",
+		up: driverSQL{
+			sqlite: `
+	CREATE TABLE audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		actor_user_id INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		entity TEXT NOT NULL,
+		entity_id INTEGER NOT NULL DEFAULT 0,
+		member_id INTEGER NOT NULL DEFAULT 0,
+		before_json TEXT,
+		after_json TEXT,
+		request_id TEXT NOT NULL DEFAULT '',
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE INDEX idx_audit_log_user_created ON audit_log(user_id, created_at);
+	`,
+			postgres: `
+	CREATE TABLE audit_log (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		actor_user_id INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		entity TEXT NOT NULL,
+		entity_id INTEGER NOT NULL DEFAULT 0,
+		member_id INTEGER NOT NULL DEFAULT 0,
+		before_json TEXT,
+		after_json TEXT,
+		request_id TEXT NOT NULL DEFAULT '',
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX idx_audit_log_user_created ON audit_log(user_id, created_at);
+	`,
+		},
+		down: driverSQL{
+			sqlite:   "DROP TABLE audit_log",
+			postgres: "DROP TABLE audit_log",
+		},
+	},
+	// Snapshots keep shifts and counters as JSON, as members may be deleted after the snapshot
+	{
+		version: 3,
+		name:    "plan_snapshots",
+		up: driverSQL{
+			sqlite: `
+	CREATE TABLE plan_snapshots (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		reason TEXT NOT NULL,
+		start_date TEXT NOT NULL DEFAULT '',
+		end_date TEXT NOT NULL DEFAULT '',
+		shift_count INTEGER NOT NULL DEFAULT 0,
+		shifts_json TEXT NOT NULL,
+		counters_json TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE INDEX idx_plan_snapshots_user_id ON plan_snapshots(user_id);
+	`,
+			postgres: `
+	CREATE TABLE plan_snapshots (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		reason TEXT NOT NULL,
+		start_date TEXT NOT NULL DEFAULT '',
+		end_date TEXT NOT NULL DEFAULT '',
+		shift_count INTEGER NOT NULL DEFAULT 0,
+		shifts_json TEXT NOT NULL,
+		counters_json TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX idx_plan_snapshots_user_id ON plan_snapshots(user_id);
+	`,
+		},
+		down: driverSQL{
+			sqlite:   "DROP TABLE plan_snapshots",
+			postgres: "DROP TABLE plan_snapshots",
+		},
+	},
+	// Every existing user gets a workspace with their own ID, so rows only change column name
+	{
+		version: 4,
+		name:    "workspaces",
+		up: driverSQL{
+			sqlite:   workspacesSQLite,
+			postgres: workspacesPostgres + workspaceColumnsPostgres() + workspaceIndexes,
+		},
+		down: driverSQL{
+			sqlite:   workspaceColumnsDown() + workspacesDownSQLite,
+			postgres: workspaceColumnsDownPostgres() + workspaceColumnsDown() + workspacesDownPostgres,
+		},
+		upgrade: rebuildWorkspaceTablesSQLite,
+	},
+	// A user is linked to at most one member per workspace
+	// SQLite leaves out the reference to users, as it can't drop such a column when reverting.
+	{
+		version: 5,
+		name:    "member_links",
+		up: driverSQL{
+			sqlite: `
+	ALTER TABLE members ADD COLUMN user_id INTEGER;
+	CREATE UNIQUE INDEX idx_members_workspace_user ON members(workspace_id, user_id);
+	CREATE TABLE member_invites (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		workspace_id INTEGER NOT NULL,
+		member_id INTEGER NOT NULL,
+		token TEXT NOT NULL UNIQUE,
+		expires_at TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE,
+		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE
+	);
+	`,
+			postgres: `
+	ALTER TABLE members ADD COLUMN user_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+	CREATE UNIQUE INDEX idx_members_workspace_user ON members(workspace_id, user_id);
+	CREATE TABLE member_invites (
+		id SERIAL PRIMARY KEY,
+		workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+		member_id INTEGER NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+		token TEXT NOT NULL UNIQUE,
+		expires_at TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+	`,
+		},
+		down: driverSQL{
+			sqlite: `
+	DROP TABLE member_invites;
+	DROP INDEX idx_members_workspace_user;
+	ALTER TABLE members DROP COLUMN user_id;
+	`,
+			postgres: `
+	DROP TABLE member_invites;
+	ALTER TABLE members DROP COLUMN user_id;
+	`,
+		},
+	},
+	// Only the hash of the invite token is stored
+	{
+		version: 6,
+		name:    "workspace_invites",
+		up: driverSQL{
+			sqlite: `
+	CREATE TABLE workspace_invites (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		workspace_id INTEGER NOT NULL,
+		email TEXT NOT NULL,
+		role TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		invited_by INTEGER NOT NULL,
+		expires_at TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE
+	);
+	CREATE INDEX idx_workspace_invites_workspace_id ON workspace_invites(workspace_id);
+	`,
+			postgres: `
+	CREATE TABLE workspace_invites (
+		id SERIAL PRIMARY KEY,
+		workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+		email TEXT NOT NULL,
+		role TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		invited_by INTEGER NOT NULL,
+		expires_at TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX idx_workspace_invites_workspace_id ON workspace_invites(workspace_id);
+	`,
+		},
+		down: driverSQL{
+			sqlite:   "DROP TABLE workspace_invites",
+			postgres: "DROP TABLE workspace_invites",
+		},
+	},
+	// Only the hash of an API key is stored, with its prefix to tell keys apart
+	{
+		version: 7,
+		name:    "api_keys",
+		up: driverSQL{
+			sqlite: `
+	CREATE TABLE api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		workspace_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		scope TEXT NOT NULL,
+		last_used_at TEXT,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE
+	);
+	CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+	`,
+			postgres: `
+	CREATE TABLE api_keys (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		scope TEXT NOT NULL,
+		last_used_at TEXT,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+	`,
+		},
+		down: driverSQL{
+			sqlite:   "DROP TABLE api_keys",
+			postgres: "DROP TABLE api_keys",
+		},
+	},
+	// Links users to their account at an OpenID Connect provider
+	{
+		version: 8,
+		name:    "user_identities",
+		up: driverSQL{
+			sqlite: `
+	CREATE TABLE user_identities (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		issuer TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		email TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		UNIQUE(issuer, subject)
+	);
+	CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+	`,
+			postgres: `
+	CREATE TABLE user_identities (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		issuer TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		email TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		UNIQUE(issuer, subject)
+	);
+	CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+	`,
+		},
+		down: driverSQL{
+			sqlite:   "DROP TABLE user_identities",
+			postgres: "DROP TABLE user_identities",
+		},
+	},
+	// Sessions keep only the hash of their token, and record where they are used
+	// Reverting deletes all sessions, as their tokens can't be recovered.
+	{
+		version: 9,
+		name:    "session_details",
+		up: driverSQL{
+			sqlite: `
+	DROP INDEX IF EXISTS idx_sessions_token;
+	ALTER TABLE sessions RENAME COLUMN token TO token_hash;
+	ALTER TABLE sessions ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+	ALTER TABLE sessions ADD COLUMN ip_address TEXT NOT NULL DEFAULT '';
+	ALTER TABLE sessions ADD COLUMN last_seen_at DATETIME;
+	CREATE INDEX idx_sessions_token_hash ON sessions(token_hash);
+	`,
+			postgres: `
+	DROP INDEX IF EXISTS idx_sessions_token;
+	ALTER TABLE sessions RENAME COLUMN token TO token_hash;
+	ALTER TABLE sessions ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+	ALTER TABLE sessions ADD COLUMN ip_address TEXT NOT NULL DEFAULT '';
+	ALTER TABLE sessions ADD COLUMN last_seen_at TIMESTAMPTZ;
+	CREATE INDEX idx_sessions_token_hash ON sessions(token_hash);
+	`,
+		},
+		down: driverSQL{
+			sqlite: `
+	DELETE FROM sessions;
+	DROP INDEX idx_sessions_token_hash;
+	ALTER TABLE sessions DROP COLUMN last_seen_at;
+	ALTER TABLE sessions DROP COLUMN ip_address;
+	ALTER TABLE sessions DROP COLUMN user_agent;
+	ALTER TABLE sessions RENAME COLUMN token_hash TO token;
+	CREATE INDEX idx_sessions_token ON sessions(token);
+	`,
+			postgres: `
+	DELETE FROM sessions;
+	DROP INDEX idx_sessions_token_hash;
+	ALTER TABLE sessions DROP COLUMN last_seen_at;
+	ALTER TABLE sessions DROP COLUMN ip_address;
+	ALTER TABLE sessions DROP COLUMN user_agent;
+	ALTER TABLE sessions RENAME COLUMN token_hash TO token;
+	CREATE INDEX idx_sessions_token ON sessions(token);
+	`,
+		},
+		upgrade: hashSessionTokens,
+	},
+	// TOTP two-factor authentication; recovery codes and pre-auth tokens are stored hashed
+	{
+		version: 10,
+		name:    "two_factor",
+		up: driverSQL{
+			sqlite: `
+	CREATE TABLE user_two_factor (
+		user_id INTEGER PRIMARY KEY,
+		secret TEXT NOT NULL,
+		last_step INTEGER NOT NULL DEFAULT 0,
+		enabled_at TEXT,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE TABLE recovery_codes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		code_hash TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE INDEX idx_recovery_codes_user_id ON recovery_codes(user_id);
+	CREATE TABLE login_challenges (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		failures INTEGER NOT NULL DEFAULT 0,
+		expires_at TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	ALTER TABLE workspaces ADD COLUMN require_two_factor BOOLEAN NOT NULL DEFAULT 0;
+	`,
+			postgres: `
+	CREATE TABLE user_two_factor (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		secret TEXT NOT NULL,
+		last_step BIGINT NOT NULL DEFAULT 0,
+		enabled_at TEXT,
+		created_at TEXT NOT NULL
+	);
+	CREATE TABLE recovery_codes (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX idx_recovery_codes_user_id ON recovery_codes(user_id);
+	CREATE TABLE login_challenges (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash TEXT NOT NULL UNIQUE,
+		failures INTEGER NOT NULL DEFAULT 0,
+		expires_at TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+	ALTER TABLE workspaces ADD COLUMN require_two_factor BOOLEAN NOT NULL DEFAULT FALSE;
+	`,
+		},
+		down: driverSQL{
+			sqlite: `
+	ALTER TABLE workspaces DROP COLUMN require_two_factor;
+	DROP TABLE login_challenges;
+	DROP TABLE recovery_codes;
+	DROP TABLE user_two_factor;
+	`,
+			postgres: `
+	ALTER TABLE workspaces DROP COLUMN require_two_factor;
+	DROP TABLE login_challenges;
+	DROP TABLE recovery_codes;
+	DROP TABLE user_two_factor;
+	`,
+		},
+	},
+	// Users can give an email address to reset a forgotten password; reset tokens are stored hashed
+	{
+		version: 11,
+		name:    "password_resets",
+		up: driverSQL{
+			sqlite: `
+	ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';
+	CREATE TABLE password_resets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		expires_at TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE INDEX idx_password_resets_user_id ON password_resets(user_id);
+	`,
+			postgres: `
+	ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';
+	CREATE TABLE password_resets (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash TEXT NOT NULL UNIQUE,
+		expires_at TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX idx_password_resets_user_id ON password_resets(user_id);
+	`,
+		},
+		down: driverSQL{
+			sqlite: `
+	DROP TABLE password_resets;
+	ALTER TABLE users DROP COLUMN email;
+	`,
+			postgres: `
+	DROP TABLE password_resets;
+	ALTER TABLE users DROP COLUMN email;
+	`,
+		},
+	},
+	// Feed tokens keep only their hash and the first characters to tell them apart
+	// Reverting deletes all feed tokens, as they can't be recovered.
+	{
+		version: 12,
+		name:    "feed_token_hashes",
+		up: driverSQL{
+			sqlite: `
+	ALTER TABLE feed_tokens RENAME COLUMN token TO token_hash;
+	ALTER TABLE feed_tokens ADD COLUMN prefix TEXT NOT NULL DEFAULT '';
+	`,
+			postgres: `
+	ALTER TABLE feed_tokens RENAME COLUMN token TO token_hash;
+	ALTER TABLE feed_tokens ADD COLUMN prefix TEXT NOT NULL DEFAULT '';
+	`,
+		},
+		down: driverSQL{
+			sqlite: `
+	DELETE FROM feed_tokens;
+	ALTER TABLE feed_tokens DROP COLUMN prefix;
+	ALTER TABLE feed_tokens RENAME COLUMN token_hash TO token;
+	`,
+			postgres: `
+	DELETE FROM feed_tokens;
+	ALTER TABLE feed_tokens DROP COLUMN prefix;
+	ALTER TABLE feed_tokens RENAME COLUMN token_hash TO token;
+	`,
+		},
+		upgrade: hashFeedTokens,
+	},
+	// Member invites keep only the hash of their token
+	// Reverting deletes all member invites, as their tokens can't be recovered.
+	{
+		version: 13,
+		name:    "member_invite_token_hashes",
+		up: driverSQL{
+			sqlite:   "ALTER TABLE member_invites RENAME COLUMN token TO token_hash",
+			postgres: "ALTER TABLE member_invites RENAME COLUMN token TO token_hash",
+		},
+		down: driverSQL{
+			sqlite: `
+	DELETE FROM member_invites;
+	ALTER TABLE member_invites RENAME COLUMN token_hash TO token;
+	`,
+			postgres: `
+	DELETE FROM member_invites;
+	ALTER TABLE member_invites RENAME COLUMN token_hash TO token;
+	`,
+		},
+		upgrade: hashMemberInviteTokens,
+	},
+	// Session times are stored as UTC text, like the expiries of all other tables,
+	// so they compare correctly as text in both databases
+	{
+		version: 14,
+		name:    "session_times_as_text",
+		up: driverSQL{
+			sqlite: `
+	UPDATE sessions SET
+		expires_at = strftime('%Y-%m-%d %H:%M:%S', expires_at),
+		last_seen_at = strftime('%Y-%m-%d %H:%M:%S', last_seen_at),
+		created_at = strftime('%Y-%m-%d %H:%M:%S', created_at);
+	`,
+			postgres: `
+	ALTER TABLE sessions ALTER COLUMN created_at DROP DEFAULT;
+	ALTER TABLE sessions ALTER COLUMN expires_at TYPE TEXT USING to_char(expires_at AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS');
+	ALTER TABLE sessions ALTER COLUMN last_seen_at TYPE TEXT USING to_char(last_seen_at AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS');
+	ALTER TABLE sessions ALTER COLUMN created_at TYPE TEXT USING to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS');
+	`,
+		},
+		down: driverSQL{
+			// SQLite keeps the text, which its date functions read as UTC
+			sqlite: "SELECT 1",
+			postgres: `
+	ALTER TABLE sessions ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at::timestamp AT TIME ZONE 'UTC';
+	ALTER TABLE sessions ALTER COLUMN last_seen_at TYPE TIMESTAMPTZ USING last_seen_at::timestamp AT TIME ZONE 'UTC';
+	ALTER TABLE sessions ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::timestamp AT TIME ZONE 'UTC';
+	ALTER TABLE sessions ALTER COLUMN created_at SET DEFAULT CURRENT_TIMESTAMP;
+	`,
+		},
+	},
+	// Workspace roles given by single sign-on groups are marked, so later logins only change those
+	// Existing memberships count as granted by hand, since their origin isn't known.
+	{
+		version: 15,
+		name:    "workspace_users_sso_granted",
+		up: driverSQL{
+			sqlite:   "ALTER TABLE workspace_users ADD COLUMN sso_granted BOOLEAN NOT NULL DEFAULT 0",
+			postgres: "ALTER TABLE workspace_users ADD COLUMN sso_granted BOOLEAN NOT NULL DEFAULT FALSE",
+		},
+		down: driverSQL{
+			sqlite:   "ALTER TABLE workspace_users DROP COLUMN sso_granted",
+			postgres: "ALTER TABLE workspace_users DROP COLUMN sso_granted",
+		},
+	},
+	// Members can ask to swap shifts; requests go with their shifts
+	{
+		version: 16,
+		name:    "swap_requests",
+		up: driverSQL{
+			sqlite: `
+	CREATE TABLE swap_requests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		workspace_id INTEGER NOT NULL,
+		shift_id INTEGER NOT NULL,
+		member_id INTEGER NOT NULL,
+		with_shift_id INTEGER NOT NULL,
+		with_member_id INTEGER NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		note TEXT NOT NULL DEFAULT '',
+		decided_at TEXT,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE,
+		FOREIGN KEY (shift_id) REFERENCES shifts(id) ON DELETE CASCADE,
+		FOREIGN KEY (with_shift_id) REFERENCES shifts(id) ON DELETE CASCADE
+	);
+	CREATE INDEX idx_swap_requests_workspace_id ON swap_requests(workspace_id);
+	`,
+			postgres: `
+	CREATE TABLE swap_requests (
+		id SERIAL PRIMARY KEY,
+		workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+		shift_id INTEGER NOT NULL REFERENCES shifts(id) ON DELETE CASCADE,
+		member_id INTEGER NOT NULL,
+		with_shift_id INTEGER NOT NULL REFERENCES shifts(id) ON DELETE CASCADE,
+		with_member_id INTEGER NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		note TEXT NOT NULL DEFAULT '',
+		decided_at TEXT,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX idx_swap_requests_workspace_id ON swap_requests(workspace_id);
+	`,
+		},
+		down: driverSQL{
+			sqlite:   "DROP TABLE swap_requests",
+			postgres: "DROP TABLE swap_requests",
+		},
+	},
+}
+
+// initialSchemaSQLite creates all tables in SQLite
+const initialSchemaSQLite = `
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS members (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		hidden_normal_shifts INTEGER DEFAULT 0,
+		hidden_long_shifts INTEGER DEFAULT 0,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS shifts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		member_id INTEGER NOT NULL,
+		start_date DATE NOT NULL,
+		end_date DATE NOT NULL,
+		is_long_shift BOOLEAN DEFAULT 0,
+		sequence INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		token TEXT NOT NULL UNIQUE,
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS leave_days (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		member_id INTEGER NOT NULL,
+		leave_date DATE NOT NULL,
+		leave_type TEXT NOT NULL DEFAULT 'annual',
+		leave_request_id INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE,
+		UNIQUE(user_id, member_id, leave_date)
+	);
+
+	CREATE TABLE IF NOT EXISTS leave_requests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		member_id INTEGER NOT NULL,
+		start_date DATE NOT NULL,
+		end_date DATE NOT NULL,
+		leave_type TEXT NOT NULL DEFAULT 'annual',
+		status TEXT NOT NULL DEFAULT 'pending',
+		note TEXT NOT NULL DEFAULT '',
+		decided_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS leave_allowances (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		member_id INTEGER NOT NULL,
+		year INTEGER NOT NULL,
+		leave_type TEXT NOT NULL,
+		days INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE,
+		UNIQUE(user_id, member_id, year, leave_type)
+	);
+
+	CREATE TABLE IF NOT EXISTS unavailability_rules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		member_id INTEGER NOT NULL,
+		frequency TEXT NOT NULL,
+		weekdays TEXT NOT NULL,
+		nth INTEGER NOT NULL DEFAULT 0,
+		start_date DATE NOT NULL,
+		end_date DATE,
+		note TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS imports (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		kind TEXT NOT NULL,
+		content_hash TEXT NOT NULL,
+		filename TEXT NOT NULL DEFAULT '',
+		members_created INTEGER NOT NULL DEFAULT 0,
+		shifts_created INTEGER NOT NULL DEFAULT 0,
+		shifts_updated INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS feed_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		token TEXT NOT NULL UNIQUE,
+		name TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+`
+
+// initialSchemaPostgres creates all tables in Postgres
+// Dates and decision times are stored as text in the same formats as in SQLite,
+// so both databases share their queries.
+const initialSchemaPostgres = `
+	CREATE TABLE IF NOT EXISTS users (
+		id SERIAL PRIMARY KEY,
+		username TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS members (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		hidden_normal_shifts INTEGER DEFAULT 0,
+		hidden_long_shifts INTEGER DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS shifts (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		member_id INTEGER NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+		start_date TEXT NOT NULL,
+		end_date TEXT NOT NULL,
+		is_long_shift BOOLEAN DEFAULT FALSE,
+		sequence INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS sessions (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token TEXT NOT NULL UNIQUE,
+		expires_at TIMESTAMPTZ NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS leave_days (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		member_id INTEGER NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+		leave_date TEXT NOT NULL,
+		leave_type TEXT NOT NULL DEFAULT 'annual',
+		leave_request_id INTEGER,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(user_id, member_id, leave_date)
+	);
+
+	CREATE TABLE IF NOT EXISTS leave_requests (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		member_id INTEGER NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+		start_date TEXT NOT NULL,
+		end_date TEXT NOT NULL,
+		leave_type TEXT NOT NULL DEFAULT 'annual',
+		status TEXT NOT NULL DEFAULT 'pending',
+		note TEXT NOT NULL DEFAULT '',
+		decided_at TEXT,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS leave_allowances (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		member_id INTEGER NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+		year INTEGER NOT NULL,
+		leave_type TEXT NOT NULL,
+		days INTEGER NOT NULL DEFAULT 0,
+		UNIQUE(user_id, member_id, year, leave_type)
+	);
+
+	CREATE TABLE IF NOT EXISTS unavailability_rules (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		member_id INTEGER NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+		frequency TEXT NOT NULL,
+		weekdays TEXT NOT NULL,
+		nth INTEGER NOT NULL DEFAULT 0,
+		start_date TEXT NOT NULL,
+		end_date TEXT,
+		note TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS imports (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		kind TEXT NOT NULL,
+		content_hash TEXT NOT NULL,
+		filename TEXT NOT NULL DEFAULT '',
+		members_created INTEGER NOT NULL DEFAULT 0,
+		shifts_created INTEGER NOT NULL DEFAULT 0,
+		shifts_updated INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS feed_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token TEXT NOT NULL UNIQUE,
+		name TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+`
+
+// initialSchemaIndexes creates the indexes of all tables, in both SQLite and Postgres
+const initialSchemaIndexes = `
+	CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
+	CREATE INDEX IF NOT EXISTS idx_members_user_id ON members(user_id);
+	CREATE INDEX IF NOT EXISTS idx_shifts_user_id ON shifts(user_id);
+	CREATE INDEX IF NOT EXISTS idx_shifts_member_id ON shifts(member_id);
+	CREATE INDEX IF NOT EXISTS idx_shifts_start_date ON shifts(start_date);
+	CREATE INDEX IF NOT EXISTS idx_shifts_end_date ON shifts(end_date);
+	CREATE INDEX IF NOT EXISTS idx_sessions_token ON sessions(token);
+	CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+	CREATE INDEX IF NOT EXISTS idx_leave_days_user_id ON leave_days(user_id);
+	CREATE INDEX IF NOT EXISTS idx_leave_days_member_id ON leave_days(member_id);
+	CREATE INDEX IF NOT EXISTS idx_leave_days_date ON leave_days(leave_date);
+	CREATE INDEX IF NOT EXISTS idx_leave_requests_user_id ON leave_requests(user_id);
+	CREATE INDEX IF NOT EXISTS idx_leave_requests_member_id ON leave_requests(member_id);
+	CREATE INDEX IF NOT EXISTS idx_leave_allowances_user_id ON leave_allowances(user_id);
+	CREATE INDEX IF NOT EXISTS idx_unavailability_rules_user_id ON unavailability_rules(user_id);
+	CREATE INDEX IF NOT EXISTS idx_imports_user_hash ON imports(user_id, kind, content_hash);
+	CREATE INDEX IF NOT EXISTS idx_feed_tokens_user_id ON feed_tokens(user_id);
+`
+
+const initialSchemaDown = `
+	DROP TABLE IF EXISTS feed_tokens;
+	DROP TABLE IF EXISTS imports;
+	DROP TABLE IF EXISTS unavailability_rules;
+	DROP TABLE IF EXISTS leave_allowances;
+	DROP TABLE IF EXISTS leave_requests;
+	DROP TABLE IF EXISTS leave_days;
+	DROP TABLE IF EXISTS sessions;
+	DROP TABLE IF EXISTS shifts;
+	DROP TABLE IF EXISTS members;
+	DROP TABLE IF EXISTS users;
+`
+
+// workspacesSQLite creates the workspace tables and a workspace for every user
+const workspacesSQLite = `
+	CREATE TABLE workspaces (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+	CREATE TABLE workspace_users (
+		workspace_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		role TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		PRIMARY KEY (workspace_id, user_id),
+		FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE INDEX idx_workspace_users_user_id ON workspace_users(user_id);
+	INSERT INTO workspaces (id, name, created_at)
+		SELECT id, username, strftime('%Y-%m-%d %H:%M:%S', 'now') FROM users;
+	INSERT INTO workspace_users (workspace_id, user_id, role, created_at)
+		SELECT id, id, 'owner', strftime('%Y-%m-%d %H:%M:%S', 'now') FROM users;
+	ALTER TABLE feed_tokens ADD COLUMN workspace_id INTEGER NOT NULL DEFAULT 0;
+	UPDATE feed_tokens SET workspace_id = user_id;
+`
+
+// workspacesPostgres creates the workspace tables and a workspace for every user
+const workspacesPostgres = `
+	CREATE TABLE workspaces (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+	CREATE TABLE workspace_users (
+		workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		role TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		PRIMARY KEY (workspace_id, user_id)
+	);
+	CREATE INDEX idx_workspace_users_user_id ON workspace_users(user_id);
+	INSERT INTO workspaces (id, name, created_at)
+		SELECT id, username, to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS') FROM users;
+	INSERT INTO workspace_users (workspace_id, user_id, role, created_at)
+		SELECT id, id, 'owner', to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS') FROM users;
+	SELECT setval(pg_get_serial_sequence('workspaces', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM workspaces;
+	ALTER TABLE feed_tokens ADD COLUMN workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE;
+	UPDATE feed_tokens SET workspace_id = user_id;
+	ALTER TABLE feed_tokens ALTER COLUMN workspace_id SET NOT NULL;
+`
+
+const workspacesDownSQLite = `
+	ALTER TABLE feed_tokens DROP COLUMN workspace_id;
+	DROP TABLE workspace_users;
+	DROP TABLE workspaces;
+`
+
+const workspacesDownPostgres = `
+	ALTER TABLE feed_tokens DROP COLUMN workspace_id;
+	DROP TABLE workspace_users;
+	DROP TABLE workspaces CASCADE;
+`
+
+// workspaceTables hold their data per workspace since migration 4, in user_id before it
+// The owner column is indexed, alone or followed by the listed columns.
+var workspaceTables = []struct{ table, userIndex, workspaceIndex, columns string }{
+	{"members", "idx_members_user_id", "idx_members_workspace_id", ""},
+	{"shifts", "idx_shifts_user_id", "idx_shifts_workspace_id", ""},
+	{"leave_days", "idx_leave_days_user_id", "idx_leave_days_workspace_id", ""},
+	{"leave_requests", "idx_leave_requests_user_id", "idx_leave_requests_workspace_id", ""},
+	{"leave_allowances", "idx_leave_allowances_user_id", "idx_leave_allowances_workspace_id", ""},
+	{"unavailability_rules", "idx_unavailability_rules_user_id", "idx_unavailability_rules_workspace_id", ""},
+	{"imports", "idx_imports_user_hash", "idx_imports_workspace_hash", ", kind, content_hash"},
+	{"audit_log", "idx_audit_log_user_created", "idx_audit_log_workspace_created", ", created_at"},
+	{"plan_snapshots", "idx_plan_snapshots_user_id", "idx_plan_snapshots_workspace_id", ""},
+}
+
+// workspaceIndexes replaces the user_id indexes of the workspace tables
+var workspaceIndexes = workspaceIndexStatements(true)
+
+// workspaceIndexStatements moves the workspace table indexes to workspace_id, or back to user_id
+func workspaceIndexStatements(toWorkspace bool) string {
+	var b strings.Builder
+	for _, t := range workspaceTables {
+		drop, create, column := t.workspaceIndex, t.userIndex, "user_id"
+		if toWorkspace {
+			drop, create, column = t.userIndex, t.workspaceIndex, "workspace_id"
+		}
+		fmt.Fprintf(&b, "\tDROP INDEX IF EXISTS %s;\n", drop)
+		fmt.Fprintf(&b, "\tCREATE INDEX %s ON %s(%s%s);\n", create, t.table, column, t.columns)
+	}
+	return b.String()
+}
+
+// workspaceColumnsPostgres renames user_id to workspace_id and points it at workspaces
+func workspaceColumnsPostgres() string {
+	var b strings.Builder
+	for _, t := range workspaceTables {
+		fmt.Fprintf(&b, "\tALTER TABLE %s RENAME COLUMN user_id TO workspace_id;\n", t.table)
+		fmt.Fprintf(&b, "\tALTER TABLE %s DROP CONSTRAINT IF EXISTS %s_user_id_fkey;\n", t.table, t.table)
+		fmt.Fprintf(&b, "\tALTER TABLE %s ADD FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE;\n", t.table)
+	}
+	return b.String()
+}
+
+// workspaceColumnsDownPostgres points the owner columns back at users
+// NOT VALID keeps rows of workspaces created after the migration, whose ID
+// doesn't belong to a user.
+func workspaceColumnsDownPostgres() string {
+	var b strings.Builder
+	for _, t := range workspaceTables {
+		fmt.Fprintf(&b, "\tALTER TABLE %s DROP CONSTRAINT IF EXISTS %s_workspace_id_fkey;\n", t.table, t.table)
+		fmt.Fprintf(&b, "\tALTER TABLE %s ADD FOREIGN KEY (workspace_id) REFERENCES users(id) ON DELETE CASCADE NOT VALID;\n", t.table)
+	}
+	return b.String()
+}
+
+// workspaceColumnsDown renames workspace_id back to user_id
+func workspaceColumnsDown() string {
+	var b strings.Builder
+	for _, t := range workspaceTables {
+		fmt.Fprintf(&b, "\tALTER TABLE %s RENAME COLUMN workspace_id TO user_id;\n", t.table)
+	}
+	return b.String() + workspaceIndexStatements(false)
+}
+
+var (
+	userIDColumn      = regexp.MustCompile(`\buser_id\b`)
+	createTableHeader = regexp.MustCompile(`^CREATE TABLE (IF NOT EXISTS )?"?\w+"?`)
+)
+
+// rebuildWorkspaceTablesSQLite renames user_id to workspace_id in SQLite
+// SQLite can't change a foreign key in place, so each table is copied into a
+// new table created from its own definition with the column and reference replaced.
+func rebuildWorkspaceTablesSQLite(tx *sql.Tx) error {
+	if Driver != DriverSQLite {
+		return nil
+	}
+
+	for _, t := range workspaceTables {
+		var definition string
+		err := tx.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", t.table).Scan(&definition)
+		if err != nil {
+			return fmt.Errorf("reading definition of %s: %w", t.table, err)
+		}
+
+		definition = userIDColumn.ReplaceAllString(definition, "workspace_id")
+		definition = strings.ReplaceAll(definition, "REFERENCES users(id)", "REFERENCES workspaces(id)")
+		definition = createTableHeader.ReplaceAllString(definition, "CREATE TABLE "+t.table+"_new")
+
+		statements := []string{
+			definition,
+			"INSERT INTO " + t.table + "_new SELECT * FROM " + t.table,
+			"DROP TABLE " + t.table,
+			"ALTER TABLE " + t.table + "_new RENAME TO " + t.table,
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return fmt.Errorf("rebuilding %s: %w", t.table, err)
+			}
+		}
+	}
+
+	_, err := tx.Exec(workspaceIndexes)
+	return err
+}
+
+// legacyColumns were added with ALTER TABLE by the schema setup used before
+// versioned migrations. Databases created by it may still lack some of them.
+var legacyColumns = []struct{ table, column, definition string }{
+	{"members", "hidden_normal_shifts", "INTEGER DEFAULT 0"},
+	{"members", "hidden_long_shifts", "INTEGER DEFAULT 0"},
+	{"shifts", "sequence", "INTEGER NOT NULL DEFAULT 0"},
+	{"leave_days", "leave_type", "TEXT NOT NULL DEFAULT 'annual'"},
+	{"leave_days", "leave_request_id", "INTEGER"},
+}
+
+// addLegacyColumns adds the legacy columns missing from tables that predate migration 1
+func addLegacyColumns(tx *sql.Tx) error {
+	for _, c := range legacyColumns {
+		exists, err := columnExists(tx, c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := tx.Exec("ALTER TABLE " + c.table + " ADD COLUMN " + c.column + " " + c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashSessionTokens replaces the tokens of existing sessions with their hash, so they stay logged in
+// The hash must match auth.HashToken: hex encoded SHA-256.
+func hashSessionTokens(tx *sql.Tx) error {
+	tokens, err := tokensByID(tx, "sessions")
+	if err != nil {
+		return err
+	}
+
+	update := driverSQL{
+		sqlite:   "UPDATE sessions SET token_hash = ?, last_seen_at = created_at WHERE id = ?",
+		postgres: "UPDATE sessions SET token_hash = $1, last_seen_at = created_at WHERE id = $2",
+	}
+	for id, token := range tokens {
+		sum := sha256.Sum256([]byte(token))
+		if _, err := tx.Exec(update.forDriver(), hex.EncodeToString(sum[:]), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// feedTokenPrefixLength characters of a feed token kept in the clear
+// It must match the visible length used by auth.CreateFeedToken.
+const feedTokenPrefixLength = 8
+
+// hashFeedTokens replaces existing feed tokens with their hash and prefix, so subscribed calendars keep working
+// The hash must match auth.HashToken: hex encoded SHA-256.
+func hashFeedTokens(tx *sql.Tx) error {
+	tokens, err := tokensByID(tx, "feed_tokens")
+	if err != nil {
+		return err
+	}
+
+	update := driverSQL{
+		sqlite:   "UPDATE feed_tokens SET token_hash = ?, prefix = ? WHERE id = ?",
+		postgres: "UPDATE feed_tokens SET token_hash = $1, prefix = $2 WHERE id = $3",
+	}
+	for id, token := range tokens {
+		sum := sha256.Sum256([]byte(token))
+		prefix := token[:min(len(token), feedTokenPrefixLength)]
+		if _, err := tx.Exec(update.forDriver(), hex.EncodeToString(sum[:]), prefix, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashMemberInviteTokens replaces the tokens of pending member invites with their hash
+// The hash must match auth.HashToken: hex encoded SHA-256.
+func hashMemberInviteTokens(tx *sql.Tx) error {
+	tokens, err := tokensByID(tx, "member_invites")
+	if err != nil {
+		return err
+	}
+
+	update := driverSQL{
+		sqlite:   "UPDATE member_invites SET token_hash = ? WHERE id = ?",
+		postgres: "UPDATE member_invites SET token_hash = $1 WHERE id = $2",
+	}
+	for id, token := range tokens {
+		sum := sha256.Sum256([]byte(token))
+		if _, err := tx.Exec(update.forDriver(), hex.EncodeToString(sum[:]), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tokensByID reads the plaintext tokens of a table, just renamed to token_hash, by row ID
+func tokensByID(tx *sql.Tx, table string) (map[int]string, error) {
+	rows, err := tx.Query("SELECT id, token_hash FROM " + table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var token string
+		if err := rows.Scan(&id, &token); err != nil {
+			return nil, err
+		}
+		tokens[id] = token
+	}
+	return tokens, rows.Err()
+}
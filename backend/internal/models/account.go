@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// PasswordReset pending reset of a forgotten password
+// Only the hash of its token is stored.
+type PasswordReset struct {
+	ID        int
+	UserID    int
+	ExpiresAt time.Time
+}
+
+// AccountExport everything stored about a user, handed out before the account is deleted
+// Workspaces lists all workspaces of the user with their role; the ones deleted
+// together with the account, as nobody else uses them, come with a backup.
+type AccountExport struct {
+	ExportedAt time.Time                `json:"exported_at"`
+	User       User                     `json:"user"`
+	Workspaces []AccountExportWorkspace `json:"workspaces"`
+	Sessions   []Session                `json:"sessions"`
+	TwoFactor  bool                     `json:"two_factor"`
+}
+
+// AccountExportWorkspace workspace of a user in an account export
+type AccountExportWorkspace struct {
+	Workspace
+	Member     *Member     `json:"member,omitempty"`
+	APIKeys    []APIKey    `json:"api_keys"`
+	FeedTokens []FeedToken `json:"feed_tokens"`
+	Backup     *Backup     `json:"backup,omitempty"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// API key scopes
+// A key never grants more than its user's role in the key's workspace.
+const (
+	APIKeyScopeReadOnly      = "read-only"
+	APIKeyScopeScheduleWrite = "schedule-write"
+	APIKeyScopeAdmin         = "admin"
+)
+
+// apiKeyScopeRoles the highest role each scope grants
+var apiKeyScopeRoles = map[string]string{
+	APIKeyScopeReadOnly:      RoleViewer,
+	APIKeyScopeScheduleWrite: RoleScheduler,
+	APIKeyScopeAdmin:         RoleOwner,
+}
+
+// APIKey long-lived key for scripts, bound to a user and a workspace
+// Key is only set when the key is created; just its hash is stored.
+// Prefix is the start of the key, kept to tell keys apart.
+type APIKey struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	WorkspaceID int        `json:"workspace_id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Scope       string     `json:"scope"`
+	Key         string     `json:"key,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// IsValidAPIKeyScope checks if a scope name is valid
+func IsValidAPIKeyScope(scope string) bool {
+	_, ok := apiKeyScopeRoles[scope]
+	return ok
+}
+
+// APIKeyScopeRole returns the highest role a scope grants
+func APIKeyScopeRole(scope string) string {
+	return apiKeyScopeRoles[scope]
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit actions
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionImport  = "import"
+	AuditActionRestore = "restore"
+)
+
+// Audited entities
+const (
+	AuditEntityMember             = "member"
+	AuditEntityShift              = "shift"
+	AuditEntityLeaveDay           = "leave_day"
+	AuditEntityLeaveRequest       = "leave_request"
+	AuditEntitySwapRequest        = "swap_request"
+	AuditEntityLeaveAllowance     = "leave_allowance"
+	AuditEntityUnavailabilityRule = "unavailability_rule"
+	AuditEntityWorkspace          = "workspace"
+	AuditEntityPlanSnapshot       = "plan_snapshot"
+)
+
+// AuditEntry a single change in the append-only audit log
+// Before and After hold the entity as JSON; Before is empty for creations and
+// After for deletions. MemberID is the member the entity belongs to after the
+// change (before it for deletions), 0 when there is none.
+type AuditEntry struct {
+	ID          int             `json:"id"`
+	ActorUserID int             `json:"actor_user_id"`
+	Action      string          `json:"action"`
+	Entity      string          `json:"entity"`
+	EntityID    int             `json:"entity_id,omitempty"`
+	MemberID    int             `json:"member_id,omitempty"`
+	Before      json.RawMessage `json:"before,omitempty"`
+	After       json.RawMessage `json:"after,omitempty"`
+	RequestID   string          `json:"request_id,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// AuditFilter selects audit entries; zero fields don't filter
+// The date range is inclusive and applies to the time of the change.
+type AuditFilter struct {
+	Entity    string
+	MemberID  int
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+// IsValidAuditEntity checks if an entity name can appear in the audit log
+func IsValidAuditEntity(entity string) bool {
+	switch entity {
+	case AuditEntityMember, AuditEntityShift, AuditEntityLeaveDay, AuditEntityLeaveRequest, AuditEntitySwapRequest,
+		AuditEntityLeaveAllowance, AuditEntityUnavailabilityRule, AuditEntityWorkspace, AuditEntityPlanSnapshot:
+		return true
+	}
+	return false
+}
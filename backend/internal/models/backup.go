@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BackupFormat identifies workspace backup archives
+const BackupFormat = "shiftplanner-backup"
+
+// BackupVersion current version of the backup archive format
+// Bump it when the archive layout changes and teach UpgradeBackup to read the old one.
+const BackupVersion = 1
+
+// Backup portable archive of a whole workspace
+// IDs are only meaningful inside the archive and are remapped on restore.
+// Dates use the YYYY-MM-DD format.
+type Backup struct {
+	Format              string                 `json:"format"`
+	Version             int                    `json:"version"`
+	ExportedAt          time.Time              `json:"exported_at"`
+	Members             []BackupMember         `json:"members"`
+	Shifts              []BackupShift          `json:"shifts"`
+	LeaveRequests       []BackupLeaveRequest   `json:"leave_requests"`
+	LeaveDays           []BackupLeaveDay       `json:"leave_days"`
+	LeaveAllowances     []BackupLeaveAllowance `json:"leave_allowances"`
+	UnavailabilityRules []BackupUnavailability `json:"unavailability_rules"`
+}
+
+// BackupMember member in a backup archive, including the hidden shift counters
+type BackupMember struct {
+	ID                 int    `json:"id"`
+	Name               string `json:"name"`
+	HiddenNormalShifts int    `json:"hidden_normal_shifts"`
+	HiddenLongShifts   int    `json:"hidden_long_shifts"`
+}
+
+// BackupShift shift in a backup archive
+type BackupShift struct {
+	MemberID    int    `json:"member_id"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date"`
+	IsLongShift bool   `json:"is_long_shift"`
+	Sequence    int    `json:"sequence"`
+}
+
+// BackupLeaveRequest leave request in a backup archive
+type BackupLeaveRequest struct {
+	ID        int    `json:"id"`
+	MemberID  int    `json:"member_id"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	LeaveType string `json:"leave_type"`
+	Status    string `json:"status"`
+	Note      string `json:"note,omitempty"`
+}
+
+// BackupLeaveDay leave day in a backup archive
+type BackupLeaveDay struct {
+	MemberID       int    `json:"member_id"`
+	LeaveDate      string `json:"leave_date"`
+	LeaveType      string `json:"leave_type"`
+	LeaveRequestID int    `json:"leave_request_id,omitempty"`
+}
+
+// BackupLeaveAllowance leave allowance in a backup archive
+type BackupLeaveAllowance struct {
+	MemberID  int    `json:"member_id"`
+	Year      int    `json:"year"`
+	LeaveType string `json:"leave_type"`
+	Days      int    `json:"days"`
+}
+
+// BackupUnavailability unavailability rule in a backup archive
+type BackupUnavailability struct {
+	MemberID  int    `json:"member_id"`
+	Frequency string `json:"frequency"`
+	Weekdays  []int  `json:"weekdays"`
+	Nth       int    `json:"nth,omitempty"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+// RestoreResult number of records restored from a backup archive
+type RestoreResult struct {
+	Members             int `json:"members"`
+	Shifts              int `json:"shifts"`
+	LeaveRequests       int `json:"leave_requests"`
+	LeaveDays           int `json:"leave_days"`
+	LeaveAllowances     int `json:"leave_allowances"`
+	UnavailabilityRules int `json:"unavailability_rules"`
+}
+
+var (
+	// ErrInvalidBackup is wrapped by errors about malformed backup archives
+	ErrInvalidBackup = errors.New("invalid backup archive")
+	// ErrBackupTooNew is returned for archives written by a newer version
+	ErrBackupTooNew = errors.New("backup archive was created by a newer version")
+)
+
+// UpgradeBackup checks that b is a backup archive this version can read and
+// brings archives of older versions up to BackupVersion
+func UpgradeBackup(b *Backup) error {
+	if b.Format != BackupFormat {
+		return fmt.Errorf("%w: unknown format %q", ErrInvalidBackup, b.Format)
+	}
+	if b.Version < 1 {
+		return fmt.Errorf("%w: version %d", ErrInvalidBackup, b.Version)
+	}
+	if b.Version > BackupVersion {
+		return ErrBackupTooNew
+	}
+
+	// Version 1 is the current layout; fill in defaults for optional fields
+	for i := range b.LeaveDays {
+		if b.LeaveDays[i].LeaveType == "" {
+			b.LeaveDays[i].LeaveType = LeaveTypeAnnual
+		}
+	}
+	for i := range b.LeaveRequests {
+		if b.LeaveRequests[i].LeaveType == "" {
+			b.LeaveRequests[i].LeaveType = LeaveTypeAnnual
+		}
+		if b.LeaveRequests[i].Status == "" {
+			b.LeaveRequests[i].Status = LeaveStatusPending
+		}
+	}
+
+	b.Version = BackupVersion
+	return nil
+}
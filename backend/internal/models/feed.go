@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// FeedToken read-only token for calendar feeds
+// Feed tokens are managed separately from session tokens and never expire.
+// Token is only set when the token is created; just its hash is stored.
+// Prefix is the start of the token, kept to tell tokens apart.
+type FeedToken struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Prefix    string    `json:"prefix"`
+	Token     string    `json:"token,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -106,3 +106,21 @@ func WillBeLongShift(date time.Time) bool {
 	nextDay := date.AddDate(0, 0, 1)
 	return IsHoliday(nextDay) || IsWeekend(nextDay)
 }
+
+// LongShiftEndDate returns the last day of a long shift starting on the specified date
+// A long shift continues until the day before the next working day
+func LongShiftEndDate(date time.Time) time.Time {
+	return GetNextWorkingDay(date).AddDate(0, 0, -1)
+}
+
+// CountWorkingDays counts working days between the specified dates (inclusive)
+// Weekends and public holidays are not counted
+func CountWorkingDays(startDate, endDate time.Time) int {
+	count := 0
+	for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
+		if IsWorkingDay(d) {
+			count++
+		}
+	}
+	return count
+}
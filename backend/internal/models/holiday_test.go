@@ -169,3 +169,31 @@ func TestWillBeLongShift(t *testing.T) {
 		})
 	}
 }
+
+func TestLongShiftEndDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		date     time.Time
+		expected time.Time
+	}{
+		{
+			name:     "Friday runs until Sunday",
+			date:     time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Friday before Eid al-Fitr runs until the end of the holiday",
+			date:     time.Date(2025, 3, 28, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := LongShiftEndDate(tt.date)
+			if !result.Equal(tt.expected) {
+				t.Errorf("LongShiftEndDate(%v) = %v, want %v", tt.date, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+)
+
+// Import kinds
+const (
+	ImportKindShifts = "shifts"
+)
+
+// Import actions reported for each imported row
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+)
+
+// ImportRecord an applied import, identified by the SHA-256 hash of the uploaded file
+type ImportRecord struct {
+	ID             int       `json:"id"`
+	Kind           string    `json:"kind"`
+	ContentHash    string    `json:"content_hash"`
+	Filename       string    `json:"filename"`
+	MembersCreated int       `json:"members_created"`
+	ShiftsCreated  int       `json:"shifts_created"`
+	ShiftsUpdated  int       `json:"shifts_updated"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ShiftImportRow a validated row of a shift import file
+// HasRange is set when the row defines its end date (given in the file or
+// expanded from a long shift); otherwise an existing shift keeps its range.
+type ShiftImportRow struct {
+	Row         int
+	Name        string
+	StartDate   time.Time
+	EndDate     time.Time
+	IsLongShift bool
+	HasRange    bool
+}
+
+// ShiftImportChange what an import did (or would do) for a single row
+type ShiftImportChange struct {
+	Row                int       `json:"row"`
+	StartDate          time.Time `json:"start_date"`
+	EndDate            time.Time `json:"end_date"`
+	MemberName         string    `json:"member_name"`
+	PreviousMemberName string    `json:"previous_member_name,omitempty"`
+	IsLongShift        bool      `json:"is_long_shift"`
+	NewMember          bool      `json:"new_member,omitempty"`
+	ShiftsReplaced     int       `json:"shifts_replaced,omitempty"`
+	Action             string    `json:"action"`
+}
+
+// ShiftImportResult summary of a shift import
+type ShiftImportResult struct {
+	MembersCreated  int                 `json:"members_created"`
+	NewMembers      []string            `json:"new_members"`
+	ShiftsCreated   int                 `json:"shifts_created"`
+	ShiftsUpdated   int                 `json:"shifts_updated"`
+	ShiftsUnchanged int                 `json:"shifts_unchanged"`
+	ShiftsReplaced  int                 `json:"shifts_replaced"`
+	Changes         []ShiftImportChange `json:"changes"`
+}
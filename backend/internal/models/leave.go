@@ -4,12 +4,76 @@ import (
 	"time"
 )
 
+// Leave types
+const (
+	LeaveTypeAnnual = "annual"
+	LeaveTypeSick   = "sick"
+	LeaveTypeUnpaid = "unpaid"
+	LeaveTypeOther  = "other"
+)
+
+// Leave request statuses
+const (
+	LeaveStatusPending  = "pending"
+	LeaveStatusApproved = "approved"
+	LeaveStatusRejected = "rejected"
+)
+
 // LeaveDay leave day model
+// Leave days expanded from an unavailability rule have no ID and carry the RuleID
 type LeaveDay struct {
-	ID        int       `json:"id"`
-	MemberID  int       `json:"member_id"`
-	MemberName string   `json:"member_name,omitempty"`
-	LeaveDate time.Time `json:"leave_date"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         int       `json:"id"`
+	MemberID   int       `json:"member_id"`
+	MemberName string    `json:"member_name,omitempty"`
+	LeaveDate  time.Time `json:"leave_date"`
+	LeaveType  string    `json:"leave_type"`
+	RuleID     int       `json:"rule_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// LeaveRequest leave request model
+// Leave days are only created once a request is approved
+type LeaveRequest struct {
+	ID         int        `json:"id"`
+	MemberID   int        `json:"member_id"`
+	MemberName string     `json:"member_name,omitempty"`
+	StartDate  time.Time  `json:"start_date"`
+	EndDate    time.Time  `json:"end_date"`
+	LeaveType  string     `json:"leave_type"`
+	Status     string     `json:"status"`
+	Note       string     `json:"note,omitempty"`
+	DecidedAt  *time.Time `json:"decided_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
 }
 
+// LeaveAllowance yearly leave allowance for a member and leave type
+type LeaveAllowance struct {
+	ID         int    `json:"id"`
+	MemberID   int    `json:"member_id"`
+	MemberName string `json:"member_name,omitempty"`
+	Year       int    `json:"year"`
+	LeaveType  string `json:"leave_type"`
+	Days       int    `json:"days"`
+}
+
+// LeaveBalance leave balance of a member for a year and leave type
+// Used, pending and remaining days count working days only
+type LeaveBalance struct {
+	MemberID   int    `json:"member_id"`
+	MemberName string `json:"member_name,omitempty"`
+	Year       int    `json:"year"`
+	LeaveType  string `json:"leave_type"`
+	Allowance  int    `json:"allowance"`
+	Used       int    `json:"used"`
+	Pending    int    `json:"pending"`
+	Remaining  int    `json:"remaining"`
+}
+
+// IsValidLeaveType checks if the specified leave type is known
+func IsValidLeaveType(leaveType string) bool {
+	switch leaveType {
+	case LeaveTypeAnnual, LeaveTypeSick, LeaveTypeUnpaid, LeaveTypeOther:
+		return true
+	}
+	return false
+}
@@ -5,9 +5,18 @@ import (
 )
 
 // Member team member model
+// UserID is the login account linked to the member, 0 if none.
 type Member struct {
 	ID        int       `json:"id"`
 	Name      string    `json:"name"`
+	UserID    int       `json:"user_id,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// MemberInvite single-use token that links the account accepting it to a member
+// Token is only set when the invite is created; just its hash is stored.
+type MemberInvite struct {
+	MemberID  int       `json:"member_id"`
+	Token     string    `json:"token,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -4,6 +4,12 @@ import (
 	"time"
 )
 
+// Shift types used in import files
+const (
+	ShiftTypeNormal = "normal"
+	ShiftTypeLong   = "long"
+)
+
 // Shift shift model
 type Shift struct {
 	ID          int       `json:"id"`
@@ -12,6 +18,7 @@ type Shift struct {
 	StartDate   time.Time `json:"start_date"`
 	EndDate     time.Time `json:"end_date"`
 	IsLongShift bool      `json:"is_long_shift"`
+	Sequence    int       `json:"sequence"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
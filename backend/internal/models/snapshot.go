@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Reasons a plan snapshot was taken
+const (
+	SnapshotReasonGenerate = "generate"
+	SnapshotReasonClear    = "clear"
+	SnapshotReasonRestore  = "restore"
+)
+
+// PlanSnapshot shifts and hidden shift counters saved before a bulk change to the plan
+// StartDate and EndDate (YYYY-MM-DD) are the range of the change; a snapshot
+// without a range covers all shifts. Counters hold every member of the workspace.
+type PlanSnapshot struct {
+	ID         int               `json:"id"`
+	Reason     string            `json:"reason"`
+	StartDate  string            `json:"start_date,omitempty"`
+	EndDate    string            `json:"end_date,omitempty"`
+	ShiftCount int               `json:"shift_count"`
+	Shifts     []BackupShift     `json:"shifts,omitempty"`
+	Counters   []SnapshotCounter `json:"counters,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// SnapshotCounter hidden shift counters of a member in a plan snapshot
+type SnapshotCounter struct {
+	MemberID           int `json:"member_id"`
+	HiddenNormalShifts int `json:"hidden_normal_shifts"`
+	HiddenLongShifts   int `json:"hidden_long_shifts"`
+}
+
+// SnapshotRestoreResult outcome of restoring a plan snapshot
+// Shifts of members deleted since the snapshot, or on days that are covered by
+// a shift outside the snapshot range, are skipped.
+type SnapshotRestoreResult struct {
+	SnapshotID    int `json:"snapshot_id"`
+	Shifts        int `json:"shifts"`
+	SkippedShifts int `json:"skipped_shifts"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// SwapRequest request to swap the members of two shifts
+// Swap requests share the statuses of leave requests, and the shifts only
+// change hands once a scheduler approves the request. MemberID and
+// WithMemberID are the members of the shifts when the request was made.
+type SwapRequest struct {
+	ID             int        `json:"id"`
+	ShiftID        int        `json:"shift_id"`
+	MemberID       int        `json:"member_id"`
+	MemberName     string     `json:"member_name,omitempty"`
+	WithShiftID    int        `json:"with_shift_id"`
+	WithMemberID   int        `json:"with_member_id"`
+	WithMemberName string     `json:"with_member_name,omitempty"`
+	Status         string     `json:"status"`
+	Note           string     `json:"note,omitempty"`
+	DecidedAt      *time.Time `json:"decided_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
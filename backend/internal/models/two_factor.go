@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// TwoFactor TOTP settings of a user
+// The secret is stored when enrollment starts; two-factor authentication is on once EnabledAt is set.
+type TwoFactor struct {
+	UserID        int        `json:"-"`
+	Secret        string     `json:"-"`
+	LastStep      int64      `json:"-"`
+	RecoveryCodes int        `json:"recovery_codes"`
+	EnabledAt     *time.Time `json:"enabled_at"`
+}
+
+// Enabled reports whether logins need a second factor
+func (t *TwoFactor) Enabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
+
+// LoginChallenge login that passed the password check and waits for a second factor
+type LoginChallenge struct {
+	ID        int
+	UserID    int
+	Failures  int
+	ExpiresAt time.Time
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"time"
+)
+
+// Unavailability rule frequencies
+const (
+	FrequencyWeekly   = "weekly"
+	FrequencyBiweekly = "biweekly"
+	FrequencyMonthly  = "monthly"
+)
+
+// LeaveTypeUnavailable is the leave type of leave days expanded from unavailability rules
+const LeaveTypeUnavailable = "unavailable"
+
+// UnavailabilityRule recurring unavailability of a member
+//
+// Weekly rules repeat on the given weekdays every week, biweekly rules every
+// second week counted from the week of StartDate. Monthly rules repeat on the
+// Nth occurrence of the given weekdays in each month (1-5, or -1 for the last).
+// A nil EndDate means the rule never ends.
+type UnavailabilityRule struct {
+	ID         int            `json:"id"`
+	MemberID   int            `json:"member_id"`
+	MemberName string         `json:"member_name,omitempty"`
+	Frequency  string         `json:"frequency"`
+	Weekdays   []time.Weekday `json:"weekdays"`
+	Nth        int            `json:"nth,omitempty"`
+	StartDate  time.Time      `json:"start_date"`
+	EndDate    *time.Time     `json:"end_date,omitempty"`
+	Note       string         `json:"note,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// IsValidFrequency checks if the specified rule frequency is known
+func IsValidFrequency(frequency string) bool {
+	switch frequency {
+	case FrequencyWeekly, FrequencyBiweekly, FrequencyMonthly:
+		return true
+	}
+	return false
+}
+
+// Occurrences returns the dates the rule applies to between startDate and endDate (inclusive)
+func (r UnavailabilityRule) Occurrences(startDate, endDate time.Time) []time.Time {
+	from := startDate
+	if from.Before(r.StartDate) {
+		from = r.StartDate
+	}
+	to := endDate
+	if r.EndDate != nil && r.EndDate.Before(to) {
+		to = *r.EndDate
+	}
+
+	var dates []time.Time
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if r.Matches(d) {
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
+
+// Matches checks if the rule applies to the specified date
+func (r UnavailabilityRule) Matches(date time.Time) bool {
+	if date.Before(r.StartDate) || (r.EndDate != nil && date.After(*r.EndDate)) {
+		return false
+	}
+
+	onWeekday := false
+	for _, wd := range r.Weekdays {
+		if date.Weekday() == wd {
+			onWeekday = true
+			break
+		}
+	}
+	if !onWeekday {
+		return false
+	}
+
+	switch r.Frequency {
+	case FrequencyWeekly:
+		return true
+	case FrequencyBiweekly:
+		weeks := int(weekStart(date).Sub(weekStart(r.StartDate)).Hours() / 24 / 7)
+		return weeks%2 == 0
+	case FrequencyMonthly:
+		if r.Nth == -1 {
+			// Last occurrence: the same weekday next week is in another month
+			return date.AddDate(0, 0, 7).Month() != date.Month()
+		}
+		return (date.Day()-1)/7+1 == r.Nth
+	}
+	return false
+}
+
+// weekStart returns the Monday of the week containing the specified date
+func weekStart(date time.Time) time.Time {
+	offset := (int(date.Weekday()) + 6) % 7
+	d := date.AddDate(0, 0, -offset)
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnavailabilityRuleOccurrences(t *testing.T) {
+	endDate := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		rule     UnavailabilityRule
+		expected []string
+	}{
+		{
+			name: "Weekly on Wednesday",
+			rule: UnavailabilityRule{
+				Frequency: FrequencyWeekly,
+				Weekdays:  []time.Weekday{time.Wednesday},
+				StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			expected: []string{"2025-01-01", "2025-01-08", "2025-01-15", "2025-01-22", "2025-01-29"},
+		},
+		{
+			name: "Every second Tuesday",
+			rule: UnavailabilityRule{
+				Frequency: FrequencyBiweekly,
+				Weekdays:  []time.Weekday{time.Tuesday},
+				StartDate: time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC),
+			},
+			expected: []string{"2025-01-07", "2025-01-21"},
+		},
+		{
+			name: "First Monday of the month",
+			rule: UnavailabilityRule{
+				Frequency: FrequencyMonthly,
+				Weekdays:  []time.Weekday{time.Monday},
+				Nth:       1,
+				StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+				EndDate:   &endDate,
+			},
+			expected: []string{"2025-01-06"},
+		},
+		{
+			name: "Last Friday of the month",
+			rule: UnavailabilityRule{
+				Frequency: FrequencyMonthly,
+				Weekdays:  []time.Weekday{time.Friday},
+				Nth:       -1,
+				StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			expected: []string{"2025-01-31"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.rule.Occurrences(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC))
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Occurrences count mismatch: got %d, want %d (%v)", len(result), len(tt.expected), result)
+			}
+			for i, d := range result {
+				if d.Format("2006-01-02") != tt.expected[i] {
+					t.Errorf("Occurrence %d mismatch: got %s, want %s", i, d.Format("2006-01-02"), tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestUnavailabilityRuleEndDate(t *testing.T) {
+	endDate := time.Date(2025, 1, 14, 0, 0, 0, 0, time.UTC)
+	rule := UnavailabilityRule{
+		Frequency: FrequencyWeekly,
+		Weekdays:  []time.Weekday{time.Tuesday},
+		StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   &endDate,
+	}
+
+	result := rule.Occurrences(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC))
+	if len(result) != 2 {
+		t.Errorf("Occurrences count mismatch: got %d, want 2", len(result))
+	}
+}
@@ -5,18 +5,26 @@ import (
 )
 
 // User represents a user model
+// Email is where password reset tokens are sent, empty if the user set none.
 type User struct {
 	ID        int       `json:"id"`
 	Username  string    `json:"username"`
+	Email     string    `json:"email,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
 // Session represents a session model
+// Token is only set when the session is created; just its hash is stored.
+// Current marks the session of the request when sessions are listed.
 type Session struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	Token      string    `json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	Current    bool      `json:"current"`
 }
 
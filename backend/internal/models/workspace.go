@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// Workspace roles, from most to least privileged
+// Owners manage the workspace and its users, schedulers plan and edit the
+// schedule, members view it and request leave, viewers only view it.
+const (
+	RoleOwner     = "owner"
+	RoleScheduler = "scheduler"
+	RoleMember    = "member"
+	RoleViewer    = "viewer"
+)
+
+// roleRanks orders the roles; a higher rank includes the permissions of lower ones
+var roleRanks = map[string]int{
+	RoleViewer:    1,
+	RoleMember:    2,
+	RoleScheduler: 3,
+	RoleOwner:     4,
+}
+
+// Workspace team that shares one plan
+// Role is the role of the user the workspace was loaded for. With
+// RequireTwoFactor, schedulers and owners need two-factor authentication.
+type Workspace struct {
+	ID               int       `json:"id"`
+	Name             string    `json:"name"`
+	Role             string    `json:"role,omitempty"`
+	RequireTwoFactor bool      `json:"require_two_factor"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// WorkspaceUser user with access to a workspace
+type WorkspaceUser struct {
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WorkspaceInvite invitation to join a workspace with a role
+// Token is only set when the invite is created; just its hash is stored.
+type WorkspaceInvite struct {
+	ID        int       `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Token     string    `json:"token,omitempty"`
+	InvitedBy int       `json:"invited_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsValidRole checks if a role name is valid
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAtLeast reports whether role grants everything required grants
+func RoleAtLeast(role, required string) bool {
+	return IsValidRole(role) && roleRanks[role] >= roleRanks[required]
+}
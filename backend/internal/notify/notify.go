@@ -0,0 +1,50 @@
+// Package notify delivers notifications, such as password reset tokens, to users
+package notify
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Message is a plain text notification to one recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// validate rejects messages that would break out of their headers
+func (m Message) validate() error {
+	if m.To == "" {
+		return fmt.Errorf("message has no recipient")
+	}
+	if strings.ContainsAny(m.To+m.Subject, "\r\n") {
+		return fmt.Errorf("line break in message header")
+	}
+	return nil
+}
+
+// Sender delivers messages
+type Sender interface {
+	Send(msg Message) error
+}
+
+// LogSender writes messages to the log instead of delivering them, for local development
+type LogSender struct {
+	// Logger defaults to the standard logger
+	Logger *log.Logger
+}
+
+// Send logs the message
+func (s LogSender) Send(msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	logger := s.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf("Notification to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
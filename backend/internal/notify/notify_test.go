@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogSender(t *testing.T) {
+	var buf bytes.Buffer
+	sender := LogSender{Logger: log.New(&buf, "", 0)}
+
+	if err := sender.Send(Message{To: "alice@example.com", Subject: "Hello", Body: "Token: abc"}); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	if !strings.Contains(buf.String(), "alice@example.com") || !strings.Contains(buf.String(), "Token: abc") {
+		t.Errorf("Message not logged: %q", buf.String())
+	}
+
+	if err := sender.Send(Message{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Hello"}); err == nil {
+		t.Error("Expected an error for a line break in a header")
+	}
+}
+
+func TestSMTPSender_Message(t *testing.T) {
+	sender := &SMTPSender{Addr: "localhost:25", From: "planner@example.com"}
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	message := string(sender.message(Message{To: "alice@example.com", Subject: "Passwort zurücksetzen", Body: "Line 1\nLine 2"}, now))
+
+	for _, want := range []string{
+		"From: planner@example.com\r\n",
+		"To: alice@example.com\r\n",
+		"Subject: =?utf-8?q?Passwort_zur=C3=BCcksetzen?=\r\n",
+		"Date: Thu, 02 Jan 2025 03:04:05 +0000\r\n",
+		"\r\n\r\nLine 1\r\nLine 2",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("Message lacks %q:\n%s", want, message)
+		}
+	}
+}
+
+func TestSenderFromEnv(t *testing.T) {
+	t.Setenv("SMTP_ADDR", "")
+	sender, err := SenderFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to read settings: %v", err)
+	}
+	if _, ok := sender.(LogSender); !ok {
+		t.Errorf("Expected a LogSender without SMTP_ADDR, got %T", sender)
+	}
+
+	t.Setenv("SMTP_ADDR", "mail.example.com:587")
+	if _, err := SenderFromEnv(); err == nil {
+		t.Error("Expected an error without SMTP_FROM")
+	}
+
+	t.Setenv("SMTP_FROM", "planner@example.com")
+	sender, err = SenderFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to read settings: %v", err)
+	}
+	if smtpSender, ok := sender.(*SMTPSender); !ok || smtpSender.Addr != "mail.example.com:587" {
+		t.Errorf("Expected an SMTPSender, got %+v", sender)
+	}
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"time"
+)
+
+// SMTPSender delivers messages as email through an SMTP server
+// The connection is upgraded with STARTTLS when the server offers it.
+type SMTPSender struct {
+	// Addr is the server's host:port
+	Addr string
+	// Username and Password authenticate with PLAIN auth; without a username no auth is used
+	Username string
+	Password string
+	From     string
+}
+
+// SenderFromEnv reads the SMTP settings from SMTP_* environment variables
+// Without SMTP_ADDR messages are only logged.
+func SenderFromEnv() (Sender, error) {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		return LogSender{}, nil
+	}
+
+	sender := &SMTPSender{
+		Addr:     addr,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("SMTP_ADDR must be host:port: %w", err)
+	}
+	if sender.From == "" {
+		return nil, fmt.Errorf("SMTP_FROM is required with SMTP_ADDR")
+	}
+	return sender, nil
+}
+
+// Send sends the message as a plain text email
+func (s *SMTPSender) Send(msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	return smtp.SendMail(s.Addr, auth, s.From, []string{msg.To}, s.message(msg, time.Now()))
+}
+
+// message formats msg as an email
+func (s *SMTPSender) message(msg Message, now time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.Write(bytes.ReplaceAll([]byte(msg.Body), []byte("\n"), []byte("\r\n")))
+	return b.Bytes()
+}
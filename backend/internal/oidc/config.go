@@ -0,0 +1,118 @@
+package oidc
+
+import (
+	"fmt"
+	"os"
+	"shiftplanner/backend/internal/models"
+	"strconv"
+	"strings"
+)
+
+// Config settings of the OpenID Connect provider users log in with
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback URL registered at the provider
+	RedirectURL string
+	// AllowedDomains limits logins to these email domains; empty allows all
+	AllowedDomains []string
+	// GroupsClaim names the ID token claim listing the user's groups
+	GroupsClaim string
+	GroupRoles  []GroupRole
+	// PostLoginURL, when set, is where the browser is sent with the session token after login
+	PostLoginURL string
+}
+
+// GroupRole gives members of a provider group a role in a workspace
+type GroupRole struct {
+	Group       string
+	WorkspaceID int
+	Role        string
+}
+
+// ConfigFromEnv reads the provider settings from OIDC_* environment variables
+// It returns nil when OIDC_ISSUER isn't set, which leaves single sign-on disabled.
+func ConfigFromEnv() (*Config, error) {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil, nil
+	}
+
+	config := &Config{
+		Issuer:       issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		GroupsClaim:  os.Getenv("OIDC_GROUPS_CLAIM"),
+		PostLoginURL: os.Getenv("OIDC_POST_LOGIN_URL"),
+	}
+	if config.ClientID == "" || config.RedirectURL == "" {
+		return nil, fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER")
+	}
+
+	for _, domain := range strings.Split(os.Getenv("OIDC_ALLOWED_DOMAINS"), ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			config.AllowedDomains = append(config.AllowedDomains, domain)
+		}
+	}
+
+	groupRoles, err := ParseGroupRoles(os.Getenv("OIDC_GROUP_ROLES"))
+	if err != nil {
+		return nil, err
+	}
+	config.GroupRoles = groupRoles
+
+	return config, nil
+}
+
+// ParseGroupRoles parses a comma-separated list of group=workspaceID:role mappings
+func ParseGroupRoles(value string) ([]GroupRole, error) {
+	var groupRoles []GroupRole
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		group, target, ok := strings.Cut(entry, "=")
+		workspace, role, ok2 := strings.Cut(target, ":")
+		workspaceID, err := strconv.Atoi(workspace)
+		if !ok || !ok2 || group == "" || err != nil || !models.IsValidRole(role) {
+			return nil, fmt.Errorf("invalid group role mapping %q (use group=workspaceID:role)", entry)
+		}
+		groupRoles = append(groupRoles, GroupRole{Group: group, WorkspaceID: workspaceID, Role: role})
+	}
+	return groupRoles, nil
+}
+
+// EmailAllowed reports whether an email address is in one of the allowed domains
+func (c *Config) EmailAllowed(email string) bool {
+	if len(c.AllowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, allowed := range c.AllowedDomains {
+		if strings.EqualFold(domain, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkspaceRoles maps the groups of a user to the highest role per workspace
+func (c *Config) WorkspaceRoles(groups []string) map[int]string {
+	roles := make(map[int]string)
+	for _, groupRole := range c.GroupRoles {
+		for _, group := range groups {
+			if group == groupRole.Group && !models.RoleAtLeast(roles[groupRole.WorkspaceID], groupRole.Role) {
+				roles[groupRole.WorkspaceID] = groupRole.Role
+			}
+		}
+	}
+	return roles
+}
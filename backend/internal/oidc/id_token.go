@@ -0,0 +1,177 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// clockSkew tolerance for the time claims of ID tokens
+const clockSkew = time.Minute
+
+// verifyIDToken checks the signature and standard claims of an ID token
+func (p *Provider) verifyIDToken(ctx context.Context, rawToken, nonce string, now time.Time) (*Claims, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidIDToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidIDToken, header.Alg)
+	}
+
+	key, err := p.signingKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidIDToken)
+	}
+
+	var claims map[string]json.RawMessage
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	var standard struct {
+		Issuer            string          `json:"iss"`
+		Subject           string          `json:"sub"`
+		Audience          json.RawMessage `json:"aud"`
+		Expiry            int64           `json:"exp"`
+		IssuedAt          int64           `json:"iat"`
+		Nonce             string          `json:"nonce"`
+		Email             string          `json:"email"`
+		EmailVerified     interface{}     `json:"email_verified"`
+		Name              string          `json:"name"`
+		PreferredUsername string          `json:"preferred_username"`
+	}
+	if err := decodeSegment(parts[1], &standard); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	switch {
+	case standard.Issuer != p.config.Issuer:
+		return nil, fmt.Errorf("%w: wrong issuer", ErrInvalidIDToken)
+	case !hasAudience(standard.Audience, p.config.ClientID):
+		return nil, fmt.Errorf("%w: wrong audience", ErrInvalidIDToken)
+	case standard.Subject == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	case now.After(time.Unix(standard.Expiry, 0).Add(clockSkew)):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	case standard.IssuedAt != 0 && time.Unix(standard.IssuedAt, 0).After(now.Add(clockSkew)):
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidIDToken)
+	case standard.Nonce != nonce:
+		return nil, fmt.Errorf("%w: wrong nonce", ErrInvalidIDToken)
+	}
+
+	result := &Claims{
+		Subject:           standard.Subject,
+		Email:             standard.Email,
+		Name:              standard.Name,
+		PreferredUsername: standard.PreferredUsername,
+	}
+	// Some providers send email_verified as a string
+	switch verified := standard.EmailVerified.(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+	if raw, ok := claims[p.config.GroupsClaim]; ok {
+		if err := json.Unmarshal(raw, &result.Groups); err != nil {
+			return nil, fmt.Errorf("%w: %s claim is not a list of strings", ErrInvalidIDToken, p.config.GroupsClaim)
+		}
+	}
+	return result, nil
+}
+
+// signingKey returns the provider key with the given ID
+// The key set is fetched again when the ID is unknown, so key rotation is picked up.
+func (p *Provider) signingKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := getJSON(ctx, p.client, p.jwksURI, &jwks); err != nil {
+		return nil, fmt.Errorf("oidc keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidIDToken, kid)
+}
+
+// hasAudience reports whether an aud claim, a string or a list, contains the client ID
+func hasAudience(raw json.RawMessage, clientID string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == clientID
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return false
+	}
+	for _, audience := range list {
+		if audience == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a token
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
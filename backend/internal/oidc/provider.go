@@ -0,0 +1,248 @@
+// Package oidc logs users in through an OpenID Connect provider
+// It implements the authorization code flow with PKCE and verifies RS256 signed ID tokens.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginExpiry how long a started login can be finished
+const LoginExpiry = 10 * time.Minute
+
+// maxPendingLogins caps the started logins kept in memory
+// The login endpoint is public, so without a cap every request would grow the map until the entries expire.
+const maxPendingLogins = 10000
+
+var (
+	// ErrUnknownLogin is returned when the state of a callback doesn't match a started login
+	ErrUnknownLogin = errors.New("unknown or expired login")
+	// ErrTooManyLogins is returned when too many logins are waiting for the provider's callback
+	ErrTooManyLogins = errors.New("too many pending logins")
+	// ErrInvalidIDToken is returned when the ID token from the provider doesn't verify
+	ErrInvalidIDToken = errors.New("invalid ID token")
+)
+
+// Claims identity of a user as asserted by the provider
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Groups            []string
+}
+
+// Provider talks to a discovered OpenID Connect provider
+// Started logins are kept in memory until they are finished or expire, at most maxPendingLogins at a time.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	authorizationEndpoint string
+	tokenEndpoint         string
+	jwksURI               string
+
+	mu     sync.Mutex
+	keys   map[string]*rsa.PublicKey
+	logins map[string]pendingLogin
+}
+
+// pendingLogin login waiting for the provider's callback
+type pendingLogin struct {
+	codeVerifier string
+	nonce        string
+	expiresAt    time.Time
+}
+
+// NewProvider reads the discovery document of the configured issuer
+func NewProvider(ctx context.Context, config Config, client *http.Client) (*Provider, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+
+	var discovery struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	discoveryURL := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, client, discoveryURL, &discovery); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if discovery.Issuer != config.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q doesn't match %q", discovery.Issuer, config.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: missing endpoints")
+	}
+
+	return &Provider{
+		config:                config,
+		client:                client,
+		authorizationEndpoint: discovery.AuthorizationEndpoint,
+		tokenEndpoint:         discovery.TokenEndpoint,
+		jwksURI:               discovery.JWKSURI,
+		keys:                  make(map[string]*rsa.PublicKey),
+		logins:                make(map[string]pendingLogin),
+	}, nil
+}
+
+// Config returns the settings of the provider
+func (p *Provider) Config() *Config {
+	return &p.config
+}
+
+// StartLogin begins a login and returns the provider URL to send the browser to
+// The returned state must be bound to the browser (e.g. in a cookie) and passed to FinishLogin,
+// so a callback started in another browser is refused.
+func (p *Provider) StartLogin() (authURL, state string, err error) {
+	state, err = randomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	for key, login := range p.logins {
+		if now.After(login.expiresAt) {
+			delete(p.logins, key)
+		}
+	}
+	if len(p.logins) >= maxPendingLogins {
+		p.mu.Unlock()
+		return "", "", ErrTooManyLogins
+	}
+	p.logins[state] = pendingLogin{codeVerifier: codeVerifier, nonce: nonce, expiresAt: now.Add(LoginExpiry)}
+	p.mu.Unlock()
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {"openid email profile"},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(p.authorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.authorizationEndpoint + separator + query.Encode(), state, nil
+}
+
+// FinishLogin exchanges the code of a callback and returns the verified claims
+// boundState is the state StartLogin returned to the browser; it must match the callback's state.
+// Each state can be used once.
+func (p *Provider) FinishLogin(ctx context.Context, boundState, state, code string) (*Claims, error) {
+	if state == "" || subtle.ConstantTimeCompare([]byte(boundState), []byte(state)) != 1 {
+		return nil, ErrUnknownLogin
+	}
+
+	p.mu.Lock()
+	login, ok := p.logins[state]
+	delete(p.logins, state)
+	p.mu.Unlock()
+	if !ok || time.Now().After(login.expiresAt) {
+		return nil, ErrUnknownLogin
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"code_verifier": {login.codeVerifier},
+	}
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc token exchange: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("oidc token exchange: status %d: %s", resp.StatusCode, body)
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("oidc token exchange: %w", err)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("oidc token exchange: no id_token in response")
+	}
+
+	return p.verifyIDToken(ctx, token.IDToken, login.nonce, time.Now())
+}
+
+// CodeChallenge derives the S256 PKCE challenge of a code verifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// randomString returns 32 random bytes, URL-safe encoded
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// getJSON fetches a URL and decodes its JSON body
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,153 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/oidc/oidctest"
+	"testing"
+	"time"
+)
+
+func setupTestProvider(t *testing.T) (*Provider, *oidctest.Issuer) {
+	issuer := oidctest.NewIssuer(t, "shiftplanner", "secret")
+	provider, err := NewProvider(context.Background(), Config{
+		Issuer:       issuer.URL,
+		ClientID:     "shiftplanner",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8080/api/auth/oidc/callback",
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	return provider, issuer
+}
+
+// login runs a whole login against the issuer
+func login(t *testing.T, provider *Provider, issuer *oidctest.Issuer) (*Claims, error) {
+	authURL, state, err := provider.StartLogin()
+	if err != nil {
+		t.Fatalf("Failed to start login: %v", err)
+	}
+	callback, err := issuer.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Failed to authorize: %v", err)
+	}
+	return provider.FinishLogin(context.Background(), state, callback.Query().Get("state"), callback.Query().Get("code"))
+}
+
+func TestLogin(t *testing.T) {
+	provider, issuer := setupTestProvider(t)
+	issuer.SetClaims(map[string]interface{}{
+		"sub":            "alice-id",
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"groups":         []string{"planners"},
+	})
+
+	claims, err := login(t, provider, issuer)
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	if claims.Subject != "alice-id" || claims.Email != "alice@example.com" || !claims.EmailVerified ||
+		len(claims.Groups) != 1 || claims.Groups[0] != "planners" {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+}
+
+func TestLogin_StateIsSingleUse(t *testing.T) {
+	provider, issuer := setupTestProvider(t)
+
+	authURL, state, _ := provider.StartLogin()
+	callback, _ := issuer.Authorize(authURL)
+	if _, err := provider.FinishLogin(context.Background(), state, state, callback.Query().Get("code")); err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	if _, err := provider.FinishLogin(context.Background(), state, state, callback.Query().Get("code")); !errors.Is(err, ErrUnknownLogin) {
+		t.Errorf("Expected ErrUnknownLogin for a used state, got %v", err)
+	}
+	if _, err := provider.FinishLogin(context.Background(), "unknown", "unknown", "code"); !errors.Is(err, ErrUnknownLogin) {
+		t.Errorf("Expected ErrUnknownLogin for an unknown state, got %v", err)
+	}
+}
+
+func TestLogin_StateMustMatchBrowser(t *testing.T) {
+	provider, issuer := setupTestProvider(t)
+	issuer.SetClaims(map[string]interface{}{"sub": "alice-id"})
+
+	// A callback started by someone else's browser carries a state this browser wasn't given
+	ownState := func() string {
+		_, state, _ := provider.StartLogin()
+		return state
+	}()
+	authURL, _, _ := provider.StartLogin()
+	callback, _ := issuer.Authorize(authURL)
+	for _, boundState := range []string{ownState, ""} {
+		if _, err := provider.FinishLogin(context.Background(), boundState, callback.Query().Get("state"), callback.Query().Get("code")); !errors.Is(err, ErrUnknownLogin) {
+			t.Errorf("Expected ErrUnknownLogin for bound state %q, got %v", boundState, err)
+		}
+	}
+}
+
+func TestStartLogin_CapsPendingLogins(t *testing.T) {
+	provider, _ := setupTestProvider(t)
+
+	for i := 0; i < maxPendingLogins; i++ {
+		if _, _, err := provider.StartLogin(); err != nil {
+			t.Fatalf("Failed to start login %d: %v", i, err)
+		}
+	}
+	if _, _, err := provider.StartLogin(); !errors.Is(err, ErrTooManyLogins) {
+		t.Errorf("Expected ErrTooManyLogins, got %v", err)
+	}
+}
+
+func TestLogin_RejectsBadIDTokens(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"wrong issuer":   {"sub": "alice-id", "iss": "https://evil.example.com"},
+		"wrong audience": {"sub": "alice-id", "aud": "other-client"},
+		"expired":        {"sub": "alice-id", "exp": time.Now().Add(-time.Hour).Unix()},
+		"wrong nonce":    {"sub": "alice-id", "nonce": "replayed"},
+		"no subject":     {"email": "alice@example.com"},
+	}
+	for name, claims := range tests {
+		t.Run(name, func(t *testing.T) {
+			provider, issuer := setupTestProvider(t)
+			issuer.SetClaims(claims)
+			if _, err := login(t, provider, issuer); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("Expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewProvider_IssuerMismatch(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, "shiftplanner", "secret")
+	_, err := NewProvider(context.Background(), Config{Issuer: issuer.URL + "/other", ClientID: "shiftplanner"}, nil)
+	if err == nil {
+		t.Error("Expected an error for an issuer that doesn't match discovery")
+	}
+}
+
+func TestConfig(t *testing.T) {
+	groupRoles, err := ParseGroupRoles("planners=1:scheduler, admins=1:owner,viewers=2:viewer")
+	if err != nil {
+		t.Fatalf("Failed to parse group roles: %v", err)
+	}
+	config := Config{AllowedDomains: []string{"example.com"}, GroupRoles: groupRoles}
+
+	roles := config.WorkspaceRoles([]string{"planners", "admins", "unmapped"})
+	if len(roles) != 1 || roles[1] != models.RoleOwner {
+		t.Errorf("Expected the highest role per workspace, got %v", roles)
+	}
+
+	if !config.EmailAllowed("alice@Example.com") || config.EmailAllowed("alice@example.com.evil.net") || config.EmailAllowed("alice") {
+		t.Error("Unexpected email domain check")
+	}
+
+	for _, invalid := range []string{"planners", "planners=x:owner", "planners=1:admin", "=1:owner"} {
+		if _, err := ParseGroupRoles(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
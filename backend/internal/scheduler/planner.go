@@ -44,10 +44,17 @@ func (p *PlanShiftRequest) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// Store is the storage the planner reads members and leave from and writes shifts to
+type Store interface {
+	storage.MemberStore
+	storage.ShiftStore
+	storage.LeaveStore
+}
+
 // PlanShift creates a shift plan for the specified date range
-func PlanShift(userID int, startDate, endDate time.Time) ([]models.Shift, error) {
+func PlanShift(store Store, workspaceID int, startDate, endDate time.Time) ([]models.Shift, error) {
 	// Get existing members
-	members, err := storage.GetAllMembers(userID)
+	members, err := store.GetAllMembers(workspaceID)
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +76,7 @@ func PlanShift(userID int, startDate, endDate time.Time) ([]models.Shift, error)
 	longShiftDays := make(map[int]int)   // memberID -> hidden long shift days
 
 	// Get hidden shift counts from database (not visible shift counts)
-	hiddenCounts, err := storage.GetAllHiddenShiftCounts(userID)
+	hiddenCounts, err := store.GetAllHiddenShiftCounts(workspaceID)
 	if err == nil {
 		for memberID, counts := range hiddenCounts {
 			normalShiftDays[memberID] = counts.NormalShifts
@@ -88,20 +95,20 @@ func PlanShift(userID int, startDate, endDate time.Time) ([]models.Shift, error)
 	}
 
 	// Get existing shifts (for conflict check)
-	existingShifts, err := storage.GetShiftsByDateRange(userID, startDate, endDate)
+	existingShifts, err := store.GetShiftsByDateRange(workspaceID, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
 
 	// Delete conflicting shifts (to overwrite)
 	if len(existingShifts) > 0 {
-		if err := storage.DeleteShiftsByDateRange(userID, startDate, endDate); err != nil {
+		if err := store.DeleteShiftsByDateRange(workspaceID, startDate, endDate); err != nil {
 			return nil, err
 		}
 	}
 
 	// Get leave days for the planning period
-	leaveDays, err := storage.GetLeaveDaysByDateRange(userID, startDate, endDate)
+	leaveDays, err := store.GetLeaveDaysByDateRange(workspaceID, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
@@ -159,8 +166,7 @@ func PlanShift(userID int, startDate, endDate time.Time) ([]models.Shift, error)
 		endDateForShift := currentDate
 		if isLongShift {
 			// Long shift continues until next working day
-			nextWorkingDay := models.GetNextWorkingDay(currentDate)
-			endDateForShift = nextWorkingDay.AddDate(0, 0, -1)
+			endDateForShift = models.LongShiftEndDate(currentDate)
 		}
 		if endDateForShift.After(endDate) {
 			endDateForShift = endDate
@@ -1,6 +1,8 @@
 package scheduler
 
 import (
+	"shiftplanner/backend/internal/models"
+	"shiftplanner/backend/internal/storage"
 	"testing"
 	"time"
 )
@@ -47,3 +49,28 @@ func TestPlanShiftRequest_UnmarshalJSON_WithTime(t *testing.T) {
 		t.Errorf("End date mismatch: got %v, want %v", req.EndDate, expectedEnd)
 	}
 }
+
+func TestPlanShift_SkipsMembersOnLeave(t *testing.T) {
+	store := storage.NewMemoryStore()
+	user, _ := store.CreateUser("testuser", "testpassword")
+	alice, _ := store.CreateMember(user.ID, "Alice")
+	bob, _ := store.CreateMember(user.ID, "Bob")
+
+	// Monday 2025-01-06 to Wednesday 2025-01-08, Alice is on leave on Tuesday
+	startDate := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC)
+	store.CreateLeaveDay(user.ID, alice.ID, time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC), models.LeaveTypeAnnual)
+
+	shifts, err := PlanShift(store, user.ID, startDate, endDate)
+	if err != nil {
+		t.Fatalf("Failed to plan shifts: %v", err)
+	}
+
+	if len(shifts) != 3 {
+		t.Fatalf("Shift count mismatch: got %d, want 3", len(shifts))
+	}
+
+	if shifts[1].MemberID != bob.ID {
+		t.Errorf("Tuesday should be assigned to Bob, got member %d", shifts[1].MemberID)
+	}
+}
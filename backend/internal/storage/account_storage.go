@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+// SetUserEmail sets the address password resets are sent to
+func (store *SQLStore) SetUserEmail(userID int, email string) error {
+	result, err := store.db.Exec("UPDATE users SET email = ? WHERE id = ?", email, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// workspaceDataTables hold the rows of a workspace, children before their parents
+var workspaceDataTables = []string{
+	"swap_requests", "shifts", "leave_days", "leave_requests", "leave_allowances", "unavailability_rules",
+	"member_invites", "members", "imports", "audit_log", "plan_snapshots",
+	"workspace_invites", "api_keys", "feed_tokens", "workspace_users",
+}
+
+// userDataTables hold the rows of a user
+var userDataTables = []string{
+	"sessions", "feed_tokens", "api_keys", "user_identities", "user_two_factor",
+	"recovery_codes", "login_challenges", "password_resets", "workspace_users",
+}
+
+// DeleteUser deletes a user together with the workspaces nobody else uses
+// Rows are deleted explicitly rather than through ON DELETE CASCADE, which
+// SQLite only follows with foreign keys turned on. Members linked to the user
+// in remaining workspaces are unlinked.
+func (store *SQLStore) DeleteUser(userID int) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var soleOwnerships int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM workspace_users wu
+		WHERE wu.user_id = ? AND wu.role = ?
+		AND NOT EXISTS (SELECT 1 FROM workspace_users o WHERE o.workspace_id = wu.workspace_id AND o.user_id <> wu.user_id AND o.role = ?)
+		AND EXISTS (SELECT 1 FROM workspace_users o WHERE o.workspace_id = wu.workspace_id AND o.user_id <> wu.user_id)
+	`, userID, models.RoleOwner, models.RoleOwner).Scan(&soleOwnerships)
+	if err != nil {
+		return err
+	}
+	if soleOwnerships > 0 {
+		return ErrLastOwner
+	}
+
+	rows, err := tx.Query(`
+		SELECT wu.workspace_id FROM workspace_users wu
+		WHERE wu.user_id = ?
+		AND NOT EXISTS (SELECT 1 FROM workspace_users o WHERE o.workspace_id = wu.workspace_id AND o.user_id <> wu.user_id)
+	`, userID)
+	if err != nil {
+		return err
+	}
+	var unused []int
+	for rows.Next() {
+		var workspaceID int
+		if err := rows.Scan(&workspaceID); err != nil {
+			rows.Close()
+			return err
+		}
+		unused = append(unused, workspaceID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, workspaceID := range unused {
+		for _, table := range workspaceDataTables {
+			if _, err := tx.Exec("DELETE FROM "+table+" WHERE workspace_id = ?", workspaceID); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM workspaces WHERE id = ?", workspaceID); err != nil {
+			return err
+		}
+	}
+
+	for _, table := range userDataTables {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE user_id = ?", userID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("UPDATE members SET user_id = NULL WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	result, err := tx.Exec("DELETE FROM users WHERE id = ?", userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+// CreatePasswordReset stores a password reset under the hash of its token, replacing earlier ones
+func (store *SQLStore) CreatePasswordReset(userID int, tokenHash string, expiresAt time.Time) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM password_resets WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO password_resets (user_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?)",
+		userID, tokenHash, expiresAt.UTC().Format("2006-01-02 15:04:05"), time.Now().UTC().Format("2006-01-02 15:04:05"),
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetPasswordReset gets an unexpired password reset by the hash of its token
+func (store *SQLStore) GetPasswordReset(tokenHash string, now time.Time) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	var expiresAtStr string
+	err := store.db.QueryRow(
+		"SELECT id, user_id, expires_at FROM password_resets WHERE token_hash = ? AND expires_at > ?",
+		tokenHash, now.UTC().Format("2006-01-02 15:04:05"),
+	).Scan(&reset.ID, &reset.UserID, &expiresAtStr)
+	if err == sql.ErrNoRows {
+		return nil, ErrPasswordResetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	reset.ExpiresAt = parseDateTime(expiresAtStr)
+	return &reset, nil
+}
+
+// ResetPassword uses up a password reset, sets the new password and deletes the user's sessions
+func (store *SQLStore) ResetPassword(resetID int, password string) error {
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRow("SELECT user_id FROM password_resets WHERE id = ?", resetID).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return ErrPasswordResetNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// Deleting first makes a concurrent use of the same reset fail
+	result, err := tx.Exec("DELETE FROM password_resets WHERE id = ?", resetID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrPasswordResetNotFound
+	}
+	if _, err := tx.Exec("UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM sessions WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
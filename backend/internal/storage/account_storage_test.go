@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestUserEmail(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, userID int) {
+		if err := store.SetUserEmail(userID, "test@example.com"); err != nil {
+			t.Fatalf("Failed to set email: %v", err)
+		}
+		user, _, err := store.GetUserByUsername("testuser")
+		if err != nil || user.Email != "test@example.com" {
+			t.Errorf("Email not stored: %+v, %v", user, err)
+		}
+		if user, _ := store.GetUserByID(userID); user.Email != "test@example.com" {
+			t.Errorf("Email not returned by ID: %+v", user)
+		}
+
+		if err := store.SetUserEmail(userID+100, "x@example.com"); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for a missing user, got %v", err)
+		}
+	})
+}
+
+func TestPasswordResets(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, userID int) {
+		now := time.Now().UTC().Truncate(time.Second)
+		store.CreateSession(userID, "session", "", "", now.Add(time.Hour))
+		if err := store.CreatePasswordReset(userID, "first", now.Add(time.Hour)); err != nil {
+			t.Fatalf("Failed to create reset: %v", err)
+		}
+		store.CreatePasswordReset(userID, "second", now.Add(time.Hour))
+
+		// A new reset replaces the earlier one
+		if _, err := store.GetPasswordReset("first", now); err != ErrPasswordResetNotFound {
+			t.Errorf("Expected ErrPasswordResetNotFound for a replaced reset, got %v", err)
+		}
+		reset, err := store.GetPasswordReset("second", now)
+		if err != nil {
+			t.Fatalf("Failed to get reset: %v", err)
+		}
+		if reset.UserID != userID || !reset.ExpiresAt.Equal(now.Add(time.Hour)) {
+			t.Errorf("Reset mismatch: %+v", reset)
+		}
+		if _, err := store.GetPasswordReset("second", now.Add(2*time.Hour)); err != ErrPasswordResetNotFound {
+			t.Errorf("Expected ErrPasswordResetNotFound for an expired reset, got %v", err)
+		}
+
+		if err := store.ResetPassword(reset.ID, "new password"); err != nil {
+			t.Fatalf("Failed to reset password: %v", err)
+		}
+		_, passwordHash, _ := store.GetUserByUsername("testuser")
+		if !ValidatePassword("new password", passwordHash) {
+			t.Error("New password not stored")
+		}
+		if _, err := store.GetSession("session"); err != ErrSessionNotFound {
+			t.Errorf("Expected sessions to be deleted, got %v", err)
+		}
+
+		// Resets work once
+		if err := store.ResetPassword(reset.ID, "other password"); err != ErrPasswordResetNotFound {
+			t.Errorf("Expected ErrPasswordResetNotFound for a used reset, got %v", err)
+		}
+
+		store.CreatePasswordReset(userID, "expired", now.Add(-time.Minute))
+		if err := store.DeleteExpiredSessions(now); err != nil {
+			t.Fatalf("Failed to delete expired: %v", err)
+		}
+		if _, err := store.GetPasswordReset("expired", now.Add(-time.Hour)); err != ErrPasswordResetNotFound {
+			t.Errorf("Expected expired resets to be deleted, got %v", err)
+		}
+	})
+}
+
+func TestUserSessionsRevokedExceptOne(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, userID int) {
+		expiresAt := time.Now().Add(time.Hour)
+		keep, _ := store.CreateSession(userID, "keep", "", "", expiresAt)
+		store.CreateSession(userID, "other", "", "", expiresAt)
+		other, _ := store.CreateUser("other", "password")
+		store.CreateSession(other.ID, "other-user", "", "", expiresAt)
+
+		if err := store.DeleteUserSessions(userID, keep.ID); err != nil {
+			t.Fatalf("Failed to delete sessions: %v", err)
+		}
+		if _, err := store.GetSession("keep"); err != nil {
+			t.Errorf("Expected the kept session to remain, got %v", err)
+		}
+		if _, err := store.GetSession("other"); err != ErrSessionNotFound {
+			t.Errorf("Expected the other session to be deleted, got %v", err)
+		}
+		if _, err := store.GetSession("other-user"); err != nil {
+			t.Errorf("Expected sessions of other users to remain, got %v", err)
+		}
+	})
+}
+
+func TestDeleteUser(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, userID int) {
+		personal := personalWorkspace(t, store, userID)
+		store.CreateMember(personal, "Alice")
+		store.CreateSession(userID, "session", "", "", time.Now().Add(time.Hour))
+
+		other, _ := store.CreateUser("other", "password")
+		shared := personalWorkspace(t, store, other.ID)
+		store.SetWorkspaceUserRole(shared, userID, models.RoleOwner)
+		member, _ := store.CreateMember(shared, "Test User")
+		store.CreateMemberInvite(shared, member.ID, "member-invite", time.Now().Add(time.Hour))
+		if _, _, err := store.AcceptMemberInvite(userID, "member-invite", time.Now()); err != nil {
+			t.Fatalf("Failed to link member: %v", err)
+		}
+
+		// The only owner of a workspace others use can't leave
+		store.SetWorkspaceUserRole(shared, other.ID, models.RoleMember)
+		if err := store.DeleteUser(userID); err != ErrLastOwner {
+			t.Fatalf("Expected ErrLastOwner, got %v", err)
+		}
+		if _, err := store.GetUserByID(userID); err != nil {
+			t.Fatalf("Expected the user to remain, got %v", err)
+		}
+
+		store.SetWorkspaceUserRole(shared, other.ID, models.RoleOwner)
+		if err := store.DeleteUser(userID); err != nil {
+			t.Fatalf("Failed to delete user: %v", err)
+		}
+
+		if _, err := store.GetUserByID(userID); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for the deleted user, got %v", err)
+		}
+		if _, err := store.GetSession("session"); err != ErrSessionNotFound {
+			t.Errorf("Expected sessions to be deleted, got %v", err)
+		}
+		if _, err := store.GetWorkspace(personal); err != sql.ErrNoRows {
+			t.Errorf("Expected the personal workspace to be deleted, got %v", err)
+		}
+		if members, _ := store.GetAllMembers(personal); len(members) != 0 {
+			t.Errorf("Expected the personal workspace's members to be deleted, got %+v", members)
+		}
+
+		// Shared workspaces stay, with the member unlinked
+		users, _ := store.GetWorkspaceUsers(shared)
+		if len(users) != 1 || users[0].UserID != other.ID {
+			t.Errorf("Expected only the other user in the shared workspace, got %+v", users)
+		}
+		linked, err := store.GetMemberByID(shared, member.ID)
+		if err != nil || linked.UserID != 0 {
+			t.Errorf("Expected the member to stay unlinked, got %+v, %v", linked, err)
+		}
+
+		if err := store.DeleteUser(userID); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for a missing user, got %v", err)
+		}
+	})
+}
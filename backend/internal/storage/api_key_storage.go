@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+// CreateAPIKey stores a new API key of a user for a workspace
+func (store *SQLStore) CreateAPIKey(userID, workspaceID int, name, prefix, scope, keyHash string) (*models.APIKey, error) {
+	key := &models.APIKey{
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		Name:        name,
+		Prefix:      prefix,
+		Scope:       scope,
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+
+	var err error
+	key.ID, err = store.db.insert(
+		"INSERT INTO api_keys (user_id, workspace_id, name, prefix, key_hash, scope, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		userID, workspaceID, name, prefix, keyHash, scope, key.CreatedAt.Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetAPIKeys lists the API keys of a user for a workspace, oldest first
+func (store *SQLStore) GetAPIKeys(userID, workspaceID int) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := store.queryRows(
+		"SELECT id, user_id, workspace_id, name, prefix, scope, last_used_at, created_at FROM api_keys WHERE user_id = ? AND workspace_id = ? ORDER BY id",
+		[]interface{}{userID, workspaceID},
+		func(rows *sql.Rows) error {
+			key, err := scanAPIKey(rows)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, *key)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// DeleteAPIKey revokes an API key
+func (store *SQLStore) DeleteAPIKey(userID, keyID int) error {
+	result, err := store.db.Exec("DELETE FROM api_keys WHERE id = ? AND user_id = ?", keyID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// UseAPIKey resolves an API key by its hash and records now as its last use
+// The last use is only written when the stored one is touchInterval or more
+// older, so a busy key doesn't cost a write on every request.
+func (store *SQLStore) UseAPIKey(keyHash string, now time.Time, touchInterval time.Duration) (*models.APIKey, error) {
+	key, err := scanAPIKey(store.db.QueryRow(
+		"SELECT id, user_id, workspace_id, name, prefix, scope, last_used_at, created_at FROM api_keys WHERE key_hash = ?",
+		keyHash,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lastUsedAt := now.UTC().Truncate(time.Second)
+	result, err := store.db.Exec(
+		"UPDATE api_keys SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at <= ?)",
+		lastUsedAt.Format("2006-01-02 15:04:05"), key.ID, lastUsedAt.Add(-touchInterval).Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected > 0 {
+		key.LastUsedAt = &lastUsedAt
+	}
+	return key, nil
+}
+
+// scanAPIKey scans the columns selected by the API key queries
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var lastUsedAt sql.NullString
+	var createdAtStr string
+	if err := row.Scan(&key.ID, &key.UserID, &key.WorkspaceID, &key.Name, &key.Prefix, &key.Scope, &lastUsedAt, &createdAtStr); err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		t := parseDateTime(lastUsedAt.String)
+		key.LastUsedAt = &t
+	}
+	key.CreatedAt = parseDateTime(createdAtStr)
+	return &key, nil
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"shiftplanner/backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, userID int) {
+		workspaceID := personalWorkspace(t, store, userID)
+		team, _ := store.CreateWorkspace(userID, "Team")
+
+		key, err := store.CreateAPIKey(userID, workspaceID, "CI", "sp_1234abcd", models.APIKeyScopeReadOnly, "hash-1")
+		if err != nil {
+			t.Fatalf("Failed to create API key: %v", err)
+		}
+		if key.LastUsedAt != nil {
+			t.Errorf("New key should not have been used, got %v", key.LastUsedAt)
+		}
+		store.CreateAPIKey(userID, team.ID, "Team CI", "sp_5678abcd", models.APIKeyScopeAdmin, "hash-2")
+
+		keys, err := store.GetAPIKeys(userID, workspaceID)
+		if err != nil {
+			t.Fatalf("Failed to get API keys: %v", err)
+		}
+		if len(keys) != 1 || keys[0].Name != "CI" || keys[0].Prefix != "sp_1234abcd" || keys[0].Scope != models.APIKeyScopeReadOnly {
+			t.Errorf("Expected only the key of the workspace, got %+v", keys)
+		}
+
+		now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+		used, err := store.UseAPIKey("hash-1", now, time.Minute)
+		if err != nil {
+			t.Fatalf("Failed to use API key: %v", err)
+		}
+		if used.ID != key.ID || used.UserID != userID || used.WorkspaceID != workspaceID {
+			t.Errorf("Unexpected key: %+v", used)
+		}
+		keys, _ = store.GetAPIKeys(userID, workspaceID)
+		if keys[0].LastUsedAt == nil || !keys[0].LastUsedAt.Equal(now) {
+			t.Errorf("Last use should be recorded, got %v", keys[0].LastUsedAt)
+		}
+
+		// Uses within the touch interval don't move the last use
+		store.UseAPIKey("hash-1", now.Add(30*time.Second), time.Minute)
+		keys, _ = store.GetAPIKeys(userID, workspaceID)
+		if !keys[0].LastUsedAt.Equal(now) {
+			t.Errorf("Last use within the interval should be kept, got %v", keys[0].LastUsedAt)
+		}
+		later := now.Add(time.Minute)
+		store.UseAPIKey("hash-1", later, time.Minute)
+		keys, _ = store.GetAPIKeys(userID, workspaceID)
+		if !keys[0].LastUsedAt.Equal(later) {
+			t.Errorf("Last use should be recorded again after the interval, got %v", keys[0].LastUsedAt)
+		}
+
+		if _, err := store.UseAPIKey("unknown", now, time.Minute); err != ErrAPIKeyNotFound {
+			t.Errorf("Expected ErrAPIKeyNotFound, got %v", err)
+		}
+		if err := store.DeleteAPIKey(userID+1, key.ID); err != ErrAPIKeyNotFound {
+			t.Errorf("Expected ErrAPIKeyNotFound for another user's key, got %v", err)
+		}
+		if err := store.DeleteAPIKey(userID, key.ID); err != nil {
+			t.Fatalf("Failed to delete API key: %v", err)
+		}
+		if _, err := store.UseAPIKey("hash-1", now, time.Minute); err != ErrAPIKeyNotFound {
+			t.Errorf("Expected ErrAPIKeyNotFound for a revoked key, got %v", err)
+		}
+	})
+}
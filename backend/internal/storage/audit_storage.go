@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+// AppendAuditEntry adds an entry to the audit log of a workspace
+// The entry is timestamped now; entries are never changed afterwards.
+func (store *SQLStore) AppendAuditEntry(workspaceID int, entry models.AuditEntry) (*models.AuditEntry, error) {
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	id, err := store.db.insert(
+		"INSERT INTO audit_log (workspace_id, actor_user_id, action, entity, entity_id, member_id, before_json, after_json, request_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		workspaceID, entry.ActorUserID, entry.Action, entry.Entity, entry.EntityID, entry.MemberID,
+		nullJSON(entry.Before), nullJSON(entry.After), entry.RequestID, entry.CreatedAt.Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.ID = id
+	return &entry, nil
+}
+
+// GetAuditEntries gets the audit log of a workspace, newest first
+func (store *SQLStore) GetAuditEntries(workspaceID int, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	query := "SELECT id, actor_user_id, action, entity, entity_id, member_id, before_json, after_json, request_id, created_at FROM audit_log WHERE workspace_id = ?"
+	args := []interface{}{workspaceID}
+	if filter.Entity != "" {
+		query += " AND entity = ?"
+		args = append(args, filter.Entity)
+	}
+	if filter.MemberID != 0 {
+		query += " AND member_id = ?"
+		args = append(args, filter.MemberID)
+	}
+	if !filter.StartDate.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, filter.StartDate.Format("2006-01-02")+" 00:00:00")
+	}
+	if !filter.EndDate.IsZero() {
+		query += " AND created_at < ?"
+		args = append(args, filter.EndDate.AddDate(0, 0, 1).Format("2006-01-02")+" 00:00:00")
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		var before, after sql.NullString
+		var createdAtStr string
+		if err := rows.Scan(&e.ID, &e.ActorUserID, &e.Action, &e.Entity, &e.EntityID, &e.MemberID, &before, &after, &e.RequestID, &createdAtStr); err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			e.Before = []byte(before.String)
+		}
+		if after.Valid {
+			e.After = []byte(after.String)
+		}
+		e.CreatedAt = parseDateTime(createdAtStr)
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// nullJSON stores an empty JSON value as NULL
+func nullJSON(value []byte) sql.NullString {
+	if len(value) == 0 {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(value), Valid: true}
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"encoding/json"
+	"shiftplanner/backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestAuditedStore_RecordsShiftChanges(t *testing.T) {
+	forEachStoreWithWorkspace(t, func(t *testing.T, store Store, workspaceID int) {
+		actor, _, _ := store.GetUserByUsername("testuser")
+		audited := NewAuditedStore(store, actor.ID, "req-1")
+
+		alice, _ := audited.CreateMember(workspaceID, "Alice")
+		bob, _ := audited.CreateMember(workspaceID, "Bob")
+		day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+		shift, err := audited.CreateShift(workspaceID, alice.ID, day, day, false)
+		if err != nil {
+			t.Fatalf("Failed to create shift: %v", err)
+		}
+		if err := audited.UpdateShiftMember(workspaceID, shift.ID, bob.ID); err != nil {
+			t.Fatalf("Failed to reassign shift: %v", err)
+		}
+		if err := audited.DeleteShiftsByDateRange(workspaceID, day, day); err != nil {
+			t.Fatalf("Failed to delete shifts: %v", err)
+		}
+
+		// Changes made on the wrapped store directly are not recorded
+		store.CreateMember(workspaceID, "Unaudited")
+
+		entries, err := store.GetAuditEntries(workspaceID, models.AuditFilter{Entity: models.AuditEntityShift})
+		if err != nil {
+			t.Fatalf("Failed to get audit entries: %v", err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("Expected 3 shift entries, got %d: %+v", len(entries), entries)
+		}
+
+		// Newest first: delete, update, create
+		wantActions := []string{models.AuditActionDelete, models.AuditActionUpdate, models.AuditActionCreate}
+		for i, e := range entries {
+			if e.Action != wantActions[i] || e.EntityID != shift.ID || e.ActorUserID != actor.ID || e.RequestID != "req-1" {
+				t.Errorf("Entry %d mismatch: %+v", i, e)
+			}
+		}
+
+		var before, after models.Shift
+		json.Unmarshal(entries[1].Before, &before)
+		json.Unmarshal(entries[1].After, &after)
+		if before.MemberID != alice.ID || after.MemberID != bob.ID {
+			t.Errorf("Reassignment should record Alice before and Bob after, got %d and %d", before.MemberID, after.MemberID)
+		}
+		if entries[0].After != nil || entries[2].Before != nil {
+			t.Error("Deletions should have no after value and creations no before value")
+		}
+
+		members, _ := store.GetAuditEntries(workspaceID, models.AuditFilter{Entity: models.AuditEntityMember})
+		if len(members) != 2 {
+			t.Errorf("Expected 2 member entries, got %d", len(members))
+		}
+	})
+}
+
+func TestAuditedStore_RecordsLeaveChanges(t *testing.T) {
+	forEachStoreWithWorkspace(t, func(t *testing.T, store Store, workspaceID int) {
+		actor, _, _ := store.GetUserByUsername("testuser")
+		audited := NewAuditedStore(store, actor.ID, "")
+		member, _ := audited.CreateMember(workspaceID, "Alice")
+
+		start := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+		end := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+		audited.CreateLeaveDaysRange(workspaceID, member.ID, start, start, models.LeaveTypeAnnual)
+		leaveDays, err := audited.CreateLeaveDaysRange(workspaceID, member.ID, start, end, models.LeaveTypeAnnual)
+		if err != nil || len(leaveDays) != 2 {
+			t.Fatalf("Failed to create leave days: %v (%d)", err, len(leaveDays))
+		}
+		if err := audited.DeleteLeaveDay(workspaceID, leaveDays[0].ID); err != nil {
+			t.Fatalf("Failed to delete leave day: %v", err)
+		}
+		audited.SetLeaveAllowance(workspaceID, member.ID, 2025, models.LeaveTypeAnnual, 20)
+		audited.SetLeaveAllowance(workspaceID, member.ID, 2025, models.LeaveTypeAnnual, 25)
+
+		entries, _ := store.GetAuditEntries(workspaceID, models.AuditFilter{Entity: models.AuditEntityLeaveDay, MemberID: member.ID})
+		if len(entries) != 3 {
+			t.Fatalf("Expected 2 leave day creations and 1 deletion, got %d: %+v", len(entries), entries)
+		}
+		if entries[0].Action != models.AuditActionDelete || entries[0].EntityID != leaveDays[0].ID {
+			t.Errorf("Latest entry should be the deletion, got %+v", entries[0])
+		}
+
+		allowances, _ := store.GetAuditEntries(workspaceID, models.AuditFilter{Entity: models.AuditEntityLeaveAllowance})
+		if len(allowances) != 2 || allowances[0].Action != models.AuditActionUpdate || allowances[1].Action != models.AuditActionCreate {
+			t.Errorf("Allowance entries mismatch: %+v", allowances)
+		}
+
+		// Date filters apply to the time of the change
+		today := time.Now().UTC()
+		if entries, _ := store.GetAuditEntries(workspaceID, models.AuditFilter{StartDate: today, EndDate: today}); len(entries) != 6 {
+			t.Errorf("Expected all 6 entries today, got %d", len(entries))
+		}
+		if entries, _ := store.GetAuditEntries(workspaceID, models.AuditFilter{EndDate: today.AddDate(0, 0, -1)}); len(entries) != 0 {
+			t.Errorf("Expected no entries before today, got %d", len(entries))
+		}
+		if entries, _ := store.GetAuditEntries(workspaceID+1, models.AuditFilter{}); len(entries) != 0 {
+			t.Errorf("Other workspaces should not see the entries, got %d", len(entries))
+		}
+	})
+}
@@ -0,0 +1,360 @@
+package storage
+
+import (
+	"encoding/json"
+	"log"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+// AuditedStore records every change made through it in the audit log
+// It wraps a store for the user making the changes and the request they came from.
+// Entries are appended after the change succeeded; failing to append one is logged
+// rather than returned, as the change can't be undone at that point.
+type AuditedStore struct {
+	Store
+	actorUserID int
+	requestID   string
+}
+
+// NewAuditedStore wraps store so that changes are recorded for an actor and request
+func NewAuditedStore(store Store, actorUserID int, requestID string) *AuditedStore {
+	return &AuditedStore{Store: store, actorUserID: actorUserID, requestID: requestID}
+}
+
+// record appends an entry to the audit log of a workspace
+// before and after are stored as JSON; pass nil where there is no value.
+func (s *AuditedStore) record(workspaceID int, action, entity string, entityID, memberID int, before, after interface{}) {
+	entry := models.AuditEntry{
+		ActorUserID: s.actorUserID,
+		Action:      action,
+		Entity:      entity,
+		EntityID:    entityID,
+		MemberID:    memberID,
+		Before:      auditJSON(before),
+		After:       auditJSON(after),
+		RequestID:   s.requestID,
+	}
+	if _, err := s.Store.AppendAuditEntry(workspaceID, entry); err != nil {
+		log.Printf("Warning: failed to record %s of %s %d in audit log: %v", action, entity, entityID, err)
+	}
+}
+
+// auditJSON encodes a value for the audit log, nil for a missing value
+func auditJSON(value interface{}) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return data
+}
+
+// CreateMember creates a member and records it
+func (s *AuditedStore) CreateMember(workspaceID int, name string) (*models.Member, error) {
+	member, err := s.Store.CreateMember(workspaceID, name)
+	if err != nil {
+		return nil, err
+	}
+	s.record(workspaceID, models.AuditActionCreate, models.AuditEntityMember, member.ID, member.ID, nil, member)
+	return member, nil
+}
+
+// DeleteMember deletes a member and records what was deleted
+func (s *AuditedStore) DeleteMember(workspaceID, memberID int) error {
+	before, _ := s.Store.GetMemberByID(workspaceID, memberID)
+	if err := s.Store.DeleteMember(workspaceID, memberID); err != nil {
+		return err
+	}
+	if before != nil {
+		s.record(workspaceID, models.AuditActionDelete, models.AuditEntityMember, memberID, memberID, before, nil)
+	}
+	return nil
+}
+
+// CreateShift creates a shift and records it
+func (s *AuditedStore) CreateShift(workspaceID, memberID int, startDate, endDate time.Time, isLongShift bool) (*models.Shift, error) {
+	shift, err := s.Store.CreateShift(workspaceID, memberID, startDate, endDate, isLongShift)
+	if err != nil {
+		return nil, err
+	}
+	s.record(workspaceID, models.AuditActionCreate, models.AuditEntityShift, shift.ID, shift.MemberID, nil, shift)
+	return shift, nil
+}
+
+// UpdateShiftMember reassigns a shift and records the previous and new shift
+func (s *AuditedStore) UpdateShiftMember(workspaceID, shiftID, newMemberID int) error {
+	before, err := s.Store.GetShiftByID(workspaceID, shiftID)
+	if err != nil {
+		return err
+	}
+	if err := s.Store.UpdateShiftMember(workspaceID, shiftID, newMemberID); err != nil {
+		return err
+	}
+	after, _ := s.Store.GetShiftByID(workspaceID, shiftID)
+	s.record(workspaceID, models.AuditActionUpdate, models.AuditEntityShift, shiftID, newMemberID, before, after)
+	return nil
+}
+
+// CreateSwapRequest creates a swap request and records it
+func (s *AuditedStore) CreateSwapRequest(workspaceID, shiftID, withShiftID int, note string) (*models.SwapRequest, error) {
+	sr, err := s.Store.CreateSwapRequest(workspaceID, shiftID, withShiftID, note)
+	if err != nil {
+		return nil, err
+	}
+	s.record(workspaceID, models.AuditActionCreate, models.AuditEntitySwapRequest, sr.ID, sr.MemberID, nil, sr)
+	return sr, nil
+}
+
+// ApproveSwapRequest approves a swap request and records the decision and both reassigned shifts
+func (s *AuditedStore) ApproveSwapRequest(workspaceID, requestID int) (*models.SwapRequest, error) {
+	before, err := s.Store.GetSwapRequestByID(workspaceID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	shiftBefore, _ := s.Store.GetShiftByID(workspaceID, before.ShiftID)
+	withShiftBefore, _ := s.Store.GetShiftByID(workspaceID, before.WithShiftID)
+
+	sr, err := s.Store.ApproveSwapRequest(workspaceID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	s.record(workspaceID, models.AuditActionUpdate, models.AuditEntitySwapRequest, sr.ID, sr.MemberID, before, sr)
+	for _, shiftBefore := range []*models.Shift{shiftBefore, withShiftBefore} {
+		if shiftBefore == nil {
+			continue
+		}
+		after, _ := s.Store.GetShiftByID(workspaceID, shiftBefore.ID)
+		if after != nil {
+			s.record(workspaceID, models.AuditActionUpdate, models.AuditEntityShift, after.ID, after.MemberID, shiftBefore, after)
+		}
+	}
+	return sr, nil
+}
+
+// RejectSwapRequest rejects a swap request and records the decision
+func (s *AuditedStore) RejectSwapRequest(workspaceID, requestID int) (*models.SwapRequest, error) {
+	before, _ := s.Store.GetSwapRequestByID(workspaceID, requestID)
+	sr, err := s.Store.RejectSwapRequest(workspaceID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	s.record(workspaceID, models.AuditActionUpdate, models.AuditEntitySwapRequest, sr.ID, sr.MemberID, before, sr)
+	return sr, nil
+}
+
+// CreateOrUpdateShiftForDate sets the member of a day and records the change
+func (s *AuditedStore) CreateOrUpdateShiftForDate(workspaceID, memberID int, date time.Time) (*models.Shift, error) {
+	before, err := s.Store.GetShiftByDate(workspaceID, date)
+	if err != nil {
+		return nil, err
+	}
+	shift, err := s.Store.CreateOrUpdateShiftForDate(workspaceID, memberID, date)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil {
+		s.record(workspaceID, models.AuditActionCreate, models.AuditEntityShift, shift.ID, shift.MemberID, nil, shift)
+	} else {
+		s.record(workspaceID, models.AuditActionUpdate, models.AuditEntityShift, shift.ID, shift.MemberID, before, shift)
+	}
+	return shift, nil
+}
+
+// DeleteShiftsByDateRange deletes shifts in a date range and records each deleted shift
+func (s *AuditedStore) DeleteShiftsByDateRange(workspaceID int, startDate, endDate time.Time) error {
+	shifts, err := s.Store.GetShiftsByDateRange(workspaceID, startDate, endDate)
+	if err != nil {
+		return err
+	}
+	if err := s.Store.DeleteShiftsByDateRange(workspaceID, startDate, endDate); err != nil {
+		return err
+	}
+	s.recordDeletedShifts(workspaceID, shifts)
+	return nil
+}
+
+// DeleteAllShifts deletes all shifts and records each deleted shift
+func (s *AuditedStore) DeleteAllShifts(workspaceID int) error {
+	shifts, err := s.Store.GetShiftsByDateRange(workspaceID, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		return err
+	}
+	if err := s.Store.DeleteAllShifts(workspaceID); err != nil {
+		return err
+	}
+	s.recordDeletedShifts(workspaceID, shifts)
+	return nil
+}
+
+func (s *AuditedStore) recordDeletedShifts(workspaceID int, shifts []models.Shift) {
+	for i := range shifts {
+		s.record(workspaceID, models.AuditActionDelete, models.AuditEntityShift, shifts[i].ID, shifts[i].MemberID, &shifts[i], nil)
+	}
+}
+
+// CreateLeaveDay creates a leave day and records it
+func (s *AuditedStore) CreateLeaveDay(workspaceID, memberID int, leaveDate time.Time, leaveType string) (*models.LeaveDay, error) {
+	leaveDay, err := s.Store.CreateLeaveDay(workspaceID, memberID, leaveDate, leaveType)
+	if err != nil {
+		return nil, err
+	}
+	s.record(workspaceID, models.AuditActionCreate, models.AuditEntityLeaveDay, leaveDay.ID, leaveDay.MemberID, nil, leaveDay)
+	return leaveDay, nil
+}
+
+// CreateLeaveDaysRange creates leave days for a range and records the ones that didn't exist yet
+func (s *AuditedStore) CreateLeaveDaysRange(workspaceID, memberID int, startDate, endDate time.Time, leaveType string) ([]models.LeaveDay, error) {
+	existing, err := s.Store.GetLeaveDaysByMember(workspaceID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	existingIDs := make(map[int]bool, len(existing))
+	for _, ld := range existing {
+		existingIDs[ld.ID] = true
+	}
+
+	leaveDays, err := s.Store.CreateLeaveDaysRange(workspaceID, memberID, startDate, endDate, leaveType)
+	if err != nil {
+		return nil, err
+	}
+	for i := range leaveDays {
+		if !existingIDs[leaveDays[i].ID] {
+			s.record(workspaceID, models.AuditActionCreate, models.AuditEntityLeaveDay, leaveDays[i].ID, leaveDays[i].MemberID, nil, &leaveDays[i])
+		}
+	}
+	return leaveDays, nil
+}
+
+// DeleteLeaveDay deletes a leave day and records what was deleted
+func (s *AuditedStore) DeleteLeaveDay(workspaceID, leaveDayID int) error {
+	before, _ := s.Store.GetLeaveDayByID(workspaceID, leaveDayID)
+	if err := s.Store.DeleteLeaveDay(workspaceID, leaveDayID); err != nil {
+		return err
+	}
+	if before != nil {
+		s.record(workspaceID, models.AuditActionDelete, models.AuditEntityLeaveDay, leaveDayID, before.MemberID, before, nil)
+	}
+	return nil
+}
+
+// CreateLeaveRequest creates a leave request and records it
+func (s *AuditedStore) CreateLeaveRequest(workspaceID, memberID int, startDate, endDate time.Time, leaveType, note string) (*models.LeaveRequest, error) {
+	lr, err := s.Store.CreateLeaveRequest(workspaceID, memberID, startDate, endDate, leaveType, note)
+	if err != nil {
+		return nil, err
+	}
+	s.record(workspaceID, models.AuditActionCreate, models.AuditEntityLeaveRequest, lr.ID, lr.MemberID, nil, lr)
+	return lr, nil
+}
+
+// ApproveLeaveRequest approves a leave request and records the decision
+func (s *AuditedStore) ApproveLeaveRequest(workspaceID, requestID int) (*models.LeaveRequest, error) {
+	return s.decideLeaveRequest(workspaceID, requestID, s.Store.ApproveLeaveRequest)
+}
+
+// RejectLeaveRequest rejects a leave request and records the decision
+func (s *AuditedStore) RejectLeaveRequest(workspaceID, requestID int) (*models.LeaveRequest, error) {
+	return s.decideLeaveRequest(workspaceID, requestID, s.Store.RejectLeaveRequest)
+}
+
+func (s *AuditedStore) decideLeaveRequest(workspaceID, requestID int, decide func(workspaceID, requestID int) (*models.LeaveRequest, error)) (*models.LeaveRequest, error) {
+	before, _ := s.Store.GetLeaveRequestByID(workspaceID, requestID)
+	lr, err := decide(workspaceID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	s.record(workspaceID, models.AuditActionUpdate, models.AuditEntityLeaveRequest, lr.ID, lr.MemberID, before, lr)
+	return lr, nil
+}
+
+// SetLeaveAllowance sets a leave allowance and records the previous and new allowance
+func (s *AuditedStore) SetLeaveAllowance(workspaceID, memberID, year int, leaveType string, days int) (*models.LeaveAllowance, error) {
+	var before *models.LeaveAllowance
+	allowances, err := s.Store.GetLeaveAllowances(workspaceID, year)
+	if err != nil {
+		return nil, err
+	}
+	for i := range allowances {
+		if allowances[i].MemberID == memberID && allowances[i].LeaveType == leaveType {
+			before = &allowances[i]
+		}
+	}
+
+	allowance, err := s.Store.SetLeaveAllowance(workspaceID, memberID, year, leaveType, days)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil {
+		s.record(workspaceID, models.AuditActionCreate, models.AuditEntityLeaveAllowance, allowance.ID, memberID, nil, allowance)
+	} else {
+		s.record(workspaceID, models.AuditActionUpdate, models.AuditEntityLeaveAllowance, allowance.ID, memberID, before, allowance)
+	}
+	return allowance, nil
+}
+
+// CreateUnavailabilityRule creates an unavailability rule and records it
+func (s *AuditedStore) CreateUnavailabilityRule(workspaceID int, rule models.UnavailabilityRule) (*models.UnavailabilityRule, error) {
+	created, err := s.Store.CreateUnavailabilityRule(workspaceID, rule)
+	if err != nil {
+		return nil, err
+	}
+	s.record(workspaceID, models.AuditActionCreate, models.AuditEntityUnavailabilityRule, created.ID, created.MemberID, nil, created)
+	return created, nil
+}
+
+// DeleteUnavailabilityRule deletes an unavailability rule and records what was deleted
+func (s *AuditedStore) DeleteUnavailabilityRule(workspaceID, ruleID int) error {
+	var before *models.UnavailabilityRule
+	rules, err := s.Store.GetUnavailabilityRules(workspaceID, 0)
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		if rules[i].ID == ruleID {
+			before = &rules[i]
+		}
+	}
+
+	if err := s.Store.DeleteUnavailabilityRule(workspaceID, ruleID); err != nil {
+		return err
+	}
+	if before != nil {
+		s.record(workspaceID, models.AuditActionDelete, models.AuditEntityUnavailabilityRule, ruleID, before.MemberID, before, nil)
+	}
+	return nil
+}
+
+// ImportShifts imports shifts and records the result of an applied import
+func (s *AuditedStore) ImportShifts(workspaceID int, filename, contentHash string, rows []models.ShiftImportRow, dryRun bool) (*models.ShiftImportResult, error) {
+	result, err := s.Store.ImportShifts(workspaceID, filename, contentHash, rows, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		s.record(workspaceID, models.AuditActionImport, models.AuditEntityWorkspace, 0, 0, nil, result)
+	}
+	return result, nil
+}
+
+// RestoreBackup restores a backup and records the result
+func (s *AuditedStore) RestoreBackup(workspaceID int, backup *models.Backup, replace bool) (*models.RestoreResult, error) {
+	result, err := s.Store.RestoreBackup(workspaceID, backup, replace)
+	if err != nil {
+		return nil, err
+	}
+	s.record(workspaceID, models.AuditActionRestore, models.AuditEntityWorkspace, 0, 0, nil, result)
+	return result, nil
+}
+
+// RestorePlanSnapshot restores a plan snapshot and records the result
+func (s *AuditedStore) RestorePlanSnapshot(workspaceID int, snapshot *models.PlanSnapshot) (*models.SnapshotRestoreResult, error) {
+	result, err := s.Store.RestorePlanSnapshot(workspaceID, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	s.record(workspaceID, models.AuditActionRestore, models.AuditEntityPlanSnapshot, snapshot.ID, 0, nil, result)
+	return result, nil
+}
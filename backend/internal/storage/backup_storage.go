@@ -0,0 +1,509 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"shiftplanner/backend/internal/models"
+	"strings"
+	"time"
+)
+
+// ExportBackup collects all data of a workspace into a backup archive
+func (store *SQLStore) ExportBackup(workspaceID int) (*models.Backup, error) {
+	backup := newBackup()
+
+	err := store.queryRows(
+		"SELECT id, name, COALESCE(hidden_normal_shifts, 0), COALESCE(hidden_long_shifts, 0) FROM members WHERE workspace_id = ? ORDER BY id",
+		[]interface{}{workspaceID},
+		func(rows *sql.Rows) error {
+			var m models.BackupMember
+			if err := rows.Scan(&m.ID, &m.Name, &m.HiddenNormalShifts, &m.HiddenLongShifts); err != nil {
+				return err
+			}
+			backup.Members = append(backup.Members, m)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.queryRows(
+		"SELECT member_id, start_date, end_date, is_long_shift, sequence FROM shifts WHERE workspace_id = ? ORDER BY start_date, id",
+		[]interface{}{workspaceID},
+		func(rows *sql.Rows) error {
+			var s models.BackupShift
+			var startDateStr, endDateStr string
+			if err := rows.Scan(&s.MemberID, &startDateStr, &endDateStr, &s.IsLongShift, &s.Sequence); err != nil {
+				return err
+			}
+			s.StartDate, s.EndDate = backupDate(startDateStr), backupDate(endDateStr)
+			backup.Shifts = append(backup.Shifts, s)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.queryRows(
+		"SELECT id, member_id, start_date, end_date, leave_type, status, note FROM leave_requests WHERE workspace_id = ? ORDER BY id",
+		[]interface{}{workspaceID},
+		func(rows *sql.Rows) error {
+			var lr models.BackupLeaveRequest
+			var startDateStr, endDateStr string
+			if err := rows.Scan(&lr.ID, &lr.MemberID, &startDateStr, &endDateStr, &lr.LeaveType, &lr.Status, &lr.Note); err != nil {
+				return err
+			}
+			lr.StartDate, lr.EndDate = backupDate(startDateStr), backupDate(endDateStr)
+			backup.LeaveRequests = append(backup.LeaveRequests, lr)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.queryRows(
+		"SELECT member_id, leave_date, leave_type, COALESCE(leave_request_id, 0) FROM leave_days WHERE workspace_id = ? ORDER BY leave_date, id",
+		[]interface{}{workspaceID},
+		func(rows *sql.Rows) error {
+			var ld models.BackupLeaveDay
+			var leaveDateStr string
+			if err := rows.Scan(&ld.MemberID, &leaveDateStr, &ld.LeaveType, &ld.LeaveRequestID); err != nil {
+				return err
+			}
+			ld.LeaveDate = backupDate(leaveDateStr)
+			backup.LeaveDays = append(backup.LeaveDays, ld)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.queryRows(
+		"SELECT member_id, year, leave_type, days FROM leave_allowances WHERE workspace_id = ? ORDER BY year, member_id, leave_type",
+		[]interface{}{workspaceID},
+		func(rows *sql.Rows) error {
+			var la models.BackupLeaveAllowance
+			if err := rows.Scan(&la.MemberID, &la.Year, &la.LeaveType, &la.Days); err != nil {
+				return err
+			}
+			backup.LeaveAllowances = append(backup.LeaveAllowances, la)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := store.GetUnavailabilityRules(workspaceID, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		backup.UnavailabilityRules = append(backup.UnavailabilityRules, backupUnavailability(rule))
+	}
+
+	return backup, nil
+}
+
+// newBackup creates an empty archive of the current version
+func newBackup() *models.Backup {
+	return &models.Backup{
+		Format:              models.BackupFormat,
+		Version:             models.BackupVersion,
+		ExportedAt:          time.Now().UTC(),
+		Members:             []models.BackupMember{},
+		Shifts:              []models.BackupShift{},
+		LeaveRequests:       []models.BackupLeaveRequest{},
+		LeaveDays:           []models.BackupLeaveDay{},
+		LeaveAllowances:     []models.BackupLeaveAllowance{},
+		UnavailabilityRules: []models.BackupUnavailability{},
+	}
+}
+
+// backupUnavailability converts an unavailability rule to its archive form
+func backupUnavailability(rule models.UnavailabilityRule) models.BackupUnavailability {
+	r := models.BackupUnavailability{
+		MemberID:  rule.MemberID,
+		Frequency: rule.Frequency,
+		Weekdays:  make([]int, len(rule.Weekdays)),
+		Nth:       rule.Nth,
+		StartDate: rule.StartDate.Format("2006-01-02"),
+		Note:      rule.Note,
+	}
+	for i, wd := range rule.Weekdays {
+		r.Weekdays[i] = int(wd)
+	}
+	if rule.EndDate != nil {
+		r.EndDate = rule.EndDate.Format("2006-01-02")
+	}
+	return r
+}
+
+// backupRestore is the transaction a backup archive is restored in
+// Implemented by each storage backend, so all backends share applyBackupRestore.
+// Dates are passed as validated YYYY-MM-DD strings.
+type backupRestore interface {
+	// clearWorkspace deletes all members, shifts and leave data of the workspace
+	clearWorkspace() error
+	memberIDByName(name string) (int, bool, error)
+	insertMember(name string, hiddenNormalShifts, hiddenLongShifts int) (int, error)
+	hasShiftOn(date string) (bool, error)
+	insertShift(memberID int, startDate, endDate string, isLongShift bool, sequence int) error
+	leaveRequestID(memberID int, startDate, endDate, leaveType, status string) (int, bool, error)
+	insertLeaveRequest(memberID int, startDate, endDate, leaveType, status, note string) (int, error)
+	// insertLeaveDay reports false if the member already has a leave day on that date
+	insertLeaveDay(memberID int, leaveDate, leaveType string, requestID int) (bool, error)
+	setLeaveAllowance(memberID, year int, leaveType string, days int) error
+	insertUnavailabilityRule(memberID int, frequency string, weekdays []time.Weekday, nth int, startDate, endDate, note string) error
+}
+
+// RestoreBackup restores a backup archive into a workspace in a single transaction
+func (store *SQLStore) RestoreBackup(workspaceID int, backup *models.Backup, replace bool) (*models.RestoreResult, error) {
+	if err := models.UpgradeBackup(backup); err != nil {
+		return nil, err
+	}
+
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := applyBackupRestore(&sqlBackupRestore{tx: tx, workspaceID: workspaceID}, backup, replace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// applyBackupRestore restores an upgraded backup archive within tx
+// With replace, all existing members, shifts and leave data of the workspace are
+// deleted first. Otherwise the archive is merged: members are matched by name
+// (case-insensitive), shifts on dates that already have a shift and identical
+// leave requests are skipped, and existing leave days are kept.
+// Archive IDs are remapped to the new rows.
+func applyBackupRestore(tx backupRestore, backup *models.Backup, replace bool) (*models.RestoreResult, error) {
+	if replace {
+		if err := tx.clearWorkspace(); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &models.RestoreResult{}
+
+	// Archive member ID -> restored member ID
+	memberIDs := make(map[int]int)
+	for _, m := range backup.Members {
+		name := strings.TrimSpace(m.Name)
+		if name == "" {
+			return nil, invalidBackup("member %d has an empty name", m.ID)
+		}
+		if _, exists := memberIDs[m.ID]; exists {
+			return nil, invalidBackup("duplicate member ID %d", m.ID)
+		}
+
+		existingID, found, err := tx.memberIDByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			memberIDs[m.ID] = existingID
+			continue
+		}
+
+		id, err := tx.insertMember(name, m.HiddenNormalShifts, m.HiddenLongShifts)
+		if err != nil {
+			return nil, err
+		}
+		memberIDs[m.ID] = id
+		result.Members++
+	}
+
+	member := func(kind string, archiveID int) (int, error) {
+		id, ok := memberIDs[archiveID]
+		if !ok {
+			return 0, invalidBackup("%s references unknown member %d", kind, archiveID)
+		}
+		return id, nil
+	}
+
+	for _, s := range backup.Shifts {
+		memberID, err := member("shift", s.MemberID)
+		if err != nil {
+			return nil, err
+		}
+		startDate, endDate, err := backupDateRange(s.StartDate, s.EndDate)
+		if err != nil {
+			return nil, invalidBackup("shift: %v", err)
+		}
+
+		covered, err := tx.hasShiftOn(startDate)
+		if err != nil {
+			return nil, err
+		}
+		if covered {
+			continue
+		}
+
+		if err := tx.insertShift(memberID, startDate, endDate, s.IsLongShift, s.Sequence); err != nil {
+			return nil, err
+		}
+		result.Shifts++
+	}
+
+	// Archive leave request ID -> restored leave request ID
+	requestIDs := make(map[int]int)
+	for _, lr := range backup.LeaveRequests {
+		memberID, err := member("leave request", lr.MemberID)
+		if err != nil {
+			return nil, err
+		}
+		startDate, endDate, err := backupDateRange(lr.StartDate, lr.EndDate)
+		if err != nil {
+			return nil, invalidBackup("leave request %d: %v", lr.ID, err)
+		}
+		if !models.IsValidLeaveType(lr.LeaveType) {
+			return nil, invalidBackup("leave request %d: invalid leave type '%s'", lr.ID, lr.LeaveType)
+		}
+
+		// Reuse an identical request, e.g. when merging the same archive twice
+		existingID, found, err := tx.leaveRequestID(memberID, startDate, endDate, lr.LeaveType, lr.Status)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			requestIDs[lr.ID] = existingID
+			continue
+		}
+
+		id, err := tx.insertLeaveRequest(memberID, startDate, endDate, lr.LeaveType, lr.Status, lr.Note)
+		if err != nil {
+			return nil, err
+		}
+		requestIDs[lr.ID] = id
+		result.LeaveRequests++
+	}
+
+	for _, ld := range backup.LeaveDays {
+		memberID, err := member("leave day", ld.MemberID)
+		if err != nil {
+			return nil, err
+		}
+		leaveDate, _, err := backupDateRange(ld.LeaveDate, ld.LeaveDate)
+		if err != nil {
+			return nil, invalidBackup("leave day: %v", err)
+		}
+
+		inserted, err := tx.insertLeaveDay(memberID, leaveDate, ld.LeaveType, requestIDs[ld.LeaveRequestID])
+		if err != nil {
+			return nil, err
+		}
+		if inserted {
+			result.LeaveDays++
+		}
+	}
+
+	for _, la := range backup.LeaveAllowances {
+		memberID, err := member("leave allowance", la.MemberID)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.setLeaveAllowance(memberID, la.Year, la.LeaveType, la.Days); err != nil {
+			return nil, err
+		}
+		result.LeaveAllowances++
+	}
+
+	for _, r := range backup.UnavailabilityRules {
+		memberID, err := member("unavailability rule", r.MemberID)
+		if err != nil {
+			return nil, err
+		}
+		if !models.IsValidFrequency(r.Frequency) {
+			return nil, invalidBackup("unavailability rule: invalid frequency '%s'", r.Frequency)
+		}
+		startDate, _, err := backupDateRange(r.StartDate, r.StartDate)
+		if err != nil {
+			return nil, invalidBackup("unavailability rule: %v", err)
+		}
+
+		endDate := ""
+		if r.EndDate != "" {
+			if _, endDate, err = backupDateRange(r.StartDate, r.EndDate); err != nil {
+				return nil, invalidBackup("unavailability rule: %v", err)
+			}
+		}
+
+		weekdays := make([]time.Weekday, len(r.Weekdays))
+		for i, wd := range r.Weekdays {
+			if wd < 0 || wd > 6 {
+				return nil, invalidBackup("unavailability rule: invalid weekday %d", wd)
+			}
+			weekdays[i] = time.Weekday(wd)
+		}
+
+		if err := tx.insertUnavailabilityRule(memberID, r.Frequency, weekdays, r.Nth, startDate, endDate, r.Note); err != nil {
+			return nil, err
+		}
+		result.UnavailabilityRules++
+	}
+
+	return result, nil
+}
+
+// sqlBackupRestore restores a backup archive within a SQL transaction
+type sqlBackupRestore struct {
+	tx          *sqlTx
+	workspaceID int
+}
+
+func (r *sqlBackupRestore) clearWorkspace() error {
+	for _, table := range []string{"leave_days", "leave_requests", "leave_allowances", "unavailability_rules", "swap_requests", "shifts", "members"} {
+		if _, err := r.tx.Exec("DELETE FROM "+table+" WHERE workspace_id = ?", r.workspaceID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *sqlBackupRestore) memberIDByName(name string) (int, bool, error) {
+	var id int
+	err := r.tx.QueryRow("SELECT id FROM members WHERE LOWER(name) = LOWER(?) AND workspace_id = ?", name, r.workspaceID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return id, err == nil, err
+}
+
+func (r *sqlBackupRestore) insertMember(name string, hiddenNormalShifts, hiddenLongShifts int) (int, error) {
+	return r.tx.insert(
+		"INSERT INTO members (workspace_id, name, hidden_normal_shifts, hidden_long_shifts) VALUES (?, ?, ?, ?)",
+		r.workspaceID, name, hiddenNormalShifts, hiddenLongShifts,
+	)
+}
+
+func (r *sqlBackupRestore) hasShiftOn(date string) (bool, error) {
+	var count int
+	err := r.tx.QueryRow(
+		"SELECT COUNT(*) FROM shifts WHERE workspace_id = ? AND start_date <= ? AND end_date >= ?",
+		r.workspaceID, date, date,
+	).Scan(&count)
+	return count > 0, err
+}
+
+func (r *sqlBackupRestore) insertShift(memberID int, startDate, endDate string, isLongShift bool, sequence int) error {
+	_, err := r.tx.Exec(
+		"INSERT INTO shifts (workspace_id, member_id, start_date, end_date, is_long_shift, sequence) VALUES (?, ?, ?, ?, ?, ?)",
+		r.workspaceID, memberID, startDate, endDate, isLongShift, sequence,
+	)
+	return err
+}
+
+func (r *sqlBackupRestore) leaveRequestID(memberID int, startDate, endDate, leaveType, status string) (int, bool, error) {
+	var id int
+	err := r.tx.QueryRow(
+		"SELECT id FROM leave_requests WHERE workspace_id = ? AND member_id = ? AND start_date = ? AND end_date = ? AND leave_type = ? AND status = ?",
+		r.workspaceID, memberID, startDate, endDate, leaveType, status,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return id, err == nil, err
+}
+
+func (r *sqlBackupRestore) insertLeaveRequest(memberID int, startDate, endDate, leaveType, status, note string) (int, error) {
+	return r.tx.insert(
+		"INSERT INTO leave_requests (workspace_id, member_id, start_date, end_date, leave_type, status, note) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		r.workspaceID, memberID, startDate, endDate, leaveType, status, note,
+	)
+}
+
+func (r *sqlBackupRestore) insertLeaveDay(memberID int, leaveDate, leaveType string, requestID int) (bool, error) {
+	var leaveRequestID sql.NullInt64
+	if requestID != 0 {
+		leaveRequestID = sql.NullInt64{Int64: int64(requestID), Valid: true}
+	}
+	result, err := r.tx.Exec(
+		"INSERT INTO leave_days (workspace_id, member_id, leave_date, leave_type, leave_request_id) VALUES (?, ?, ?, ?, ?) ON CONFLICT DO NOTHING",
+		r.workspaceID, memberID, leaveDate, leaveType, leaveRequestID,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *sqlBackupRestore) setLeaveAllowance(memberID, year int, leaveType string, days int) error {
+	_, err := r.tx.Exec(`
+		INSERT INTO leave_allowances (workspace_id, member_id, year, leave_type, days) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(workspace_id, member_id, year, leave_type) DO UPDATE SET days = excluded.days
+	`, r.workspaceID, memberID, year, leaveType, days)
+	return err
+}
+
+func (r *sqlBackupRestore) insertUnavailabilityRule(memberID int, frequency string, weekdays []time.Weekday, nth int, startDate, endDate, note string) error {
+	var end sql.NullString
+	if endDate != "" {
+		end = sql.NullString{String: endDate, Valid: true}
+	}
+	_, err := r.tx.Exec(
+		"INSERT INTO unavailability_rules (workspace_id, member_id, frequency, weekdays, nth, start_date, end_date, note) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		r.workspaceID, memberID, frequency, formatWeekdays(weekdays), nth, startDate, end, note,
+	)
+	return err
+}
+
+// invalidBackup formats an error about a malformed backup archive
+func invalidBackup(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", models.ErrInvalidBackup, fmt.Sprintf(format, args...))
+}
+
+// queryRows runs a query and calls scan for each row
+func (store *SQLStore) queryRows(query string, args []interface{}, scan func(rows *sql.Rows) error) error {
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// backupDate formats a stored date as YYYY-MM-DD, keeping it as is if it can't be parsed
+func backupDate(s string) string {
+	if t, err := parseDate(s); err == nil {
+		return t.Format("2006-01-02")
+	}
+	return s
+}
+
+// backupDateRange validates a YYYY-MM-DD date range from a backup archive
+func backupDateRange(start, end string) (string, string, error) {
+	startDate, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid date '%s'", start)
+	}
+	endDate, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid date '%s'", end)
+	}
+	if endDate.Before(startDate) {
+		return "", "", fmt.Errorf("end date %s is before start date %s", end, start)
+	}
+	return start, end, nil
+}
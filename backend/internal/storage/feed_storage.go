@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+// ErrFeedTokenNotFound is returned when a feed token doesn't exist
+var ErrFeedTokenNotFound = errors.New("feed token not found")
+
+// CreateFeedToken stores a new calendar feed token of a user for a workspace under the hash of the token
+func (store *SQLStore) CreateFeedToken(workspaceID, userID int, name, prefix, tokenHash string) (*models.FeedToken, error) {
+	id, err := store.db.insert(
+		"INSERT INTO feed_tokens (workspace_id, user_id, token_hash, prefix, name) VALUES (?, ?, ?, ?, ?)",
+		workspaceID, userID, tokenHash, prefix, name,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.FeedToken{
+		ID:        id,
+		Name:      name,
+		Prefix:    prefix,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// GetFeedTokens gets the calendar feed tokens of a user for a workspace
+func (store *SQLStore) GetFeedTokens(workspaceID, userID int) ([]models.FeedToken, error) {
+	rows, err := store.db.Query(
+		"SELECT id, name, prefix, created_at FROM feed_tokens WHERE workspace_id = ? AND user_id = ? ORDER BY id",
+		workspaceID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.FeedToken
+	for rows.Next() {
+		var ft models.FeedToken
+		var createdAtStr string
+		if err := rows.Scan(&ft.ID, &ft.Name, &ft.Prefix, &createdAtStr); err != nil {
+			return nil, err
+		}
+		ft.CreatedAt = parseDateTime(createdAtStr)
+		tokens = append(tokens, ft)
+	}
+
+	return tokens, rows.Err()
+}
+
+// DeleteFeedToken revokes a calendar feed token of a user for a workspace
+func (store *SQLStore) DeleteFeedToken(workspaceID, userID, tokenID int) error {
+	result, err := store.db.Exec(
+		"DELETE FROM feed_tokens WHERE id = ? AND workspace_id = ? AND user_id = ?",
+		tokenID, workspaceID, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrFeedTokenNotFound
+	}
+	return nil
+}
+
+// GetWorkspaceIDByFeedToken resolves the hash of a calendar feed token to its workspace
+// Tokens stop working once their user loses access to the workspace.
+func (store *SQLStore) GetWorkspaceIDByFeedToken(tokenHash string) (int, error) {
+	var workspaceID int
+	err := store.db.QueryRow(`
+		SELECT ft.workspace_id
+		FROM feed_tokens ft
+		JOIN workspace_users wu ON wu.workspace_id = ft.workspace_id AND wu.user_id = ft.user_id
+		WHERE ft.token_hash = ?
+	`, tokenHash).Scan(&workspaceID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrFeedTokenNotFound
+		}
+		return 0, err
+	}
+	return workspaceID, nil
+}
@@ -0,0 +1,367 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"strings"
+	"time"
+)
+
+// shiftImport is the transaction an import is applied in
+// Implemented by each storage backend, so all backends share applyShiftImport.
+type shiftImport interface {
+	// resolveMember finds a member by name (case-insensitive) or creates it
+	resolveMember(name string) (memberID int, created bool, err error)
+	memberName(memberID int) (string, error)
+	shiftByDate(date time.Time) (*models.Shift, error)
+	// removeOverlappingShifts deletes shifts starting after the row's start date
+	// and on or before its end date, except excludeID
+	removeOverlappingShifts(row models.ShiftImportRow, excludeID int) (int, error)
+	insertShift(memberID int, row models.ShiftImportRow) error
+	updateShift(shift, target *models.Shift) error
+}
+
+// ImportShifts applies validated shift import rows in a single transaction
+// With dryRun the transaction is rolled back, so the result describes what would
+// change. Applied imports are recorded together with the content hash of the file.
+func (store *SQLStore) ImportShifts(workspaceID int, filename, contentHash string, rows []models.ShiftImportRow, dryRun bool) (*models.ShiftImportResult, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := applyShiftImport(&sqlShiftImport{tx: tx, workspaceID: workspaceID, memberIDs: make(map[string]int)}, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO imports (workspace_id, kind, content_hash, filename, members_created, shifts_created, shifts_updated) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		workspaceID, models.ImportKindShifts, contentHash, filename, result.MembersCreated, result.ShiftsCreated, result.ShiftsUpdated,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// applyShiftImport applies import rows within tx
+// Members are resolved by name and created when missing. A shift already covering
+// a row's start date is reassigned (and given the row's range if it has one),
+// otherwise a new shift is created. Shifts starting inside a multi-day row are
+// replaced by it.
+func applyShiftImport(tx shiftImport, rows []models.ShiftImportRow) (*models.ShiftImportResult, error) {
+	result := &models.ShiftImportResult{
+		NewMembers: []string{},
+		Changes:    []models.ShiftImportChange{},
+	}
+
+	memberNames := make(map[int]string)
+
+	for _, row := range rows {
+		memberID, created, err := tx.resolveMember(row.Name)
+		if err != nil {
+			return nil, err
+		}
+		memberNames[memberID] = row.Name
+		if created {
+			result.MembersCreated++
+			result.NewMembers = append(result.NewMembers, row.Name)
+		}
+
+		change := models.ShiftImportChange{
+			Row:         row.Row,
+			StartDate:   row.StartDate,
+			EndDate:     row.EndDate,
+			MemberName:  row.Name,
+			IsLongShift: row.IsLongShift,
+			NewMember:   created,
+		}
+
+		existing, err := tx.shiftByDate(row.StartDate)
+		if err != nil {
+			return nil, err
+		}
+
+		// Shifts starting inside a multi-day row are superseded by it
+		excludeID := 0
+		if existing != nil {
+			excludeID = existing.ID
+		}
+		replaced := 0
+		if row.EndDate.After(row.StartDate) {
+			if replaced, err = tx.removeOverlappingShifts(row, excludeID); err != nil {
+				return nil, err
+			}
+		}
+		change.ShiftsReplaced = replaced
+		result.ShiftsReplaced += replaced
+
+		if existing == nil {
+			if err := tx.insertShift(memberID, row); err != nil {
+				return nil, err
+			}
+			change.Action = models.ImportActionCreate
+			result.ShiftsCreated++
+			result.Changes = append(result.Changes, change)
+			continue
+		}
+
+		// The existing shift keeps its range unless the row defines one
+		target := *existing
+		target.MemberID = memberID
+		if row.HasRange {
+			target.StartDate = row.StartDate
+			target.EndDate = row.EndDate
+			target.IsLongShift = row.IsLongShift
+		}
+		change.StartDate = target.StartDate
+		change.EndDate = target.EndDate
+		change.IsLongShift = target.IsLongShift
+
+		unchanged := target.MemberID == existing.MemberID &&
+			target.StartDate.Equal(existing.StartDate) &&
+			target.EndDate.Equal(existing.EndDate) &&
+			target.IsLongShift == existing.IsLongShift
+		if unchanged && replaced == 0 {
+			change.Action = models.ImportActionUnchanged
+			result.ShiftsUnchanged++
+			result.Changes = append(result.Changes, change)
+			continue
+		}
+
+		if existing.MemberID != memberID {
+			previousName, ok := memberNames[existing.MemberID]
+			if !ok {
+				if previousName, err = tx.memberName(existing.MemberID); err != nil {
+					return nil, err
+				}
+				memberNames[existing.MemberID] = previousName
+			}
+			change.PreviousMemberName = previousName
+		}
+
+		if err := tx.updateShift(existing, &target); err != nil {
+			return nil, err
+		}
+		change.Action = models.ImportActionUpdate
+		result.ShiftsUpdated++
+
+		result.Changes = append(result.Changes, change)
+	}
+
+	return result, nil
+}
+
+// GetImportByHash gets the latest applied import of a file with the given content hash
+// Returns nil if the file has not been imported before
+func (store *SQLStore) GetImportByHash(workspaceID int, kind, contentHash string) (*models.ImportRecord, error) {
+	var r models.ImportRecord
+	var createdAtStr string
+	err := store.db.QueryRow(
+		"SELECT id, kind, content_hash, filename, members_created, shifts_created, shifts_updated, created_at FROM imports WHERE workspace_id = ? AND kind = ? AND content_hash = ? ORDER BY id DESC LIMIT 1",
+		workspaceID, kind, contentHash,
+	).Scan(&r.ID, &r.Kind, &r.ContentHash, &r.Filename, &r.MembersCreated, &r.ShiftsCreated, &r.ShiftsUpdated, &createdAtStr)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	r.CreatedAt = parseDateTime(createdAtStr)
+	return &r, nil
+}
+
+// sqlShiftImport applies an import within a SQL transaction
+type sqlShiftImport struct {
+	tx          *sqlTx
+	workspaceID int
+	// Key: lower-case member name
+	memberIDs map[string]int
+}
+
+// resolveMember finds a member by name (case-insensitive) or creates it
+// New members start with the average hidden shift counters, like CreateMember
+func (imp *sqlShiftImport) resolveMember(name string) (int, bool, error) {
+	tx, workspaceID := imp.tx, imp.workspaceID
+	key := strings.ToLower(name)
+	if id, ok := imp.memberIDs[key]; ok {
+		return id, false, nil
+	}
+
+	var id int
+	err := tx.QueryRow("SELECT id FROM members WHERE LOWER(name) = LOWER(?) AND workspace_id = ?", name, workspaceID).Scan(&id)
+	if err == nil {
+		imp.memberIDs[key] = id
+		return id, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, false, err
+	}
+
+	newID, err := tx.insert(`
+		INSERT INTO members (workspace_id, name, hidden_normal_shifts, hidden_long_shifts)
+		SELECT ?, ?,
+			COALESCE(SUM(COALESCE(hidden_normal_shifts, 0)) / COUNT(*), 0),
+			COALESCE(SUM(COALESCE(hidden_long_shifts, 0)) / COUNT(*), 0)
+		FROM members WHERE workspace_id = ?
+	`, workspaceID, name, workspaceID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	imp.memberIDs[key] = newID
+	return newID, true, nil
+}
+
+// memberName gets the name of a member, empty if it doesn't exist
+func (imp *sqlShiftImport) memberName(memberID int) (string, error) {
+	var name string
+	err := imp.tx.QueryRow("SELECT name FROM members WHERE id = ? AND workspace_id = ?", memberID, imp.workspaceID).Scan(&name)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	return name, nil
+}
+
+// shiftByDate gets a shift that covers a specific date
+func (imp *sqlShiftImport) shiftByDate(date time.Time) (*models.Shift, error) {
+	tx, workspaceID := imp.tx, imp.workspaceID
+	dateStr := date.Format("2006-01-02")
+
+	var s models.Shift
+	var startDateStr, endDateStr string
+	var isLongShift bool
+	err := tx.QueryRow(
+		"SELECT id, member_id, start_date, end_date, is_long_shift FROM shifts WHERE workspace_id = ? AND start_date <= ? AND end_date >= ? LIMIT 1",
+		workspaceID, dateStr, dateStr,
+	).Scan(&s.ID, &s.MemberID, &startDateStr, &endDateStr, &isLongShift)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if s.StartDate, err = parseDate(startDateStr); err != nil {
+		return nil, err
+	}
+	if s.EndDate, err = parseDate(endDateStr); err != nil {
+		return nil, err
+	}
+	s.IsLongShift = isLongShift
+	return &s, nil
+}
+
+// insertShift creates the shift of an import row and updates hidden counters
+func (imp *sqlShiftImport) insertShift(memberID int, row models.ShiftImportRow) error {
+	tx, workspaceID := imp.tx, imp.workspaceID
+	if _, err := tx.Exec(
+		"INSERT INTO shifts (workspace_id, member_id, start_date, end_date, is_long_shift) VALUES (?, ?, ?, ?, ?)",
+		workspaceID, memberID, row.StartDate.Format("2006-01-02"), row.EndDate.Format("2006-01-02"), row.IsLongShift,
+	); err != nil {
+		return err
+	}
+
+	normalDays, longDays := shiftDayCounts(&models.Shift{StartDate: row.StartDate, EndDate: row.EndDate, IsLongShift: row.IsLongShift})
+	return adjustHiddenShiftCounts(tx, workspaceID, memberID, normalDays, longDays)
+}
+
+// updateShift changes an existing shift to the target member and range
+// Hidden counters of the old member are decreased and those of the new member increased
+func (imp *sqlShiftImport) updateShift(shift, target *models.Shift) error {
+	tx, workspaceID := imp.tx, imp.workspaceID
+	if _, err := tx.Exec(
+		"UPDATE shifts SET member_id = ?, start_date = ?, end_date = ?, is_long_shift = ?, sequence = sequence + 1 WHERE id = ? AND workspace_id = ?",
+		target.MemberID, target.StartDate.Format("2006-01-02"), target.EndDate.Format("2006-01-02"), target.IsLongShift, shift.ID, workspaceID,
+	); err != nil {
+		return err
+	}
+
+	normalDays, longDays := shiftDayCounts(shift)
+	if err := adjustHiddenShiftCounts(tx, workspaceID, shift.MemberID, -normalDays, -longDays); err != nil {
+		return err
+	}
+	normalDays, longDays = shiftDayCounts(target)
+	return adjustHiddenShiftCounts(tx, workspaceID, target.MemberID, normalDays, longDays)
+}
+
+// removeOverlappingShifts deletes shifts starting inside the row's range
+// Returns the number of deleted shifts.
+func (imp *sqlShiftImport) removeOverlappingShifts(row models.ShiftImportRow, excludeID int) (int, error) {
+	tx, workspaceID := imp.tx, imp.workspaceID
+	rows, err := tx.Query(
+		"SELECT id, member_id, start_date, end_date, is_long_shift FROM shifts WHERE workspace_id = ? AND start_date > ? AND start_date <= ? AND id != ?",
+		workspaceID, row.StartDate.Format("2006-01-02"), row.EndDate.Format("2006-01-02"), excludeID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var shifts []models.Shift
+	for rows.Next() {
+		var s models.Shift
+		var startDateStr, endDateStr string
+		var isLongShift bool
+		if err := rows.Scan(&s.ID, &s.MemberID, &startDateStr, &endDateStr, &isLongShift); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if s.StartDate, err = parseDate(startDateStr); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if s.EndDate, err = parseDate(endDateStr); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		s.IsLongShift = isLongShift
+		shifts = append(shifts, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, s := range shifts {
+		if _, err := tx.Exec("DELETE FROM shifts WHERE id = ? AND workspace_id = ?", s.ID, workspaceID); err != nil {
+			return 0, err
+		}
+		normalDays, longDays := shiftDayCounts(&s)
+		if err := adjustHiddenShiftCounts(tx, workspaceID, s.MemberID, -normalDays, -longDays); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(shifts), nil
+}
+
+// shiftDayCounts returns the normal and long shift days a shift adds to the hidden counters
+func shiftDayCounts(shift *models.Shift) (normalDays, longDays int) {
+	shiftDays := int(shift.EndDate.Sub(shift.StartDate).Hours()/24) + 1
+	if shift.IsLongShift {
+		return 0, shiftDays
+	}
+	return shiftDays, 0
+}
+
+// adjustHiddenShiftCounts applies deltas to a member's hidden shift counters within a transaction
+// Counters never go below zero, matching UpdateHiddenShiftCounts
+func adjustHiddenShiftCounts(tx *sqlTx, workspaceID, memberID, normalShiftsDelta, longShiftsDelta int) error {
+	_, err := tx.Exec(
+		"UPDATE members SET hidden_normal_shifts = "+tx.nonNegative("COALESCE(hidden_normal_shifts, 0) + ?")+
+			", hidden_long_shifts = "+tx.nonNegative("COALESCE(hidden_long_shifts, 0) + ?")+
+			" WHERE id = ? AND workspace_id = ?",
+		normalShiftsDelta, longShiftsDelta, memberID, workspaceID,
+	)
+	return err
+}
@@ -0,0 +1,367 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+// ErrLeaveRequestNotPending is returned when deciding on a request that was already decided
+var ErrLeaveRequestNotPending = errors.New("leave request is not pending")
+
+// CreateLeaveRequest creates a new pending leave request
+func (store *SQLStore) CreateLeaveRequest(workspaceID, memberID int, startDate, endDate time.Time, leaveType, note string) (*models.LeaveRequest, error) {
+	if startDate.IsZero() || endDate.IsZero() {
+		return nil, fmt.Errorf("start_date and end_date cannot be zero")
+	}
+
+	if startDate.After(endDate) {
+		return nil, fmt.Errorf("start_date must be before or equal to end_date")
+	}
+
+	// Normalize dates to UTC midnight
+	startDateUTC := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+	endDateUTC := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, time.UTC)
+
+	id, err := store.db.insert(
+		"INSERT INTO leave_requests (workspace_id, member_id, start_date, end_date, leave_type, status, note) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		workspaceID, memberID, startDateUTC.Format("2006-01-02"), endDateUTC.Format("2006-01-02"), leaveType, models.LeaveStatusPending, note,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LeaveRequest{
+		ID:        id,
+		MemberID:  memberID,
+		StartDate: startDateUTC,
+		EndDate:   endDateUTC,
+		LeaveType: leaveType,
+		Status:    models.LeaveStatusPending,
+		Note:      note,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// GetLeaveRequests gets leave requests of a workspace
+// Empty status and zero memberID mean no filtering
+func (store *SQLStore) GetLeaveRequests(workspaceID int, status string, memberID int) ([]models.LeaveRequest, error) {
+	query := "SELECT id, member_id, start_date, end_date, leave_type, status, note, decided_at, created_at FROM leave_requests WHERE workspace_id = ?"
+	args := []interface{}{workspaceID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	if memberID != 0 {
+		query += " AND member_id = ?"
+		args = append(args, memberID)
+	}
+	query += " ORDER BY start_date"
+
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []models.LeaveRequest
+	for rows.Next() {
+		lr, err := scanLeaveRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *lr)
+	}
+
+	return requests, rows.Err()
+}
+
+// GetLeaveRequestByID gets a leave request by ID (can only get own requests)
+func (store *SQLStore) GetLeaveRequestByID(workspaceID, requestID int) (*models.LeaveRequest, error) {
+	row := store.db.QueryRow(
+		"SELECT id, member_id, start_date, end_date, leave_type, status, note, decided_at, created_at FROM leave_requests WHERE id = ? AND workspace_id = ?",
+		requestID, workspaceID,
+	)
+	return scanLeaveRequest(row)
+}
+
+// ApproveLeaveRequest approves a pending leave request
+// Leave days are created for the requested range so the planner sees them.
+// The decision and the leave days are stored in one transaction, and only a
+// request that is still pending is approved, so concurrent approvals can't
+// both create leave days.
+func (store *SQLStore) ApproveLeaveRequest(workspaceID, requestID int) (*models.LeaveRequest, error) {
+	lr, err := store.GetLeaveRequestByID(workspaceID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if lr.Status != models.LeaveStatusPending {
+		return nil, ErrLeaveRequestNotPending
+	}
+
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	decided, err := decideLeaveRequest(tx.sqlConn, workspaceID, lr, models.LeaveStatusApproved)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := createLeaveDaysRange(tx.sqlConn, workspaceID, lr.MemberID, lr.StartDate, lr.EndDate, lr.LeaveType, lr.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return decided, nil
+}
+
+// RejectLeaveRequest rejects a pending leave request
+func (store *SQLStore) RejectLeaveRequest(workspaceID, requestID int) (*models.LeaveRequest, error) {
+	lr, err := store.GetLeaveRequestByID(workspaceID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if lr.Status != models.LeaveStatusPending {
+		return nil, ErrLeaveRequestNotPending
+	}
+
+	return decideLeaveRequest(store.db.sqlConn, workspaceID, lr, models.LeaveStatusRejected)
+}
+
+// decideLeaveRequest stores the decision for a leave request
+// ErrLeaveRequestNotPending is returned if the request was decided in the meantime
+func decideLeaveRequest(conn sqlConn, workspaceID int, lr *models.LeaveRequest, status string) (*models.LeaveRequest, error) {
+	decidedAt := time.Now().UTC()
+	result, err := conn.Exec(
+		"UPDATE leave_requests SET status = ?, decided_at = ? WHERE id = ? AND workspace_id = ? AND status = ?",
+		status, decidedAt.Format("2006-01-02 15:04:05"), lr.ID, workspaceID, models.LeaveStatusPending,
+	)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, ErrLeaveRequestNotPending
+	}
+
+	lr.Status = status
+	lr.DecidedAt = &decidedAt
+	return lr, nil
+}
+
+// SetLeaveAllowance sets the yearly allowance of a member for a leave type
+func (store *SQLStore) SetLeaveAllowance(workspaceID, memberID, year int, leaveType string, days int) (*models.LeaveAllowance, error) {
+	if days < 0 {
+		return nil, fmt.Errorf("days cannot be negative")
+	}
+
+	_, err := store.db.Exec(`
+		INSERT INTO leave_allowances (workspace_id, member_id, year, leave_type, days) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(workspace_id, member_id, year, leave_type) DO UPDATE SET days = excluded.days
+	`, workspaceID, memberID, year, leaveType, days)
+	if err != nil {
+		return nil, err
+	}
+
+	var a models.LeaveAllowance
+	err = store.db.QueryRow(
+		"SELECT id, member_id, year, leave_type, days FROM leave_allowances WHERE workspace_id = ? AND member_id = ? AND year = ? AND leave_type = ?",
+		workspaceID, memberID, year, leaveType,
+	).Scan(&a.ID, &a.MemberID, &a.Year, &a.LeaveType, &a.Days)
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// GetLeaveAllowances gets all leave allowances for a year
+func (store *SQLStore) GetLeaveAllowances(workspaceID, year int) ([]models.LeaveAllowance, error) {
+	rows, err := store.db.Query(
+		"SELECT id, member_id, year, leave_type, days FROM leave_allowances WHERE workspace_id = ? AND year = ? ORDER BY member_id, leave_type",
+		workspaceID, year,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var allowances []models.LeaveAllowance
+	for rows.Next() {
+		var a models.LeaveAllowance
+		if err := rows.Scan(&a.ID, &a.MemberID, &a.Year, &a.LeaveType, &a.Days); err != nil {
+			return nil, err
+		}
+		allowances = append(allowances, a)
+	}
+
+	return allowances, rows.Err()
+}
+
+// GetLeaveBalances computes leave balances for a year
+// If memberID is not 0, only that member's balances are returned.
+func (store *SQLStore) GetLeaveBalances(workspaceID, year, memberID int) ([]models.LeaveBalance, error) {
+	return leaveBalances(store, workspaceID, year, memberID)
+}
+
+// leaveBalances computes leave balances for a year from the data of a store
+// Only working days count towards used and pending days, so weekends and
+// public holidays inside a leave range don't consume allowance.
+func leaveBalances(store interface {
+	MemberStore
+	LeaveStore
+}, workspaceID, year, memberID int) ([]models.LeaveBalance, error) {
+	members, err := store.GetAllMembers(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	yearStart := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	yearEnd := time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	// Key: memberID -> leave type -> days
+	allowed := make(map[int]map[string]int)
+	used := make(map[int]map[string]int)
+	pending := make(map[int]map[string]int)
+	add := func(m map[int]map[string]int, memberID int, leaveType string, days int) {
+		if m[memberID] == nil {
+			m[memberID] = make(map[string]int)
+		}
+		m[memberID][leaveType] += days
+	}
+
+	allowances, err := store.GetLeaveAllowances(workspaceID, year)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range allowances {
+		add(allowed, a.MemberID, a.LeaveType, a.Days)
+	}
+
+	leaveDays, err := store.GetLeaveDaysByDateRange(workspaceID, yearStart, yearEnd)
+	if err != nil {
+		return nil, err
+	}
+	for _, ld := range leaveDays {
+		if models.IsWorkingDay(ld.LeaveDate) {
+			add(used, ld.MemberID, ld.LeaveType, 1)
+		}
+	}
+
+	pendingRequests, err := store.GetLeaveRequests(workspaceID, models.LeaveStatusPending, memberID)
+	if err != nil {
+		return nil, err
+	}
+	for _, lr := range pendingRequests {
+		start, end := lr.StartDate, lr.EndDate
+		if start.Before(yearStart) {
+			start = yearStart
+		}
+		if end.After(yearEnd) {
+			end = yearEnd
+		}
+		if !start.After(end) {
+			add(pending, lr.MemberID, lr.LeaveType, models.CountWorkingDays(start, end))
+		}
+	}
+
+	leaveTypes := []string{models.LeaveTypeAnnual, models.LeaveTypeSick, models.LeaveTypeUnpaid, models.LeaveTypeOther}
+
+	var balances []models.LeaveBalance
+	for _, member := range members {
+		if memberID != 0 && member.ID != memberID {
+			continue
+		}
+		for _, leaveType := range leaveTypes {
+			allowance, hasAllowance := allowed[member.ID][leaveType]
+			usedDays := used[member.ID][leaveType]
+			pendingDays := pending[member.ID][leaveType]
+
+			// Annual leave is always reported, other types only when relevant
+			if leaveType != models.LeaveTypeAnnual && !hasAllowance && usedDays == 0 && pendingDays == 0 {
+				continue
+			}
+
+			balances = append(balances, models.LeaveBalance{
+				MemberID:   member.ID,
+				MemberName: member.Name,
+				Year:       year,
+				LeaveType:  leaveType,
+				Allowance:  allowance,
+				Used:       usedDays,
+				Pending:    pendingDays,
+				Remaining:  allowance - usedDays,
+			})
+		}
+	}
+
+	return balances, nil
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanLeaveRequest scans a leave request row
+func scanLeaveRequest(row rowScanner) (*models.LeaveRequest, error) {
+	var lr models.LeaveRequest
+	var startDateStr, endDateStr, createdAtStr string
+	var decidedAtStr sql.NullString
+
+	if err := row.Scan(&lr.ID, &lr.MemberID, &startDateStr, &endDateStr, &lr.LeaveType, &lr.Status, &lr.Note, &decidedAtStr, &createdAtStr); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if lr.StartDate, err = parseDate(startDateStr); err != nil {
+		return nil, fmt.Errorf("error parsing start_date '%s': %v", startDateStr, err)
+	}
+	if lr.EndDate, err = parseDate(endDateStr); err != nil {
+		return nil, fmt.Errorf("error parsing end_date '%s': %v", endDateStr, err)
+	}
+
+	if decidedAtStr.Valid && decidedAtStr.String != "" {
+		decidedAt := parseDateTime(decidedAtStr.String)
+		lr.DecidedAt = &decidedAt
+	}
+	lr.CreatedAt = parseDateTime(createdAtStr)
+
+	return &lr, nil
+}
+
+// parseDate parses a stored date and normalizes it to UTC midnight
+// Supports both "2006-01-02" and ISO 8601 formats
+func parseDate(s string) (time.Time, error) {
+	var t time.Time
+	var err error
+	if t, err = time.Parse("2006-01-02", s); err != nil {
+		if t, err = time.Parse(time.RFC3339, s); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+// parseDateTime parses a stored SQLite datetime
+// Falls back to the current time if the value can't be parsed
+func parseDateTime(s string) time.Time {
+	if t, err := time.Parse("2006-01-02 15:04:05", s); err == nil {
+		return t.UTC()
+	}
+	if t, err := time.Parse("2006-01-02T15:04:05Z07:00", s); err == nil {
+		return t.UTC()
+	}
+	return time.Now().UTC()
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"shiftplanner/backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestApproveLeaveRequest(t *testing.T) {
+	forEachStoreWithWorkspace(t, func(t *testing.T, store Store, workspaceID int) {
+		member, _ := store.CreateMember(workspaceID, "Test Member")
+		startDate := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+		endDate := time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC)
+
+		lr, err := store.CreateLeaveRequest(workspaceID, member.ID, startDate, endDate, models.LeaveTypeAnnual, "")
+		if err != nil {
+			t.Fatalf("Failed to create leave request: %v", err)
+		}
+
+		// Pending requests must not be visible as leave days
+		leaveDays, _ := store.GetLeaveDaysByDateRange(workspaceID, startDate, endDate)
+		if len(leaveDays) != 0 {
+			t.Errorf("Pending request should not create leave days, got %d", len(leaveDays))
+		}
+
+		approved, err := store.ApproveLeaveRequest(workspaceID, lr.ID)
+		if err != nil {
+			t.Fatalf("Failed to approve leave request: %v", err)
+		}
+
+		if approved.Status != models.LeaveStatusApproved {
+			t.Errorf("Status mismatch: got %s, want %s", approved.Status, models.LeaveStatusApproved)
+		}
+
+		leaveDays, _ = store.GetLeaveDaysByDateRange(workspaceID, startDate, endDate)
+		if len(leaveDays) != 3 {
+			t.Errorf("Leave day count mismatch: got %d, want 3", len(leaveDays))
+		}
+
+		// Already decided requests can't be decided again
+		if _, err := store.RejectLeaveRequest(workspaceID, lr.ID); err != ErrLeaveRequestNotPending {
+			t.Errorf("Expected ErrLeaveRequestNotPending, got %v", err)
+		}
+	})
+}
+
+func TestApproveLeaveRequest_AlreadyDecided(t *testing.T) {
+	store := openSQLiteTestStore(t).(*SQLStore)
+	user, _ := store.CreateUser("testuser", "testpassword")
+	workspaceID := personalWorkspace(t, store, user.ID)
+	member, _ := store.CreateMember(workspaceID, "Test Member")
+	startDate := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	lr, _ := store.CreateLeaveRequest(workspaceID, member.ID, startDate, startDate, models.LeaveTypeAnnual, "")
+
+	// A concurrent decision read the request as pending before this one was stored
+	stale, _ := store.GetLeaveRequestByID(workspaceID, lr.ID)
+	if _, err := store.RejectLeaveRequest(workspaceID, lr.ID); err != nil {
+		t.Fatalf("Failed to reject leave request: %v", err)
+	}
+	if _, err := decideLeaveRequest(store.db.sqlConn, workspaceID, stale, models.LeaveStatusApproved); err != ErrLeaveRequestNotPending {
+		t.Errorf("Expected ErrLeaveRequestNotPending, got %v", err)
+	}
+	if lr, _ := store.GetLeaveRequestByID(workspaceID, lr.ID); lr.Status != models.LeaveStatusRejected {
+		t.Errorf("Status mismatch: got %s, want %s", lr.Status, models.LeaveStatusRejected)
+	}
+}
+
+func TestRejectLeaveRequest(t *testing.T) {
+	forEachStoreWithWorkspace(t, func(t *testing.T, store Store, workspaceID int) {
+		member, _ := store.CreateMember(workspaceID, "Test Member")
+		startDate := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+		endDate := time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC)
+
+		lr, _ := store.CreateLeaveRequest(workspaceID, member.ID, startDate, endDate, models.LeaveTypeSick, "")
+		rejected, err := store.RejectLeaveRequest(workspaceID, lr.ID)
+		if err != nil {
+			t.Fatalf("Failed to reject leave request: %v", err)
+		}
+
+		if rejected.Status != models.LeaveStatusRejected {
+			t.Errorf("Status mismatch: got %s, want %s", rejected.Status, models.LeaveStatusRejected)
+		}
+
+		leaveDays, _ := store.GetLeaveDaysByDateRange(workspaceID, startDate, endDate)
+		if len(leaveDays) != 0 {
+			t.Errorf("Rejected request should not create leave days, got %d", len(leaveDays))
+		}
+	})
+}
+
+func TestGetLeaveBalances(t *testing.T) {
+	forEachStoreWithWorkspace(t, func(t *testing.T, store Store, workspaceID int) {
+		member, _ := store.CreateMember(workspaceID, "Test Member")
+
+		if _, err := store.SetLeaveAllowance(workspaceID, member.ID, 2025, models.LeaveTypeAnnual, 14); err != nil {
+			t.Fatalf("Failed to set allowance: %v", err)
+		}
+
+		// Thursday 2025-01-02 to Tuesday 2025-01-07: the weekend doesn't count (4 working days)
+		_, err := store.CreateLeaveDaysRange(workspaceID, member.ID, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC), models.LeaveTypeAnnual)
+		if err != nil {
+			t.Fatalf("Failed to create leave days: %v", err)
+		}
+
+		// Pending request over the 2025-04-23 holiday (Tuesday to Thursday, 2 working days)
+		store.CreateLeaveRequest(workspaceID, member.ID, time.Date(2025, 4, 22, 0, 0, 0, 0, time.UTC), time.Date(2025, 4, 24, 0, 0, 0, 0, time.UTC), models.LeaveTypeAnnual, "")
+
+		balances, err := store.GetLeaveBalances(workspaceID, 2025, member.ID)
+		if err != nil {
+			t.Fatalf("Failed to get balances: %v", err)
+		}
+
+		if len(balances) != 1 {
+			t.Fatalf("Balance count mismatch: got %d, want 1", len(balances))
+		}
+
+		b := balances[0]
+		if b.Allowance != 14 || b.Used != 4 || b.Pending != 2 || b.Remaining != 10 {
+			t.Errorf("Balance mismatch: got allowance=%d used=%d pending=%d remaining=%d, want 14/4/2/10", b.Allowance, b.Used, b.Pending, b.Remaining)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+// GetMemberByUser gets the member linked to a user
+func (store *SQLStore) GetMemberByUser(workspaceID, userID int) (*models.Member, error) {
+	var m models.Member
+	var createdAtStr string
+	err := store.db.QueryRow(
+		"SELECT id, name, user_id, created_at FROM members WHERE workspace_id = ? AND user_id = ?",
+		workspaceID, userID,
+	).Scan(&m.ID, &m.Name, &m.UserID, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	m.CreatedAt = parseDateTime(createdAtStr)
+	return &m, nil
+}
+
+// CreateMemberInvite stores the hash of a token that links the account accepting it to a member
+func (store *SQLStore) CreateMemberInvite(workspaceID, memberID int, tokenHash string, expiresAt time.Time) (*models.MemberInvite, error) {
+	if _, err := store.GetMemberByID(workspaceID, memberID); err != nil {
+		return nil, err
+	}
+
+	_, err := store.db.Exec(
+		"INSERT INTO member_invites (workspace_id, member_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?, ?)",
+		workspaceID, memberID, tokenHash, expiresAt.UTC().Format("2006-01-02 15:04:05"),
+		time.Now().UTC().Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MemberInvite{MemberID: memberID, ExpiresAt: expiresAt}, nil
+}
+
+// AcceptMemberInvite links a user to the member of an invite and uses the invite up
+func (store *SQLStore) AcceptMemberInvite(userID int, tokenHash string, now time.Time) (int, *models.Member, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	var workspaceID, memberID int
+	err = tx.QueryRow(
+		"SELECT workspace_id, member_id FROM member_invites WHERE token_hash = ? AND expires_at > ?",
+		tokenHash, now.UTC().Format("2006-01-02 15:04:05"),
+	).Scan(&workspaceID, &memberID)
+	if err == sql.ErrNoRows {
+		return 0, nil, ErrMemberInviteNotFound
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Invites of deleted members are useless, even where foreign keys don't cascade
+	var m models.Member
+	var createdAtStr string
+	err = tx.QueryRow("SELECT id, name, created_at FROM members WHERE id = ? AND workspace_id = ?", memberID, workspaceID).
+		Scan(&m.ID, &m.Name, &createdAtStr)
+	if err == sql.ErrNoRows {
+		return 0, nil, ErrMemberInviteNotFound
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	m.UserID = userID
+	m.CreatedAt = parseDateTime(createdAtStr)
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"DELETE FROM member_invites WHERE token_hash = ?", []interface{}{tokenHash}},
+		{"UPDATE members SET user_id = NULL WHERE workspace_id = ? AND user_id = ?", []interface{}{workspaceID, userID}},
+		{"UPDATE members SET user_id = ? WHERE id = ? AND workspace_id = ?", []interface{}{userID, memberID, workspaceID}},
+	}
+	for _, s := range statements {
+		if _, err := tx.Exec(s.query, s.args...); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var role string
+	err = tx.QueryRow("SELECT role FROM workspace_users WHERE workspace_id = ? AND user_id = ?", workspaceID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		_, err = tx.Exec(
+			"INSERT INTO workspace_users (workspace_id, user_id, role, created_at) VALUES (?, ?, ?, ?)",
+			workspaceID, userID, models.RoleMember, now.UTC().Format("2006-01-02 15:04:05"),
+		)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+	return workspaceID, &m, nil
+}
+
+// UnlinkMember removes the link between a member and their user account
+func (store *SQLStore) UnlinkMember(workspaceID, memberID int) error {
+	result, err := store.db.Exec("UPDATE members SET user_id = NULL WHERE id = ? AND workspace_id = ?", memberID, workspaceID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestMemberInvite_LinksUser(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, ownerID int) {
+		workspaceID := personalWorkspace(t, store, ownerID)
+		alice, _ := store.CreateMember(workspaceID, "Alice")
+		bob, _ := store.CreateMember(workspaceID, "Bob")
+		user, _ := store.CreateUser("alice", "testpassword")
+		now := time.Now().UTC().Truncate(time.Second)
+
+		if _, err := store.GetMemberByUser(workspaceID, user.ID); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows before linking, got %v", err)
+		}
+		if _, err := store.CreateMemberInvite(workspaceID, 9999, "missing", now.Add(time.Hour)); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for a missing member, got %v", err)
+		}
+
+		store.CreateMemberInvite(workspaceID, alice.ID, "invite-alice", now.Add(time.Hour))
+		store.CreateMemberInvite(workspaceID, bob.ID, "invite-expired", now.Add(-time.Hour))
+
+		acceptedWorkspaceID, member, err := store.AcceptMemberInvite(user.ID, "invite-alice", now)
+		if err != nil {
+			t.Fatalf("Failed to accept invite: %v", err)
+		}
+		if acceptedWorkspaceID != workspaceID || member.ID != alice.ID || member.UserID != user.ID {
+			t.Errorf("Accepted invite mismatch: workspace %d, %+v", acceptedWorkspaceID, member)
+		}
+		if role, _ := store.GetWorkspaceRole(workspaceID, user.ID); role != models.RoleMember {
+			t.Errorf("Invited user should join as member, got %q", role)
+		}
+		if linked, err := store.GetMemberByUser(workspaceID, user.ID); err != nil || linked.ID != alice.ID {
+			t.Errorf("Linked member mismatch: %+v (%v)", linked, err)
+		}
+
+		// Invites are single-use and expire
+		if _, _, err := store.AcceptMemberInvite(user.ID, "invite-alice", now); err != ErrMemberInviteNotFound {
+			t.Errorf("Expected ErrMemberInviteNotFound for a used invite, got %v", err)
+		}
+		if _, _, err := store.AcceptMemberInvite(user.ID, "invite-expired", now); err != ErrMemberInviteNotFound {
+			t.Errorf("Expected ErrMemberInviteNotFound for an expired invite, got %v", err)
+		}
+
+		// Accepting an invite for another member moves the link
+		store.CreateMemberInvite(workspaceID, bob.ID, "invite-bob", now.Add(time.Hour))
+		store.AcceptMemberInvite(user.ID, "invite-bob", now)
+		if member, _ := store.GetMemberByID(workspaceID, alice.ID); member.UserID != 0 {
+			t.Errorf("Previous member should be unlinked, got user %d", member.UserID)
+		}
+
+		if err := store.UnlinkMember(workspaceID, bob.ID); err != nil {
+			t.Fatalf("Failed to unlink member: %v", err)
+		}
+		if _, err := store.GetMemberByUser(workspaceID, user.ID); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows after unlinking, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+type memoryPasswordReset struct {
+	models.PasswordReset
+	tokenHash string
+}
+
+// SetUserEmail sets the address password resets are sent to
+func (m *MemoryStore) SetUserEmail(userID int, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.data.users {
+		if m.data.users[i].ID == userID {
+			m.data.users[i].Email = email
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+// DeleteUser deletes a user with all their data, and the workspaces nobody else uses
+func (m *MemoryStore) DeleteUser(userID int) error {
+	return m.transaction(false, func(d *memoryData) error {
+		found := false
+		for _, user := range d.users {
+			found = found || user.ID == userID
+		}
+		if !found {
+			return sql.ErrNoRows
+		}
+
+		deleted := make(map[int]bool)
+		for _, wu := range d.workspaceUsers {
+			if wu.userID != userID {
+				continue
+			}
+			others := 0
+			for _, o := range d.workspaceUsers {
+				if o.workspaceID == wu.workspaceID && o.userID != userID {
+					others++
+				}
+			}
+			if others == 0 {
+				deleted[wu.workspaceID] = true
+			} else if wu.role == models.RoleOwner && d.ownerCount(wu.workspaceID) == 1 {
+				return ErrLastOwner
+			}
+		}
+
+		// Workspaces nobody else uses go with all their data
+		inDeleted := func(workspaceID int) bool { return deleted[workspaceID] }
+		d.workspaces = filterRows(d.workspaces, func(w models.Workspace) bool { return inDeleted(w.ID) })
+		d.workspaceInvites = filterRows(d.workspaceInvites, func(i memoryWorkspaceInvite) bool { return inDeleted(i.workspaceID) })
+		d.members = filterRows(d.members, func(row memoryMember) bool { return inDeleted(row.workspaceID) })
+		d.memberInvites = filterRows(d.memberInvites, func(row memoryMemberInvite) bool { return inDeleted(row.workspaceID) })
+		d.shifts = filterRows(d.shifts, func(row memoryShift) bool { return inDeleted(row.workspaceID) })
+		d.leaveDays = filterRows(d.leaveDays, func(row memoryLeaveDay) bool { return inDeleted(row.workspaceID) })
+		d.leaveRequests = filterRows(d.leaveRequests, func(row memoryLeaveRequest) bool { return inDeleted(row.workspaceID) })
+		d.swapRequests = filterRows(d.swapRequests, func(row memorySwapRequest) bool { return inDeleted(row.workspaceID) })
+		d.leaveAllowances = filterRows(d.leaveAllowances, func(row memoryLeaveAllowance) bool { return inDeleted(row.workspaceID) })
+		d.rules = filterRows(d.rules, func(row memoryRule) bool { return inDeleted(row.workspaceID) })
+		d.imports = filterRows(d.imports, func(row memoryImport) bool { return inDeleted(row.workspaceID) })
+		d.auditLog = filterRows(d.auditLog, func(row memoryAuditEntry) bool { return inDeleted(row.workspaceID) })
+		d.planSnapshots = filterRows(d.planSnapshots, func(row memoryPlanSnapshot) bool { return inDeleted(row.workspaceID) })
+
+		// The user's own rows
+		for i := range d.members {
+			if d.members[i].UserID == userID {
+				d.members[i].UserID = 0
+			}
+		}
+		d.users = filterRows(d.users, func(u memoryUser) bool { return u.ID == userID })
+		d.userIdentities = filterRows(d.userIdentities, func(i memoryUserIdentity) bool { return i.userID == userID })
+		d.workspaceUsers = filterRows(d.workspaceUsers, func(wu memoryWorkspaceUser) bool { return wu.userID == userID })
+		d.sessions = filterRows(d.sessions, func(s memorySession) bool { return s.UserID == userID })
+		d.feedTokens = filterRows(d.feedTokens, func(ft memoryFeedToken) bool { return ft.userID == userID })
+		d.apiKeys = filterRows(d.apiKeys, func(k memoryAPIKey) bool { return k.UserID == userID || inDeleted(k.WorkspaceID) })
+		d.twoFactors = filterRows(d.twoFactors, func(t models.TwoFactor) bool { return t.UserID == userID })
+		d.recoveryCodes = filterRows(d.recoveryCodes, func(c memoryRecoveryCode) bool { return c.userID == userID })
+		d.loginChallenges = filterRows(d.loginChallenges, func(c memoryLoginChallenge) bool { return c.UserID == userID })
+		d.passwordResets = filterRows(d.passwordResets, func(r memoryPasswordReset) bool { return r.UserID == userID })
+		return nil
+	})
+}
+
+// CreatePasswordReset stores a password reset under the hash of its token, replacing earlier ones
+func (m *MemoryStore) CreatePasswordReset(userID int, tokenHash string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.passwordResets = filterRows(m.data.passwordResets, func(r memoryPasswordReset) bool { return r.UserID == userID })
+	m.data.passwordResets = append(m.data.passwordResets, memoryPasswordReset{
+		PasswordReset: models.PasswordReset{
+			ID:        m.data.nextID("password_resets"),
+			UserID:    userID,
+			ExpiresAt: expiresAt,
+		},
+		tokenHash: tokenHash,
+	})
+	return nil
+}
+
+// GetPasswordReset gets an unexpired password reset by the hash of its token
+func (m *MemoryStore) GetPasswordReset(tokenHash string, now time.Time) (*models.PasswordReset, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, reset := range m.data.passwordResets {
+		if reset.tokenHash == tokenHash && reset.ExpiresAt.After(now) {
+			result := reset.PasswordReset
+			return &result, nil
+		}
+	}
+	return nil, ErrPasswordResetNotFound
+}
+
+// ResetPassword uses up a password reset, sets the new password and deletes the user's sessions
+func (m *MemoryStore) ResetPassword(resetID int, password string) error {
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	userID := 0
+	for _, reset := range m.data.passwordResets {
+		if reset.ID == resetID {
+			userID = reset.UserID
+		}
+	}
+	if userID == 0 {
+		return ErrPasswordResetNotFound
+	}
+
+	m.data.passwordResets = filterRows(m.data.passwordResets, func(r memoryPasswordReset) bool { return r.ID == resetID })
+	for i := range m.data.users {
+		if m.data.users[i].ID == userID {
+			m.data.users[i].passwordHash = passwordHash
+		}
+	}
+	m.data.sessions = filterRows(m.data.sessions, func(s memorySession) bool { return s.UserID == userID })
+	return nil
+}
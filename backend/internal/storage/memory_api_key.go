@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+type memoryAPIKey struct {
+	models.APIKey
+	keyHash string
+}
+
+// CreateAPIKey stores a new API key of a user for a workspace
+func (m *MemoryStore) CreateAPIKey(userID, workspaceID int, name, prefix, scope, keyHash string) (*models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := memoryAPIKey{
+		APIKey: models.APIKey{
+			ID:          m.data.nextID("api_keys"),
+			UserID:      userID,
+			WorkspaceID: workspaceID,
+			Name:        name,
+			Prefix:      prefix,
+			Scope:       scope,
+			CreatedAt:   time.Now().UTC(),
+		},
+		keyHash: keyHash,
+	}
+	m.data.apiKeys = append(m.data.apiKeys, key)
+	return &key.APIKey, nil
+}
+
+// GetAPIKeys lists the API keys of a user for a workspace, oldest first
+func (m *MemoryStore) GetAPIKeys(userID, workspaceID int) ([]models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var keys []models.APIKey
+	for _, key := range m.data.apiKeys {
+		if key.UserID == userID && key.WorkspaceID == workspaceID {
+			keys = append(keys, key.APIKey)
+		}
+	}
+	return keys, nil
+}
+
+// DeleteAPIKey revokes an API key
+func (m *MemoryStore) DeleteAPIKey(userID, keyID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := len(m.data.apiKeys)
+	m.data.apiKeys = filterRows(m.data.apiKeys, func(r memoryAPIKey) bool {
+		return r.UserID == userID && r.ID == keyID
+	})
+	if len(m.data.apiKeys) == count {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// UseAPIKey resolves an API key by its hash and records now as its last use
+// The last use is only updated when the stored one is touchInterval or more older.
+func (m *MemoryStore) UseAPIKey(keyHash string, now time.Time, touchInterval time.Duration) (*models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.data.apiKeys {
+		if m.data.apiKeys[i].keyHash == keyHash {
+			lastUsedAt := now.UTC().Truncate(time.Second)
+			if previous := m.data.apiKeys[i].LastUsedAt; previous == nil || lastUsedAt.Sub(*previous) >= touchInterval {
+				m.data.apiKeys[i].LastUsedAt = &lastUsedAt
+			}
+			result := m.data.apiKeys[i].APIKey
+			return &result, nil
+		}
+	}
+	return nil, ErrAPIKeyNotFound
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+// AppendAuditEntry adds an entry to the audit log of a workspace
+func (m *MemoryStore) AppendAuditEntry(workspaceID int, entry models.AuditEntry) (*models.AuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry.ID = m.data.nextID("audit_log")
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	m.data.auditLog = append(m.data.auditLog, memoryAuditEntry{AuditEntry: entry, workspaceID: workspaceID})
+	return &entry, nil
+}
+
+// GetAuditEntries gets the audit log of a workspace, newest first
+func (m *MemoryStore) GetAuditEntries(workspaceID int, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []models.AuditEntry
+	for i := len(m.data.auditLog) - 1; i >= 0; i-- {
+		e := m.data.auditLog[i]
+		if e.workspaceID != workspaceID {
+			continue
+		}
+		if filter.Entity != "" && e.Entity != filter.Entity {
+			continue
+		}
+		if filter.MemberID != 0 && e.MemberID != filter.MemberID {
+			continue
+		}
+		day := normalizeDate(e.CreatedAt)
+		if !filter.StartDate.IsZero() && day.Before(normalizeDate(filter.StartDate)) {
+			continue
+		}
+		if !filter.EndDate.IsZero() && day.After(normalizeDate(filter.EndDate)) {
+			continue
+		}
+		entries = append(entries, e.AuditEntry)
+	}
+	return entries, nil
+}
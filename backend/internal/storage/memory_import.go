@@ -0,0 +1,355 @@
+package storage
+
+import (
+	"shiftplanner/backend/internal/models"
+	"sort"
+	"time"
+)
+
+// ImportShifts applies validated shift import rows in a single transaction
+// With dryRun all changes are discarded, so the result describes what would change.
+func (m *MemoryStore) ImportShifts(workspaceID int, filename, contentHash string, rows []models.ShiftImportRow, dryRun bool) (*models.ShiftImportResult, error) {
+	var result *models.ShiftImportResult
+	err := m.transaction(dryRun, func(d *memoryData) error {
+		var err error
+		if result, err = applyShiftImport(&memoryShiftImport{data: d, workspaceID: workspaceID}, rows); err != nil {
+			return err
+		}
+
+		if !dryRun {
+			d.imports = append(d.imports, memoryImport{
+				ImportRecord: models.ImportRecord{
+					ID:             d.nextID("imports"),
+					Kind:           models.ImportKindShifts,
+					ContentHash:    contentHash,
+					Filename:       filename,
+					MembersCreated: result.MembersCreated,
+					ShiftsCreated:  result.ShiftsCreated,
+					ShiftsUpdated:  result.ShiftsUpdated,
+					CreatedAt:      time.Now().UTC(),
+				},
+				workspaceID: workspaceID,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetImportByHash gets the latest applied import of a file with the given content hash
+// Returns nil if the file has not been imported before
+func (m *MemoryStore) GetImportByHash(workspaceID int, kind, contentHash string) (*models.ImportRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.data.imports) - 1; i >= 0; i-- {
+		r := m.data.imports[i]
+		if r.workspaceID == workspaceID && r.Kind == kind && r.ContentHash == contentHash {
+			return &r.ImportRecord, nil
+		}
+	}
+	return nil, nil
+}
+
+// memoryShiftImport applies an import to the data of a MemoryStore
+type memoryShiftImport struct {
+	data        *memoryData
+	workspaceID int
+}
+
+func (imp *memoryShiftImport) resolveMember(name string) (int, bool, error) {
+	if member := imp.data.memberByName(imp.workspaceID, name); member != nil {
+		return member.ID, false, nil
+	}
+	return imp.data.createMember(imp.workspaceID, name).ID, true, nil
+}
+
+func (imp *memoryShiftImport) memberName(memberID int) (string, error) {
+	if member := imp.data.member(imp.workspaceID, memberID); member != nil {
+		return member.Name, nil
+	}
+	return "", nil
+}
+
+func (imp *memoryShiftImport) shiftByDate(date time.Time) (*models.Shift, error) {
+	shift := imp.data.shiftByDate(imp.workspaceID, date)
+	if shift == nil {
+		return nil, nil
+	}
+	result := shift.Shift
+	return &result, nil
+}
+
+func (imp *memoryShiftImport) removeOverlappingShifts(row models.ShiftImportRow, excludeID int) (int, error) {
+	removed := 0
+	imp.data.shifts = filterRows(imp.data.shifts, func(shift memoryShift) bool {
+		if shift.workspaceID != imp.workspaceID || shift.ID == excludeID ||
+			!shift.StartDate.After(row.StartDate) || shift.StartDate.After(row.EndDate) {
+			return false
+		}
+		imp.data.adjustShiftCounts(imp.workspaceID, shift.Shift, -1)
+		removed++
+		return true
+	})
+	return removed, nil
+}
+
+func (imp *memoryShiftImport) insertShift(memberID int, row models.ShiftImportRow) error {
+	shift := imp.data.createShift(imp.workspaceID, memberID, row.StartDate, row.EndDate, row.IsLongShift, 0)
+	imp.data.adjustShiftCounts(imp.workspaceID, shift, 1)
+	return nil
+}
+
+func (imp *memoryShiftImport) updateShift(shift, target *models.Shift) error {
+	for i := range imp.data.shifts {
+		s := &imp.data.shifts[i]
+		if s.workspaceID != imp.workspaceID || s.ID != shift.ID {
+			continue
+		}
+		imp.data.adjustShiftCounts(imp.workspaceID, *shift, -1)
+		s.MemberID = target.MemberID
+		s.StartDate = target.StartDate
+		s.EndDate = target.EndDate
+		s.IsLongShift = target.IsLongShift
+		s.Sequence++
+		imp.data.adjustShiftCounts(imp.workspaceID, *target, 1)
+	}
+	return nil
+}
+
+// ExportBackup collects all data of a workspace into a backup archive
+func (m *MemoryStore) ExportBackup(workspaceID int) (*models.Backup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d := m.data
+	backup := newBackup()
+
+	for _, member := range d.members {
+		if member.workspaceID == workspaceID {
+			backup.Members = append(backup.Members, models.BackupMember{
+				ID:                 member.ID,
+				Name:               member.Name,
+				HiddenNormalShifts: member.hiddenNormalShifts,
+				HiddenLongShifts:   member.hiddenLongShifts,
+			})
+		}
+	}
+
+	for _, shift := range d.shiftsByDateRange(workspaceID, time.Time{}, time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		backup.Shifts = append(backup.Shifts, models.BackupShift{
+			MemberID:    shift.MemberID,
+			StartDate:   shift.StartDate.Format("2006-01-02"),
+			EndDate:     shift.EndDate.Format("2006-01-02"),
+			IsLongShift: shift.IsLongShift,
+			Sequence:    shift.Sequence,
+		})
+	}
+
+	for _, lr := range d.leaveRequests {
+		if lr.workspaceID == workspaceID {
+			backup.LeaveRequests = append(backup.LeaveRequests, models.BackupLeaveRequest{
+				ID:        lr.ID,
+				MemberID:  lr.MemberID,
+				StartDate: lr.StartDate.Format("2006-01-02"),
+				EndDate:   lr.EndDate.Format("2006-01-02"),
+				LeaveType: lr.LeaveType,
+				Status:    lr.Status,
+				Note:      lr.Note,
+			})
+		}
+	}
+
+	leaveDays := filterRows(d.leaveDays, func(ld memoryLeaveDay) bool { return ld.workspaceID != workspaceID })
+	sort.SliceStable(leaveDays, func(i, j int) bool {
+		return leaveDays[i].LeaveDate.Before(leaveDays[j].LeaveDate)
+	})
+	for _, ld := range leaveDays {
+		backup.LeaveDays = append(backup.LeaveDays, models.BackupLeaveDay{
+			MemberID:       ld.MemberID,
+			LeaveDate:      ld.LeaveDate.Format("2006-01-02"),
+			LeaveType:      ld.LeaveType,
+			LeaveRequestID: ld.leaveRequestID,
+		})
+	}
+
+	allowances := filterRows(d.leaveAllowances, func(a memoryLeaveAllowance) bool { return a.workspaceID != workspaceID })
+	sort.SliceStable(allowances, func(i, j int) bool {
+		a, b := allowances[i], allowances[j]
+		if a.Year != b.Year {
+			return a.Year < b.Year
+		}
+		if a.MemberID != b.MemberID {
+			return a.MemberID < b.MemberID
+		}
+		return a.LeaveType < b.LeaveType
+	})
+	for _, a := range allowances {
+		backup.LeaveAllowances = append(backup.LeaveAllowances, models.BackupLeaveAllowance{
+			MemberID:  a.MemberID,
+			Year:      a.Year,
+			LeaveType: a.LeaveType,
+			Days:      a.Days,
+		})
+	}
+
+	for _, rule := range d.unavailabilityRules(workspaceID, 0) {
+		backup.UnavailabilityRules = append(backup.UnavailabilityRules, backupUnavailability(rule))
+	}
+
+	return backup, nil
+}
+
+// RestoreBackup restores a backup archive into a workspace in a single transaction
+func (m *MemoryStore) RestoreBackup(workspaceID int, backup *models.Backup, replace bool) (*models.RestoreResult, error) {
+	if err := models.UpgradeBackup(backup); err != nil {
+		return nil, err
+	}
+
+	var result *models.RestoreResult
+	err := m.transaction(false, func(d *memoryData) error {
+		var err error
+		result, err = applyBackupRestore(&memoryBackupRestore{data: d, workspaceID: workspaceID}, backup, replace)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// memoryBackupRestore restores a backup archive into the data of a MemoryStore
+type memoryBackupRestore struct {
+	data        *memoryData
+	workspaceID int
+}
+
+func (r *memoryBackupRestore) clearWorkspace() error {
+	d, workspaceID := r.data, r.workspaceID
+	d.leaveDays = filterRows(d.leaveDays, func(row memoryLeaveDay) bool { return row.workspaceID == workspaceID })
+	d.leaveRequests = filterRows(d.leaveRequests, func(row memoryLeaveRequest) bool { return row.workspaceID == workspaceID })
+	d.leaveAllowances = filterRows(d.leaveAllowances, func(row memoryLeaveAllowance) bool { return row.workspaceID == workspaceID })
+	d.rules = filterRows(d.rules, func(row memoryRule) bool { return row.workspaceID == workspaceID })
+	d.shifts = filterRows(d.shifts, func(row memoryShift) bool { return row.workspaceID == workspaceID })
+	d.members = filterRows(d.members, func(row memoryMember) bool { return row.workspaceID == workspaceID })
+	return nil
+}
+
+func (r *memoryBackupRestore) memberIDByName(name string) (int, bool, error) {
+	if member := r.data.memberByName(r.workspaceID, name); member != nil {
+		return member.ID, true, nil
+	}
+	return 0, false, nil
+}
+
+func (r *memoryBackupRestore) insertMember(name string, hiddenNormalShifts, hiddenLongShifts int) (int, error) {
+	member := memoryMember{
+		Member: models.Member{
+			ID:        r.data.nextID("members"),
+			Name:      name,
+			CreatedAt: time.Now().UTC(),
+		},
+		workspaceID:        r.workspaceID,
+		hiddenNormalShifts: hiddenNormalShifts,
+		hiddenLongShifts:   hiddenLongShifts,
+	}
+	r.data.members = append(r.data.members, member)
+	return member.ID, nil
+}
+
+func (r *memoryBackupRestore) hasShiftOn(date string) (bool, error) {
+	t, err := parseDate(date)
+	if err != nil {
+		return false, err
+	}
+	return r.data.shiftByDate(r.workspaceID, t) != nil, nil
+}
+
+func (r *memoryBackupRestore) insertShift(memberID int, startDate, endDate string, isLongShift bool, sequence int) error {
+	start, end, err := parseDateRange(startDate, endDate)
+	if err != nil {
+		return err
+	}
+	r.data.createShift(r.workspaceID, memberID, start, end, isLongShift, sequence)
+	return nil
+}
+
+func (r *memoryBackupRestore) leaveRequestID(memberID int, startDate, endDate, leaveType, status string) (int, bool, error) {
+	start, end, err := parseDateRange(startDate, endDate)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, lr := range r.data.leaveRequests {
+		if lr.workspaceID == r.workspaceID && lr.MemberID == memberID && lr.StartDate.Equal(start) && lr.EndDate.Equal(end) &&
+			lr.LeaveType == leaveType && lr.Status == status {
+			return lr.ID, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func (r *memoryBackupRestore) insertLeaveRequest(memberID int, startDate, endDate, leaveType, status, note string) (int, error) {
+	start, end, err := parseDateRange(startDate, endDate)
+	if err != nil {
+		return 0, err
+	}
+	return r.data.insertLeaveRequest(r.workspaceID, memberID, start, end, leaveType, status, note).ID, nil
+}
+
+func (r *memoryBackupRestore) insertLeaveDay(memberID int, leaveDate, leaveType string, requestID int) (bool, error) {
+	date, err := parseDate(leaveDate)
+	if err != nil {
+		return false, err
+	}
+	if r.data.leaveDay(r.workspaceID, memberID, date) != nil {
+		return false, nil
+	}
+	r.data.insertLeaveDay(r.workspaceID, memberID, date, leaveType, requestID)
+	return true, nil
+}
+
+func (r *memoryBackupRestore) setLeaveAllowance(memberID, year int, leaveType string, days int) error {
+	r.data.setLeaveAllowance(r.workspaceID, memberID, year, leaveType, days)
+	return nil
+}
+
+func (r *memoryBackupRestore) insertUnavailabilityRule(memberID int, frequency string, weekdays []time.Weekday, nth int, startDate, endDate, note string) error {
+	rule := models.UnavailabilityRule{
+		MemberID:  memberID,
+		Frequency: frequency,
+		Weekdays:  weekdays,
+		Nth:       nth,
+		Note:      note,
+	}
+
+	var err error
+	if rule.StartDate, err = parseDate(startDate); err != nil {
+		return err
+	}
+	if endDate != "" {
+		end, err := parseDate(endDate)
+		if err != nil {
+			return err
+		}
+		rule.EndDate = &end
+	}
+
+	r.data.insertUnavailabilityRule(r.workspaceID, rule)
+	return nil
+}
+
+// parseDateRange parses a stored start and end date
+func parseDateRange(start, end string) (time.Time, time.Time, error) {
+	startDate, err := parseDate(start)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	endDate, err := parseDate(end)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return startDate, endDate, nil
+}
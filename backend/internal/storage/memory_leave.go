@@ -0,0 +1,412 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"shiftplanner/backend/internal/models"
+	"sort"
+	"time"
+)
+
+// CreateLeaveDay creates a new leave day record
+func (m *MemoryStore) CreateLeaveDay(workspaceID, memberID int, leaveDate time.Time, leaveType string) (*models.LeaveDay, error) {
+	if leaveDate.IsZero() {
+		return nil, fmt.Errorf("leave_date cannot be zero")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	leaveDate = normalizeDate(leaveDate)
+	if m.data.leaveDay(workspaceID, memberID, leaveDate) != nil {
+		return nil, fmt.Errorf("leave day for member %d on %s already exists", memberID, leaveDate.Format("2006-01-02"))
+	}
+
+	ld := m.data.insertLeaveDay(workspaceID, memberID, leaveDate, leaveType, 0)
+	return &ld, nil
+}
+
+// CreateLeaveDaysRange creates leave days for a date range
+func (m *MemoryStore) CreateLeaveDaysRange(workspaceID, memberID int, startDate, endDate time.Time, leaveType string) ([]models.LeaveDay, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.data.createLeaveDaysRange(workspaceID, memberID, startDate, endDate, leaveType, 0)
+}
+
+// createLeaveDaysRange creates leave days for a date range
+// Existing leave days are kept and returned as they are. If requestID is not 0,
+// the created leave days are linked to that leave request.
+func (d *memoryData) createLeaveDaysRange(workspaceID, memberID int, startDate, endDate time.Time, leaveType string, requestID int) ([]models.LeaveDay, error) {
+	if startDate.IsZero() || endDate.IsZero() {
+		return nil, fmt.Errorf("start_date and end_date cannot be zero")
+	}
+
+	if startDate.After(endDate) {
+		return nil, fmt.Errorf("start_date must be before or equal to end_date")
+	}
+
+	var leaveDays []models.LeaveDay
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		dateUTC := normalizeDate(date)
+		if existing := d.leaveDay(workspaceID, memberID, dateUTC); existing != nil {
+			leaveDays = append(leaveDays, existing.LeaveDay)
+			continue
+		}
+		leaveDays = append(leaveDays, d.insertLeaveDay(workspaceID, memberID, dateUTC, leaveType, requestID))
+	}
+
+	return leaveDays, nil
+}
+
+// leaveDay finds a member's leave day row on a date, nil if there is none
+func (d *memoryData) leaveDay(workspaceID, memberID int, date time.Time) *memoryLeaveDay {
+	for i := range d.leaveDays {
+		ld := &d.leaveDays[i]
+		if ld.workspaceID == workspaceID && ld.MemberID == memberID && ld.LeaveDate.Equal(date) {
+			return ld
+		}
+	}
+	return nil
+}
+
+// insertLeaveDay stores a leave day without checking for duplicates
+func (d *memoryData) insertLeaveDay(workspaceID, memberID int, date time.Time, leaveType string, requestID int) models.LeaveDay {
+	ld := models.LeaveDay{
+		ID:        d.nextID("leave_days"),
+		MemberID:  memberID,
+		LeaveDate: date,
+		LeaveType: leaveType,
+		CreatedAt: time.Now().UTC(),
+	}
+	d.leaveDays = append(d.leaveDays, memoryLeaveDay{LeaveDay: ld, workspaceID: workspaceID, leaveRequestID: requestID})
+	return ld
+}
+
+// GetLeaveDaysByDateRange gets leave days for members in a date range
+// Recurring unavailability rules are expanded into leave days for the range
+func (m *MemoryStore) GetLeaveDaysByDateRange(workspaceID int, startDate, endDate time.Time) ([]models.LeaveDay, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	startDateUTC, endDateUTC := normalizeDate(startDate), normalizeDate(endDate)
+	leaveDays := m.data.sortedLeaveDays(func(ld memoryLeaveDay) bool {
+		return ld.workspaceID == workspaceID && !ld.LeaveDate.Before(startDateUTC) && !ld.LeaveDate.After(endDateUTC)
+	})
+
+	return mergeUnavailability(leaveDays, m.data.unavailabilityRules(workspaceID, 0), startDate, endDate), nil
+}
+
+// GetLeaveDaysByMember gets all leave days for a specific member
+func (m *MemoryStore) GetLeaveDaysByMember(workspaceID, memberID int) ([]models.LeaveDay, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.data.sortedLeaveDays(func(ld memoryLeaveDay) bool {
+		return ld.workspaceID == workspaceID && ld.MemberID == memberID
+	}), nil
+}
+
+// GetLeaveDayByID gets a leave day by ID (can only get own leave days)
+func (m *MemoryStore) GetLeaveDayByID(workspaceID, leaveDayID int) (*models.LeaveDay, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, ld := range m.data.leaveDays {
+		if ld.workspaceID == workspaceID && ld.ID == leaveDayID {
+			result := ld.LeaveDay
+			return &result, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// sortedLeaveDays returns the leave days matching keep, ordered by date
+func (d *memoryData) sortedLeaveDays(keep func(ld memoryLeaveDay) bool) []models.LeaveDay {
+	var leaveDays []models.LeaveDay
+	for _, ld := range d.leaveDays {
+		if keep(ld) {
+			leaveDays = append(leaveDays, ld.LeaveDay)
+		}
+	}
+	sort.SliceStable(leaveDays, func(i, j int) bool {
+		return leaveDays[i].LeaveDate.Before(leaveDays[j].LeaveDate)
+	})
+	return leaveDays
+}
+
+// IsMemberOnLeave checks if a member is on leave on a specific date
+// Recurring unavailability rules are taken into account
+func (m *MemoryStore) IsMemberOnLeave(workspaceID, memberID int, date time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dateUTC := normalizeDate(date)
+	if m.data.leaveDay(workspaceID, memberID, dateUTC) != nil {
+		return true, nil
+	}
+	for _, rule := range m.data.unavailabilityRules(workspaceID, memberID) {
+		if rule.Matches(dateUTC) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteLeaveDay deletes a leave day record
+func (m *MemoryStore) DeleteLeaveDay(workspaceID, leaveDayID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.leaveDays = filterRows(m.data.leaveDays, func(ld memoryLeaveDay) bool {
+		return ld.workspaceID == workspaceID && ld.ID == leaveDayID
+	})
+	return nil
+}
+
+// CreateLeaveRequest creates a new pending leave request
+func (m *MemoryStore) CreateLeaveRequest(workspaceID, memberID int, startDate, endDate time.Time, leaveType, note string) (*models.LeaveRequest, error) {
+	if startDate.IsZero() || endDate.IsZero() {
+		return nil, fmt.Errorf("start_date and end_date cannot be zero")
+	}
+
+	if startDate.After(endDate) {
+		return nil, fmt.Errorf("start_date must be before or equal to end_date")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lr := m.data.insertLeaveRequest(workspaceID, memberID, normalizeDate(startDate), normalizeDate(endDate), leaveType, models.LeaveStatusPending, note)
+	return &lr, nil
+}
+
+// insertLeaveRequest stores a leave request with the given status
+func (d *memoryData) insertLeaveRequest(workspaceID, memberID int, startDate, endDate time.Time, leaveType, status, note string) models.LeaveRequest {
+	lr := models.LeaveRequest{
+		ID:        d.nextID("leave_requests"),
+		MemberID:  memberID,
+		StartDate: startDate,
+		EndDate:   endDate,
+		LeaveType: leaveType,
+		Status:    status,
+		Note:      note,
+		CreatedAt: time.Now().UTC(),
+	}
+	d.leaveRequests = append(d.leaveRequests, memoryLeaveRequest{LeaveRequest: lr, workspaceID: workspaceID})
+	return lr
+}
+
+// GetLeaveRequests gets leave requests of a workspace
+// Empty status and zero memberID mean no filtering
+func (m *MemoryStore) GetLeaveRequests(workspaceID int, status string, memberID int) ([]models.LeaveRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var requests []models.LeaveRequest
+	for _, lr := range m.data.leaveRequests {
+		if lr.workspaceID != workspaceID || (status != "" && lr.Status != status) || (memberID != 0 && lr.MemberID != memberID) {
+			continue
+		}
+		requests = append(requests, lr.LeaveRequest)
+	}
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].StartDate.Before(requests[j].StartDate)
+	})
+	return requests, nil
+}
+
+// GetLeaveRequestByID gets a leave request by ID (can only get own requests)
+func (m *MemoryStore) GetLeaveRequestByID(workspaceID, requestID int) (*models.LeaveRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lr := m.data.leaveRequest(workspaceID, requestID)
+	if lr == nil {
+		return nil, sql.ErrNoRows
+	}
+	result := lr.LeaveRequest
+	return &result, nil
+}
+
+// leaveRequest finds a leave request row, nil if it doesn't exist
+func (d *memoryData) leaveRequest(workspaceID, requestID int) *memoryLeaveRequest {
+	for i := range d.leaveRequests {
+		if d.leaveRequests[i].workspaceID == workspaceID && d.leaveRequests[i].ID == requestID {
+			return &d.leaveRequests[i]
+		}
+	}
+	return nil
+}
+
+// ApproveLeaveRequest approves a pending leave request
+// Leave days are created for the requested range so the planner sees them;
+// if that fails the request stays pending.
+func (m *MemoryStore) ApproveLeaveRequest(workspaceID, requestID int) (*models.LeaveRequest, error) {
+	var decided *models.LeaveRequest
+	err := m.transaction(false, func(d *memoryData) error {
+		lr := d.leaveRequest(workspaceID, requestID)
+		if lr == nil {
+			return sql.ErrNoRows
+		}
+
+		if lr.Status != models.LeaveStatusPending {
+			return ErrLeaveRequestNotPending
+		}
+
+		if _, err := d.createLeaveDaysRange(workspaceID, lr.MemberID, lr.StartDate, lr.EndDate, lr.LeaveType, lr.ID); err != nil {
+			return err
+		}
+
+		decided = decideMemoryLeaveRequest(lr, models.LeaveStatusApproved)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decided, nil
+}
+
+// RejectLeaveRequest rejects a pending leave request
+func (m *MemoryStore) RejectLeaveRequest(workspaceID, requestID int) (*models.LeaveRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lr := m.data.leaveRequest(workspaceID, requestID)
+	if lr == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	if lr.Status != models.LeaveStatusPending {
+		return nil, ErrLeaveRequestNotPending
+	}
+
+	return decideMemoryLeaveRequest(lr, models.LeaveStatusRejected), nil
+}
+
+// decideMemoryLeaveRequest stores the decision for a leave request
+func decideMemoryLeaveRequest(lr *memoryLeaveRequest, status string) *models.LeaveRequest {
+	decidedAt := time.Now().UTC()
+	lr.Status = status
+	lr.DecidedAt = &decidedAt
+
+	result := lr.LeaveRequest
+	return &result
+}
+
+// SetLeaveAllowance sets the yearly allowance of a member for a leave type
+func (m *MemoryStore) SetLeaveAllowance(workspaceID, memberID, year int, leaveType string, days int) (*models.LeaveAllowance, error) {
+	if days < 0 {
+		return nil, fmt.Errorf("days cannot be negative")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := m.data.setLeaveAllowance(workspaceID, memberID, year, leaveType, days)
+	return &a, nil
+}
+
+// setLeaveAllowance creates or updates an allowance
+func (d *memoryData) setLeaveAllowance(workspaceID, memberID, year int, leaveType string, days int) models.LeaveAllowance {
+	for i := range d.leaveAllowances {
+		a := &d.leaveAllowances[i]
+		if a.workspaceID == workspaceID && a.MemberID == memberID && a.Year == year && a.LeaveType == leaveType {
+			a.Days = days
+			return a.LeaveAllowance
+		}
+	}
+
+	a := models.LeaveAllowance{
+		ID:        d.nextID("leave_allowances"),
+		MemberID:  memberID,
+		Year:      year,
+		LeaveType: leaveType,
+		Days:      days,
+	}
+	d.leaveAllowances = append(d.leaveAllowances, memoryLeaveAllowance{LeaveAllowance: a, workspaceID: workspaceID})
+	return a
+}
+
+// GetLeaveAllowances gets all leave allowances for a year
+func (m *MemoryStore) GetLeaveAllowances(workspaceID, year int) ([]models.LeaveAllowance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var allowances []models.LeaveAllowance
+	for _, a := range m.data.leaveAllowances {
+		if a.workspaceID == workspaceID && a.Year == year {
+			allowances = append(allowances, a.LeaveAllowance)
+		}
+	}
+	sort.SliceStable(allowances, func(i, j int) bool {
+		if allowances[i].MemberID != allowances[j].MemberID {
+			return allowances[i].MemberID < allowances[j].MemberID
+		}
+		return allowances[i].LeaveType < allowances[j].LeaveType
+	})
+	return allowances, nil
+}
+
+// GetLeaveBalances computes leave balances for a year
+// If memberID is not 0, only that member's balances are returned.
+func (m *MemoryStore) GetLeaveBalances(workspaceID, year, memberID int) ([]models.LeaveBalance, error) {
+	return leaveBalances(m, workspaceID, year, memberID)
+}
+
+// CreateUnavailabilityRule creates a recurring unavailability rule for a member
+func (m *MemoryStore) CreateUnavailabilityRule(workspaceID int, rule models.UnavailabilityRule) (*models.UnavailabilityRule, error) {
+	rule, err := normalizeUnavailabilityRule(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rule = m.data.insertUnavailabilityRule(workspaceID, rule)
+	return &rule, nil
+}
+
+// insertUnavailabilityRule stores a normalized rule
+func (d *memoryData) insertUnavailabilityRule(workspaceID int, rule models.UnavailabilityRule) models.UnavailabilityRule {
+	rule.ID = d.nextID("unavailability_rules")
+	rule.Weekdays = append([]time.Weekday(nil), rule.Weekdays...)
+	rule.CreatedAt = time.Now().UTC()
+	d.rules = append(d.rules, memoryRule{UnavailabilityRule: rule, workspaceID: workspaceID})
+	return rule
+}
+
+// GetUnavailabilityRules gets unavailability rules of a workspace
+// If memberID is not 0, only that member's rules are returned
+func (m *MemoryStore) GetUnavailabilityRules(workspaceID, memberID int) ([]models.UnavailabilityRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.data.unavailabilityRules(workspaceID, memberID), nil
+}
+
+// unavailabilityRules gets rules ordered by member and ID
+func (d *memoryData) unavailabilityRules(workspaceID, memberID int) []models.UnavailabilityRule {
+	var rules []models.UnavailabilityRule
+	for _, r := range d.rules {
+		if r.workspaceID == workspaceID && (memberID == 0 || r.MemberID == memberID) {
+			rules = append(rules, r.UnavailabilityRule)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].MemberID < rules[j].MemberID
+	})
+	return rules
+}
+
+// DeleteUnavailabilityRule deletes an unavailability rule
+func (m *MemoryStore) DeleteUnavailabilityRule(workspaceID, ruleID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.rules = filterRows(m.data.rules, func(r memoryRule) bool {
+		return r.workspaceID == workspaceID && r.ID == ruleID
+	})
+	return nil
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+type memoryMemberInvite struct {
+	models.MemberInvite
+	workspaceID int
+	tokenHash   string
+}
+
+// GetMemberByUser gets the member linked to a user
+func (m *MemoryStore) GetMemberByUser(workspaceID, userID int) (*models.Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, member := range m.data.members {
+		if member.workspaceID == workspaceID && member.UserID == userID {
+			result := member.Member
+			return &result, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// CreateMemberInvite stores the hash of a token that links the account accepting it to a member
+func (m *MemoryStore) CreateMemberInvite(workspaceID, memberID int, tokenHash string, expiresAt time.Time) (*models.MemberInvite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.data.member(workspaceID, memberID) == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	invite := memoryMemberInvite{
+		MemberInvite: models.MemberInvite{MemberID: memberID, ExpiresAt: expiresAt},
+		workspaceID:  workspaceID,
+		tokenHash:    tokenHash,
+	}
+	m.data.memberInvites = append(m.data.memberInvites, invite)
+	return &invite.MemberInvite, nil
+}
+
+// AcceptMemberInvite links a user to the member of an invite and uses the invite up
+func (m *MemoryStore) AcceptMemberInvite(userID int, tokenHash string, now time.Time) (int, *models.Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d := m.data
+	var invite *memoryMemberInvite
+	for i := range d.memberInvites {
+		if d.memberInvites[i].tokenHash == tokenHash && d.memberInvites[i].ExpiresAt.After(now) {
+			invite = &d.memberInvites[i]
+			break
+		}
+	}
+	if invite == nil {
+		return 0, nil, ErrMemberInviteNotFound
+	}
+	workspaceID, memberID := invite.workspaceID, invite.MemberID
+	d.memberInvites = filterRows(d.memberInvites, func(r memoryMemberInvite) bool { return r.tokenHash == tokenHash })
+
+	member := d.member(workspaceID, memberID)
+	if member == nil {
+		return 0, nil, ErrMemberInviteNotFound
+	}
+	for i := range d.members {
+		if d.members[i].workspaceID == workspaceID && d.members[i].UserID == userID {
+			d.members[i].UserID = 0
+		}
+	}
+	member.UserID = userID
+
+	if d.workspaceUser(workspaceID, userID) == nil {
+		d.workspaceUsers = append(d.workspaceUsers, memoryWorkspaceUser{
+			workspaceID: workspaceID,
+			userID:      userID,
+			role:        models.RoleMember,
+			createdAt:   now.UTC(),
+		})
+	}
+
+	result := member.Member
+	return workspaceID, &result, nil
+}
+
+// UnlinkMember removes the link between a member and their user account
+func (m *MemoryStore) UnlinkMember(workspaceID, memberID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	member := m.data.member(workspaceID, memberID)
+	if member == nil {
+		return sql.ErrNoRows
+	}
+	member.UserID = 0
+	return nil
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"sort"
+	"time"
+)
+
+// CreatePlanSnapshot saves the shifts in a date range and all hidden shift counters
+// With zero dates, all shifts of the workspace are saved.
+func (m *MemoryStore) CreatePlanSnapshot(workspaceID int, reason string, startDate, endDate time.Time) (*models.PlanSnapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := newPlanSnapshot(reason, startDate, endDate)
+
+	var shifts []models.Shift
+	if snapshot.StartDate == "" {
+		for _, shift := range m.data.shifts {
+			if shift.workspaceID == workspaceID {
+				shifts = append(shifts, shift.Shift)
+			}
+		}
+		sort.SliceStable(shifts, func(i, j int) bool {
+			return shifts[i].StartDate.Before(shifts[j].StartDate)
+		})
+	} else {
+		shifts = m.data.shiftsByDateRange(workspaceID, normalizeDate(startDate), normalizeDate(endDate))
+	}
+	for _, shift := range shifts {
+		snapshot.Shifts = append(snapshot.Shifts, models.BackupShift{
+			MemberID:    shift.MemberID,
+			StartDate:   shift.StartDate.Format("2006-01-02"),
+			EndDate:     shift.EndDate.Format("2006-01-02"),
+			IsLongShift: shift.IsLongShift,
+			Sequence:    shift.Sequence,
+		})
+	}
+	snapshot.ShiftCount = len(snapshot.Shifts)
+
+	for _, member := range m.data.members {
+		if member.workspaceID == workspaceID {
+			snapshot.Counters = append(snapshot.Counters, models.SnapshotCounter{
+				MemberID:           member.ID,
+				HiddenNormalShifts: member.hiddenNormalShifts,
+				HiddenLongShifts:   member.hiddenLongShifts,
+			})
+		}
+	}
+
+	snapshot.ID = m.data.nextID("plan_snapshots")
+	m.data.planSnapshots = append(m.data.planSnapshots, memoryPlanSnapshot{PlanSnapshot: *snapshot, workspaceID: workspaceID})
+
+	// Keep the newest snapshots of the workspace
+	var ids []int
+	for _, s := range m.data.planSnapshots {
+		if s.workspaceID == workspaceID {
+			ids = append(ids, s.ID)
+		}
+	}
+	if len(ids) > planSnapshotLimit {
+		oldestKept := ids[len(ids)-planSnapshotLimit]
+		m.data.planSnapshots = filterRows(m.data.planSnapshots, func(row memoryPlanSnapshot) bool {
+			return row.workspaceID == workspaceID && row.ID < oldestKept
+		})
+	}
+
+	return snapshot, nil
+}
+
+// GetPlanSnapshots lists the plan snapshots of a workspace, newest first
+// Shifts and counters are left out; use GetPlanSnapshot to load them.
+func (m *MemoryStore) GetPlanSnapshots(workspaceID int) ([]models.PlanSnapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var snapshots []models.PlanSnapshot
+	for i := len(m.data.planSnapshots) - 1; i >= 0; i-- {
+		s := m.data.planSnapshots[i]
+		if s.workspaceID == workspaceID {
+			s.Shifts, s.Counters = nil, nil
+			snapshots = append(snapshots, s.PlanSnapshot)
+		}
+	}
+	return snapshots, nil
+}
+
+// GetPlanSnapshot gets a plan snapshot with its shifts and counters
+func (m *MemoryStore) GetPlanSnapshot(workspaceID, snapshotID int) (*models.PlanSnapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.data.planSnapshots {
+		if s.workspaceID == workspaceID && s.ID == snapshotID {
+			snapshot := s.PlanSnapshot
+			snapshot.Shifts = append([]models.BackupShift{}, s.Shifts...)
+			snapshot.Counters = append([]models.SnapshotCounter{}, s.Counters...)
+			return &snapshot, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// RestorePlanSnapshot puts the shifts and hidden counters of a snapshot back in a single transaction
+func (m *MemoryStore) RestorePlanSnapshot(workspaceID int, snapshot *models.PlanSnapshot) (*models.SnapshotRestoreResult, error) {
+	var result *models.SnapshotRestoreResult
+	err := m.transaction(false, func(d *memoryData) error {
+		var err error
+		result, err = applySnapshotRestore(&memorySnapshotRestore{memoryBackupRestore{data: d, workspaceID: workspaceID}}, snapshot)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// memorySnapshotRestore restores a plan snapshot into the data of a MemoryStore
+type memorySnapshotRestore struct {
+	memoryBackupRestore
+}
+
+func (r *memorySnapshotRestore) deleteShifts(startDate, endDate string) error {
+	workspaceID := r.workspaceID
+	if startDate == "" {
+		r.data.shifts = filterRows(r.data.shifts, func(row memoryShift) bool { return row.workspaceID == workspaceID })
+		return nil
+	}
+
+	start, end, err := parseDateRange(startDate, endDate)
+	if err != nil {
+		return err
+	}
+	r.data.shifts = filterRows(r.data.shifts, func(row memoryShift) bool {
+		return row.workspaceID == workspaceID && !row.StartDate.After(end) && !row.EndDate.Before(start)
+	})
+	return nil
+}
+
+func (r *memorySnapshotRestore) memberExists(memberID int) (bool, error) {
+	return r.data.member(r.workspaceID, memberID) != nil, nil
+}
+
+func (r *memorySnapshotRestore) setHiddenShiftCounts(memberID, normalShifts, longShifts int) error {
+	if member := r.data.member(r.workspaceID, memberID); member != nil {
+		member.hiddenNormalShifts = normalShifts
+		member.hiddenLongShifts = longShifts
+	}
+	return nil
+}
@@ -0,0 +1,880 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"shiftplanner/backend/internal/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps all data in memory
+// It behaves like SQLStore and is meant for tests; data is lost on exit.
+// Lookups of missing rows return the same errors as SQLStore (sql.ErrNoRows
+// and the package's Err values), so callers don't depend on the backend.
+type MemoryStore struct {
+	mu   sync.Mutex
+	data *memoryData
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: &memoryData{lastID: make(map[string]int)}}
+}
+
+// memoryData holds the tables of a MemoryStore
+// Slices are kept in insertion (ID) order.
+type memoryData struct {
+	lastID           map[string]int
+	users            []memoryUser
+	userIdentities   []memoryUserIdentity
+	workspaces       []models.Workspace
+	workspaceUsers   []memoryWorkspaceUser
+	workspaceInvites []memoryWorkspaceInvite
+	sessions         []memorySession
+	members          []memoryMember
+	memberInvites    []memoryMemberInvite
+	shifts           []memoryShift
+	leaveDays        []memoryLeaveDay
+	leaveRequests    []memoryLeaveRequest
+	swapRequests     []memorySwapRequest
+	leaveAllowances  []memoryLeaveAllowance
+	rules            []memoryRule
+	feedTokens       []memoryFeedToken
+	apiKeys          []memoryAPIKey
+	twoFactors       []models.TwoFactor
+	recoveryCodes    []memoryRecoveryCode
+	loginChallenges  []memoryLoginChallenge
+	passwordResets   []memoryPasswordReset
+	imports          []memoryImport
+	auditLog         []memoryAuditEntry
+	planSnapshots    []memoryPlanSnapshot
+}
+
+type memoryUser struct {
+	models.User
+	passwordHash string
+}
+
+type memorySession struct {
+	models.Session
+	tokenHash string
+}
+
+type memoryUserIdentity struct {
+	userID  int
+	issuer  string
+	subject string
+	email   string
+}
+
+type memoryWorkspaceUser struct {
+	workspaceID int
+	userID      int
+	role        string
+	ssoGranted  bool
+	createdAt   time.Time
+}
+
+type memoryWorkspaceInvite struct {
+	models.WorkspaceInvite
+	workspaceID int
+	tokenHash   string
+}
+
+type memoryMember struct {
+	models.Member
+	workspaceID        int
+	hiddenNormalShifts int
+	hiddenLongShifts   int
+}
+
+type memoryShift struct {
+	models.Shift
+	workspaceID int
+}
+
+type memoryLeaveDay struct {
+	models.LeaveDay
+	workspaceID    int
+	leaveRequestID int
+}
+
+type memoryLeaveRequest struct {
+	models.LeaveRequest
+	workspaceID int
+}
+
+type memorySwapRequest struct {
+	models.SwapRequest
+	workspaceID int
+}
+
+type memoryLeaveAllowance struct {
+	models.LeaveAllowance
+	workspaceID int
+}
+
+type memoryRule struct {
+	models.UnavailabilityRule
+	workspaceID int
+}
+
+type memoryFeedToken struct {
+	models.FeedToken
+	workspaceID int
+	userID      int
+	tokenHash   string
+}
+
+type memoryImport struct {
+	models.ImportRecord
+	workspaceID int
+}
+
+type memoryAuditEntry struct {
+	models.AuditEntry
+	workspaceID int
+}
+
+type memoryPlanSnapshot struct {
+	models.PlanSnapshot
+	workspaceID int
+}
+
+// nextID returns the next ID of a table
+func (d *memoryData) nextID(table string) int {
+	d.lastID[table]++
+	return d.lastID[table]
+}
+
+// clone copies all tables, so a failed transaction can be rolled back
+// Rows are stored as values, so copying the slices is enough.
+func (d *memoryData) clone() *memoryData {
+	c := &memoryData{
+		lastID:           make(map[string]int, len(d.lastID)),
+		users:            append([]memoryUser(nil), d.users...),
+		workspaces:       append([]models.Workspace(nil), d.workspaces...),
+		workspaceUsers:   append([]memoryWorkspaceUser(nil), d.workspaceUsers...),
+		workspaceInvites: append([]memoryWorkspaceInvite(nil), d.workspaceInvites...),
+		sessions:         append([]memorySession(nil), d.sessions...),
+		members:          append([]memoryMember(nil), d.members...),
+		memberInvites:    append([]memoryMemberInvite(nil), d.memberInvites...),
+		shifts:           append([]memoryShift(nil), d.shifts...),
+		leaveDays:        append([]memoryLeaveDay(nil), d.leaveDays...),
+		leaveRequests:    append([]memoryLeaveRequest(nil), d.leaveRequests...),
+		swapRequests:     append([]memorySwapRequest(nil), d.swapRequests...),
+		leaveAllowances:  append([]memoryLeaveAllowance(nil), d.leaveAllowances...),
+		rules:            append([]memoryRule(nil), d.rules...),
+		feedTokens:       append([]memoryFeedToken(nil), d.feedTokens...),
+		userIdentities:   append([]memoryUserIdentity(nil), d.userIdentities...),
+		apiKeys:          append([]memoryAPIKey(nil), d.apiKeys...),
+		twoFactors:       append([]models.TwoFactor(nil), d.twoFactors...),
+		recoveryCodes:    append([]memoryRecoveryCode(nil), d.recoveryCodes...),
+		loginChallenges:  append([]memoryLoginChallenge(nil), d.loginChallenges...),
+		passwordResets:   append([]memoryPasswordReset(nil), d.passwordResets...),
+		imports:          append([]memoryImport(nil), d.imports...),
+		auditLog:         append([]memoryAuditEntry(nil), d.auditLog...),
+		planSnapshots:    append([]memoryPlanSnapshot(nil), d.planSnapshots...),
+	}
+	for table, id := range d.lastID {
+		c.lastID[table] = id
+	}
+	return c
+}
+
+// transaction runs fn on the data and rolls all changes back if it fails
+// With rollback, changes are discarded even if fn succeeds.
+func (m *MemoryStore) transaction(rollback bool, fn func(d *memoryData) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := m.data.clone()
+	err := fn(m.data)
+	if err != nil || rollback {
+		m.data = snapshot
+	}
+	return err
+}
+
+// normalizeDate returns the date of t at UTC midnight
+func normalizeDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// GetAllMembers gets all members of a workspace
+func (m *MemoryStore) GetAllMembers(workspaceID int) ([]models.Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var members []models.Member
+	for _, member := range m.data.members {
+		if member.workspaceID == workspaceID {
+			members = append(members, member.Member)
+		}
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+	return members, nil
+}
+
+// CreateMember creates a new member
+// Hidden shift counters start at the average of the other members' counters
+func (m *MemoryStore) CreateMember(workspaceID int, name string) (*models.Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	member := m.data.createMember(workspaceID, name)
+	return &member.Member, nil
+}
+
+// createMember creates a member with the average hidden shift counters
+func (d *memoryData) createMember(workspaceID int, name string) memoryMember {
+	totalNormalShifts, totalLongShifts, count := 0, 0, 0
+	for _, member := range d.members {
+		if member.workspaceID == workspaceID {
+			totalNormalShifts += member.hiddenNormalShifts
+			totalLongShifts += member.hiddenLongShifts
+			count++
+		}
+	}
+
+	member := memoryMember{
+		Member: models.Member{
+			ID:        d.nextID("members"),
+			Name:      name,
+			CreatedAt: time.Now().UTC(),
+		},
+		workspaceID: workspaceID,
+	}
+	if count > 0 {
+		member.hiddenNormalShifts = totalNormalShifts / count
+		member.hiddenLongShifts = totalLongShifts / count
+	}
+	d.members = append(d.members, member)
+	return member
+}
+
+// DeleteMember deletes a member together with their shifts and leave data
+func (m *MemoryStore) DeleteMember(workspaceID, memberID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d := m.data
+	d.members = filterRows(d.members, func(r memoryMember) bool { return r.workspaceID == workspaceID && r.ID == memberID })
+	d.memberInvites = filterRows(d.memberInvites, func(r memoryMemberInvite) bool { return r.workspaceID == workspaceID && r.MemberID == memberID })
+	d.shifts = filterRows(d.shifts, func(r memoryShift) bool { return r.workspaceID == workspaceID && r.MemberID == memberID })
+	d.leaveDays = filterRows(d.leaveDays, func(r memoryLeaveDay) bool { return r.workspaceID == workspaceID && r.MemberID == memberID })
+	d.leaveRequests = filterRows(d.leaveRequests, func(r memoryLeaveRequest) bool { return r.workspaceID == workspaceID && r.MemberID == memberID })
+	d.leaveAllowances = filterRows(d.leaveAllowances, func(r memoryLeaveAllowance) bool { return r.workspaceID == workspaceID && r.MemberID == memberID })
+	d.rules = filterRows(d.rules, func(r memoryRule) bool { return r.workspaceID == workspaceID && r.MemberID == memberID })
+	return nil
+}
+
+// filterRows returns rows without those matching remove
+func filterRows[T any](rows []T, remove func(T) bool) []T {
+	kept := rows[:0:0]
+	for _, row := range rows {
+		if !remove(row) {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
+
+// GetMemberByID gets a member by ID (can only get own members)
+func (m *MemoryStore) GetMemberByID(workspaceID, memberID int) (*models.Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	member := m.data.member(workspaceID, memberID)
+	if member == nil {
+		return nil, sql.ErrNoRows
+	}
+	result := member.Member
+	return &result, nil
+}
+
+// GetMemberByName gets a member by name (case-insensitive, can only get own members)
+func (m *MemoryStore) GetMemberByName(workspaceID int, name string) (*models.Member, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	member := m.data.memberByName(workspaceID, name)
+	if member == nil {
+		return nil, sql.ErrNoRows
+	}
+	result := member.Member
+	return &result, nil
+}
+
+// member finds a member row, nil if it doesn't exist
+func (d *memoryData) member(workspaceID, memberID int) *memoryMember {
+	for i := range d.members {
+		if d.members[i].workspaceID == workspaceID && d.members[i].ID == memberID {
+			return &d.members[i]
+		}
+	}
+	return nil
+}
+
+// memberByName finds a member row by name (case-insensitive), nil if it doesn't exist
+func (d *memoryData) memberByName(workspaceID int, name string) *memoryMember {
+	for i := range d.members {
+		if d.members[i].workspaceID == workspaceID && strings.EqualFold(d.members[i].Name, name) {
+			return &d.members[i]
+		}
+	}
+	return nil
+}
+
+// GetHiddenShiftCounts gets hidden shift counts for a member
+func (m *MemoryStore) GetHiddenShiftCounts(workspaceID, memberID int) (normalShifts int, longShifts int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	member := m.data.member(workspaceID, memberID)
+	if member == nil {
+		return 0, 0, sql.ErrNoRows
+	}
+	return member.hiddenNormalShifts, member.hiddenLongShifts, nil
+}
+
+// UpdateHiddenShiftCounts updates hidden shift counts for a member
+func (m *MemoryStore) UpdateHiddenShiftCounts(workspaceID, memberID int, normalShiftsDelta, longShiftsDelta int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.data.adjustHiddenShiftCounts(workspaceID, memberID, normalShiftsDelta, longShiftsDelta)
+}
+
+// adjustHiddenShiftCounts applies deltas to a member's hidden shift counters
+// Counters never go below zero
+func (d *memoryData) adjustHiddenShiftCounts(workspaceID, memberID, normalShiftsDelta, longShiftsDelta int) error {
+	member := d.member(workspaceID, memberID)
+	if member == nil {
+		return sql.ErrNoRows
+	}
+	member.hiddenNormalShifts = max(0, member.hiddenNormalShifts+normalShiftsDelta)
+	member.hiddenLongShifts = max(0, member.hiddenLongShifts+longShiftsDelta)
+	return nil
+}
+
+// adjustShiftCounts adds (sign 1) or removes (sign -1) a shift's days from the hidden counters
+// Missing members are ignored, like in SQLStore.
+func (d *memoryData) adjustShiftCounts(workspaceID int, shift models.Shift, sign int) {
+	normalDays, longDays := shiftDayCounts(&shift)
+	d.adjustHiddenShiftCounts(workspaceID, shift.MemberID, sign*normalDays, sign*longDays)
+}
+
+// GetAllHiddenShiftCounts gets hidden shift counts for all members
+func (m *MemoryStore) GetAllHiddenShiftCounts(workspaceID int) (map[int]HiddenShiftCounts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := make(map[int]HiddenShiftCounts)
+	for _, member := range m.data.members {
+		if member.workspaceID == workspaceID {
+			counts[member.ID] = HiddenShiftCounts{
+				NormalShifts: member.hiddenNormalShifts,
+				LongShifts:   member.hiddenLongShifts,
+			}
+		}
+	}
+	return counts, nil
+}
+
+// CreateShift creates a new shift record
+// Also updates hidden shift counters for the member
+func (m *MemoryStore) CreateShift(workspaceID, memberID int, startDate, endDate time.Time, isLongShift bool) (*models.Shift, error) {
+	if startDate.IsZero() || endDate.IsZero() {
+		return nil, fmt.Errorf("start_date and end_date cannot be zero")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	shift := m.data.createShift(workspaceID, memberID, normalizeDate(startDate), normalizeDate(endDate), isLongShift, 0)
+	m.data.adjustShiftCounts(workspaceID, shift, 1)
+	return &shift, nil
+}
+
+// createShift stores a shift without touching hidden counters
+func (d *memoryData) createShift(workspaceID, memberID int, startDate, endDate time.Time, isLongShift bool, sequence int) models.Shift {
+	shift := models.Shift{
+		ID:          d.nextID("shifts"),
+		MemberID:    memberID,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		IsLongShift: isLongShift,
+		Sequence:    sequence,
+		CreatedAt:   time.Now().UTC(),
+	}
+	d.shifts = append(d.shifts, memoryShift{Shift: shift, workspaceID: workspaceID})
+	return shift
+}
+
+// GetShiftsByDateRange gets shifts by date range
+func (m *MemoryStore) GetShiftsByDateRange(workspaceID int, startDate, endDate time.Time) ([]models.Shift, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.data.shiftsByDateRange(workspaceID, normalizeDate(startDate), normalizeDate(endDate)), nil
+}
+
+// shiftsByDateRange gets shifts overlapping the date range, ordered by start date
+func (d *memoryData) shiftsByDateRange(workspaceID int, startDate, endDate time.Time) []models.Shift {
+	var shifts []models.Shift
+	for _, shift := range d.shifts {
+		if shift.workspaceID == workspaceID && !shift.StartDate.After(endDate) && !shift.EndDate.Before(startDate) {
+			shifts = append(shifts, shift.Shift)
+		}
+	}
+	sort.SliceStable(shifts, func(i, j int) bool {
+		return shifts[i].StartDate.Before(shifts[j].StartDate)
+	})
+	return shifts
+}
+
+// GetShiftByDate gets a shift that covers a specific date
+func (m *MemoryStore) GetShiftByDate(workspaceID int, date time.Time) (*models.Shift, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	shift := m.data.shiftByDate(workspaceID, normalizeDate(date))
+	if shift == nil {
+		return nil, nil // No shift found
+	}
+	result := shift.Shift
+	return &result, nil
+}
+
+// GetShiftByID gets a shift by ID (can only get own shifts)
+func (m *MemoryStore) GetShiftByID(workspaceID, shiftID int) (*models.Shift, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, shift := range m.data.shifts {
+		if shift.workspaceID == workspaceID && shift.ID == shiftID {
+			result := shift.Shift
+			return &result, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// shiftByDate finds the shift row covering a date, nil if there is none
+func (d *memoryData) shiftByDate(workspaceID int, date time.Time) *memoryShift {
+	for i := range d.shifts {
+		shift := &d.shifts[i]
+		if shift.workspaceID == workspaceID && !shift.StartDate.After(date) && !shift.EndDate.Before(date) {
+			return shift
+		}
+	}
+	return nil
+}
+
+// UpdateShiftMember updates the member for a shift
+// Also updates hidden shift counters for both old and new members
+func (m *MemoryStore) UpdateShiftMember(workspaceID, shiftID, newMemberID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.data.shifts {
+		shift := &m.data.shifts[i]
+		if shift.workspaceID != workspaceID || shift.ID != shiftID {
+			continue
+		}
+		if shift.MemberID == newMemberID {
+			return nil
+		}
+
+		m.data.adjustShiftCounts(workspaceID, shift.Shift, -1)
+		shift.MemberID = newMemberID
+		shift.Sequence++
+		m.data.adjustShiftCounts(workspaceID, shift.Shift, 1)
+		return nil
+	}
+	return sql.ErrNoRows
+}
+
+// CreateOrUpdateShiftForDate creates or updates a shift for a specific date
+func (m *MemoryStore) CreateOrUpdateShiftForDate(workspaceID, memberID int, date time.Time) (*models.Shift, error) {
+	return createOrUpdateShiftForDate(m, workspaceID, memberID, date)
+}
+
+// DeleteShiftsByDateRange deletes shifts that overlap with the date range
+// Also updates hidden shift counters for affected members
+func (m *MemoryStore) DeleteShiftsByDateRange(workspaceID int, startDate, endDate time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	startDate, endDate = normalizeDate(startDate), normalizeDate(endDate)
+	m.data.shifts = filterRows(m.data.shifts, func(shift memoryShift) bool {
+		if shift.workspaceID != workspaceID || shift.StartDate.After(endDate) || shift.EndDate.Before(startDate) {
+			return false
+		}
+		m.data.adjustShiftCounts(workspaceID, shift.Shift, -1)
+		return true
+	})
+	return nil
+}
+
+// DeleteAllShifts deletes all shifts of a workspace
+// Also updates hidden shift counters for all members
+func (m *MemoryStore) DeleteAllShifts(workspaceID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.shifts = filterRows(m.data.shifts, func(shift memoryShift) bool {
+		if shift.workspaceID != workspaceID {
+			return false
+		}
+		m.data.adjustShiftCounts(workspaceID, shift.Shift, -1)
+		return true
+	})
+	return nil
+}
+
+// GetMemberShiftStats gets shift statistics for a member
+func (m *MemoryStore) GetMemberShiftStats(workspaceID, memberID int) (totalDays int, longShiftCount int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, shift := range m.data.shifts {
+		if shift.workspaceID != workspaceID || shift.MemberID != memberID {
+			continue
+		}
+		normalDays, longDays := shiftDayCounts(&shift.Shift)
+		totalDays += normalDays + longDays
+		if shift.IsLongShift {
+			longShiftCount++
+		}
+	}
+	return totalDays, longShiftCount, nil
+}
+
+// GetAllMembersStats gets statistics for all members
+func (m *MemoryStore) GetAllMembersStats(workspaceID int) (map[int]models.MemberStats, error) {
+	return allMembersStats(m, workspaceID)
+}
+
+// CreateUser creates a new user with a personal workspace they own
+func (m *MemoryStore) CreateUser(username, password string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.data.users {
+		if user.Username == username {
+			return nil, fmt.Errorf("username '%s' already exists", username)
+		}
+	}
+
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := memoryUser{
+		User: models.User{
+			ID:        m.data.nextID("users"),
+			Username:  username,
+			CreatedAt: time.Now().UTC(),
+		},
+		passwordHash: passwordHash,
+	}
+	m.data.users = append(m.data.users, user)
+	m.data.createWorkspace(user.ID, username)
+	return &user.User, nil
+}
+
+// GetUserByUsername gets a user by username
+func (m *MemoryStore) GetUserByUsername(username string) (*models.User, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.data.users {
+		if user.Username == username {
+			result := user.User
+			return &result, user.passwordHash, nil
+		}
+	}
+	return nil, "", sql.ErrNoRows
+}
+
+// GetUserByID gets a user by ID
+func (m *MemoryStore) GetUserByID(userID int) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.data.users {
+		if user.ID == userID {
+			result := user.User
+			return &result, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// UpdatePassword replaces a user's password hash with a new hash of password
+func (m *MemoryStore) UpdatePassword(userID int, password string) error {
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.data.users {
+		if m.data.users[i].ID == userID {
+			m.data.users[i].passwordHash = passwordHash
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+// GetUserByIdentity gets the user linked to an OpenID Connect account
+func (m *MemoryStore) GetUserByIdentity(issuer, subject string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, identity := range m.data.userIdentities {
+		if identity.issuer == issuer && identity.subject == subject {
+			for _, user := range m.data.users {
+				if user.ID == identity.userID {
+					result := user.User
+					return &result, nil
+				}
+			}
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// CreateUserWithIdentity creates a user linked to an OpenID Connect account
+// The empty password hash never validates, so the user can only log in through the provider.
+func (m *MemoryStore) CreateUserWithIdentity(username, issuer, subject, email string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.data.users {
+		if user.Username == username {
+			return nil, fmt.Errorf("username '%s' already exists", username)
+		}
+	}
+	for _, identity := range m.data.userIdentities {
+		if identity.issuer == issuer && identity.subject == subject {
+			return nil, errors.New("identity already linked to a user")
+		}
+	}
+
+	user := memoryUser{
+		User: models.User{
+			ID:        m.data.nextID("users"),
+			Username:  username,
+			CreatedAt: time.Now().UTC(),
+		},
+	}
+	m.data.users = append(m.data.users, user)
+	m.data.userIdentities = append(m.data.userIdentities, memoryUserIdentity{
+		userID:  user.ID,
+		issuer:  issuer,
+		subject: subject,
+		email:   email,
+	})
+	m.data.createWorkspace(user.ID, username)
+	return &user.User, nil
+}
+
+// CreateSession stores a new session under the hash of its token
+func (m *MemoryStore) CreateSession(userID int, tokenHash, userAgent, ipAddress string, expiresAt time.Time) (*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, session := range m.data.sessions {
+		if session.tokenHash == tokenHash {
+			return nil, errors.New("session token already exists")
+		}
+	}
+
+	now := time.Now()
+	session := memorySession{
+		Session: models.Session{
+			ID:         m.data.nextID("sessions"),
+			UserID:     userID,
+			UserAgent:  userAgent,
+			IPAddress:  ipAddress,
+			LastSeenAt: now,
+			ExpiresAt:  expiresAt,
+			CreatedAt:  now,
+		},
+		tokenHash: tokenHash,
+	}
+	m.data.sessions = append(m.data.sessions, session)
+	return &session.Session, nil
+}
+
+// GetSession gets a session by the hash of its token
+func (m *MemoryStore) GetSession(tokenHash string) (*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, session := range m.data.sessions {
+		if session.tokenHash == tokenHash {
+			result := session.Session
+			return &result, nil
+		}
+	}
+	return nil, ErrSessionNotFound
+}
+
+// TouchSession records a use of a session and moves its expiry
+func (m *MemoryStore) TouchSession(sessionID int, lastSeenAt, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.data.sessions {
+		if m.data.sessions[i].ID == sessionID {
+			m.data.sessions[i].LastSeenAt = lastSeenAt
+			m.data.sessions[i].ExpiresAt = expiresAt
+		}
+	}
+	return nil
+}
+
+// GetUserSessions lists the sessions of a user that haven't expired, most recently used first
+func (m *MemoryStore) GetUserSessions(userID int, now time.Time) ([]models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var sessions []models.Session
+	for _, session := range m.data.sessions {
+		if session.UserID == userID && !session.ExpiresAt.Before(now) {
+			sessions = append(sessions, session.Session)
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if !sessions[i].LastSeenAt.Equal(sessions[j].LastSeenAt) {
+			return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+	return sessions, nil
+}
+
+// DeleteSession deletes a session by the hash of its token
+func (m *MemoryStore) DeleteSession(tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.sessions = filterRows(m.data.sessions, func(s memorySession) bool { return s.tokenHash == tokenHash })
+	return nil
+}
+
+// DeleteUserSession revokes a session of a user
+func (m *MemoryStore) DeleteUserSession(userID, sessionID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := len(m.data.sessions)
+	m.data.sessions = filterRows(m.data.sessions, func(s memorySession) bool { return s.UserID == userID && s.ID == sessionID })
+	if len(m.data.sessions) == count {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteUserSessions revokes all sessions of a user except keepSessionID
+func (m *MemoryStore) DeleteUserSessions(userID, keepSessionID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.sessions = filterRows(m.data.sessions, func(s memorySession) bool { return s.UserID == userID && s.ID != keepSessionID })
+	return nil
+}
+
+// DeleteExpiredSessions deletes sessions, login challenges and password resets that expired before now
+func (m *MemoryStore) DeleteExpiredSessions(now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.sessions = filterRows(m.data.sessions, func(s memorySession) bool { return s.ExpiresAt.Before(now) })
+	m.data.loginChallenges = filterRows(m.data.loginChallenges, func(c memoryLoginChallenge) bool { return c.ExpiresAt.Before(now) })
+	m.data.passwordResets = filterRows(m.data.passwordResets, func(r memoryPasswordReset) bool { return r.ExpiresAt.Before(now) })
+	return nil
+}
+
+// CreateFeedToken stores a new calendar feed token of a user for a workspace under the hash of the token
+func (m *MemoryStore) CreateFeedToken(workspaceID, userID int, name, prefix, tokenHash string) (*models.FeedToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, ft := range m.data.feedTokens {
+		if ft.tokenHash == tokenHash {
+			return nil, errors.New("feed token already exists")
+		}
+	}
+
+	ft := memoryFeedToken{
+		FeedToken: models.FeedToken{
+			ID:        m.data.nextID("feed_tokens"),
+			Name:      name,
+			Prefix:    prefix,
+			CreatedAt: time.Now().UTC(),
+		},
+		workspaceID: workspaceID,
+		userID:      userID,
+		tokenHash:   tokenHash,
+	}
+	m.data.feedTokens = append(m.data.feedTokens, ft)
+	return &ft.FeedToken, nil
+}
+
+// GetFeedTokens gets the calendar feed tokens of a user for a workspace
+func (m *MemoryStore) GetFeedTokens(workspaceID, userID int) ([]models.FeedToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tokens []models.FeedToken
+	for _, ft := range m.data.feedTokens {
+		if ft.workspaceID == workspaceID && ft.userID == userID {
+			tokens = append(tokens, ft.FeedToken)
+		}
+	}
+	return tokens, nil
+}
+
+// DeleteFeedToken revokes a calendar feed token of a user for a workspace
+func (m *MemoryStore) DeleteFeedToken(workspaceID, userID, tokenID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := len(m.data.feedTokens)
+	m.data.feedTokens = filterRows(m.data.feedTokens, func(ft memoryFeedToken) bool {
+		return ft.workspaceID == workspaceID && ft.userID == userID && ft.ID == tokenID
+	})
+	if len(m.data.feedTokens) == count {
+		return ErrFeedTokenNotFound
+	}
+	return nil
+}
+
+// GetWorkspaceIDByFeedToken resolves the hash of a calendar feed token to its workspace
+// Tokens stop working when their user loses access to the workspace.
+func (m *MemoryStore) GetWorkspaceIDByFeedToken(tokenHash string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, ft := range m.data.feedTokens {
+		if ft.tokenHash == tokenHash && m.data.workspaceUser(ft.workspaceID, ft.userID) != nil {
+			return ft.workspaceID, nil
+		}
+	}
+	return 0, ErrFeedTokenNotFound
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+// CreateSwapRequest creates a pending request to swap the members of two shifts
+// The current members of both shifts are recorded; sql.ErrNoRows if a shift doesn't exist.
+func (m *MemoryStore) CreateSwapRequest(workspaceID, shiftID, withShiftID int, note string) (*models.SwapRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	shift, withShift := m.data.shift(workspaceID, shiftID), m.data.shift(workspaceID, withShiftID)
+	if shift == nil || withShift == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	sr := models.SwapRequest{
+		ID:           m.data.nextID("swap_requests"),
+		ShiftID:      shiftID,
+		MemberID:     shift.MemberID,
+		WithShiftID:  withShiftID,
+		WithMemberID: withShift.MemberID,
+		Status:       models.LeaveStatusPending,
+		Note:         note,
+		CreatedAt:    time.Now().UTC().Truncate(time.Second),
+	}
+	m.data.swapRequests = append(m.data.swapRequests, memorySwapRequest{SwapRequest: sr, workspaceID: workspaceID})
+	return &sr, nil
+}
+
+// GetSwapRequests gets swap requests of a workspace, oldest first
+// Empty status and zero memberID mean no filtering; memberID matches either side of a swap.
+func (m *MemoryStore) GetSwapRequests(workspaceID int, status string, memberID int) ([]models.SwapRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var requests []models.SwapRequest
+	for _, sr := range m.data.swapRequests {
+		if sr.workspaceID != workspaceID || !m.data.swapRequestLive(sr) ||
+			(status != "" && sr.Status != status) ||
+			(memberID != 0 && sr.MemberID != memberID && sr.WithMemberID != memberID) {
+			continue
+		}
+		requests = append(requests, sr.SwapRequest)
+	}
+	return requests, nil
+}
+
+// GetSwapRequestByID gets a swap request of a workspace
+func (m *MemoryStore) GetSwapRequestByID(workspaceID, requestID int) (*models.SwapRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sr := m.data.swapRequest(workspaceID, requestID)
+	if sr == nil {
+		return nil, sql.ErrNoRows
+	}
+	result := sr.SwapRequest
+	return &result, nil
+}
+
+// ApproveSwapRequest approves a pending swap request and swaps the members of its shifts
+// If either shift changed hands since the request, ErrSwapRequestOutdated is
+// returned and nothing changes.
+func (m *MemoryStore) ApproveSwapRequest(workspaceID, requestID int) (*models.SwapRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sr := m.data.swapRequest(workspaceID, requestID)
+	if sr == nil {
+		return nil, sql.ErrNoRows
+	}
+	if sr.Status != models.LeaveStatusPending {
+		return nil, ErrSwapRequestNotPending
+	}
+	shift, withShift := m.data.shift(workspaceID, sr.ShiftID), m.data.shift(workspaceID, sr.WithShiftID)
+	if shift.MemberID != sr.MemberID || withShift.MemberID != sr.WithMemberID {
+		return nil, ErrSwapRequestOutdated
+	}
+
+	for _, change := range []struct {
+		shift    *memoryShift
+		memberID int
+	}{{shift, sr.WithMemberID}, {withShift, sr.MemberID}} {
+		m.data.adjustShiftCounts(workspaceID, change.shift.Shift, -1)
+		change.shift.MemberID = change.memberID
+		change.shift.Sequence++
+		m.data.adjustShiftCounts(workspaceID, change.shift.Shift, 1)
+	}
+
+	return decideMemorySwapRequest(sr, models.LeaveStatusApproved), nil
+}
+
+// RejectSwapRequest rejects a pending swap request
+func (m *MemoryStore) RejectSwapRequest(workspaceID, requestID int) (*models.SwapRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sr := m.data.swapRequest(workspaceID, requestID)
+	if sr == nil {
+		return nil, sql.ErrNoRows
+	}
+	if sr.Status != models.LeaveStatusPending {
+		return nil, ErrSwapRequestNotPending
+	}
+
+	return decideMemorySwapRequest(sr, models.LeaveStatusRejected), nil
+}
+
+// decideMemorySwapRequest stores the decision on a swap request row
+func decideMemorySwapRequest(sr *memorySwapRequest, status string) *models.SwapRequest {
+	decidedAt := time.Now().UTC().Truncate(time.Second)
+	sr.Status = status
+	sr.DecidedAt = &decidedAt
+	result := sr.SwapRequest
+	return &result
+}
+
+// swapRequest finds a swap request row whose shifts still exist, nil otherwise
+func (d *memoryData) swapRequest(workspaceID, requestID int) *memorySwapRequest {
+	for i := range d.swapRequests {
+		sr := &d.swapRequests[i]
+		if sr.workspaceID == workspaceID && sr.ID == requestID && d.swapRequestLive(*sr) {
+			return sr
+		}
+	}
+	return nil
+}
+
+// swapRequestLive reports whether both shifts of a swap request still exist
+// Requests go with their shifts; the ones left behind by deleted shifts are never returned.
+func (d *memoryData) swapRequestLive(sr memorySwapRequest) bool {
+	return d.shift(sr.workspaceID, sr.ShiftID) != nil && d.shift(sr.workspaceID, sr.WithShiftID) != nil
+}
+
+// shift finds a shift row, nil if it doesn't exist
+func (d *memoryData) shift(workspaceID, shiftID int) *memoryShift {
+	for i := range d.shifts {
+		if d.shifts[i].workspaceID == workspaceID && d.shifts[i].ID == shiftID {
+			return &d.shifts[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+type memoryRecoveryCode struct {
+	userID   int
+	codeHash string
+}
+
+type memoryLoginChallenge struct {
+	models.LoginChallenge
+	tokenHash string
+}
+
+// twoFactor finds the TOTP settings of a user, nil if there are none
+func (d *memoryData) twoFactor(userID int) *models.TwoFactor {
+	for i := range d.twoFactors {
+		if d.twoFactors[i].UserID == userID {
+			return &d.twoFactors[i]
+		}
+	}
+	return nil
+}
+
+// GetTwoFactor gets the TOTP settings of a user with the number of unused recovery codes
+func (m *MemoryStore) GetTwoFactor(userID int) (*models.TwoFactor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	twoFactor := m.data.twoFactor(userID)
+	if twoFactor == nil {
+		return nil, ErrTwoFactorNotFound
+	}
+	result := *twoFactor
+	for _, code := range m.data.recoveryCodes {
+		if code.userID == userID {
+			result.RecoveryCodes++
+		}
+	}
+	return &result, nil
+}
+
+// SetTOTPSecret starts enrollment with a new secret, turning two-factor off until it is enabled again
+func (m *MemoryStore) SetTOTPSecret(userID int, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.twoFactors = filterRows(m.data.twoFactors, func(t models.TwoFactor) bool { return t.UserID == userID })
+	m.data.twoFactors = append(m.data.twoFactors, models.TwoFactor{UserID: userID, Secret: secret})
+	m.data.recoveryCodes = filterRows(m.data.recoveryCodes, func(r memoryRecoveryCode) bool { return r.userID == userID })
+	return nil
+}
+
+// EnableTwoFactor turns two-factor on and replaces the recovery codes
+func (m *MemoryStore) EnableTwoFactor(userID int, recoveryCodeHashes []string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	twoFactor := m.data.twoFactor(userID)
+	if twoFactor == nil {
+		return ErrTwoFactorNotFound
+	}
+	enabledAt := now.UTC()
+	twoFactor.EnabledAt = &enabledAt
+	m.data.replaceRecoveryCodes(userID, recoveryCodeHashes)
+	return nil
+}
+
+// DisableTwoFactor removes the secret and recovery codes of a user
+func (m *MemoryStore) DisableTwoFactor(userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.twoFactors = filterRows(m.data.twoFactors, func(t models.TwoFactor) bool { return t.UserID == userID })
+	m.data.recoveryCodes = filterRows(m.data.recoveryCodes, func(r memoryRecoveryCode) bool { return r.userID == userID })
+	return nil
+}
+
+// UseTOTPStep records the time step of a used code, so the code can't be replayed
+func (m *MemoryStore) UseTOTPStep(userID int, step int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	twoFactor := m.data.twoFactor(userID)
+	if twoFactor == nil || twoFactor.LastStep >= step {
+		return ErrTOTPCodeUsed
+	}
+	twoFactor.LastStep = step
+	return nil
+}
+
+// ReplaceRecoveryCodes replaces all recovery codes of a user
+func (m *MemoryStore) ReplaceRecoveryCodes(userID int, recoveryCodeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.replaceRecoveryCodes(userID, recoveryCodeHashes)
+	return nil
+}
+
+// replaceRecoveryCodes deletes the recovery codes of a user and stores new ones
+func (d *memoryData) replaceRecoveryCodes(userID int, recoveryCodeHashes []string) {
+	d.recoveryCodes = filterRows(d.recoveryCodes, func(r memoryRecoveryCode) bool { return r.userID == userID })
+	for _, codeHash := range recoveryCodeHashes {
+		d.recoveryCodes = append(d.recoveryCodes, memoryRecoveryCode{userID: userID, codeHash: codeHash})
+	}
+}
+
+// UseRecoveryCode uses up a recovery code of a user
+func (m *MemoryStore) UseRecoveryCode(userID int, codeHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := len(m.data.recoveryCodes)
+	m.data.recoveryCodes = filterRows(m.data.recoveryCodes, func(r memoryRecoveryCode) bool {
+		return r.userID == userID && r.codeHash == codeHash
+	})
+	if len(m.data.recoveryCodes) == count {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
+
+// CreateLoginChallenge stores the challenge of a login waiting for a second factor
+func (m *MemoryStore) CreateLoginChallenge(userID int, tokenHash string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.loginChallenges = append(m.data.loginChallenges, memoryLoginChallenge{
+		LoginChallenge: models.LoginChallenge{
+			ID:        m.data.nextID("login_challenges"),
+			UserID:    userID,
+			ExpiresAt: expiresAt.UTC(),
+		},
+		tokenHash: tokenHash,
+	})
+	return nil
+}
+
+// GetLoginChallenge gets an unexpired login challenge by the hash of its token
+func (m *MemoryStore) GetLoginChallenge(tokenHash string, now time.Time) (*models.LoginChallenge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, challenge := range m.data.loginChallenges {
+		if challenge.tokenHash == tokenHash && challenge.ExpiresAt.After(now) {
+			result := challenge.LoginChallenge
+			return &result, nil
+		}
+	}
+	return nil, ErrLoginChallengeNotFound
+}
+
+// FailLoginChallenge counts a wrong code for a login challenge
+func (m *MemoryStore) FailLoginChallenge(challengeID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.data.loginChallenges {
+		if m.data.loginChallenges[i].ID == challengeID {
+			m.data.loginChallenges[i].Failures++
+			return m.data.loginChallenges[i].Failures, nil
+		}
+	}
+	return 0, ErrLoginChallengeNotFound
+}
+
+// DeleteLoginChallenge deletes a login challenge once it is used up
+func (m *MemoryStore) DeleteLoginChallenge(challengeID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.loginChallenges = filterRows(m.data.loginChallenges, func(c memoryLoginChallenge) bool { return c.ID == challengeID })
+	return nil
+}
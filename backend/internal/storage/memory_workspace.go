@@ -0,0 +1,344 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"sort"
+	"time"
+)
+
+// CreateWorkspace creates a workspace owned by a user
+func (m *MemoryStore) CreateWorkspace(ownerUserID int, name string) (*models.Workspace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	workspace := m.data.createWorkspace(ownerUserID, name)
+	return &workspace, nil
+}
+
+// createWorkspace stores a workspace and makes ownerUserID its owner
+func (d *memoryData) createWorkspace(ownerUserID int, name string) models.Workspace {
+	workspace := models.Workspace{
+		ID:        d.nextID("workspaces"),
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+	}
+	d.workspaces = append(d.workspaces, workspace)
+	d.workspaceUsers = append(d.workspaceUsers, memoryWorkspaceUser{
+		workspaceID: workspace.ID,
+		userID:      ownerUserID,
+		role:        models.RoleOwner,
+		createdAt:   workspace.CreatedAt,
+	})
+	workspace.Role = models.RoleOwner
+	return workspace
+}
+
+// workspaceUser finds the access of a user to a workspace, nil if there is none
+func (d *memoryData) workspaceUser(workspaceID, userID int) *memoryWorkspaceUser {
+	for i := range d.workspaceUsers {
+		wu := &d.workspaceUsers[i]
+		if wu.workspaceID == workspaceID && wu.userID == userID {
+			return wu
+		}
+	}
+	return nil
+}
+
+// ownerCount counts the owners of a workspace
+func (d *memoryData) ownerCount(workspaceID int) int {
+	count := 0
+	for _, wu := range d.workspaceUsers {
+		if wu.workspaceID == workspaceID && wu.role == models.RoleOwner {
+			count++
+		}
+	}
+	return count
+}
+
+// GetUserWorkspaces lists the workspaces a user has access to, oldest first
+func (m *MemoryStore) GetUserWorkspaces(userID int) ([]models.Workspace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var workspaces []models.Workspace
+	for _, workspace := range m.data.workspaces {
+		if wu := m.data.workspaceUser(workspace.ID, userID); wu != nil {
+			workspace.Role = wu.role
+			workspaces = append(workspaces, workspace)
+		}
+	}
+	return workspaces, nil
+}
+
+// GetWorkspace gets a workspace by ID
+func (m *MemoryStore) GetWorkspace(workspaceID int) (*models.Workspace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, workspace := range m.data.workspaces {
+		if workspace.ID == workspaceID {
+			return &workspace, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// SetWorkspaceRequireTwoFactor sets whether schedulers and owners of a workspace need two-factor authentication
+func (m *MemoryStore) SetWorkspaceRequireTwoFactor(workspaceID int, required bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.data.workspaces {
+		if m.data.workspaces[i].ID == workspaceID {
+			m.data.workspaces[i].RequireTwoFactor = required
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+// GetWorkspaceRole gets the role of a user in a workspace
+func (m *MemoryStore) GetWorkspaceRole(workspaceID, userID int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if wu := m.data.workspaceUser(workspaceID, userID); wu != nil {
+		return wu.role, nil
+	}
+	return "", sql.ErrNoRows
+}
+
+// GetWorkspaceUsers lists the users of a workspace, ordered by username
+func (m *MemoryStore) GetWorkspaceUsers(workspaceID int) ([]models.WorkspaceUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var users []models.WorkspaceUser
+	for _, user := range m.data.users {
+		if wu := m.data.workspaceUser(workspaceID, user.ID); wu != nil {
+			users = append(users, models.WorkspaceUser{
+				UserID:    user.ID,
+				Username:  user.Username,
+				Role:      wu.role,
+				CreatedAt: wu.createdAt,
+			})
+		}
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+	return users, nil
+}
+
+// SetWorkspaceUserRole adds a user to a workspace or changes their role
+// The last owner can't be demoted.
+func (m *MemoryStore) SetWorkspaceUserRole(workspaceID, userID int, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wu := m.data.workspaceUser(workspaceID, userID)
+	if wu == nil {
+		m.data.workspaceUsers = append(m.data.workspaceUsers, memoryWorkspaceUser{
+			workspaceID: workspaceID,
+			userID:      userID,
+			role:        role,
+			createdAt:   time.Now().UTC(),
+		})
+		return nil
+	}
+
+	if wu.role == models.RoleOwner && role != models.RoleOwner && m.data.ownerCount(workspaceID) == 1 {
+		return ErrLastOwner
+	}
+	wu.role = role
+	wu.ssoGranted = false
+	return nil
+}
+
+// GetSSOWorkspaceRoles maps the workspaces where single sign-on granted a user's role to that role
+func (m *MemoryStore) GetSSOWorkspaceRoles(userID int) (map[int]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roles := make(map[int]string)
+	for _, wu := range m.data.workspaceUsers {
+		if wu.userID == userID && wu.ssoGranted {
+			roles[wu.workspaceID] = wu.role
+		}
+	}
+	return roles, nil
+}
+
+// SetSSOWorkspaceRole adds a user to a workspace or changes a role single sign-on granted
+// Roles granted by hand are left as they are, and the last owner can't be demoted.
+func (m *MemoryStore) SetSSOWorkspaceRole(workspaceID, userID int, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wu := m.data.workspaceUser(workspaceID, userID)
+	switch {
+	case wu == nil:
+		m.data.workspaceUsers = append(m.data.workspaceUsers, memoryWorkspaceUser{
+			workspaceID: workspaceID,
+			userID:      userID,
+			role:        role,
+			ssoGranted:  true,
+			createdAt:   time.Now().UTC(),
+		})
+		return nil
+	case !wu.ssoGranted || wu.role == role:
+		return nil
+	case wu.role == models.RoleOwner && m.data.ownerCount(workspaceID) == 1:
+		return ErrLastOwner
+	}
+	wu.role = role
+	return nil
+}
+
+// RemoveSSOWorkspaceRole takes away access single sign-on granted
+// Roles granted by hand are left as they are, and the last owner can't be removed.
+func (m *MemoryStore) RemoveSSOWorkspaceRole(workspaceID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wu := m.data.workspaceUser(workspaceID, userID)
+	if wu == nil || !wu.ssoGranted {
+		return nil
+	}
+	if wu.role == models.RoleOwner && m.data.ownerCount(workspaceID) == 1 {
+		return ErrLastOwner
+	}
+
+	m.data.workspaceUsers = filterRows(m.data.workspaceUsers, func(row memoryWorkspaceUser) bool {
+		return row.workspaceID == workspaceID && row.userID == userID
+	})
+	return nil
+}
+
+// RemoveWorkspaceUser takes a user's access to a workspace away
+// The last owner can't be removed.
+func (m *MemoryStore) RemoveWorkspaceUser(workspaceID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wu := m.data.workspaceUser(workspaceID, userID)
+	if wu == nil {
+		return sql.ErrNoRows
+	}
+	if wu.role == models.RoleOwner && m.data.ownerCount(workspaceID) == 1 {
+		return ErrLastOwner
+	}
+
+	m.data.workspaceUsers = filterRows(m.data.workspaceUsers, func(row memoryWorkspaceUser) bool {
+		return row.workspaceID == workspaceID && row.userID == userID
+	})
+	return nil
+}
+
+// CreateWorkspaceInvite stores an invite to join a workspace
+func (m *MemoryStore) CreateWorkspaceInvite(workspaceID, invitedBy int, email, role, tokenHash string, expiresAt time.Time) (*models.WorkspaceInvite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	invite := memoryWorkspaceInvite{
+		WorkspaceInvite: models.WorkspaceInvite{
+			ID:        m.data.nextID("workspace_invites"),
+			Email:     email,
+			Role:      role,
+			InvitedBy: invitedBy,
+			ExpiresAt: expiresAt.UTC(),
+			CreatedAt: time.Now().UTC(),
+		},
+		workspaceID: workspaceID,
+		tokenHash:   tokenHash,
+	}
+	m.data.workspaceInvites = append(m.data.workspaceInvites, invite)
+	return &invite.WorkspaceInvite, nil
+}
+
+// GetWorkspaceInvites lists the invites of a workspace that haven't expired, oldest first
+func (m *MemoryStore) GetWorkspaceInvites(workspaceID int, now time.Time) ([]models.WorkspaceInvite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var invites []models.WorkspaceInvite
+	for _, invite := range m.data.workspaceInvites {
+		if invite.workspaceID == workspaceID && invite.ExpiresAt.After(now) {
+			invites = append(invites, invite.WorkspaceInvite)
+		}
+	}
+	return invites, nil
+}
+
+// GetWorkspaceInvite gets an invite that can still be accepted by the hash of its token
+func (m *MemoryStore) GetWorkspaceInvite(tokenHash string, now time.Time) (*models.WorkspaceInvite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, invite := range m.data.workspaceInvites {
+		if invite.tokenHash == tokenHash && invite.ExpiresAt.After(now) {
+			result := invite.WorkspaceInvite
+			return &result, nil
+		}
+	}
+	return nil, ErrWorkspaceInviteNotFound
+}
+
+// DeleteWorkspaceInvite revokes an invite
+func (m *MemoryStore) DeleteWorkspaceInvite(workspaceID, inviteID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := len(m.data.workspaceInvites)
+	m.data.workspaceInvites = filterRows(m.data.workspaceInvites, func(r memoryWorkspaceInvite) bool {
+		return r.workspaceID == workspaceID && r.ID == inviteID
+	})
+	if len(m.data.workspaceInvites) == count {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AcceptWorkspaceInvite uses an invite up and adds the user to its workspace
+func (m *MemoryStore) AcceptWorkspaceInvite(tokenHash string, userID int, now time.Time) (*models.Workspace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d := m.data
+	var workspace *models.Workspace
+	var role string
+	for _, invite := range d.workspaceInvites {
+		if invite.tokenHash == tokenHash && invite.ExpiresAt.After(now) {
+			for i := range d.workspaces {
+				if d.workspaces[i].ID == invite.workspaceID {
+					result := d.workspaces[i]
+					workspace, role = &result, invite.Role
+				}
+			}
+		}
+	}
+	if workspace == nil {
+		return nil, ErrWorkspaceInviteNotFound
+	}
+	d.workspaceInvites = filterRows(d.workspaceInvites, func(r memoryWorkspaceInvite) bool { return r.tokenHash == tokenHash })
+
+	wu := d.workspaceUser(workspace.ID, userID)
+	switch {
+	case wu == nil:
+		d.workspaceUsers = append(d.workspaceUsers, memoryWorkspaceUser{
+			workspaceID: workspace.ID,
+			userID:      userID,
+			role:        role,
+			createdAt:   now.UTC(),
+		})
+	case !models.RoleAtLeast(wu.role, role):
+		wu.role = role
+		fallthrough
+	default:
+		// An accepted invite grants the role by hand
+		wu.ssoGranted = false
+	}
+	workspace.Role = d.workspaceUser(workspace.ID, userID).role
+	return workspace, nil
+}
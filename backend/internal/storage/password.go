@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// argon2Params cost parameters of argon2id password hashes
+// Memory is in KiB. Hashes made with other parameters are rehashed on login.
+type argon2Params struct {
+	memory     uint32
+	iterations uint32
+	threads    uint8
+	saltLength int
+	keyLength  uint32
+}
+
+// passwordParams are the parameters new password hashes are made with (OWASP minimum for argon2id)
+var passwordParams = argon2Params{
+	memory:     19 * 1024,
+	iterations: 2,
+	threads:    1,
+	saltLength: 16,
+	keyLength:  32,
+}
+
+// dummyPasswordHash is checked in place of a missing hash, so logins of
+// unknown usernames and users without a password take as long as wrong passwords
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := hashPassword("dummy password")
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// Bounds of the parameters accepted from stored hashes, so a tampered or
+// corrupt hash can't make a login panic or exhaust memory and CPU
+const (
+	maxArgon2Memory     = 256 * 1024 // 256 MiB
+	maxArgon2Iterations = 16
+	maxArgon2Threads    = 16
+	maxArgon2KeyLength  = 128
+)
+
+// hashPassword hashes a password with argon2id and a random salt
+// The result uses the PHC string format, which records the algorithm and parameters:
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, passwordParams.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := passwordParams
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.threads, p.keyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.iterations, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// ValidatePassword checks a password against a stored hash in constant time
+// Both argon2id hashes and legacy unsalted SHA-256 hashes are accepted. An
+// empty hash never matches, but takes as long to check as an argon2id hash.
+func ValidatePassword(password, passwordHash string) bool {
+	// Users created through single sign-on have no password; the hash is still
+	// computed so they can't be told apart from users with one
+	if passwordHash == "" {
+		ValidatePassword(password, dummyPasswordHash())
+		return false
+	}
+	if !strings.HasPrefix(passwordHash, "$") {
+		legacy := sha256.Sum256([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(legacy[:])), []byte(passwordHash)) == 1
+	}
+
+	p, salt, key, err := decodePasswordHash(passwordHash)
+	if err != nil {
+		return false
+	}
+	other := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.threads, p.keyLength)
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+// PasswordNeedsRehash reports whether a stored hash is a legacy hash or was
+// made with other parameters than new hashes, so it should be replaced after
+// the next successful login
+func PasswordNeedsRehash(passwordHash string) bool {
+	p, salt, _, err := decodePasswordHash(passwordHash)
+	if err != nil {
+		return true
+	}
+	return p.memory != passwordParams.memory || p.iterations != passwordParams.iterations ||
+		p.threads != passwordParams.threads || p.keyLength != passwordParams.keyLength ||
+		len(salt) != passwordParams.saltLength
+}
+
+// decodePasswordHash parses an argon2id hash in the PHC string format
+func decodePasswordHash(passwordHash string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+
+	parts := strings.Split(passwordHash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return p, nil, nil, fmt.Errorf("unsupported password hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.threads); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 parameters: %v", err)
+	}
+	if p.threads < 1 || p.threads > maxArgon2Threads || p.iterations < 1 || p.iterations > maxArgon2Iterations ||
+		p.memory < 8*uint32(p.threads) || p.memory > maxArgon2Memory {
+		return p, nil, nil, fmt.Errorf("invalid hash")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("invalid salt: %v", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 || len(key) > maxArgon2KeyLength {
+		return p, nil, nil, fmt.Errorf("invalid hash")
+	}
+	p.saltLength = len(salt)
+	p.keyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
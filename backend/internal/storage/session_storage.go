@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+// CreateSession stores a new session under the hash of its token
+// Times are stored as UTC text with second precision, like all other expiries.
+func (store *SQLStore) CreateSession(userID int, tokenHash, userAgent, ipAddress string, expiresAt time.Time) (*models.Session, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	expiresAt = expiresAt.UTC().Truncate(time.Second)
+	nowStr := now.Format("2006-01-02 15:04:05")
+	id, err := store.db.insert(
+		"INSERT INTO sessions (user_id, token_hash, user_agent, ip_address, last_seen_at, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		userID, tokenHash, userAgent, ipAddress, nowStr, expiresAt.Format("2006-01-02 15:04:05"), nowStr,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Session{
+		ID:         id,
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+		CreatedAt:  now,
+	}, nil
+}
+
+// sessionColumns are the columns scanned by scanSession
+const sessionColumns = "id, user_id, user_agent, ip_address, last_seen_at, expires_at, created_at"
+
+// scanSession scans the sessionColumns of a row
+func scanSession(row rowScanner) (*models.Session, error) {
+	var session models.Session
+	var lastSeenAtStr, expiresAtStr, createdAtStr string
+	if err := row.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress, &lastSeenAtStr, &expiresAtStr, &createdAtStr); err != nil {
+		return nil, err
+	}
+	session.LastSeenAt = parseDateTime(lastSeenAtStr)
+	session.ExpiresAt = parseDateTime(expiresAtStr)
+	session.CreatedAt = parseDateTime(createdAtStr)
+	return &session, nil
+}
+
+// GetSession gets a session by the hash of its token
+func (store *SQLStore) GetSession(tokenHash string) (*models.Session, error) {
+	session, err := scanSession(store.db.QueryRow(
+		"SELECT "+sessionColumns+" FROM sessions WHERE token_hash = ?",
+		tokenHash,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// TouchSession records a use of a session and moves its expiry
+func (store *SQLStore) TouchSession(sessionID int, lastSeenAt, expiresAt time.Time) error {
+	_, err := store.db.Exec(
+		"UPDATE sessions SET last_seen_at = ?, expires_at = ? WHERE id = ?",
+		lastSeenAt.UTC().Format("2006-01-02 15:04:05"), expiresAt.UTC().Format("2006-01-02 15:04:05"), sessionID,
+	)
+	return err
+}
+
+// GetUserSessions lists the sessions of a user that haven't expired, most recently used first
+func (store *SQLStore) GetUserSessions(userID int, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := store.queryRows(
+		"SELECT "+sessionColumns+" FROM sessions WHERE user_id = ? AND expires_at >= ? ORDER BY last_seen_at DESC, id DESC",
+		[]interface{}{userID, now.UTC().Format("2006-01-02 15:04:05")},
+		func(rows *sql.Rows) error {
+			session, err := scanSession(rows)
+			if err != nil {
+				return err
+			}
+			sessions = append(sessions, *session)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// DeleteSession deletes a session by the hash of its token
+func (store *SQLStore) DeleteSession(tokenHash string) error {
+	_, err := store.db.Exec("DELETE FROM sessions WHERE token_hash = ?", tokenHash)
+	return err
+}
+
+// DeleteUserSession revokes a session of a user
+func (store *SQLStore) DeleteUserSession(userID, sessionID int) error {
+	result, err := store.db.Exec("DELETE FROM sessions WHERE id = ? AND user_id = ?", sessionID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteUserSessions revokes all sessions of a user except keepSessionID
+func (store *SQLStore) DeleteUserSessions(userID, keepSessionID int) error {
+	_, err := store.db.Exec("DELETE FROM sessions WHERE user_id = ? AND id <> ?", userID, keepSessionID)
+	return err
+}
+
+// DeleteExpiredSessions deletes sessions, login challenges and password resets that expired before now
+func (store *SQLStore) DeleteExpiredSessions(now time.Time) error {
+	nowStr := now.UTC().Format("2006-01-02 15:04:05")
+	if _, err := store.db.Exec("DELETE FROM sessions WHERE expires_at < ?", nowStr); err != nil {
+		return err
+	}
+	if _, err := store.db.Exec("DELETE FROM login_challenges WHERE expires_at < ?", nowStr); err != nil {
+		return err
+	}
+	_, err := store.db.Exec("DELETE FROM password_resets WHERE expires_at < ?", nowStr)
+	return err
+}
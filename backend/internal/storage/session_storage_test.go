@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, userID int) {
+		now := time.Now().UTC().Truncate(time.Second)
+		if _, err := store.CreateSession(userID, "active", "Firefox", "192.0.2.1", now.Add(time.Hour)); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		store.CreateSession(userID, "expired", "", "", now.Add(-time.Hour))
+
+		session, err := store.GetSession("active")
+		if err != nil {
+			t.Fatalf("Failed to get session: %v", err)
+		}
+		if session.UserID != userID || !session.ExpiresAt.Equal(now.Add(time.Hour)) ||
+			session.UserAgent != "Firefox" || session.IPAddress != "192.0.2.1" {
+			t.Errorf("Session mismatch: %+v", session)
+		}
+
+		if err := store.DeleteExpiredSessions(now); err != nil {
+			t.Fatalf("Failed to delete expired sessions: %v", err)
+		}
+		if _, err := store.GetSession("expired"); err != ErrSessionNotFound {
+			t.Errorf("Expected ErrSessionNotFound for expired session, got %v", err)
+		}
+
+		store.DeleteSession("active")
+		if _, err := store.GetSession("active"); err != ErrSessionNotFound {
+			t.Errorf("Expected ErrSessionNotFound after logout, got %v", err)
+		}
+	})
+}
+
+func TestUserSessions(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, userID int) {
+		now := time.Now().UTC().Truncate(time.Second)
+		older, _ := store.CreateSession(userID, "older", "", "", now.Add(time.Hour))
+		newer, _ := store.CreateSession(userID, "newer", "", "", now.Add(time.Hour))
+		store.CreateSession(userID, "expired", "", "", now.Add(-time.Hour))
+
+		// Using the older session moves it to the front
+		if err := store.TouchSession(older.ID, now.Add(time.Minute), now.Add(2*time.Hour)); err != nil {
+			t.Fatalf("Failed to touch session: %v", err)
+		}
+		touched, _ := store.GetSession("older")
+		if !touched.LastSeenAt.Equal(now.Add(time.Minute)) || !touched.ExpiresAt.Equal(now.Add(2*time.Hour)) {
+			t.Errorf("Touch not stored: %+v", touched)
+		}
+
+		sessions, err := store.GetUserSessions(userID, now)
+		if err != nil {
+			t.Fatalf("Failed to get sessions: %v", err)
+		}
+		if len(sessions) != 2 || sessions[0].ID != older.ID || sessions[1].ID != newer.ID {
+			t.Fatalf("Expected the two active sessions, most recently used first, got %+v", sessions)
+		}
+
+		other, _ := store.CreateUser("other", "password")
+		if err := store.DeleteUserSession(other.ID, newer.ID); err != ErrSessionNotFound {
+			t.Errorf("Expected ErrSessionNotFound for another user's session, got %v", err)
+		}
+		if err := store.DeleteUserSession(userID, newer.ID); err != nil {
+			t.Fatalf("Failed to delete session: %v", err)
+		}
+		if _, err := store.GetSession("newer"); err != ErrSessionNotFound {
+			t.Errorf("Expected ErrSessionNotFound after revoking, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+// planSnapshotLimit number of plan snapshots kept per workspace
+// Older snapshots are deleted when a new one is taken.
+const planSnapshotLimit = 20
+
+// CreatePlanSnapshot saves the shifts in a date range and all hidden shift counters
+// With zero dates, all shifts of the workspace are saved.
+func (store *SQLStore) CreatePlanSnapshot(workspaceID int, reason string, startDate, endDate time.Time) (*models.PlanSnapshot, error) {
+	snapshot := newPlanSnapshot(reason, startDate, endDate)
+
+	query := "SELECT member_id, start_date, end_date, is_long_shift, sequence FROM shifts WHERE workspace_id = ?"
+	args := []interface{}{workspaceID}
+	if snapshot.StartDate != "" {
+		query += " AND start_date <= ? AND end_date >= ?"
+		args = append(args, snapshot.EndDate, snapshot.StartDate)
+	}
+	err := store.queryRows(query+" ORDER BY start_date, id", args, func(rows *sql.Rows) error {
+		var s models.BackupShift
+		var startDateStr, endDateStr string
+		if err := rows.Scan(&s.MemberID, &startDateStr, &endDateStr, &s.IsLongShift, &s.Sequence); err != nil {
+			return err
+		}
+		s.StartDate, s.EndDate = backupDate(startDateStr), backupDate(endDateStr)
+		snapshot.Shifts = append(snapshot.Shifts, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.queryRows(
+		"SELECT id, COALESCE(hidden_normal_shifts, 0), COALESCE(hidden_long_shifts, 0) FROM members WHERE workspace_id = ? ORDER BY id",
+		[]interface{}{workspaceID},
+		func(rows *sql.Rows) error {
+			var c models.SnapshotCounter
+			if err := rows.Scan(&c.MemberID, &c.HiddenNormalShifts, &c.HiddenLongShifts); err != nil {
+				return err
+			}
+			snapshot.Counters = append(snapshot.Counters, c)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.ShiftCount = len(snapshot.Shifts)
+
+	shiftsJSON, err := json.Marshal(snapshot.Shifts)
+	if err != nil {
+		return nil, err
+	}
+	countersJSON, err := json.Marshal(snapshot.Counters)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	snapshot.ID, err = tx.insert(
+		"INSERT INTO plan_snapshots (workspace_id, reason, start_date, end_date, shift_count, shifts_json, counters_json, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		workspaceID, snapshot.Reason, snapshot.StartDate, snapshot.EndDate, snapshot.ShiftCount,
+		string(shiftsJSON), string(countersJSON), snapshot.CreatedAt.Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(
+		"DELETE FROM plan_snapshots WHERE workspace_id = ? AND id NOT IN (SELECT id FROM plan_snapshots WHERE workspace_id = ? ORDER BY id DESC LIMIT ?)",
+		workspaceID, workspaceID, planSnapshotLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// GetPlanSnapshots lists the plan snapshots of a workspace, newest first
+// Shifts and counters are left out; use GetPlanSnapshot to load them.
+func (store *SQLStore) GetPlanSnapshots(workspaceID int) ([]models.PlanSnapshot, error) {
+	var snapshots []models.PlanSnapshot
+	err := store.queryRows(
+		"SELECT id, reason, start_date, end_date, shift_count, created_at FROM plan_snapshots WHERE workspace_id = ? ORDER BY id DESC",
+		[]interface{}{workspaceID},
+		func(rows *sql.Rows) error {
+			var s models.PlanSnapshot
+			var createdAtStr string
+			if err := rows.Scan(&s.ID, &s.Reason, &s.StartDate, &s.EndDate, &s.ShiftCount, &createdAtStr); err != nil {
+				return err
+			}
+			s.CreatedAt = parseDateTime(createdAtStr)
+			snapshots = append(snapshots, s)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// GetPlanSnapshot gets a plan snapshot with its shifts and counters
+func (store *SQLStore) GetPlanSnapshot(workspaceID, snapshotID int) (*models.PlanSnapshot, error) {
+	var s models.PlanSnapshot
+	var shiftsJSON, countersJSON, createdAtStr string
+	err := store.db.QueryRow(
+		"SELECT id, reason, start_date, end_date, shift_count, shifts_json, counters_json, created_at FROM plan_snapshots WHERE id = ? AND workspace_id = ?",
+		snapshotID, workspaceID,
+	).Scan(&s.ID, &s.Reason, &s.StartDate, &s.EndDate, &s.ShiftCount, &shiftsJSON, &countersJSON, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(shiftsJSON), &s.Shifts); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(countersJSON), &s.Counters); err != nil {
+		return nil, err
+	}
+	s.CreatedAt = parseDateTime(createdAtStr)
+	return &s, nil
+}
+
+// RestorePlanSnapshot puts the shifts and hidden counters of a snapshot back in a single transaction
+func (store *SQLStore) RestorePlanSnapshot(workspaceID int, snapshot *models.PlanSnapshot) (*models.SnapshotRestoreResult, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := applySnapshotRestore(&sqlSnapshotRestore{sqlBackupRestore{tx: tx, workspaceID: workspaceID}}, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// newPlanSnapshot creates an empty snapshot taken now
+func newPlanSnapshot(reason string, startDate, endDate time.Time) *models.PlanSnapshot {
+	snapshot := &models.PlanSnapshot{
+		Reason:    reason,
+		Shifts:    []models.BackupShift{},
+		Counters:  []models.SnapshotCounter{},
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if !startDate.IsZero() && !endDate.IsZero() {
+		snapshot.StartDate = startDate.Format("2006-01-02")
+		snapshot.EndDate = endDate.Format("2006-01-02")
+	}
+	return snapshot
+}
+
+// snapshotRestore is the transaction a plan snapshot is restored in
+// Implemented by each storage backend, so all backends share applySnapshotRestore.
+// Dates are passed as validated YYYY-MM-DD strings.
+type snapshotRestore interface {
+	// deleteShifts deletes shifts overlapping the range (all shifts without a range)
+	// without touching the hidden counters
+	deleteShifts(startDate, endDate string) error
+	memberExists(memberID int) (bool, error)
+	hasShiftOn(date string) (bool, error)
+	insertShift(memberID int, startDate, endDate string, isLongShift bool, sequence int) error
+	setHiddenShiftCounts(memberID, normalShifts, longShifts int) error
+}
+
+// applySnapshotRestore replaces the shifts in the snapshot range with the saved
+// shifts and sets the hidden counters to their saved values
+// Members deleted since the snapshot are skipped; members created since keep their counters.
+func applySnapshotRestore(tx snapshotRestore, snapshot *models.PlanSnapshot) (*models.SnapshotRestoreResult, error) {
+	if err := tx.deleteShifts(snapshot.StartDate, snapshot.EndDate); err != nil {
+		return nil, err
+	}
+
+	result := &models.SnapshotRestoreResult{SnapshotID: snapshot.ID}
+	for _, s := range snapshot.Shifts {
+		startDate, endDate, err := backupDateRange(s.StartDate, s.EndDate)
+		if err != nil {
+			return nil, err
+		}
+
+		exists, err := tx.memberExists(s.MemberID)
+		if err != nil {
+			return nil, err
+		}
+		covered, err := tx.hasShiftOn(startDate)
+		if err != nil {
+			return nil, err
+		}
+		if !exists || covered {
+			result.SkippedShifts++
+			continue
+		}
+
+		if err := tx.insertShift(s.MemberID, startDate, endDate, s.IsLongShift, s.Sequence); err != nil {
+			return nil, err
+		}
+		result.Shifts++
+	}
+
+	for _, c := range snapshot.Counters {
+		if err := tx.setHiddenShiftCounts(c.MemberID, c.HiddenNormalShifts, c.HiddenLongShifts); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// sqlSnapshotRestore restores a plan snapshot within a SQL transaction
+type sqlSnapshotRestore struct {
+	sqlBackupRestore
+}
+
+func (r *sqlSnapshotRestore) deleteShifts(startDate, endDate string) error {
+	if startDate == "" {
+		_, err := r.tx.Exec("DELETE FROM shifts WHERE workspace_id = ?", r.workspaceID)
+		return err
+	}
+	_, err := r.tx.Exec(
+		"DELETE FROM shifts WHERE workspace_id = ? AND start_date <= ? AND end_date >= ?",
+		r.workspaceID, endDate, startDate,
+	)
+	return err
+}
+
+func (r *sqlSnapshotRestore) memberExists(memberID int) (bool, error) {
+	var count int
+	err := r.tx.QueryRow("SELECT COUNT(*) FROM members WHERE id = ? AND workspace_id = ?", memberID, r.workspaceID).Scan(&count)
+	return count > 0, err
+}
+
+func (r *sqlSnapshotRestore) setHiddenShiftCounts(memberID, normalShifts, longShifts int) error {
+	_, err := r.tx.Exec(
+		"UPDATE members SET hidden_normal_shifts = ?, hidden_long_shifts = ? WHERE id = ? AND workspace_id = ?",
+		normalShifts, longShifts, memberID, r.workspaceID,
+	)
+	return err
+}
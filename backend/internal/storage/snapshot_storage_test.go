@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"shiftplanner/backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestRestorePlanSnapshot(t *testing.T) {
+	forEachStoreWithWorkspace(t, func(t *testing.T, store Store, workspaceID int) {
+		alice, _ := store.CreateMember(workspaceID, "Alice")
+		bob, _ := store.CreateMember(workspaceID, "Bob")
+		jan6 := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+		jan7 := jan6.AddDate(0, 0, 1)
+		store.CreateShift(workspaceID, alice.ID, jan6, jan6, false)
+		store.CreateShift(workspaceID, bob.ID, jan7, jan7, true)
+		store.UpdateHiddenShiftCounts(workspaceID, alice.ID, 10, 2)
+
+		before, _ := store.GetAllHiddenShiftCounts(workspaceID)
+
+		snapshot, err := store.CreatePlanSnapshot(workspaceID, models.SnapshotReasonClear, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("Failed to create snapshot: %v", err)
+		}
+		if snapshot.ShiftCount != 2 {
+			t.Errorf("Expected 2 shifts in snapshot, got %d", snapshot.ShiftCount)
+		}
+
+		// Clear, then plan something else that the restore must replace
+		store.DeleteAllShifts(workspaceID)
+		store.CreateShift(workspaceID, bob.ID, jan6, jan6, false)
+
+		saved, err := store.GetPlanSnapshot(workspaceID, snapshot.ID)
+		if err != nil {
+			t.Fatalf("Failed to get snapshot: %v", err)
+		}
+		result, err := store.RestorePlanSnapshot(workspaceID, saved)
+		if err != nil {
+			t.Fatalf("Failed to restore snapshot: %v", err)
+		}
+		if result.Shifts != 2 || result.SkippedShifts != 0 {
+			t.Errorf("Expected 2 restored shifts, got %+v", result)
+		}
+
+		shifts, _ := store.GetShiftsByDateRange(workspaceID, jan6, jan7)
+		if len(shifts) != 2 || shifts[0].MemberID != alice.ID || shifts[1].MemberID != bob.ID || !shifts[1].IsLongShift {
+			t.Errorf("Restored shifts mismatch: %+v", shifts)
+		}
+
+		after, _ := store.GetAllHiddenShiftCounts(workspaceID)
+		for memberID, counts := range before {
+			if after[memberID] != counts {
+				t.Errorf("Member %d counters: expected %+v, got %+v", memberID, counts, after[memberID])
+			}
+		}
+	})
+}
+
+func TestRestorePlanSnapshot_Range(t *testing.T) {
+	forEachStoreWithWorkspace(t, func(t *testing.T, store Store, workspaceID int) {
+		alice, _ := store.CreateMember(workspaceID, "Alice")
+		bob, _ := store.CreateMember(workspaceID, "Bob")
+		jan6 := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+		feb3 := time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)
+		store.CreateShift(workspaceID, alice.ID, jan6, jan6, false)
+		store.CreateShift(workspaceID, alice.ID, feb3, feb3, false)
+
+		snapshot, _ := store.CreatePlanSnapshot(workspaceID, models.SnapshotReasonGenerate, jan6, jan6)
+		if snapshot.StartDate != "2025-01-06" || snapshot.ShiftCount != 1 {
+			t.Fatalf("Snapshot should cover only 2025-01-06, got %+v", snapshot)
+		}
+
+		store.DeleteShiftsByDateRange(workspaceID, jan6, jan6)
+		store.CreateShift(workspaceID, bob.ID, jan6, jan6, false)
+		store.DeleteMember(workspaceID, bob.ID)
+
+		saved, _ := store.GetPlanSnapshot(workspaceID, snapshot.ID)
+		if _, err := store.RestorePlanSnapshot(workspaceID, saved); err != nil {
+			t.Fatalf("Failed to restore snapshot: %v", err)
+		}
+
+		// Shifts outside the range are left alone
+		shifts, _ := store.GetShiftsByDateRange(workspaceID, jan6, feb3)
+		if len(shifts) != 2 || shifts[0].MemberID != alice.ID {
+			t.Errorf("Expected Alice's two shifts, got %+v", shifts)
+		}
+	})
+}
+
+func TestPlanSnapshots_Pruned(t *testing.T) {
+	forEachStoreWithWorkspace(t, func(t *testing.T, store Store, workspaceID int) {
+		first, _ := store.CreatePlanSnapshot(workspaceID, models.SnapshotReasonClear, time.Time{}, time.Time{})
+		for i := 0; i < planSnapshotLimit; i++ {
+			store.CreatePlanSnapshot(workspaceID, models.SnapshotReasonClear, time.Time{}, time.Time{})
+		}
+
+		snapshots, err := store.GetPlanSnapshots(workspaceID)
+		if err != nil {
+			t.Fatalf("Failed to list snapshots: %v", err)
+		}
+		if len(snapshots) != planSnapshotLimit {
+			t.Errorf("Expected %d snapshots, got %d", planSnapshotLimit, len(snapshots))
+		}
+		if snapshots[0].ID < snapshots[len(snapshots)-1].ID {
+			t.Error("Snapshots should be listed newest first")
+		}
+		if _, err := store.GetPlanSnapshot(workspaceID, first.ID); err == nil {
+			t.Error("Oldest snapshot should have been pruned")
+		}
+	})
+}
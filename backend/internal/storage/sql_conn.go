@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// dialect is the SQL flavour spoken by the database behind a SQLStore
+type dialect int
+
+const (
+	sqliteDialect dialect = iota
+	postgresDialect
+)
+
+// sqlQuerier is implemented by both *sql.DB and *sql.Tx
+type sqlQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// sqlConn runs queries written for SQLite against the database's dialect
+// Queries use ? placeholders, which are rewritten to $n for Postgres
+type sqlConn struct {
+	q       sqlQuerier
+	dialect dialect
+}
+
+func (c sqlConn) rebind(query string) string {
+	if c.dialect != postgresDialect {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (c sqlConn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.q.Exec(c.rebind(query), args...)
+}
+
+func (c sqlConn) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.q.Query(c.rebind(query), args...)
+}
+
+func (c sqlConn) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.q.QueryRow(c.rebind(query), args...)
+}
+
+// insert runs an INSERT statement and returns the ID of the new row
+// Postgres has no LastInsertId, so the ID is read back with RETURNING instead
+func (c sqlConn) insert(query string, args ...interface{}) (int, error) {
+	if c.dialect == postgresDialect {
+		var id int
+		err := c.QueryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := c.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// dayCount returns an expression for the number of days from start to end, inclusive
+func (c sqlConn) dayCount(start, end string) string {
+	if c.dialect == postgresDialect {
+		return "(" + end + "::date - " + start + "::date + 1)"
+	}
+	return "(julianday(" + end + ") - julianday(" + start + ") + 1)"
+}
+
+// nonNegative returns an expression that clamps expr at zero
+func (c sqlConn) nonNegative(expr string) string {
+	if c.dialect == postgresDialect {
+		return "GREATEST(0, " + expr + ")"
+	}
+	return "MAX(0, " + expr + ")"
+}
+
+// sqlDB is a database connection pool of a SQLStore
+type sqlDB struct {
+	sqlConn
+	db *sql.DB
+}
+
+// Begin starts a transaction in the same dialect
+func (d *sqlDB) Begin() (*sqlTx, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{sqlConn: sqlConn{q: tx, dialect: d.dialect}, tx: tx}, nil
+}
+
+// sqlTx is a transaction of a SQLStore
+type sqlTx struct {
+	sqlConn
+	tx *sql.Tx
+}
+
+func (t *sqlTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *sqlTx) Rollback() error {
+	return t.tx.Rollback()
+}
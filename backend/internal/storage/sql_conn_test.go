@@ -0,0 +1,18 @@
+package storage
+
+import "testing"
+
+func TestSQLConnRebind(t *testing.T) {
+	query := "SELECT id FROM shifts WHERE user_id = ? AND start_date <= ? AND end_date >= ?"
+
+	sqlite := sqlConn{dialect: sqliteDialect}
+	if got := sqlite.rebind(query); got != query {
+		t.Errorf("SQLite query should be unchanged, got %s", got)
+	}
+
+	postgres := sqlConn{dialect: postgresDialect}
+	want := "SELECT id FROM shifts WHERE user_id = $1 AND start_date <= $2 AND end_date >= $3"
+	if got := postgres.rebind(query); got != want {
+		t.Errorf("Postgres query mismatch:\ngot:  %s\nwant: %s", got, want)
+	}
+}
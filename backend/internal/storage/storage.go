@@ -4,14 +4,13 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"shiftplanner/backend/internal/database"
 	"shiftplanner/backend/internal/models"
 	"time"
 )
 
-// GetAllMembers gets all members for a user
-func GetAllMembers(userID int) ([]models.Member, error) {
-	rows, err := database.DB.Query("SELECT id, name, created_at FROM members WHERE user_id = ? ORDER BY name", userID)
+// GetAllMembers gets all members of a workspace
+func (store *SQLStore) GetAllMembers(workspaceID int) ([]models.Member, error) {
+	rows, err := store.db.Query("SELECT id, name, COALESCE(user_id, 0), created_at FROM members WHERE workspace_id = ? ORDER BY name", workspaceID)
 	if err != nil {
 		return nil, err
 	}
@@ -21,7 +20,7 @@ func GetAllMembers(userID int) ([]models.Member, error) {
 	for rows.Next() {
 		var m models.Member
 		var createdAtStr string
-		if err := rows.Scan(&m.ID, &m.Name, &createdAtStr); err != nil {
+		if err := rows.Scan(&m.ID, &m.Name, &m.UserID, &createdAtStr); err != nil {
 			return nil, err
 		}
 		// Parse SQLite datetime format
@@ -41,13 +40,13 @@ func GetAllMembers(userID int) ([]models.Member, error) {
 // CreateMember creates a new member
 // When a new member is created, their hidden shift counters are initialized
 // to the average of all other members' hidden shift counters
-func CreateMember(userID int, name string) (*models.Member, error) {
+func (store *SQLStore) CreateMember(workspaceID int, name string) (*models.Member, error) {
 	// Calculate average hidden shifts for existing members
 	avgNormalShifts := 0
 	avgLongShifts := 0
 
-	// Get all existing members for this user
-	existingMembers, err := GetAllMembers(userID)
+	// Get all existing members of this workspace
+	existingMembers, err := store.GetAllMembers(workspaceID)
 	if err == nil && len(existingMembers) > 0 {
 		// Calculate average hidden shifts
 		totalNormalShifts := 0
@@ -55,7 +54,7 @@ func CreateMember(userID int, name string) (*models.Member, error) {
 		count := 0
 
 		for _, member := range existingMembers {
-			normalShifts, longShifts, err := GetHiddenShiftCounts(userID, member.ID)
+			normalShifts, longShifts, err := store.GetHiddenShiftCounts(workspaceID, member.ID)
 			if err == nil {
 				totalNormalShifts += normalShifts
 				totalLongShifts += longShifts
@@ -70,38 +69,33 @@ func CreateMember(userID int, name string) (*models.Member, error) {
 	}
 
 	// Insert new member with average hidden shift counts
-	result, err := database.DB.Exec(
-		"INSERT INTO members (user_id, name, hidden_normal_shifts, hidden_long_shifts) VALUES (?, ?, ?, ?)",
-		userID, name, avgNormalShifts, avgLongShifts,
+	id, err := store.db.insert(
+		"INSERT INTO members (workspace_id, name, hidden_normal_shifts, hidden_long_shifts) VALUES (?, ?, ?, ?)",
+		workspaceID, name, avgNormalShifts, avgLongShifts,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return nil, err
-	}
-
 	return &models.Member{
-		ID:        int(id),
+		ID:        id,
 		Name:      name,
 		CreatedAt: time.Now(),
 	}, nil
 }
 
 // DeleteMember deletes a member (can only delete own members)
-func DeleteMember(userID, memberID int) error {
-	_, err := database.DB.Exec("DELETE FROM members WHERE id = ? AND user_id = ?", memberID, userID)
+func (store *SQLStore) DeleteMember(workspaceID, memberID int) error {
+	_, err := store.db.Exec("DELETE FROM members WHERE id = ? AND workspace_id = ?", memberID, workspaceID)
 	return err
 }
 
 // GetMemberByID gets a member by ID (can only get own members)
-func GetMemberByID(userID, memberID int) (*models.Member, error) {
+func (store *SQLStore) GetMemberByID(workspaceID, memberID int) (*models.Member, error) {
 	var m models.Member
 	var createdAtStr string
-	err := database.DB.QueryRow("SELECT id, name, created_at FROM members WHERE id = ? AND user_id = ?", memberID, userID).
-		Scan(&m.ID, &m.Name, &createdAtStr)
+	err := store.db.QueryRow("SELECT id, name, COALESCE(user_id, 0), created_at FROM members WHERE id = ? AND workspace_id = ?", memberID, workspaceID).
+		Scan(&m.ID, &m.Name, &m.UserID, &createdAtStr)
 	if err != nil {
 		return nil, err
 	}
@@ -117,11 +111,11 @@ func GetMemberByID(userID, memberID int) (*models.Member, error) {
 }
 
 // GetMemberByName gets a member by name (case-insensitive, can only get own members)
-func GetMemberByName(userID int, name string) (*models.Member, error) {
+func (store *SQLStore) GetMemberByName(workspaceID int, name string) (*models.Member, error) {
 	var m models.Member
 	var createdAtStr string
-	err := database.DB.QueryRow("SELECT id, name, created_at FROM members WHERE LOWER(name) = LOWER(?) AND user_id = ?", name, userID).
-		Scan(&m.ID, &m.Name, &createdAtStr)
+	err := store.db.QueryRow("SELECT id, name, COALESCE(user_id, 0), created_at FROM members WHERE LOWER(name) = LOWER(?) AND workspace_id = ?", name, workspaceID).
+		Scan(&m.ID, &m.Name, &m.UserID, &createdAtStr)
 	if err != nil {
 		return nil, err
 	}
@@ -138,7 +132,7 @@ func GetMemberByName(userID int, name string) (*models.Member, error) {
 
 // CreateShift creates a new shift record
 // Also updates hidden shift counters for the member
-func CreateShift(userID, memberID int, startDate, endDate time.Time, isLongShift bool) (*models.Shift, error) {
+func (store *SQLStore) CreateShift(workspaceID, memberID int, startDate, endDate time.Time, isLongShift bool) (*models.Shift, error) {
 	// Validate dates are not zero
 	if startDate.IsZero() || endDate.IsZero() {
 		return nil, fmt.Errorf("start_date and end_date cannot be zero")
@@ -151,31 +145,26 @@ func CreateShift(userID, memberID int, startDate, endDate time.Time, isLongShift
 	startDateStr := startDateUTC.Format("2006-01-02")
 	endDateStr := endDateUTC.Format("2006-01-02")
 
-	result, err := database.DB.Exec(
-		"INSERT INTO shifts (user_id, member_id, start_date, end_date, is_long_shift) VALUES (?, ?, ?, ?, ?)",
-		userID, memberID, startDateStr, endDateStr, isLongShift,
+	id, err := store.db.insert(
+		"INSERT INTO shifts (workspace_id, member_id, start_date, end_date, is_long_shift) VALUES (?, ?, ?, ?, ?)",
+		workspaceID, memberID, startDateStr, endDateStr, isLongShift,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return nil, err
-	}
-
 	// Calculate shift days
 	shiftDays := int(endDateUTC.Sub(startDateUTC).Hours()/24) + 1
 
 	// Update hidden shift counters
 	if isLongShift {
-		UpdateHiddenShiftCounts(userID, memberID, 0, shiftDays)
+		store.UpdateHiddenShiftCounts(workspaceID, memberID, 0, shiftDays)
 	} else {
-		UpdateHiddenShiftCounts(userID, memberID, shiftDays, 0)
+		store.UpdateHiddenShiftCounts(workspaceID, memberID, shiftDays, 0)
 	}
 
 	return &models.Shift{
-		ID:          int(id),
+		ID:          id,
 		MemberID:    memberID,
 		StartDate:   startDateUTC,
 		EndDate:     endDateUTC,
@@ -185,13 +174,13 @@ func CreateShift(userID, memberID int, startDate, endDate time.Time, isLongShift
 }
 
 // GetShiftsByDateRange gets shifts by date range
-func GetShiftsByDateRange(userID int, startDate, endDate time.Time) ([]models.Shift, error) {
+func (store *SQLStore) GetShiftsByDateRange(workspaceID int, startDate, endDate time.Time) ([]models.Shift, error) {
 	startDateStr := startDate.Format("2006-01-02")
 	endDateStr := endDate.Format("2006-01-02")
 
-	rows, err := database.DB.Query(
-		"SELECT id, member_id, start_date, end_date, is_long_shift, created_at FROM shifts WHERE user_id = ? AND start_date <= ? AND end_date >= ? ORDER BY start_date",
-		userID, endDateStr, startDateStr,
+	rows, err := store.db.Query(
+		"SELECT id, member_id, start_date, end_date, is_long_shift, sequence, created_at FROM shifts WHERE workspace_id = ? AND start_date <= ? AND end_date >= ? ORDER BY start_date",
+		workspaceID, endDateStr, startDateStr,
 	)
 	if err != nil {
 		return nil, err
@@ -202,9 +191,9 @@ func GetShiftsByDateRange(userID int, startDate, endDate time.Time) ([]models.Sh
 	for rows.Next() {
 		var s models.Shift
 		var startDateStr, endDateStr, createdAtStr string
-		var isLongShift int
+		var isLongShift bool
 
-		if err := rows.Scan(&s.ID, &s.MemberID, &startDateStr, &endDateStr, &isLongShift, &createdAtStr); err != nil {
+		if err := rows.Scan(&s.ID, &s.MemberID, &startDateStr, &endDateStr, &isLongShift, &s.Sequence, &createdAtStr); err != nil {
 			return nil, err
 		}
 
@@ -268,7 +257,7 @@ func GetShiftsByDateRange(userID int, startDate, endDate time.Time) ([]models.Sh
 		// Normalize to UTC midnight
 		s.EndDate = time.Date(endTime.Year(), endTime.Month(), endTime.Day(), 0, 0, 0, 0, time.UTC)
 
-		s.IsLongShift = isLongShift == 1
+		s.IsLongShift = isLongShift
 		// Parse SQLite datetime format
 		if t, err := time.Parse("2006-01-02 15:04:05", createdAtStr); err == nil {
 			s.CreatedAt = t.UTC()
@@ -285,14 +274,14 @@ func GetShiftsByDateRange(userID int, startDate, endDate time.Time) ([]models.Sh
 }
 
 // GetMemberShiftStats gets shift statistics for a member
-func GetMemberShiftStats(userID, memberID int) (totalDays int, longShiftCount int, err error) {
+func (store *SQLStore) GetMemberShiftStats(workspaceID, memberID int) (totalDays int, longShiftCount int, err error) {
 	// Total shift days count
 	var totalDaysResult sql.NullInt64
-	err = database.DB.QueryRow(`
-		SELECT COALESCE(SUM(julianday(end_date) - julianday(start_date) + 1), 0) 
+	err = store.db.QueryRow(`
+		SELECT COALESCE(SUM(`+store.db.dayCount("start_date", "end_date")+`), 0) 
 		FROM shifts 
-		WHERE user_id = ? AND member_id = ?
-	`, userID, memberID).Scan(&totalDaysResult)
+		WHERE workspace_id = ? AND member_id = ?
+	`, workspaceID, memberID).Scan(&totalDaysResult)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -301,11 +290,11 @@ func GetMemberShiftStats(userID, memberID int) (totalDays int, longShiftCount in
 	}
 
 	// Long shift count
-	err = database.DB.QueryRow(`
+	err = store.db.QueryRow(`
 		SELECT COUNT(*) 
 		FROM shifts 
-		WHERE user_id = ? AND member_id = ? AND is_long_shift = 1
-	`, userID, memberID).Scan(&longShiftCount)
+		WHERE workspace_id = ? AND member_id = ? AND is_long_shift = ?
+	`, workspaceID, memberID, true).Scan(&longShiftCount)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -314,15 +303,23 @@ func GetMemberShiftStats(userID, memberID int) (totalDays int, longShiftCount in
 }
 
 // GetAllMembersStats gets statistics for all members
-func GetAllMembersStats(userID int) (map[int]models.MemberStats, error) {
-	members, err := GetAllMembers(userID)
+func (store *SQLStore) GetAllMembersStats(workspaceID int) (map[int]models.MemberStats, error) {
+	return allMembersStats(store, workspaceID)
+}
+
+// allMembersStats gets statistics for all members from the data of a store
+func allMembersStats(store interface {
+	MemberStore
+	ShiftStore
+}, workspaceID int) (map[int]models.MemberStats, error) {
+	members, err := store.GetAllMembers(workspaceID)
 	if err != nil {
 		return nil, err
 	}
 
 	stats := make(map[int]models.MemberStats)
 	for _, member := range members {
-		totalDays, longShiftCount, err := GetMemberShiftStats(userID, member.ID)
+		totalDays, longShiftCount, err := store.GetMemberShiftStats(workspaceID, member.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -338,12 +335,12 @@ func GetAllMembersStats(userID int) (map[int]models.MemberStats, error) {
 // DeleteShiftsByDateRange deletes shifts that overlap with the date range
 // This ensures we don't have duplicate shifts when regenerating
 // Also updates hidden shift counters for affected members
-func DeleteShiftsByDateRange(userID int, startDate, endDate time.Time) error {
+func (store *SQLStore) DeleteShiftsByDateRange(workspaceID int, startDate, endDate time.Time) error {
 	startDateStr := startDate.Format("2006-01-02")
 	endDateStr := endDate.Format("2006-01-02")
 
 	// Get shifts that will be deleted to update hidden counters
-	shiftsToDelete, err := GetShiftsByDateRange(userID, startDate, endDate)
+	shiftsToDelete, err := store.GetShiftsByDateRange(workspaceID, startDate, endDate)
 	if err != nil {
 		return err
 	}
@@ -352,26 +349,26 @@ func DeleteShiftsByDateRange(userID int, startDate, endDate time.Time) error {
 	for _, shift := range shiftsToDelete {
 		shiftDays := int(shift.EndDate.Sub(shift.StartDate).Hours()/24) + 1
 		if shift.IsLongShift {
-			UpdateHiddenShiftCounts(userID, shift.MemberID, 0, -shiftDays)
+			store.UpdateHiddenShiftCounts(workspaceID, shift.MemberID, 0, -shiftDays)
 		} else {
-			UpdateHiddenShiftCounts(userID, shift.MemberID, -shiftDays, 0)
+			store.UpdateHiddenShiftCounts(workspaceID, shift.MemberID, -shiftDays, 0)
 		}
 	}
 
 	// Delete shifts that overlap with the date range
 	// A shift overlaps if: start_date <= endDate AND end_date >= startDate
-	_, err = database.DB.Exec(
-		"DELETE FROM shifts WHERE user_id = ? AND start_date <= ? AND end_date >= ?",
-		userID, endDateStr, startDateStr,
+	_, err = store.db.Exec(
+		"DELETE FROM shifts WHERE workspace_id = ? AND start_date <= ? AND end_date >= ?",
+		workspaceID, endDateStr, startDateStr,
 	)
 	return err
 }
 
-// DeleteAllShifts deletes all shifts for a user
+// DeleteAllShifts deletes all shifts of a workspace
 // Also resets hidden shift counters for all members
-func DeleteAllShifts(userID int) error {
+func (store *SQLStore) DeleteAllShifts(workspaceID int) error {
 	// Get all shifts to update hidden counters
-	allShifts, err := GetShiftsByDateRange(userID, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC))
+	allShifts, err := store.GetShiftsByDateRange(workspaceID, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC))
 	if err != nil {
 		return err
 	}
@@ -380,36 +377,46 @@ func DeleteAllShifts(userID int) error {
 	for _, shift := range allShifts {
 		shiftDays := int(shift.EndDate.Sub(shift.StartDate).Hours()/24) + 1
 		if shift.IsLongShift {
-			UpdateHiddenShiftCounts(userID, shift.MemberID, 0, -shiftDays)
+			store.UpdateHiddenShiftCounts(workspaceID, shift.MemberID, 0, -shiftDays)
 		} else {
-			UpdateHiddenShiftCounts(userID, shift.MemberID, -shiftDays, 0)
+			store.UpdateHiddenShiftCounts(workspaceID, shift.MemberID, -shiftDays, 0)
 		}
 	}
 
-	_, err = database.DB.Exec(
-		"DELETE FROM shifts WHERE user_id = ?",
-		userID,
+	_, err = store.db.Exec(
+		"DELETE FROM shifts WHERE workspace_id = ?",
+		workspaceID,
 	)
 	return err
 }
 
 // GetShiftByDate gets a shift that covers a specific date
-func GetShiftByDate(userID int, date time.Time) (*models.Shift, error) {
+func (store *SQLStore) GetShiftByDate(workspaceID int, date time.Time) (*models.Shift, error) {
 	dateStr := date.Format("2006-01-02")
 
+	shift, err := store.queryShift("workspace_id = ? AND start_date <= ? AND end_date >= ?", workspaceID, dateStr, dateStr)
+	if err == sql.ErrNoRows {
+		return nil, nil // No shift found
+	}
+	return shift, err
+}
+
+// GetShiftByID gets a shift by ID (can only get own shifts)
+func (store *SQLStore) GetShiftByID(workspaceID, shiftID int) (*models.Shift, error) {
+	return store.queryShift("id = ? AND workspace_id = ?", shiftID, workspaceID)
+}
+
+// queryShift gets the first shift matching a WHERE clause
+func (store *SQLStore) queryShift(where string, args ...interface{}) (*models.Shift, error) {
 	var s models.Shift
 	var startDateStr, endDateStr, createdAtStr string
-	var isLongShift int
-
-	err := database.DB.QueryRow(
-		"SELECT id, member_id, start_date, end_date, is_long_shift, created_at FROM shifts WHERE user_id = ? AND start_date <= ? AND end_date >= ? LIMIT 1",
-		userID, dateStr, dateStr,
-	).Scan(&s.ID, &s.MemberID, &startDateStr, &endDateStr, &isLongShift, &createdAtStr)
+	var isLongShift bool
 
+	err := store.db.QueryRow(
+		"SELECT id, member_id, start_date, end_date, is_long_shift, sequence, created_at FROM shifts WHERE "+where+" LIMIT 1",
+		args...,
+	).Scan(&s.ID, &s.MemberID, &startDateStr, &endDateStr, &isLongShift, &s.Sequence, &createdAtStr)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // No shift found
-		}
 		return nil, err
 	}
 
@@ -439,7 +446,7 @@ func GetShiftByDate(userID int, date time.Time) (*models.Shift, error) {
 	}
 	s.EndDate = time.Date(endTime.Year(), endTime.Month(), endTime.Day(), 0, 0, 0, 0, time.UTC)
 
-	s.IsLongShift = isLongShift == 1
+	s.IsLongShift = isLongShift
 
 	// Parse created_at
 	if t, err := time.Parse("2006-01-02 15:04:05", createdAtStr); err == nil {
@@ -455,15 +462,15 @@ func GetShiftByDate(userID int, date time.Time) (*models.Shift, error) {
 
 // UpdateShiftMember updates the member for a shift
 // Also updates hidden shift counters for both old and new members
-func UpdateShiftMember(userID, shiftID, newMemberID int) error {
+func (store *SQLStore) UpdateShiftMember(workspaceID, shiftID, newMemberID int) error {
 	// Get the shift to find old member and shift details
 	var oldMemberID int
 	var startDateStr, endDateStr string
-	var isLongShift int
+	var isLongShift bool
 
-	err := database.DB.QueryRow(
-		"SELECT member_id, start_date, end_date, is_long_shift FROM shifts WHERE id = ? AND user_id = ?",
-		shiftID, userID,
+	err := store.db.QueryRow(
+		"SELECT member_id, start_date, end_date, is_long_shift FROM shifts WHERE id = ? AND workspace_id = ?",
+		shiftID, workspaceID,
 	).Scan(&oldMemberID, &startDateStr, &endDateStr, &isLongShift)
 	if err != nil {
 		return err
@@ -471,9 +478,9 @@ func UpdateShiftMember(userID, shiftID, newMemberID int) error {
 
 	// If member didn't change, no need to update counters
 	if oldMemberID == newMemberID {
-		_, err := database.DB.Exec(
-			"UPDATE shifts SET member_id = ? WHERE id = ? AND user_id = ?",
-			newMemberID, shiftID, userID,
+		_, err := store.db.Exec(
+			"UPDATE shifts SET member_id = ? WHERE id = ? AND workspace_id = ?",
+			newMemberID, shiftID, workspaceID,
 		)
 		return err
 	}
@@ -499,48 +506,53 @@ func UpdateShiftMember(userID, shiftID, newMemberID int) error {
 	// Calculate shift days
 	shiftDays := int(endDate.Sub(startDate).Hours()/24) + 1
 
-	// Update shift member
-	_, err = database.DB.Exec(
-		"UPDATE shifts SET member_id = ? WHERE id = ? AND user_id = ?",
-		newMemberID, shiftID, userID,
+	// Update shift member and bump the sequence so calendar clients pick up the change
+	_, err = store.db.Exec(
+		"UPDATE shifts SET member_id = ?, sequence = sequence + 1 WHERE id = ? AND workspace_id = ?",
+		newMemberID, shiftID, workspaceID,
 	)
 	if err != nil {
 		return err
 	}
 
 	// Update hidden shift counters: decrease for old member, increase for new member
-	if isLongShift == 1 {
-		UpdateHiddenShiftCounts(userID, oldMemberID, 0, -shiftDays)
-		UpdateHiddenShiftCounts(userID, newMemberID, 0, shiftDays)
+	if isLongShift {
+		store.UpdateHiddenShiftCounts(workspaceID, oldMemberID, 0, -shiftDays)
+		store.UpdateHiddenShiftCounts(workspaceID, newMemberID, 0, shiftDays)
 	} else {
-		UpdateHiddenShiftCounts(userID, oldMemberID, -shiftDays, 0)
-		UpdateHiddenShiftCounts(userID, newMemberID, shiftDays, 0)
+		store.UpdateHiddenShiftCounts(workspaceID, oldMemberID, -shiftDays, 0)
+		store.UpdateHiddenShiftCounts(workspaceID, newMemberID, shiftDays, 0)
 	}
 
 	return nil
 }
 
 // CreateOrUpdateShiftForDate creates or updates a shift for a specific date
+func (store *SQLStore) CreateOrUpdateShiftForDate(workspaceID, memberID int, date time.Time) (*models.Shift, error) {
+	return createOrUpdateShiftForDate(store, workspaceID, memberID, date)
+}
+
+// createOrUpdateShiftForDate creates or updates a shift for a specific date in a store
 // If a shift exists for that date, updates the member_id
 // If no shift exists, creates a new single-day shift
-func CreateOrUpdateShiftForDate(userID, memberID int, date time.Time) (*models.Shift, error) {
+func createOrUpdateShiftForDate(store ShiftStore, workspaceID, memberID int, date time.Time) (*models.Shift, error) {
 	// Normalize date to UTC midnight
 	dateUTC := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
 
 	// Check if shift exists for this date
-	existingShift, err := GetShiftByDate(userID, dateUTC)
+	existingShift, err := store.GetShiftByDate(workspaceID, dateUTC)
 	if err != nil {
 		return nil, err
 	}
 
 	if existingShift != nil {
 		// Update existing shift member
-		if err := UpdateShiftMember(userID, existingShift.ID, memberID); err != nil {
+		if err := store.UpdateShiftMember(workspaceID, existingShift.ID, memberID); err != nil {
 			return nil, err
 		}
 
 		// Fetch updated shift
-		updatedShift, err := GetShiftByDate(userID, dateUTC)
+		updatedShift, err := store.GetShiftByDate(workspaceID, dateUTC)
 		if err != nil {
 			return nil, err
 		}
@@ -555,11 +567,11 @@ func CreateOrUpdateShiftForDate(userID, memberID int, date time.Time) (*models.S
 		isLongShift = true
 	}
 
-	return CreateShift(userID, memberID, dateUTC, dateUTC, isLongShift)
+	return store.CreateShift(workspaceID, memberID, dateUTC, dateUTC, isLongShift)
 }
 
 // CreateLeaveDay creates a new leave day record
-func CreateLeaveDay(userID, memberID int, leaveDate time.Time) (*models.LeaveDay, error) {
+func (store *SQLStore) CreateLeaveDay(workspaceID, memberID int, leaveDate time.Time, leaveType string) (*models.LeaveDay, error) {
 	// Validate date is not zero
 	if leaveDate.IsZero() {
 		return nil, fmt.Errorf("leave_date cannot be zero")
@@ -569,29 +581,31 @@ func CreateLeaveDay(userID, memberID int, leaveDate time.Time) (*models.LeaveDay
 	leaveDateUTC := time.Date(leaveDate.Year(), leaveDate.Month(), leaveDate.Day(), 0, 0, 0, 0, time.UTC)
 	leaveDateStr := leaveDateUTC.Format("2006-01-02")
 
-	result, err := database.DB.Exec(
-		"INSERT INTO leave_days (user_id, member_id, leave_date) VALUES (?, ?, ?)",
-		userID, memberID, leaveDateStr,
+	id, err := store.db.insert(
+		"INSERT INTO leave_days (workspace_id, member_id, leave_date, leave_type) VALUES (?, ?, ?, ?)",
+		workspaceID, memberID, leaveDateStr, leaveType,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return nil, err
-	}
-
 	return &models.LeaveDay{
-		ID:        int(id),
+		ID:        id,
 		MemberID:  memberID,
 		LeaveDate: leaveDateUTC,
+		LeaveType: leaveType,
 		CreatedAt: time.Now().UTC(),
 	}, nil
 }
 
 // CreateLeaveDaysRange creates leave days for a date range
-func CreateLeaveDaysRange(userID, memberID int, startDate, endDate time.Time) ([]models.LeaveDay, error) {
+func (store *SQLStore) CreateLeaveDaysRange(workspaceID, memberID int, startDate, endDate time.Time, leaveType string) ([]models.LeaveDay, error) {
+	return createLeaveDaysRange(store.db.sqlConn, workspaceID, memberID, startDate, endDate, leaveType, 0)
+}
+
+// createLeaveDaysRange creates leave days for a date range
+// If requestID is not 0, the created leave days are linked to that leave request
+func createLeaveDaysRange(conn sqlConn, workspaceID, memberID int, startDate, endDate time.Time, leaveType string, requestID int) ([]models.LeaveDay, error) {
 	if startDate.IsZero() || endDate.IsZero() {
 		return nil, fmt.Errorf("start_date and end_date cannot be zero")
 	}
@@ -611,19 +625,19 @@ func CreateLeaveDaysRange(userID, memberID int, startDate, endDate time.Time) ([
 
 		// Check if leave day already exists
 		var existingID int
-		err := database.DB.QueryRow(
-			"SELECT id FROM leave_days WHERE user_id = ? AND member_id = ? AND leave_date = ?",
-			userID, memberID, dateStr,
+		err := conn.QueryRow(
+			"SELECT id FROM leave_days WHERE workspace_id = ? AND member_id = ? AND leave_date = ?",
+			workspaceID, memberID, dateStr,
 		).Scan(&existingID)
 
 		if err == nil {
 			// Already exists, fetch it
 			var existingLeaveDay models.LeaveDay
 			var createdAtStr string
-			err := database.DB.QueryRow(
-				"SELECT id, member_id, leave_date, created_at FROM leave_days WHERE id = ?",
+			err := conn.QueryRow(
+				"SELECT id, member_id, leave_date, leave_type, created_at FROM leave_days WHERE id = ?",
 				existingID,
-			).Scan(&existingLeaveDay.ID, &existingLeaveDay.MemberID, &dateStr, &createdAtStr)
+			).Scan(&existingLeaveDay.ID, &existingLeaveDay.MemberID, &dateStr, &existingLeaveDay.LeaveType, &createdAtStr)
 			if err == nil {
 				// Parse the date
 				if t, parseErr := time.Parse("2006-01-02", dateStr); parseErr == nil {
@@ -641,23 +655,25 @@ func CreateLeaveDaysRange(userID, memberID int, startDate, endDate time.Time) ([
 			}
 		} else {
 			// Doesn't exist, insert it
-			result, err := database.DB.Exec(
-				"INSERT INTO leave_days (user_id, member_id, leave_date) VALUES (?, ?, ?)",
-				userID, memberID, dateStr,
+			var leaveRequestID sql.NullInt64
+			if requestID != 0 {
+				leaveRequestID = sql.NullInt64{Int64: int64(requestID), Valid: true}
+			}
+			id, err := conn.insert(
+				"INSERT INTO leave_days (workspace_id, member_id, leave_date, leave_type, leave_request_id) VALUES (?, ?, ?, ?, ?)",
+				workspaceID, memberID, dateStr, leaveType, leaveRequestID,
 			)
 			if err != nil {
 				return nil, err
 			}
 
-			id, err := result.LastInsertId()
-			if err == nil {
-				leaveDays = append(leaveDays, models.LeaveDay{
-					ID:        int(id),
-					MemberID:  memberID,
-					LeaveDate: dateUTC,
-					CreatedAt: time.Now().UTC(),
-				})
-			}
+			leaveDays = append(leaveDays, models.LeaveDay{
+				ID:        id,
+				MemberID:  memberID,
+				LeaveDate: dateUTC,
+				LeaveType: leaveType,
+				CreatedAt: time.Now().UTC(),
+			})
 		}
 
 		// Move to next day
@@ -668,13 +684,14 @@ func CreateLeaveDaysRange(userID, memberID int, startDate, endDate time.Time) ([
 }
 
 // GetLeaveDaysByDateRange gets leave days for members in a date range
-func GetLeaveDaysByDateRange(userID int, startDate, endDate time.Time) ([]models.LeaveDay, error) {
+// Recurring unavailability rules are expanded into leave days for the range
+func (store *SQLStore) GetLeaveDaysByDateRange(workspaceID int, startDate, endDate time.Time) ([]models.LeaveDay, error) {
 	startDateStr := startDate.Format("2006-01-02")
 	endDateStr := endDate.Format("2006-01-02")
 
-	rows, err := database.DB.Query(
-		"SELECT id, member_id, leave_date, created_at FROM leave_days WHERE user_id = ? AND leave_date >= ? AND leave_date <= ? ORDER BY leave_date",
-		userID, startDateStr, endDateStr,
+	rows, err := store.db.Query(
+		"SELECT id, member_id, leave_date, leave_type, created_at FROM leave_days WHERE workspace_id = ? AND leave_date >= ? AND leave_date <= ? ORDER BY leave_date",
+		workspaceID, startDateStr, endDateStr,
 	)
 	if err != nil {
 		return nil, err
@@ -686,7 +703,7 @@ func GetLeaveDaysByDateRange(userID int, startDate, endDate time.Time) ([]models
 		var ld models.LeaveDay
 		var leaveDateStr, createdAtStr string
 
-		if err := rows.Scan(&ld.ID, &ld.MemberID, &leaveDateStr, &createdAtStr); err != nil {
+		if err := rows.Scan(&ld.ID, &ld.MemberID, &leaveDateStr, &ld.LeaveType, &createdAtStr); err != nil {
 			return nil, err
 		}
 
@@ -723,14 +740,23 @@ func GetLeaveDaysByDateRange(userID int, startDate, endDate time.Time) ([]models
 		leaveDays = append(leaveDays, ld)
 	}
 
-	return leaveDays, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rules, err := store.GetUnavailabilityRules(workspaceID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeUnavailability(leaveDays, rules, startDate, endDate), nil
 }
 
 // GetLeaveDaysByMember gets all leave days for a specific member
-func GetLeaveDaysByMember(userID, memberID int) ([]models.LeaveDay, error) {
-	rows, err := database.DB.Query(
-		"SELECT id, member_id, leave_date, created_at FROM leave_days WHERE user_id = ? AND member_id = ? ORDER BY leave_date",
-		userID, memberID,
+func (store *SQLStore) GetLeaveDaysByMember(workspaceID, memberID int) ([]models.LeaveDay, error) {
+	rows, err := store.db.Query(
+		"SELECT id, member_id, leave_date, leave_type, created_at FROM leave_days WHERE workspace_id = ? AND member_id = ? ORDER BY leave_date",
+		workspaceID, memberID,
 	)
 	if err != nil {
 		return nil, err
@@ -742,7 +768,7 @@ func GetLeaveDaysByMember(userID, memberID int) ([]models.LeaveDay, error) {
 		var ld models.LeaveDay
 		var leaveDateStr, createdAtStr string
 
-		if err := rows.Scan(&ld.ID, &ld.MemberID, &leaveDateStr, &createdAtStr); err != nil {
+		if err := rows.Scan(&ld.ID, &ld.MemberID, &leaveDateStr, &ld.LeaveType, &createdAtStr); err != nil {
 			return nil, err
 		}
 
@@ -783,33 +809,68 @@ func GetLeaveDaysByMember(userID, memberID int) ([]models.LeaveDay, error) {
 }
 
 // IsMemberOnLeave checks if a member is on leave on a specific date
-func IsMemberOnLeave(userID, memberID int, date time.Time) (bool, error) {
+// Recurring unavailability rules are taken into account
+func (store *SQLStore) IsMemberOnLeave(workspaceID, memberID int, date time.Time) (bool, error) {
 	dateStr := date.Format("2006-01-02")
 	var count int
-	err := database.DB.QueryRow(
-		"SELECT COUNT(*) FROM leave_days WHERE user_id = ? AND member_id = ? AND leave_date = ?",
-		userID, memberID, dateStr,
+	err := store.db.QueryRow(
+		"SELECT COUNT(*) FROM leave_days WHERE workspace_id = ? AND member_id = ? AND leave_date = ?",
+		workspaceID, memberID, dateStr,
 	).Scan(&count)
 	if err != nil {
 		return false, err
 	}
-	return count > 0, nil
+	if count > 0 {
+		return true, nil
+	}
+
+	rules, err := store.GetUnavailabilityRules(workspaceID, memberID)
+	if err != nil {
+		return false, err
+	}
+	dateUTC := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	for _, rule := range rules {
+		if rule.Matches(dateUTC) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetLeaveDayByID gets a leave day by ID (can only get own leave days)
+func (store *SQLStore) GetLeaveDayByID(workspaceID, leaveDayID int) (*models.LeaveDay, error) {
+	var ld models.LeaveDay
+	var leaveDateStr, createdAtStr string
+	err := store.db.QueryRow(
+		"SELECT id, member_id, leave_date, leave_type, created_at FROM leave_days WHERE id = ? AND workspace_id = ?",
+		leaveDayID, workspaceID,
+	).Scan(&ld.ID, &ld.MemberID, &leaveDateStr, &ld.LeaveType, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if ld.LeaveDate, err = parseDate(leaveDateStr); err != nil {
+		return nil, fmt.Errorf("error parsing leave_date '%s' for leave day ID %d: %v", leaveDateStr, ld.ID, err)
+	}
+	ld.CreatedAt = parseDateTime(createdAtStr)
+
+	return &ld, nil
 }
 
 // DeleteLeaveDay deletes a leave day record
-func DeleteLeaveDay(userID, leaveDayID int) error {
-	_, err := database.DB.Exec(
-		"DELETE FROM leave_days WHERE id = ? AND user_id = ?",
-		leaveDayID, userID,
+func (store *SQLStore) DeleteLeaveDay(workspaceID, leaveDayID int) error {
+	_, err := store.db.Exec(
+		"DELETE FROM leave_days WHERE id = ? AND workspace_id = ?",
+		leaveDayID, workspaceID,
 	)
 	return err
 }
 
 // GetHiddenShiftCounts gets hidden shift counts for a member
-func GetHiddenShiftCounts(userID, memberID int) (normalShifts int, longShifts int, err error) {
-	err = database.DB.QueryRow(
-		"SELECT COALESCE(hidden_normal_shifts, 0), COALESCE(hidden_long_shifts, 0) FROM members WHERE id = ? AND user_id = ?",
-		memberID, userID,
+func (store *SQLStore) GetHiddenShiftCounts(workspaceID, memberID int) (normalShifts int, longShifts int, err error) {
+	err = store.db.QueryRow(
+		"SELECT COALESCE(hidden_normal_shifts, 0), COALESCE(hidden_long_shifts, 0) FROM members WHERE id = ? AND workspace_id = ?",
+		memberID, workspaceID,
 	).Scan(&normalShifts, &longShifts)
 	if err != nil {
 		return 0, 0, err
@@ -818,9 +879,9 @@ func GetHiddenShiftCounts(userID, memberID int) (normalShifts int, longShifts in
 }
 
 // UpdateHiddenShiftCounts updates hidden shift counts for a member
-func UpdateHiddenShiftCounts(userID, memberID int, normalShiftsDelta, longShiftsDelta int) error {
+func (store *SQLStore) UpdateHiddenShiftCounts(workspaceID, memberID int, normalShiftsDelta, longShiftsDelta int) error {
 	// Get current counts
-	currentNormal, currentLong, err := GetHiddenShiftCounts(userID, memberID)
+	currentNormal, currentLong, err := store.GetHiddenShiftCounts(workspaceID, memberID)
 	if err != nil {
 		return err
 	}
@@ -837,32 +898,32 @@ func UpdateHiddenShiftCounts(userID, memberID int, normalShiftsDelta, longShifts
 		newLong = 0
 	}
 
-	_, err = database.DB.Exec(
-		"UPDATE members SET hidden_normal_shifts = ?, hidden_long_shifts = ? WHERE id = ? AND user_id = ?",
-		newNormal, newLong, memberID, userID,
+	_, err = store.db.Exec(
+		"UPDATE members SET hidden_normal_shifts = ?, hidden_long_shifts = ? WHERE id = ? AND workspace_id = ?",
+		newNormal, newLong, memberID, workspaceID,
 	)
 	return err
 }
 
 // GetAllHiddenShiftCounts gets hidden shift counts for all members
-func GetAllHiddenShiftCounts(userID int) (map[int]struct{ NormalShifts, LongShifts int }, error) {
-	rows, err := database.DB.Query(
-		"SELECT id, COALESCE(hidden_normal_shifts, 0), COALESCE(hidden_long_shifts, 0) FROM members WHERE user_id = ?",
-		userID,
+func (store *SQLStore) GetAllHiddenShiftCounts(workspaceID int) (map[int]HiddenShiftCounts, error) {
+	rows, err := store.db.Query(
+		"SELECT id, COALESCE(hidden_normal_shifts, 0), COALESCE(hidden_long_shifts, 0) FROM members WHERE workspace_id = ?",
+		workspaceID,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	counts := make(map[int]struct{ NormalShifts, LongShifts int })
+	counts := make(map[int]HiddenShiftCounts)
 	for rows.Next() {
 		var memberID int
 		var normalShifts, longShifts int
 		if err := rows.Scan(&memberID, &normalShifts, &longShifts); err != nil {
 			return nil, err
 		}
-		counts[memberID] = struct{ NormalShifts, LongShifts int }{
+		counts[memberID] = HiddenShiftCounts{
 			NormalShifts: normalShifts,
 			LongShifts:   longShifts,
 		}
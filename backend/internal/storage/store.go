@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+var (
+	// ErrSessionNotFound is returned when a session token doesn't exist
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrLastOwner is returned when a change would leave a workspace without an owner
+	ErrLastOwner = errors.New("a workspace needs at least one owner")
+	// ErrMemberInviteNotFound is returned when a member invite doesn't exist, was used or expired
+	ErrMemberInviteNotFound = errors.New("member invite not found")
+	// ErrWorkspaceInviteNotFound is returned when a workspace invite doesn't exist, was used or expired
+	ErrWorkspaceInviteNotFound = errors.New("workspace invite not found")
+	// ErrAPIKeyNotFound is returned when an API key doesn't exist or was revoked
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrTwoFactorNotFound is returned when a user never started two-factor enrollment
+	ErrTwoFactorNotFound = errors.New("two-factor authentication not set up")
+	// ErrTOTPCodeUsed is returned when a TOTP code, or a later one, was already used
+	ErrTOTPCodeUsed = errors.New("TOTP code already used")
+	// ErrRecoveryCodeNotFound is returned when a recovery code doesn't exist or was used
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+	// ErrLoginChallengeNotFound is returned when a pre-auth token doesn't exist, was used or expired
+	ErrLoginChallengeNotFound = errors.New("login challenge not found")
+	// ErrPasswordResetNotFound is returned when a password reset token doesn't exist, was used or expired
+	ErrPasswordResetNotFound = errors.New("password reset not found")
+)
+
+// HiddenShiftCounts hidden shift counters of a member
+type HiddenShiftCounts = struct{ NormalShifts, LongShifts int }
+
+// MemberStore stores team members and their hidden shift counters
+// All methods are scoped to the workspace of workspaceID.
+type MemberStore interface {
+	GetAllMembers(workspaceID int) ([]models.Member, error)
+	CreateMember(workspaceID int, name string) (*models.Member, error)
+	DeleteMember(workspaceID, memberID int) error
+	GetMemberByID(workspaceID, memberID int) (*models.Member, error)
+	GetMemberByName(workspaceID int, name string) (*models.Member, error)
+	GetHiddenShiftCounts(workspaceID, memberID int) (normalShifts int, longShifts int, err error)
+	UpdateHiddenShiftCounts(workspaceID, memberID int, normalShiftsDelta, longShiftsDelta int) error
+	GetAllHiddenShiftCounts(workspaceID int) (map[int]HiddenShiftCounts, error)
+
+	// GetMemberByUser gets the member linked to a user, sql.ErrNoRows if there is none
+	GetMemberByUser(workspaceID, userID int) (*models.Member, error)
+	// Member invites are looked up by the hash of their token
+	CreateMemberInvite(workspaceID, memberID int, tokenHash string, expiresAt time.Time) (*models.MemberInvite, error)
+	// AcceptMemberInvite links the user to the invite's member, replacing earlier links
+	// of both, and adds the user to the workspace as a member if they have no role there.
+	// Returns the workspace of the member.
+	AcceptMemberInvite(userID int, tokenHash string, now time.Time) (int, *models.Member, error)
+	UnlinkMember(workspaceID, memberID int) error
+}
+
+// ShiftStore stores shifts and shift imports
+type ShiftStore interface {
+	CreateShift(workspaceID, memberID int, startDate, endDate time.Time, isLongShift bool) (*models.Shift, error)
+	GetShiftsByDateRange(workspaceID int, startDate, endDate time.Time) ([]models.Shift, error)
+	GetShiftByDate(workspaceID int, date time.Time) (*models.Shift, error)
+	GetShiftByID(workspaceID, shiftID int) (*models.Shift, error)
+	UpdateShiftMember(workspaceID, shiftID, newMemberID int) error
+	CreateOrUpdateShiftForDate(workspaceID, memberID int, date time.Time) (*models.Shift, error)
+	DeleteShiftsByDateRange(workspaceID int, startDate, endDate time.Time) error
+	DeleteAllShifts(workspaceID int) error
+	GetMemberShiftStats(workspaceID, memberID int) (totalDays int, longShiftCount int, err error)
+	GetAllMembersStats(workspaceID int) (map[int]models.MemberStats, error)
+	ImportShifts(workspaceID int, filename, contentHash string, rows []models.ShiftImportRow, dryRun bool) (*models.ShiftImportResult, error)
+	GetImportByHash(workspaceID int, kind, contentHash string) (*models.ImportRecord, error)
+
+	CreateSwapRequest(workspaceID, shiftID, withShiftID int, note string) (*models.SwapRequest, error)
+	GetSwapRequests(workspaceID int, status string, memberID int) ([]models.SwapRequest, error)
+	GetSwapRequestByID(workspaceID, requestID int) (*models.SwapRequest, error)
+	ApproveSwapRequest(workspaceID, requestID int) (*models.SwapRequest, error)
+	RejectSwapRequest(workspaceID, requestID int) (*models.SwapRequest, error)
+}
+
+// LeaveStore stores leave days, leave requests, allowances and unavailability rules
+type LeaveStore interface {
+	CreateLeaveDay(workspaceID, memberID int, leaveDate time.Time, leaveType string) (*models.LeaveDay, error)
+	CreateLeaveDaysRange(workspaceID, memberID int, startDate, endDate time.Time, leaveType string) ([]models.LeaveDay, error)
+	GetLeaveDaysByDateRange(workspaceID int, startDate, endDate time.Time) ([]models.LeaveDay, error)
+	GetLeaveDaysByMember(workspaceID, memberID int) ([]models.LeaveDay, error)
+	GetLeaveDayByID(workspaceID, leaveDayID int) (*models.LeaveDay, error)
+	IsMemberOnLeave(workspaceID, memberID int, date time.Time) (bool, error)
+	DeleteLeaveDay(workspaceID, leaveDayID int) error
+
+	CreateLeaveRequest(workspaceID, memberID int, startDate, endDate time.Time, leaveType, note string) (*models.LeaveRequest, error)
+	GetLeaveRequests(workspaceID int, status string, memberID int) ([]models.LeaveRequest, error)
+	GetLeaveRequestByID(workspaceID, requestID int) (*models.LeaveRequest, error)
+	ApproveLeaveRequest(workspaceID, requestID int) (*models.LeaveRequest, error)
+	RejectLeaveRequest(workspaceID, requestID int) (*models.LeaveRequest, error)
+
+	SetLeaveAllowance(workspaceID, memberID, year int, leaveType string, days int) (*models.LeaveAllowance, error)
+	GetLeaveAllowances(workspaceID, year int) ([]models.LeaveAllowance, error)
+	GetLeaveBalances(workspaceID, year, memberID int) ([]models.LeaveBalance, error)
+
+	CreateUnavailabilityRule(workspaceID int, rule models.UnavailabilityRule) (*models.UnavailabilityRule, error)
+	GetUnavailabilityRules(workspaceID, memberID int) ([]models.UnavailabilityRule, error)
+	DeleteUnavailabilityRule(workspaceID, ruleID int) error
+}
+
+// UserStore stores user accounts
+type UserStore interface {
+	// CreateUser creates a user together with a personal workspace they own
+	CreateUser(username, password string) (*models.User, error)
+	GetUserByUsername(username string) (*models.User, string, error)
+	// GetUserByID returns sql.ErrNoRows if the user doesn't exist
+	GetUserByID(userID int) (*models.User, error)
+	UpdatePassword(userID int, password string) error
+
+	// GetUserByIdentity gets the user linked to an OpenID Connect account, sql.ErrNoRows if there is none
+	GetUserByIdentity(issuer, subject string) (*models.User, error)
+	// CreateUserWithIdentity creates a user without a password, linked to an OpenID Connect account,
+	// together with a personal workspace they own
+	CreateUserWithIdentity(username, issuer, subject, email string) (*models.User, error)
+
+	SetUserEmail(userID int, email string) error
+	// DeleteUser deletes a user with all their data, and the workspaces nobody else uses;
+	// ErrLastOwner if they are the only owner of a workspace others use
+	DeleteUser(userID int) error
+
+	// Password resets are looked up by the hash of their token
+	// CreatePasswordReset replaces the user's earlier resets
+	CreatePasswordReset(userID int, tokenHash string, expiresAt time.Time) error
+	// GetPasswordReset returns ErrPasswordResetNotFound if the reset doesn't exist or expired
+	GetPasswordReset(tokenHash string, now time.Time) (*models.PasswordReset, error)
+	// ResetPassword uses up a reset, sets the new password and logs the user out everywhere
+	ResetPassword(resetID int, password string) error
+}
+
+// WorkspaceStore stores workspaces and the roles of their users
+type WorkspaceStore interface {
+	CreateWorkspace(ownerUserID int, name string) (*models.Workspace, error)
+	// GetUserWorkspaces lists the workspaces a user has access to, oldest first, with their role
+	GetUserWorkspaces(userID int) ([]models.Workspace, error)
+	// GetWorkspace returns sql.ErrNoRows if the workspace doesn't exist; Role is left empty
+	GetWorkspace(workspaceID int) (*models.Workspace, error)
+	// GetWorkspaceRole returns sql.ErrNoRows if the user has no access to the workspace
+	GetWorkspaceRole(workspaceID, userID int) (string, error)
+	SetWorkspaceRequireTwoFactor(workspaceID int, required bool) error
+	GetWorkspaceUsers(workspaceID int) ([]models.WorkspaceUser, error)
+	// SetWorkspaceUserRole adds a user to a workspace or changes their role
+	// The role then counts as granted by hand, even if single sign-on granted it before.
+	SetWorkspaceUserRole(workspaceID, userID int, role string) error
+	RemoveWorkspaceUser(workspaceID, userID int) error
+
+	// Roles granted by single sign-on groups are kept apart from roles granted by hand,
+	// which single sign-on never changes.
+	// GetSSOWorkspaceRoles maps the workspaces where single sign-on granted a user's role to that role
+	GetSSOWorkspaceRoles(userID int) (map[int]string, error)
+	// SetSSOWorkspaceRole adds a user to a workspace or changes a role single sign-on granted
+	SetSSOWorkspaceRole(workspaceID, userID int, role string) error
+	// RemoveSSOWorkspaceRole takes away access single sign-on granted
+	RemoveSSOWorkspaceRole(workspaceID, userID int) error
+
+	// Invites are looked up by the hash of their token
+	CreateWorkspaceInvite(workspaceID, invitedBy int, email, role, tokenHash string, expiresAt time.Time) (*models.WorkspaceInvite, error)
+	// GetWorkspaceInvites lists the invites that can still be accepted
+	GetWorkspaceInvites(workspaceID int, now time.Time) ([]models.WorkspaceInvite, error)
+	// GetWorkspaceInvite gets an invite that can still be accepted by the hash of its token
+	GetWorkspaceInvite(tokenHash string, now time.Time) (*models.WorkspaceInvite, error)
+	DeleteWorkspaceInvite(workspaceID, inviteID int) error
+	// AcceptWorkspaceInvite uses an invite up and gives the user its role,
+	// unless they already have a higher one in the workspace
+	AcceptWorkspaceInvite(tokenHash string, userID int, now time.Time) (*models.Workspace, error)
+}
+
+// SessionStore stores login sessions
+// Sessions are looked up by the hash of their token.
+type SessionStore interface {
+	CreateSession(userID int, tokenHash, userAgent, ipAddress string, expiresAt time.Time) (*models.Session, error)
+	GetSession(tokenHash string) (*models.Session, error)
+	// TouchSession records a use of a session and moves its expiry
+	TouchSession(sessionID int, lastSeenAt, expiresAt time.Time) error
+	// GetUserSessions lists the sessions of a user that haven't expired, most recently used first
+	GetUserSessions(userID int, now time.Time) ([]models.Session, error)
+	DeleteSession(tokenHash string) error
+	// DeleteUserSession revokes a session of a user, ErrSessionNotFound if the user has no such session
+	DeleteUserSession(userID, sessionID int) error
+	// DeleteUserSessions revokes all sessions of a user except keepSessionID
+	DeleteUserSessions(userID, keepSessionID int) error
+	// DeleteExpiredSessions deletes expired sessions, login challenges and password resets
+	DeleteExpiredSessions(now time.Time) error
+}
+
+// FeedTokenStore stores calendar feed tokens by the hash of the token
+type FeedTokenStore interface {
+	CreateFeedToken(workspaceID, userID int, name, prefix, tokenHash string) (*models.FeedToken, error)
+	// GetFeedTokens lists the tokens of a user for a workspace
+	GetFeedTokens(workspaceID, userID int) ([]models.FeedToken, error)
+	DeleteFeedToken(workspaceID, userID, tokenID int) error
+	// GetWorkspaceIDByFeedToken resolves a token while its user still has access to the workspace
+	GetWorkspaceIDByFeedToken(tokenHash string) (int, error)
+}
+
+// APIKeyStore stores API keys by the hash of the key
+type APIKeyStore interface {
+	CreateAPIKey(userID, workspaceID int, name, prefix, scope, keyHash string) (*models.APIKey, error)
+	// GetAPIKeys lists the keys of a user for a workspace
+	GetAPIKeys(userID, workspaceID int) ([]models.APIKey, error)
+	DeleteAPIKey(userID, keyID int) error
+	// UseAPIKey resolves a key and records now as its last use, unless that
+	// was recorded less than touchInterval ago
+	UseAPIKey(keyHash string, now time.Time, touchInterval time.Duration) (*models.APIKey, error)
+}
+
+// TwoFactorStore stores TOTP secrets, hashed recovery codes, and the challenges
+// of logins waiting for a second factor
+type TwoFactorStore interface {
+	// GetTwoFactor returns ErrTwoFactorNotFound if the user never started enrollment
+	GetTwoFactor(userID int) (*models.TwoFactor, error)
+	// SetTOTPSecret starts enrollment with a new secret; two-factor stays off until it is enabled
+	SetTOTPSecret(userID int, secret string) error
+	// EnableTwoFactor turns two-factor on and replaces the recovery codes
+	EnableTwoFactor(userID int, recoveryCodeHashes []string, now time.Time) error
+	// DisableTwoFactor removes the secret and recovery codes
+	DisableTwoFactor(userID int) error
+	// UseTOTPStep records the time step of a used code, ErrTOTPCodeUsed if it or a later one was used before
+	UseTOTPStep(userID int, step int64) error
+	ReplaceRecoveryCodes(userID int, recoveryCodeHashes []string) error
+	// UseRecoveryCode uses up a recovery code, ErrRecoveryCodeNotFound if the user has no such code
+	UseRecoveryCode(userID int, codeHash string) error
+
+	// Login challenges are looked up by the hash of their pre-auth token
+	CreateLoginChallenge(userID int, tokenHash string, expiresAt time.Time) error
+	// GetLoginChallenge returns ErrLoginChallengeNotFound if the challenge doesn't exist or expired
+	GetLoginChallenge(tokenHash string, now time.Time) (*models.LoginChallenge, error)
+	// FailLoginChallenge counts a wrong code and returns the failures so far
+	FailLoginChallenge(challengeID int) (int, error)
+	DeleteLoginChallenge(challengeID int) error
+}
+
+// BackupStore exports and restores whole workspaces
+type BackupStore interface {
+	ExportBackup(workspaceID int) (*models.Backup, error)
+	RestoreBackup(workspaceID int, backup *models.Backup, replace bool) (*models.RestoreResult, error)
+}
+
+// AuditStore stores the append-only audit log of changes to a workspace
+type AuditStore interface {
+	AppendAuditEntry(workspaceID int, entry models.AuditEntry) (*models.AuditEntry, error)
+	GetAuditEntries(workspaceID int, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+// SnapshotStore saves the plan before bulk changes so they can be undone
+type SnapshotStore interface {
+	CreatePlanSnapshot(workspaceID int, reason string, startDate, endDate time.Time) (*models.PlanSnapshot, error)
+	GetPlanSnapshots(workspaceID int) ([]models.PlanSnapshot, error)
+	GetPlanSnapshot(workspaceID, snapshotID int) (*models.PlanSnapshot, error)
+	RestorePlanSnapshot(workspaceID int, snapshot *models.PlanSnapshot) (*models.SnapshotRestoreResult, error)
+}
+
+// Store is implemented by complete storage backends
+type Store interface {
+	MemberStore
+	ShiftStore
+	LeaveStore
+	UserStore
+	WorkspaceStore
+	SessionStore
+	FeedTokenStore
+	APIKeyStore
+	TwoFactorStore
+	BackupStore
+	AuditStore
+	SnapshotStore
+}
+
+// SQLStore stores all data in a SQLite or Postgres database
+type SQLStore struct {
+	db *sqlDB
+}
+
+// NewSQLiteStore creates a store on an open SQLite database with the schema in place
+func NewSQLiteStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: &sqlDB{sqlConn: sqlConn{q: db, dialect: sqliteDialect}, db: db}}
+}
+
+// NewPostgresStore creates a store on an open Postgres database with the schema in place
+func NewPostgresStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: &sqlDB{sqlConn: sqlConn{q: db, dialect: postgresDialect}, db: db}}
+}
+
+var (
+	_ Store = (*SQLStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
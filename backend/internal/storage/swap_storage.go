@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+var (
+	// ErrSwapRequestNotPending is returned when deciding on a swap request that was already decided
+	ErrSwapRequestNotPending = errors.New("swap request is not pending")
+	// ErrSwapRequestOutdated is returned when approving a swap whose shifts changed hands since the request
+	ErrSwapRequestOutdated = errors.New("the shifts of the swap request changed hands")
+)
+
+// swapRequestLive matches swap requests whose shifts both still exist
+// Requests go with their shifts; the ones left behind by deleted shifts are never returned.
+const swapRequestLive = "EXISTS (SELECT 1 FROM shifts WHERE shifts.id = swap_requests.shift_id) AND EXISTS (SELECT 1 FROM shifts WHERE shifts.id = swap_requests.with_shift_id)"
+
+// CreateSwapRequest creates a pending request to swap the members of two shifts
+// The current members of both shifts are recorded; sql.ErrNoRows if a shift doesn't exist.
+func (store *SQLStore) CreateSwapRequest(workspaceID, shiftID, withShiftID int, note string) (*models.SwapRequest, error) {
+	sr := &models.SwapRequest{
+		ShiftID:     shiftID,
+		WithShiftID: withShiftID,
+		Status:      models.LeaveStatusPending,
+		Note:        note,
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+	err := store.db.QueryRow("SELECT member_id FROM shifts WHERE id = ? AND workspace_id = ?", shiftID, workspaceID).Scan(&sr.MemberID)
+	if err != nil {
+		return nil, err
+	}
+	err = store.db.QueryRow("SELECT member_id FROM shifts WHERE id = ? AND workspace_id = ?", withShiftID, workspaceID).Scan(&sr.WithMemberID)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := store.db.insert(
+		"INSERT INTO swap_requests (workspace_id, shift_id, member_id, with_shift_id, with_member_id, status, note, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		workspaceID, shiftID, sr.MemberID, withShiftID, sr.WithMemberID, sr.Status, note, sr.CreatedAt.Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	sr.ID = id
+	return sr, nil
+}
+
+// GetSwapRequests gets swap requests of a workspace, oldest first
+// Empty status and zero memberID mean no filtering; memberID matches either side of a swap.
+func (store *SQLStore) GetSwapRequests(workspaceID int, status string, memberID int) ([]models.SwapRequest, error) {
+	query := "SELECT id, shift_id, member_id, with_shift_id, with_member_id, status, note, decided_at, created_at FROM swap_requests WHERE workspace_id = ? AND " + swapRequestLive
+	args := []interface{}{workspaceID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	if memberID != 0 {
+		query += " AND (member_id = ? OR with_member_id = ?)"
+		args = append(args, memberID, memberID)
+	}
+	query += " ORDER BY id"
+
+	var requests []models.SwapRequest
+	err := store.queryRows(query, args, func(rows *sql.Rows) error {
+		sr, err := scanSwapRequest(rows)
+		if err != nil {
+			return err
+		}
+		requests = append(requests, *sr)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// GetSwapRequestByID gets a swap request of a workspace
+func (store *SQLStore) GetSwapRequestByID(workspaceID, requestID int) (*models.SwapRequest, error) {
+	row := store.db.QueryRow(
+		"SELECT id, shift_id, member_id, with_shift_id, with_member_id, status, note, decided_at, created_at FROM swap_requests WHERE id = ? AND workspace_id = ? AND "+swapRequestLive,
+		requestID, workspaceID,
+	)
+	return scanSwapRequest(row)
+}
+
+// ApproveSwapRequest approves a pending swap request and swaps the members of its shifts
+// The decision, the shifts and the hidden shift counters are updated in one
+// transaction. If either shift changed hands since the request,
+// ErrSwapRequestOutdated is returned and nothing changes.
+func (store *SQLStore) ApproveSwapRequest(workspaceID, requestID int) (*models.SwapRequest, error) {
+	sr, err := store.GetSwapRequestByID(workspaceID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if sr.Status != models.LeaveStatusPending {
+		return nil, ErrSwapRequestNotPending
+	}
+
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	decided, err := decideSwapRequest(tx.sqlConn, workspaceID, sr, models.LeaveStatusApproved)
+	if err != nil {
+		return nil, err
+	}
+	shift, err := swapShift(tx, workspaceID, sr.ShiftID)
+	if err != nil {
+		return nil, err
+	}
+	withShift, err := swapShift(tx, workspaceID, sr.WithShiftID)
+	if err != nil {
+		return nil, err
+	}
+	if shift.MemberID != sr.MemberID || withShift.MemberID != sr.WithMemberID {
+		return nil, ErrSwapRequestOutdated
+	}
+
+	for _, change := range []struct {
+		shift    *models.Shift
+		memberID int
+	}{{shift, sr.WithMemberID}, {withShift, sr.MemberID}} {
+		if _, err := tx.Exec(
+			"UPDATE shifts SET member_id = ?, sequence = sequence + 1 WHERE id = ? AND workspace_id = ?",
+			change.memberID, change.shift.ID, workspaceID,
+		); err != nil {
+			return nil, err
+		}
+		normalDays, longDays := shiftDayCounts(change.shift)
+		if err := adjustHiddenShiftCounts(tx, workspaceID, change.shift.MemberID, -normalDays, -longDays); err != nil {
+			return nil, err
+		}
+		if err := adjustHiddenShiftCounts(tx, workspaceID, change.memberID, normalDays, longDays); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return decided, nil
+}
+
+// RejectSwapRequest rejects a pending swap request
+func (store *SQLStore) RejectSwapRequest(workspaceID, requestID int) (*models.SwapRequest, error) {
+	sr, err := store.GetSwapRequestByID(workspaceID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if sr.Status != models.LeaveStatusPending {
+		return nil, ErrSwapRequestNotPending
+	}
+
+	return decideSwapRequest(store.db.sqlConn, workspaceID, sr, models.LeaveStatusRejected)
+}
+
+// decideSwapRequest stores the decision for a swap request
+// ErrSwapRequestNotPending is returned if the request was decided in the meantime
+func decideSwapRequest(conn sqlConn, workspaceID int, sr *models.SwapRequest, status string) (*models.SwapRequest, error) {
+	decidedAt := time.Now().UTC().Truncate(time.Second)
+	result, err := conn.Exec(
+		"UPDATE swap_requests SET status = ?, decided_at = ? WHERE id = ? AND workspace_id = ? AND status = ?",
+		status, decidedAt.Format("2006-01-02 15:04:05"), sr.ID, workspaceID, models.LeaveStatusPending,
+	)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, ErrSwapRequestNotPending
+	}
+
+	sr.Status = status
+	sr.DecidedAt = &decidedAt
+	return sr, nil
+}
+
+// swapShift reads a shift of a swap request within a transaction
+func swapShift(tx *sqlTx, workspaceID, shiftID int) (*models.Shift, error) {
+	var s models.Shift
+	var startDateStr, endDateStr string
+	err := tx.QueryRow(
+		"SELECT id, member_id, start_date, end_date, is_long_shift FROM shifts WHERE id = ? AND workspace_id = ?",
+		shiftID, workspaceID,
+	).Scan(&s.ID, &s.MemberID, &startDateStr, &endDateStr, &s.IsLongShift)
+	if err != nil {
+		return nil, err
+	}
+	if s.StartDate, s.EndDate, err = parseDateRange(startDateStr, endDateStr); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// scanSwapRequest scans a swap request row
+func scanSwapRequest(row rowScanner) (*models.SwapRequest, error) {
+	var sr models.SwapRequest
+	var decidedAtStr sql.NullString
+	var createdAtStr string
+	if err := row.Scan(&sr.ID, &sr.ShiftID, &sr.MemberID, &sr.WithShiftID, &sr.WithMemberID, &sr.Status, &sr.Note, &decidedAtStr, &createdAtStr); err != nil {
+		return nil, err
+	}
+	if decidedAtStr.Valid && decidedAtStr.String != "" {
+		decidedAt := parseDateTime(decidedAtStr.String)
+		sr.DecidedAt = &decidedAt
+	}
+	sr.CreatedAt = parseDateTime(createdAtStr)
+	return &sr, nil
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestApproveSwapRequest(t *testing.T) {
+	forEachStoreWithWorkspace(t, func(t *testing.T, store Store, workspaceID int) {
+		alice, _ := store.CreateMember(workspaceID, "Alice")
+		bob, _ := store.CreateMember(workspaceID, "Bob")
+		day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+		aliceShift, _ := store.CreateShift(workspaceID, alice.ID, day, day.AddDate(0, 0, 2), true)
+		bobShift, _ := store.CreateShift(workspaceID, bob.ID, day.AddDate(0, 0, 3), day.AddDate(0, 0, 3), false)
+
+		sr, err := store.CreateSwapRequest(workspaceID, aliceShift.ID, bobShift.ID, "Dentist")
+		if err != nil {
+			t.Fatalf("Failed to create swap request: %v", err)
+		}
+		if sr.Status != models.LeaveStatusPending || sr.MemberID != alice.ID || sr.WithMemberID != bob.ID {
+			t.Errorf("Unexpected swap request: %+v", sr)
+		}
+
+		// Pending requests leave the shifts alone
+		if shift, _ := store.GetShiftByID(workspaceID, aliceShift.ID); shift.MemberID != alice.ID {
+			t.Errorf("Pending request reassigned the shift to member %d", shift.MemberID)
+		}
+
+		approved, err := store.ApproveSwapRequest(workspaceID, sr.ID)
+		if err != nil {
+			t.Fatalf("Failed to approve swap request: %v", err)
+		}
+		if approved.Status != models.LeaveStatusApproved || approved.DecidedAt == nil {
+			t.Errorf("Unexpected approved request: %+v", approved)
+		}
+
+		if shift, _ := store.GetShiftByID(workspaceID, aliceShift.ID); shift.MemberID != bob.ID {
+			t.Errorf("Alice's shift member mismatch: got %d, want %d", shift.MemberID, bob.ID)
+		}
+		if shift, _ := store.GetShiftByID(workspaceID, bobShift.ID); shift.MemberID != alice.ID {
+			t.Errorf("Bob's shift member mismatch: got %d, want %d", shift.MemberID, alice.ID)
+		}
+		if _, longShifts, _ := store.GetMemberShiftStats(workspaceID, bob.ID); longShifts != 1 {
+			t.Errorf("Bob's long shift count mismatch: got %d, want 1", longShifts)
+		}
+
+		// Both sides of the swap see the request
+		for _, memberID := range []int{alice.ID, bob.ID} {
+			if requests, _ := store.GetSwapRequests(workspaceID, models.LeaveStatusApproved, memberID); len(requests) != 1 {
+				t.Errorf("Expected 1 approved request for member %d, got %d", memberID, len(requests))
+			}
+		}
+
+		// Already decided requests can't be decided again
+		if _, err := store.RejectSwapRequest(workspaceID, sr.ID); err != ErrSwapRequestNotPending {
+			t.Errorf("Expected ErrSwapRequestNotPending, got %v", err)
+		}
+	})
+}
+
+func TestApproveSwapRequest_Outdated(t *testing.T) {
+	forEachStoreWithWorkspace(t, func(t *testing.T, store Store, workspaceID int) {
+		alice, _ := store.CreateMember(workspaceID, "Alice")
+		bob, _ := store.CreateMember(workspaceID, "Bob")
+		carol, _ := store.CreateMember(workspaceID, "Carol")
+		day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+		aliceShift, _ := store.CreateShift(workspaceID, alice.ID, day, day, false)
+		bobShift, _ := store.CreateShift(workspaceID, bob.ID, day.AddDate(0, 0, 1), day.AddDate(0, 0, 1), false)
+
+		sr, _ := store.CreateSwapRequest(workspaceID, aliceShift.ID, bobShift.ID, "")
+
+		// A scheduler gave Bob's shift to Carol in the meantime
+		store.UpdateShiftMember(workspaceID, bobShift.ID, carol.ID)
+
+		if _, err := store.ApproveSwapRequest(workspaceID, sr.ID); err != ErrSwapRequestOutdated {
+			t.Fatalf("Expected ErrSwapRequestOutdated, got %v", err)
+		}
+		if shift, _ := store.GetShiftByID(workspaceID, aliceShift.ID); shift.MemberID != alice.ID {
+			t.Errorf("Outdated swap reassigned Alice's shift to member %d", shift.MemberID)
+		}
+		if sr, _ := store.GetSwapRequestByID(workspaceID, sr.ID); sr.Status != models.LeaveStatusPending {
+			t.Errorf("Status mismatch: got %s, want %s", sr.Status, models.LeaveStatusPending)
+		}
+	})
+}
+
+func TestSwapRequestGoesWithShift(t *testing.T) {
+	forEachStoreWithWorkspace(t, func(t *testing.T, store Store, workspaceID int) {
+		alice, _ := store.CreateMember(workspaceID, "Alice")
+		bob, _ := store.CreateMember(workspaceID, "Bob")
+		day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+		aliceShift, _ := store.CreateShift(workspaceID, alice.ID, day, day, false)
+		bobShift, _ := store.CreateShift(workspaceID, bob.ID, day.AddDate(0, 0, 1), day.AddDate(0, 0, 1), false)
+
+		sr, _ := store.CreateSwapRequest(workspaceID, aliceShift.ID, bobShift.ID, "")
+		if err := store.DeleteShiftsByDateRange(workspaceID, day, day); err != nil {
+			t.Fatalf("Failed to delete shift: %v", err)
+		}
+
+		if _, err := store.GetSwapRequestByID(workspaceID, sr.ID); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows, got %v", err)
+		}
+		if requests, _ := store.GetSwapRequests(workspaceID, "", 0); len(requests) != 0 {
+			t.Errorf("Expected no swap requests, got %+v", requests)
+		}
+	})
+}
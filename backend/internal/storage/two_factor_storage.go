@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+// GetTwoFactor gets the TOTP settings of a user with the number of unused recovery codes
+func (store *SQLStore) GetTwoFactor(userID int) (*models.TwoFactor, error) {
+	twoFactor := models.TwoFactor{UserID: userID}
+	var enabledAt sql.NullString
+	err := store.db.QueryRow(`
+		SELECT secret, last_step, enabled_at, (SELECT COUNT(*) FROM recovery_codes WHERE user_id = t.user_id)
+		FROM user_two_factor t
+		WHERE t.user_id = ?
+	`, userID).Scan(&twoFactor.Secret, &twoFactor.LastStep, &enabledAt, &twoFactor.RecoveryCodes)
+	if err == sql.ErrNoRows {
+		return nil, ErrTwoFactorNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if enabledAt.Valid {
+		t := parseDateTime(enabledAt.String)
+		twoFactor.EnabledAt = &t
+	}
+	return &twoFactor, nil
+}
+
+// SetTOTPSecret starts enrollment with a new secret, turning two-factor off until it is enabled again
+func (store *SQLStore) SetTOTPSecret(userID int, secret string) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO user_two_factor (user_id, secret, last_step, enabled_at, created_at) VALUES (?, ?, 0, NULL, ?)
+		ON CONFLICT(user_id) DO UPDATE SET secret = excluded.secret, last_step = 0, enabled_at = NULL, created_at = excluded.created_at
+	`, userID, secret, time.Now().UTC().Format("2006-01-02 15:04:05")); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// EnableTwoFactor turns two-factor on and replaces the recovery codes
+func (store *SQLStore) EnableTwoFactor(userID int, recoveryCodeHashes []string, now time.Time) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE user_two_factor SET enabled_at = ? WHERE user_id = ?",
+		now.UTC().Format("2006-01-02 15:04:05"), userID,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrTwoFactorNotFound
+	}
+	if err := replaceRecoveryCodes(tx.sqlConn, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DisableTwoFactor removes the secret and recovery codes of a user
+func (store *SQLStore) DisableTwoFactor(userID int) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM user_two_factor WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep records the time step of a used code, so the code can't be replayed
+func (store *SQLStore) UseTOTPStep(userID int, step int64) error {
+	result, err := store.db.Exec(
+		"UPDATE user_two_factor SET last_step = ? WHERE user_id = ? AND last_step < ?",
+		step, userID, step,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrTOTPCodeUsed
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes replaces all recovery codes of a user
+func (store *SQLStore) ReplaceRecoveryCodes(userID int, recoveryCodeHashes []string) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx.sqlConn, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// replaceRecoveryCodes deletes the recovery codes of a user and stores new ones
+func replaceRecoveryCodes(conn sqlConn, userID int, recoveryCodeHashes []string) error {
+	if _, err := conn.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	createdAt := time.Now().UTC().Format("2006-01-02 15:04:05")
+	for _, codeHash := range recoveryCodeHashes {
+		if _, err := conn.Exec(
+			"INSERT INTO recovery_codes (user_id, code_hash, created_at) VALUES (?, ?, ?)",
+			userID, codeHash, createdAt,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode uses up a recovery code of a user
+func (store *SQLStore) UseRecoveryCode(userID int, codeHash string) error {
+	result, err := store.db.Exec("DELETE FROM recovery_codes WHERE user_id = ? AND code_hash = ?", userID, codeHash)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
+
+// CreateLoginChallenge stores the challenge of a login waiting for a second factor
+func (store *SQLStore) CreateLoginChallenge(userID int, tokenHash string, expiresAt time.Time) error {
+	_, err := store.db.Exec(
+		"INSERT INTO login_challenges (user_id, token_hash, failures, expires_at, created_at) VALUES (?, ?, 0, ?, ?)",
+		userID, tokenHash, expiresAt.UTC().Format("2006-01-02 15:04:05"), time.Now().UTC().Format("2006-01-02 15:04:05"),
+	)
+	return err
+}
+
+// GetLoginChallenge gets an unexpired login challenge by the hash of its token
+func (store *SQLStore) GetLoginChallenge(tokenHash string, now time.Time) (*models.LoginChallenge, error) {
+	var challenge models.LoginChallenge
+	var expiresAtStr string
+	err := store.db.QueryRow(
+		"SELECT id, user_id, failures, expires_at FROM login_challenges WHERE token_hash = ? AND expires_at > ?",
+		tokenHash, now.UTC().Format("2006-01-02 15:04:05"),
+	).Scan(&challenge.ID, &challenge.UserID, &challenge.Failures, &expiresAtStr)
+	if err == sql.ErrNoRows {
+		return nil, ErrLoginChallengeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	challenge.ExpiresAt = parseDateTime(expiresAtStr)
+	return &challenge, nil
+}
+
+// FailLoginChallenge counts a wrong code for a login challenge
+func (store *SQLStore) FailLoginChallenge(challengeID int) (int, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE login_challenges SET failures = failures + 1 WHERE id = ?", challengeID); err != nil {
+		return 0, err
+	}
+	var failures int
+	err = tx.QueryRow("SELECT failures FROM login_challenges WHERE id = ?", challengeID).Scan(&failures)
+	if err == sql.ErrNoRows {
+		return 0, ErrLoginChallengeNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return failures, nil
+}
+
+// DeleteLoginChallenge deletes a login challenge once it is used up
+func (store *SQLStore) DeleteLoginChallenge(challengeID int) error {
+	_, err := store.db.Exec("DELETE FROM login_challenges WHERE id = ?", challengeID)
+	return err
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTwoFactor(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, userID int) {
+		if _, err := store.GetTwoFactor(userID); err != ErrTwoFactorNotFound {
+			t.Errorf("Expected ErrTwoFactorNotFound before enrollment, got %v", err)
+		}
+		if err := store.EnableTwoFactor(userID, nil, time.Now()); err != ErrTwoFactorNotFound {
+			t.Errorf("Expected ErrTwoFactorNotFound enabling without a secret, got %v", err)
+		}
+
+		if err := store.SetTOTPSecret(userID, "SECRET"); err != nil {
+			t.Fatalf("Failed to set secret: %v", err)
+		}
+		twoFactor, err := store.GetTwoFactor(userID)
+		if err != nil {
+			t.Fatalf("Failed to get two-factor: %v", err)
+		}
+		if twoFactor.Secret != "SECRET" || twoFactor.Enabled() {
+			t.Errorf("Expected a pending secret, got %+v", twoFactor)
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+		if err := store.EnableTwoFactor(userID, []string{"hash1", "hash2"}, now); err != nil {
+			t.Fatalf("Failed to enable two-factor: %v", err)
+		}
+		twoFactor, _ = store.GetTwoFactor(userID)
+		if !twoFactor.Enabled() || !twoFactor.EnabledAt.Equal(now) || twoFactor.RecoveryCodes != 2 {
+			t.Errorf("Expected two-factor enabled with 2 recovery codes, got %+v", twoFactor)
+		}
+
+		// Steps only move forward
+		if err := store.UseTOTPStep(userID, 100); err != nil {
+			t.Fatalf("Failed to use step: %v", err)
+		}
+		if err := store.UseTOTPStep(userID, 100); err != ErrTOTPCodeUsed {
+			t.Errorf("Expected ErrTOTPCodeUsed for the same step, got %v", err)
+		}
+		if err := store.UseTOTPStep(userID, 99); err != ErrTOTPCodeUsed {
+			t.Errorf("Expected ErrTOTPCodeUsed for an earlier step, got %v", err)
+		}
+
+		if err := store.UseRecoveryCode(userID, "hash1"); err != nil {
+			t.Fatalf("Failed to use recovery code: %v", err)
+		}
+		if err := store.UseRecoveryCode(userID, "hash1"); err != ErrRecoveryCodeNotFound {
+			t.Errorf("Expected ErrRecoveryCodeNotFound for a used code, got %v", err)
+		}
+		if err := store.ReplaceRecoveryCodes(userID, []string{"hash3"}); err != nil {
+			t.Fatalf("Failed to replace recovery codes: %v", err)
+		}
+		if err := store.UseRecoveryCode(userID, "hash2"); err != ErrRecoveryCodeNotFound {
+			t.Errorf("Replaced codes should be gone, got %v", err)
+		}
+
+		if err := store.DisableTwoFactor(userID); err != nil {
+			t.Fatalf("Failed to disable two-factor: %v", err)
+		}
+		if _, err := store.GetTwoFactor(userID); err != ErrTwoFactorNotFound {
+			t.Errorf("Expected ErrTwoFactorNotFound after disabling, got %v", err)
+		}
+		if err := store.UseRecoveryCode(userID, "hash3"); err != ErrRecoveryCodeNotFound {
+			t.Errorf("Recovery codes should be deleted with two-factor, got %v", err)
+		}
+	})
+}
+
+func TestLoginChallenges(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, userID int) {
+		now := time.Now().UTC().Truncate(time.Second)
+		if err := store.CreateLoginChallenge(userID, "active", now.Add(5*time.Minute)); err != nil {
+			t.Fatalf("Failed to create challenge: %v", err)
+		}
+		store.CreateLoginChallenge(userID, "expired", now.Add(-time.Minute))
+
+		challenge, err := store.GetLoginChallenge("active", now)
+		if err != nil {
+			t.Fatalf("Failed to get challenge: %v", err)
+		}
+		if challenge.UserID != userID || challenge.Failures != 0 || !challenge.ExpiresAt.Equal(now.Add(5*time.Minute)) {
+			t.Errorf("Challenge mismatch: %+v", challenge)
+		}
+		if _, err := store.GetLoginChallenge("expired", now); err != ErrLoginChallengeNotFound {
+			t.Errorf("Expected ErrLoginChallengeNotFound for an expired challenge, got %v", err)
+		}
+
+		store.FailLoginChallenge(challenge.ID)
+		if failures, err := store.FailLoginChallenge(challenge.ID); err != nil || failures != 2 {
+			t.Errorf("Expected 2 failures, got %d, %v", failures, err)
+		}
+
+		if err := store.DeleteExpiredSessions(now); err != nil {
+			t.Fatalf("Failed to delete expired sessions: %v", err)
+		}
+		if _, err := store.GetLoginChallenge("expired", now.Add(-time.Hour)); err != ErrLoginChallengeNotFound {
+			t.Errorf("Expired challenges should be deleted with expired sessions, got %v", err)
+		}
+
+		if err := store.DeleteLoginChallenge(challenge.ID); err != nil {
+			t.Fatalf("Failed to delete challenge: %v", err)
+		}
+		if _, err := store.GetLoginChallenge("active", now); err != ErrLoginChallengeNotFound {
+			t.Errorf("Expected ErrLoginChallengeNotFound after deleting, got %v", err)
+		}
+	})
+}
+
+func TestWorkspaceRequireTwoFactor(t *testing.T) {
+	forEachStoreWithWorkspace(t, func(t *testing.T, store Store, workspaceID int) {
+		if err := store.SetWorkspaceRequireTwoFactor(workspaceID, true); err != nil {
+			t.Fatalf("Failed to require two-factor: %v", err)
+		}
+		workspace, err := store.GetWorkspace(workspaceID)
+		if err != nil {
+			t.Fatalf("Failed to get workspace: %v", err)
+		}
+		if !workspace.RequireTwoFactor {
+			t.Error("Workspace should require two-factor")
+		}
+
+		user, _, _ := store.GetUserByUsername("testuser")
+		workspaces, _ := store.GetUserWorkspaces(user.ID)
+		if len(workspaces) != 1 || !workspaces[0].RequireTwoFactor {
+			t.Errorf("Listed workspaces should show the setting, got %+v", workspaces)
+		}
+		if byID, err := store.GetUserByID(user.ID); err != nil || byID.Username != "testuser" {
+			t.Errorf("Failed to get user by ID: %+v, %v", byID, err)
+		}
+
+		if err := store.SetWorkspaceRequireTwoFactor(workspaceID+100, true); err == nil {
+			t.Error("Expected an error for a missing workspace")
+		}
+	})
+}
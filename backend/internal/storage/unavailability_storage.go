@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"shiftplanner/backend/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CreateUnavailabilityRule creates a recurring unavailability rule for a member
+func (store *SQLStore) CreateUnavailabilityRule(workspaceID int, rule models.UnavailabilityRule) (*models.UnavailabilityRule, error) {
+	rule, err := normalizeUnavailabilityRule(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	var endDate sql.NullString
+	if rule.EndDate != nil {
+		endDate = sql.NullString{String: rule.EndDate.Format("2006-01-02"), Valid: true}
+	}
+
+	id, err := store.db.insert(
+		"INSERT INTO unavailability_rules (workspace_id, member_id, frequency, weekdays, nth, start_date, end_date, note) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		workspaceID, rule.MemberID, rule.Frequency, formatWeekdays(rule.Weekdays), rule.Nth, rule.StartDate.Format("2006-01-02"), endDate, rule.Note,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.ID = id
+	rule.CreatedAt = time.Now().UTC()
+	return &rule, nil
+}
+
+// GetUnavailabilityRules gets unavailability rules of a workspace
+// If memberID is not 0, only that member's rules are returned
+func (store *SQLStore) GetUnavailabilityRules(workspaceID, memberID int) ([]models.UnavailabilityRule, error) {
+	query := "SELECT id, member_id, frequency, weekdays, nth, start_date, end_date, note, created_at FROM unavailability_rules WHERE workspace_id = ?"
+	args := []interface{}{workspaceID}
+	if memberID != 0 {
+		query += " AND member_id = ?"
+		args = append(args, memberID)
+	}
+	query += " ORDER BY member_id, id"
+
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.UnavailabilityRule
+	for rows.Next() {
+		var r models.UnavailabilityRule
+		var weekdaysStr, startDateStr, createdAtStr string
+		var endDateStr sql.NullString
+
+		if err := rows.Scan(&r.ID, &r.MemberID, &r.Frequency, &weekdaysStr, &r.Nth, &startDateStr, &endDateStr, &r.Note, &createdAtStr); err != nil {
+			return nil, err
+		}
+
+		if r.Weekdays, err = parseWeekdays(weekdaysStr); err != nil {
+			return nil, fmt.Errorf("error parsing weekdays '%s' for rule ID %d: %v", weekdaysStr, r.ID, err)
+		}
+		if r.StartDate, err = parseDate(startDateStr); err != nil {
+			return nil, fmt.Errorf("error parsing start_date '%s' for rule ID %d: %v", startDateStr, r.ID, err)
+		}
+		if endDateStr.Valid && endDateStr.String != "" {
+			endDate, err := parseDate(endDateStr.String)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing end_date '%s' for rule ID %d: %v", endDateStr.String, r.ID, err)
+			}
+			r.EndDate = &endDate
+		}
+		r.CreatedAt = parseDateTime(createdAtStr)
+
+		rules = append(rules, r)
+	}
+
+	return rules, rows.Err()
+}
+
+// DeleteUnavailabilityRule deletes an unavailability rule
+func (store *SQLStore) DeleteUnavailabilityRule(workspaceID, ruleID int) error {
+	_, err := store.db.Exec(
+		"DELETE FROM unavailability_rules WHERE id = ? AND workspace_id = ?",
+		ruleID, workspaceID,
+	)
+	return err
+}
+
+// normalizeUnavailabilityRule validates a rule and normalizes its dates to UTC midnight
+func normalizeUnavailabilityRule(rule models.UnavailabilityRule) (models.UnavailabilityRule, error) {
+	if !models.IsValidFrequency(rule.Frequency) {
+		return rule, fmt.Errorf("invalid frequency '%s'", rule.Frequency)
+	}
+
+	if len(rule.Weekdays) == 0 {
+		return rule, fmt.Errorf("at least one weekday is required")
+	}
+
+	for _, wd := range rule.Weekdays {
+		if wd < time.Sunday || wd > time.Saturday {
+			return rule, fmt.Errorf("invalid weekday %d", wd)
+		}
+	}
+
+	if rule.Frequency == models.FrequencyMonthly && (rule.Nth == 0 || rule.Nth < -1 || rule.Nth > 5) {
+		return rule, fmt.Errorf("nth must be between 1 and 5, or -1 for the last occurrence")
+	}
+
+	if rule.StartDate.IsZero() {
+		return rule, fmt.Errorf("start_date cannot be zero")
+	}
+
+	rule.StartDate = time.Date(rule.StartDate.Year(), rule.StartDate.Month(), rule.StartDate.Day(), 0, 0, 0, 0, time.UTC)
+	if rule.EndDate != nil {
+		endDateUTC := time.Date(rule.EndDate.Year(), rule.EndDate.Month(), rule.EndDate.Day(), 0, 0, 0, 0, time.UTC)
+		if endDateUTC.Before(rule.StartDate) {
+			return rule, fmt.Errorf("end_date must be after or equal to start_date")
+		}
+		rule.EndDate = &endDateUTC
+	}
+
+	if rule.Frequency != models.FrequencyMonthly {
+		rule.Nth = 0
+	}
+
+	return rule, nil
+}
+
+// mergeUnavailability adds leave days expanded from unavailability rules to leaveDays
+// Dates already covered by a stored leave day for the same member are skipped
+func mergeUnavailability(leaveDays []models.LeaveDay, rules []models.UnavailabilityRule, startDate, endDate time.Time) []models.LeaveDay {
+	if len(rules) == 0 {
+		return leaveDays
+	}
+
+	// Key: memberID:date
+	seen := make(map[string]bool)
+	for _, ld := range leaveDays {
+		seen[strconv.Itoa(ld.MemberID)+":"+ld.LeaveDate.Format("2006-01-02")] = true
+	}
+
+	for _, rule := range rules {
+		for _, date := range rule.Occurrences(startDate, endDate) {
+			key := strconv.Itoa(rule.MemberID) + ":" + date.Format("2006-01-02")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			leaveDays = append(leaveDays, models.LeaveDay{
+				MemberID:  rule.MemberID,
+				LeaveDate: date,
+				LeaveType: models.LeaveTypeUnavailable,
+				RuleID:    rule.ID,
+				CreatedAt: rule.CreatedAt,
+			})
+		}
+	}
+
+	sort.SliceStable(leaveDays, func(i, j int) bool {
+		return leaveDays[i].LeaveDate.Before(leaveDays[j].LeaveDate)
+	})
+
+	return leaveDays
+}
+
+// formatWeekdays formats weekdays as a comma separated list (0 = Sunday)
+func formatWeekdays(weekdays []time.Weekday) string {
+	parts := make([]string, len(weekdays))
+	for i, wd := range weekdays {
+		parts[i] = strconv.Itoa(int(wd))
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseWeekdays parses a comma separated list of weekdays
+func parseWeekdays(s string) ([]time.Weekday, error) {
+	var weekdays []time.Weekday
+	for _, part := range strings.Split(s, ",") {
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || n > 6 {
+			return nil, fmt.Errorf("invalid weekday '%s'", part)
+		}
+		weekdays = append(weekdays, time.Weekday(n))
+	}
+	return weekdays, nil
+}
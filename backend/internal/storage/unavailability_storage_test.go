@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"shiftplanner/backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestGetLeaveDaysByDateRange_ExpandsRules(t *testing.T) {
+	forEachStoreWithWorkspace(t, func(t *testing.T, store Store, workspaceID int) {
+		member, _ := store.CreateMember(workspaceID, "Test Member")
+
+		_, err := store.CreateUnavailabilityRule(workspaceID, models.UnavailabilityRule{
+			MemberID:  member.ID,
+			Frequency: models.FrequencyBiweekly,
+			Weekdays:  []time.Weekday{time.Tuesday},
+			StartDate: time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create rule: %v", err)
+		}
+
+		// A stored leave day on a rule date must not be duplicated
+		store.CreateLeaveDay(workspaceID, member.ID, time.Date(2025, 1, 21, 0, 0, 0, 0, time.UTC), models.LeaveTypeAnnual)
+
+		leaveDays, err := store.GetLeaveDaysByDateRange(workspaceID, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("Failed to get leave days: %v", err)
+		}
+
+		if len(leaveDays) != 2 {
+			t.Fatalf("Leave day count mismatch: got %d, want 2", len(leaveDays))
+		}
+
+		if leaveDays[0].RuleID == 0 || leaveDays[0].LeaveType != models.LeaveTypeUnavailable {
+			t.Errorf("First leave day should come from the rule: %+v", leaveDays[0])
+		}
+
+		if leaveDays[1].RuleID != 0 || leaveDays[1].LeaveType != models.LeaveTypeAnnual {
+			t.Errorf("Second leave day should be the stored one: %+v", leaveDays[1])
+		}
+
+		onLeave, _ := store.IsMemberOnLeave(workspaceID, member.ID, time.Date(2025, 2, 4, 0, 0, 0, 0, time.UTC))
+		if !onLeave {
+			t.Error("Member should be unavailable on 2025-02-04")
+		}
+
+		onLeave, _ = store.IsMemberOnLeave(workspaceID, member.ID, time.Date(2025, 1, 14, 0, 0, 0, 0, time.UTC))
+		if onLeave {
+			t.Error("Member should be available on 2025-01-14")
+		}
+	})
+}
+
+func TestCreateUnavailabilityRule_InvalidNth(t *testing.T) {
+	forEachStoreWithWorkspace(t, func(t *testing.T, store Store, workspaceID int) {
+		member, _ := store.CreateMember(workspaceID, "Test Member")
+
+		_, err := store.CreateUnavailabilityRule(workspaceID, models.UnavailabilityRule{
+			MemberID:  member.ID,
+			Frequency: models.FrequencyMonthly,
+			Weekdays:  []time.Weekday{time.Monday},
+			StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+		if err == nil {
+			t.Error("Monthly rule without nth should fail")
+		}
+	})
+}
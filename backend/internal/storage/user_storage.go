@@ -1,19 +1,25 @@
 package storage
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
-	"shiftplanner/backend/internal/database"
+	"database/sql"
 	"shiftplanner/backend/internal/models"
 	"time"
 )
 
-// CreateUser creates a new user
-func CreateUser(username, password string) (*models.User, error) {
-	// Hash password
-	passwordHash := hashPassword(password)
+// CreateUser creates a new user with a personal workspace they own
+func (store *SQLStore) CreateUser(username, password string) (*models.User, error) {
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
-	result, err := database.DB.Exec(
+	id, err := tx.insert(
 		"INSERT INTO users (username, password_hash) VALUES (?, ?)",
 		username, passwordHash,
 	)
@@ -21,28 +27,31 @@ func CreateUser(username, password string) (*models.User, error) {
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
+	if _, err := createWorkspace(tx.sqlConn, id, username); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
 	return &models.User{
-		ID:        int(id),
+		ID:        id,
 		Username:  username,
 		CreatedAt: time.Now(),
 	}, nil
 }
 
 // GetUserByUsername gets a user by username
-func GetUserByUsername(username string) (*models.User, string, error) {
+func (store *SQLStore) GetUserByUsername(username string) (*models.User, string, error) {
 	var user models.User
 	var passwordHash string
 	var createdAtStr string
 
-	err := database.DB.QueryRow(
-		"SELECT id, username, password_hash, created_at FROM users WHERE username = ?",
+	err := store.db.QueryRow(
+		"SELECT id, username, email, password_hash, created_at FROM users WHERE username = ?",
 		username,
-	).Scan(&user.ID, &user.Username, &passwordHash, &createdAtStr)
+	).Scan(&user.ID, &user.Username, &user.Email, &passwordHash, &createdAtStr)
 
 	if err != nil {
 		return nil, "", err
@@ -52,14 +61,92 @@ func GetUserByUsername(username string) (*models.User, string, error) {
 	return &user, passwordHash, nil
 }
 
-// ValidatePassword validates a password
-func ValidatePassword(password, passwordHash string) bool {
-	hashed := hashPassword(password)
-	return hashed == passwordHash
+// GetUserByID gets a user by ID
+func (store *SQLStore) GetUserByID(userID int) (*models.User, error) {
+	var user models.User
+	var createdAtStr string
+	err := store.db.QueryRow(
+		"SELECT id, username, email, created_at FROM users WHERE id = ?",
+		userID,
+	).Scan(&user.ID, &user.Username, &user.Email, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	user.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	return &user, nil
+}
+
+// UpdatePassword replaces a user's password hash with a new hash of password
+func (store *SQLStore) UpdatePassword(userID int, password string) error {
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	result, err := store.db.Exec("UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetUserByIdentity gets the user linked to an OpenID Connect account
+func (store *SQLStore) GetUserByIdentity(issuer, subject string) (*models.User, error) {
+	var user models.User
+	var createdAtStr string
+	err := store.db.QueryRow(`
+		SELECT u.id, u.username, u.email, u.created_at
+		FROM user_identities i
+		JOIN users u ON u.id = i.user_id
+		WHERE i.issuer = ? AND i.subject = ?
+	`, issuer, subject).Scan(&user.ID, &user.Username, &user.Email, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	user.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	return &user, nil
 }
 
-// hashPassword hashes a password
-func hashPassword(password string) string {
-	hash := sha256.Sum256([]byte(password))
-	return hex.EncodeToString(hash[:])
+// CreateUserWithIdentity creates a user linked to an OpenID Connect account
+// The empty password hash never validates, so the user can only log in through the provider.
+func (store *SQLStore) CreateUserWithIdentity(username, issuer, subject, email string) (*models.User, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	id, err := tx.insert(
+		"INSERT INTO users (username, password_hash) VALUES (?, ?)",
+		username, "",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO user_identities (user_id, issuer, subject, email, created_at) VALUES (?, ?, ?, ?, ?)",
+		id, issuer, subject, email, time.Now().UTC().Format("2006-01-02 15:04:05"),
+	); err != nil {
+		return nil, err
+	}
+
+	if _, err := createWorkspace(tx.sqlConn, id, username); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &models.User{
+		ID:        id,
+		Username:  username,
+		CreatedAt: time.Now(),
+	}, nil
 }
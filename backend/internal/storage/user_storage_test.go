@@ -2,148 +2,229 @@ package storage
 
 import (
 	"database/sql"
-	"os"
-	"path/filepath"
-	"shiftplanner/backend/internal/database"
+	"strings"
 	"testing"
-
-	_ "modernc.org/sqlite"
 )
 
-func setupUserStorageTestDB(t *testing.T) {
-	testDBPath := filepath.Join(os.TempDir(), "test_user_storage.db")
-	os.Remove(testDBPath)
-
-	var err error
-	database.DB, err = sql.Open("sqlite", testDBPath)
-	if err != nil {
-		t.Fatalf("Failed to create test database: %v", err)
-	}
+func TestCreateUser(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		user, err := store.CreateUser("testuser", "testpassword")
+		if err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+
+		if user.Username != "testuser" {
+			t.Errorf("Username mismatch: got %s, want testuser", user.Username)
+		}
+
+		if user.ID == 0 {
+			t.Error("User ID cannot be 0")
+		}
+	})
+}
 
-	createUsersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		username TEXT NOT NULL UNIQUE,
-		password_hash TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
+func TestCreateUser_DuplicateUsername(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		store.CreateUser("testuser", "password1")
+
+		// Try to create again with same username
+		_, err := store.CreateUser("testuser", "password2")
+		if err == nil {
+			t.Error("Expected error for duplicate username")
+		}
+	})
+}
 
-	if _, err := database.DB.Exec(createUsersTable); err != nil {
-		t.Fatalf("Failed to create users table: %v", err)
-	}
+func TestGetUserByUsername(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		store.CreateUser("testuser", "testpassword")
+
+		user, passwordHash, err := store.GetUserByUsername("testuser")
+		if err != nil {
+			t.Fatalf("Failed to get user: %v", err)
+		}
+
+		if user.Username != "testuser" {
+			t.Errorf("Username mismatch: got %s, want testuser", user.Username)
+		}
+
+		if passwordHash == "" {
+			t.Error("Password hash cannot be empty")
+		}
+	})
 }
 
-func teardownUserStorageTestDB(t *testing.T) {
-	if database.DB != nil {
-		database.DB.Close()
-		database.DB = nil
-	}
-	testDBPath := filepath.Join(os.TempDir(), "test_user_storage.db")
-	os.Remove(testDBPath)
+func TestGetUserByUsername_NotFound(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		_, _, err := store.GetUserByUsername("nonexistent")
+		if err == nil {
+			t.Error("Expected error for non-existent user")
+		}
+	})
 }
 
-func TestCreateUser(t *testing.T) {
-	setupUserStorageTestDB(t)
-	defer teardownUserStorageTestDB(t)
+func TestValidatePassword(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		password := "testpassword"
+		user, _ := store.CreateUser("testuser", password)
+
+		// Get password hash
+		_, passwordHash, _ := store.GetUserByUsername("testuser")
+
+		// Correct password
+		if !ValidatePassword(password, passwordHash) {
+			t.Error("Correct password was not validated")
+		}
+
+		// Wrong password
+		if ValidatePassword("wrongpassword", passwordHash) {
+			t.Error("Wrong password should not be validated")
+		}
+
+		_ = user // Prevent unused variable warning
+	})
+}
 
-	user, err := CreateUser("testuser", "testpassword")
+func TestHashPassword(t *testing.T) {
+	password := "testpassword"
+	hash1, err := hashPassword(password)
 	if err != nil {
-		t.Fatalf("Failed to create user: %v", err)
+		t.Fatalf("Failed to hash password: %v", err)
 	}
+	hash2, _ := hashPassword(password)
 
-	if user.Username != "testuser" {
-		t.Errorf("Username mismatch: got %s, want testuser", user.Username)
+	// Hashes are salted, so the same password produces different hashes
+	if hash1 == hash2 {
+		t.Error("Same hash produced twice for the same password")
 	}
 
-	if user.ID == 0 {
-		t.Error("User ID cannot be 0")
+	if !strings.HasPrefix(hash1, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("Hash should record the algorithm and parameters, got %s", hash1)
 	}
-}
-
-func TestCreateUser_DuplicateUsername(t *testing.T) {
-	setupUserStorageTestDB(t)
-	defer teardownUserStorageTestDB(t)
 
-	CreateUser("testuser", "password1")
+	if !ValidatePassword(password, hash1) || !ValidatePassword(password, hash2) {
+		t.Error("Both hashes should validate the password")
+	}
 
-	// Try to create again with same username
-	_, err := CreateUser("testuser", "password2")
-	if err == nil {
-		t.Error("Expected error for duplicate username")
+	if PasswordNeedsRehash(hash1) {
+		t.Error("A fresh hash should not need rehashing")
 	}
 }
 
-func TestGetUserByUsername(t *testing.T) {
-	setupUserStorageTestDB(t)
-	defer teardownUserStorageTestDB(t)
-
-	CreateUser("testuser", "testpassword")
+func TestValidatePassword_LegacySHA256(t *testing.T) {
+	// Unsalted SHA-256 of "testpassword", as stored by earlier versions
+	legacyHash := "9f735e0df9a1ddc702bf0a1a7b83033f9f7153a00c29de82cedadc9957289b05"
 
-	user, passwordHash, err := GetUserByUsername("testuser")
-	if err != nil {
-		t.Fatalf("Failed to get user: %v", err)
+	if !ValidatePassword("testpassword", legacyHash) {
+		t.Error("Legacy hash should validate the correct password")
 	}
-
-	if user.Username != "testuser" {
-		t.Errorf("Username mismatch: got %s, want testuser", user.Username)
+	if ValidatePassword("wrongpassword", legacyHash) {
+		t.Error("Legacy hash should not validate a wrong password")
 	}
-
-	if passwordHash == "" {
-		t.Error("Password hash cannot be empty")
+	if !PasswordNeedsRehash(legacyHash) {
+		t.Error("Legacy hash should need rehashing")
 	}
 }
 
-func TestGetUserByUsername_NotFound(t *testing.T) {
-	setupUserStorageTestDB(t)
-	defer teardownUserStorageTestDB(t)
+func TestValidatePassword_EmptyHash(t *testing.T) {
+	if ValidatePassword("", "") || ValidatePassword("dummy password", "") {
+		t.Error("Empty hash should not validate any password")
+	}
 
-	_, _, err := GetUserByUsername("nonexistent")
-	if err == nil {
-		t.Error("Expected error for non-existent user")
+	// The stand-in costs as much as the hashes new passwords get
+	if PasswordNeedsRehash(dummyPasswordHash()) {
+		t.Errorf("Dummy hash should use the current parameters, got %s", dummyPasswordHash())
 	}
 }
 
-func TestValidatePassword(t *testing.T) {
-	setupUserStorageTestDB(t)
-	defer teardownUserStorageTestDB(t)
-
-	password := "testpassword"
-	user, _ := CreateUser("testuser", password)
-
-	// Get password hash
-	_, passwordHash, _ := GetUserByUsername("testuser")
+func TestPasswordNeedsRehash_OutdatedParameters(t *testing.T) {
+	params := passwordParams
+	passwordParams.iterations = 1
+	outdated, _ := hashPassword("testpassword")
+	passwordParams = params
 
-	// Correct password
-	if !ValidatePassword(password, passwordHash) {
-		t.Error("Correct password was not validated")
+	if !ValidatePassword("testpassword", outdated) {
+		t.Error("Hash with other parameters should still validate")
 	}
-
-	// Wrong password
-	if ValidatePassword("wrongpassword", passwordHash) {
-		t.Error("Wrong password should not be validated")
+	if !PasswordNeedsRehash(outdated) {
+		t.Error("Hash with other parameters should need rehashing")
+	}
+	if ValidatePassword("testpassword", "$argon2id$v=19$m=19456,t=2,p=1$bad") {
+		t.Error("Malformed hash should not validate")
 	}
-
-	_ = user // Prevent unused variable warning
 }
 
-func TestHashPassword(t *testing.T) {
-	password := "testpassword"
-	hash1 := hashPassword(password)
-	hash2 := hashPassword(password)
+func TestUpdatePassword(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		user, _ := store.CreateUser("testuser", "oldpassword")
 
-	// Same password should produce same hash
-	if hash1 != hash2 {
-		t.Error("Different hash produced for same password")
-	}
+		if err := store.UpdatePassword(user.ID, "newpassword"); err != nil {
+			t.Fatalf("Failed to update password: %v", err)
+		}
 
-	// Hash should not be empty
-	if hash1 == "" {
-		t.Error("Hash cannot be empty")
-	}
+		_, passwordHash, _ := store.GetUserByUsername("testuser")
+		if ValidatePassword("oldpassword", passwordHash) || !ValidatePassword("newpassword", passwordHash) {
+			t.Error("Only the new password should be valid")
+		}
+
+		if err := store.UpdatePassword(user.ID+100, "newpassword"); err == nil {
+			t.Error("Expected error for unknown user")
+		}
+	})
+}
+
+func TestUserIdentity(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		issuer := "https://id.example.com"
+		if _, err := store.GetUserByIdentity(issuer, "alice-id"); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for an unknown identity, got %v", err)
+		}
+
+		user, err := store.CreateUserWithIdentity("alice@example.com", issuer, "alice-id", "alice@example.com")
+		if err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		if workspaces, _ := store.GetUserWorkspaces(user.ID); len(workspaces) != 1 {
+			t.Errorf("Expected a personal workspace, got %+v", workspaces)
+		}
+
+		found, err := store.GetUserByIdentity(issuer, "alice-id")
+		if err != nil || found.ID != user.ID || found.Username != "alice@example.com" {
+			t.Errorf("Unexpected user %+v (%v)", found, err)
+		}
+		if _, err := store.GetUserByIdentity("https://other.example.com", "alice-id"); err != sql.ErrNoRows {
+			t.Errorf("Identities should be per issuer, got %v", err)
+		}
+
+		// Users created through single sign-on have no usable password
+		_, passwordHash, _ := store.GetUserByUsername("alice@example.com")
+		if ValidatePassword("", passwordHash) {
+			t.Error("Empty password should not validate")
+		}
+
+		if _, err := store.CreateUserWithIdentity("alice2", issuer, "alice-id", "alice@example.com"); err == nil {
+			t.Error("Expected error for an identity that is already linked")
+		}
+	})
+}
 
-	// Different password should produce different hash
-	hash3 := hashPassword("differentpassword")
-	if hash1 == hash3 {
-		t.Error("Same hash produced for different passwords")
+func TestValidatePassword_InvalidParameters(t *testing.T) {
+	hash, _ := hashPassword("testpassword")
+	valid := "$m=19456,t=2,p=1$"
+
+	for _, params := range []string{
+		"$m=19456,t=2,p=0$",          // no threads
+		"$m=19456,t=0,p=1$",          // no iterations
+		"$m=4,t=2,p=1$",              // less than 8 KiB per thread
+		"$m=4294967295,t=2,p=1$",     // more memory than allowed
+		"$m=19456,t=4294967295,p=1$", // more iterations than allowed
+	} {
+		tampered := strings.Replace(hash, valid, params, 1)
+		if _, _, _, err := decodePasswordHash(tampered); err == nil {
+			t.Errorf("Hash with parameters %s should be rejected", params)
+		}
+		if ValidatePassword("testpassword", tampered) {
+			t.Errorf("Hash with parameters %s should not validate", params)
+		}
 	}
 }
@@ -0,0 +1,457 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"time"
+)
+
+// CreateWorkspace creates a workspace owned by a user
+func (store *SQLStore) CreateWorkspace(ownerUserID int, name string) (*models.Workspace, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	workspace, err := createWorkspace(tx.sqlConn, ownerUserID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+// createWorkspace inserts a workspace and makes ownerUserID its owner
+func createWorkspace(conn sqlConn, ownerUserID int, name string) (*models.Workspace, error) {
+	createdAt := time.Now().UTC().Truncate(time.Second)
+	createdAtStr := createdAt.Format("2006-01-02 15:04:05")
+
+	id, err := conn.insert("INSERT INTO workspaces (name, created_at) VALUES (?, ?)", name, createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.Exec(
+		"INSERT INTO workspace_users (workspace_id, user_id, role, created_at) VALUES (?, ?, ?, ?)",
+		id, ownerUserID, models.RoleOwner, createdAtStr,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Workspace{
+		ID:        id,
+		Name:      name,
+		Role:      models.RoleOwner,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// GetUserWorkspaces lists the workspaces a user has access to, oldest first
+func (store *SQLStore) GetUserWorkspaces(userID int) ([]models.Workspace, error) {
+	var workspaces []models.Workspace
+	err := store.queryRows(`
+		SELECT w.id, w.name, wu.role, w.require_two_factor, w.created_at
+		FROM workspaces w
+		JOIN workspace_users wu ON wu.workspace_id = w.id
+		WHERE wu.user_id = ?
+		ORDER BY w.id
+	`, []interface{}{userID}, func(rows *sql.Rows) error {
+		var w models.Workspace
+		var createdAtStr string
+		if err := rows.Scan(&w.ID, &w.Name, &w.Role, &w.RequireTwoFactor, &createdAtStr); err != nil {
+			return err
+		}
+		w.CreatedAt = parseDateTime(createdAtStr)
+		workspaces = append(workspaces, w)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+// GetWorkspace gets a workspace by ID
+func (store *SQLStore) GetWorkspace(workspaceID int) (*models.Workspace, error) {
+	var workspace models.Workspace
+	var createdAtStr string
+	err := store.db.QueryRow(
+		"SELECT id, name, require_two_factor, created_at FROM workspaces WHERE id = ?",
+		workspaceID,
+	).Scan(&workspace.ID, &workspace.Name, &workspace.RequireTwoFactor, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	workspace.CreatedAt = parseDateTime(createdAtStr)
+	return &workspace, nil
+}
+
+// SetWorkspaceRequireTwoFactor sets whether schedulers and owners of a workspace need two-factor authentication
+func (store *SQLStore) SetWorkspaceRequireTwoFactor(workspaceID int, required bool) error {
+	result, err := store.db.Exec("UPDATE workspaces SET require_two_factor = ? WHERE id = ?", required, workspaceID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetWorkspaceRole gets the role of a user in a workspace
+func (store *SQLStore) GetWorkspaceRole(workspaceID, userID int) (string, error) {
+	var role string
+	err := store.db.QueryRow(
+		"SELECT role FROM workspace_users WHERE workspace_id = ? AND user_id = ?",
+		workspaceID, userID,
+	).Scan(&role)
+	return role, err
+}
+
+// GetWorkspaceUsers lists the users of a workspace, ordered by username
+func (store *SQLStore) GetWorkspaceUsers(workspaceID int) ([]models.WorkspaceUser, error) {
+	var users []models.WorkspaceUser
+	err := store.queryRows(`
+		SELECT u.id, u.username, wu.role, wu.created_at
+		FROM workspace_users wu
+		JOIN users u ON u.id = wu.user_id
+		WHERE wu.workspace_id = ?
+		ORDER BY u.username
+	`, []interface{}{workspaceID}, func(rows *sql.Rows) error {
+		var wu models.WorkspaceUser
+		var createdAtStr string
+		if err := rows.Scan(&wu.UserID, &wu.Username, &wu.Role, &createdAtStr); err != nil {
+			return err
+		}
+		wu.CreatedAt = parseDateTime(createdAtStr)
+		users = append(users, wu)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// SetWorkspaceUserRole adds a user to a workspace or changes their role
+// The last owner can't be demoted.
+func (store *SQLStore) SetWorkspaceUserRole(workspaceID, userID int, role string) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRow(
+		"SELECT role FROM workspace_users WHERE workspace_id = ? AND user_id = ?",
+		workspaceID, userID,
+	).Scan(&current)
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = tx.Exec(
+			"INSERT INTO workspace_users (workspace_id, user_id, role, created_at) VALUES (?, ?, ?, ?)",
+			workspaceID, userID, role, time.Now().UTC().Format("2006-01-02 15:04:05"),
+		)
+	case err == nil:
+		if current == models.RoleOwner && role != models.RoleOwner {
+			if err := checkNotLastOwner(tx, workspaceID); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(
+			"UPDATE workspace_users SET role = ?, sso_granted = ? WHERE workspace_id = ? AND user_id = ?",
+			role, false, workspaceID, userID,
+		)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetSSOWorkspaceRoles maps the workspaces where single sign-on granted a user's role to that role
+func (store *SQLStore) GetSSOWorkspaceRoles(userID int) (map[int]string, error) {
+	roles := make(map[int]string)
+	err := store.queryRows(
+		"SELECT workspace_id, role FROM workspace_users WHERE user_id = ? AND sso_granted = ?",
+		[]interface{}{userID, true}, func(rows *sql.Rows) error {
+			var workspaceID int
+			var role string
+			if err := rows.Scan(&workspaceID, &role); err != nil {
+				return err
+			}
+			roles[workspaceID] = role
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// SetSSOWorkspaceRole adds a user to a workspace or changes a role single sign-on granted
+// Roles granted by hand are left as they are, and the last owner can't be demoted.
+func (store *SQLStore) SetSSOWorkspaceRole(workspaceID, userID int, role string) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current string
+	var ssoGranted bool
+	err = tx.QueryRow(
+		"SELECT role, sso_granted FROM workspace_users WHERE workspace_id = ? AND user_id = ?",
+		workspaceID, userID,
+	).Scan(&current, &ssoGranted)
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = tx.Exec(
+			"INSERT INTO workspace_users (workspace_id, user_id, role, sso_granted, created_at) VALUES (?, ?, ?, ?, ?)",
+			workspaceID, userID, role, true, time.Now().UTC().Format("2006-01-02 15:04:05"),
+		)
+	case err == nil && (!ssoGranted || current == role):
+		return nil
+	case err == nil:
+		if current == models.RoleOwner {
+			if err := checkNotLastOwner(tx, workspaceID); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(
+			"UPDATE workspace_users SET role = ? WHERE workspace_id = ? AND user_id = ?",
+			role, workspaceID, userID,
+		)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveSSOWorkspaceRole takes away access single sign-on granted
+// Roles granted by hand are left as they are, and the last owner can't be removed.
+func (store *SQLStore) RemoveSSOWorkspaceRole(workspaceID, userID int) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var role string
+	err = tx.QueryRow(
+		"SELECT role FROM workspace_users WHERE workspace_id = ? AND user_id = ? AND sso_granted = ?",
+		workspaceID, userID, true,
+	).Scan(&role)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if role == models.RoleOwner {
+		if err := checkNotLastOwner(tx, workspaceID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM workspace_users WHERE workspace_id = ? AND user_id = ?", workspaceID, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveWorkspaceUser takes a user's access to a workspace away
+// The last owner can't be removed.
+func (store *SQLStore) RemoveWorkspaceUser(workspaceID, userID int) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var role string
+	err = tx.QueryRow(
+		"SELECT role FROM workspace_users WHERE workspace_id = ? AND user_id = ?",
+		workspaceID, userID,
+	).Scan(&role)
+	if err != nil {
+		return err
+	}
+	if role == models.RoleOwner {
+		if err := checkNotLastOwner(tx, workspaceID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM workspace_users WHERE workspace_id = ? AND user_id = ?", workspaceID, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkNotLastOwner returns ErrLastOwner if the workspace has a single owner
+func checkNotLastOwner(tx *sqlTx, workspaceID int) error {
+	var owners int
+	err := tx.QueryRow(
+		"SELECT COUNT(*) FROM workspace_users WHERE workspace_id = ? AND role = ?",
+		workspaceID, models.RoleOwner,
+	).Scan(&owners)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// CreateWorkspaceInvite stores an invite to join a workspace
+func (store *SQLStore) CreateWorkspaceInvite(workspaceID, invitedBy int, email, role, tokenHash string, expiresAt time.Time) (*models.WorkspaceInvite, error) {
+	invite := &models.WorkspaceInvite{
+		Email:     email,
+		Role:      role,
+		InvitedBy: invitedBy,
+		ExpiresAt: expiresAt.UTC().Truncate(time.Second),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	var err error
+	invite.ID, err = store.db.insert(
+		"INSERT INTO workspace_invites (workspace_id, email, role, token_hash, invited_by, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		workspaceID, email, role, tokenHash, invitedBy,
+		invite.ExpiresAt.Format("2006-01-02 15:04:05"), invite.CreatedAt.Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// GetWorkspaceInvites lists the invites of a workspace that haven't expired, oldest first
+func (store *SQLStore) GetWorkspaceInvites(workspaceID int, now time.Time) ([]models.WorkspaceInvite, error) {
+	var invites []models.WorkspaceInvite
+	err := store.queryRows(
+		"SELECT id, email, role, invited_by, expires_at, created_at FROM workspace_invites WHERE workspace_id = ? AND expires_at > ? ORDER BY id",
+		[]interface{}{workspaceID, now.UTC().Format("2006-01-02 15:04:05")},
+		func(rows *sql.Rows) error {
+			var invite models.WorkspaceInvite
+			var expiresAtStr, createdAtStr string
+			if err := rows.Scan(&invite.ID, &invite.Email, &invite.Role, &invite.InvitedBy, &expiresAtStr, &createdAtStr); err != nil {
+				return err
+			}
+			invite.ExpiresAt = parseDateTime(expiresAtStr)
+			invite.CreatedAt = parseDateTime(createdAtStr)
+			invites = append(invites, invite)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// GetWorkspaceInvite gets an invite that can still be accepted by the hash of its token
+func (store *SQLStore) GetWorkspaceInvite(tokenHash string, now time.Time) (*models.WorkspaceInvite, error) {
+	var invite models.WorkspaceInvite
+	var expiresAtStr, createdAtStr string
+	err := store.db.QueryRow(
+		"SELECT id, email, role, invited_by, expires_at, created_at FROM workspace_invites WHERE token_hash = ? AND expires_at > ?",
+		tokenHash, now.UTC().Format("2006-01-02 15:04:05"),
+	).Scan(&invite.ID, &invite.Email, &invite.Role, &invite.InvitedBy, &expiresAtStr, &createdAtStr)
+	if err == sql.ErrNoRows {
+		return nil, ErrWorkspaceInviteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	invite.ExpiresAt = parseDateTime(expiresAtStr)
+	invite.CreatedAt = parseDateTime(createdAtStr)
+	return &invite, nil
+}
+
+// DeleteWorkspaceInvite revokes an invite
+func (store *SQLStore) DeleteWorkspaceInvite(workspaceID, inviteID int) error {
+	result, err := store.db.Exec("DELETE FROM workspace_invites WHERE id = ? AND workspace_id = ?", inviteID, workspaceID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AcceptWorkspaceInvite uses an invite up and adds the user to its workspace
+func (store *SQLStore) AcceptWorkspaceInvite(tokenHash string, userID int, now time.Time) (*models.Workspace, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var workspace models.Workspace
+	var createdAtStr string
+	err = tx.QueryRow(`
+		SELECT w.id, w.name, i.role, w.require_two_factor, w.created_at
+		FROM workspace_invites i
+		JOIN workspaces w ON w.id = i.workspace_id
+		WHERE i.token_hash = ? AND i.expires_at > ?
+	`, tokenHash, now.UTC().Format("2006-01-02 15:04:05")).Scan(&workspace.ID, &workspace.Name, &workspace.Role, &workspace.RequireTwoFactor, &createdAtStr)
+	if err == sql.ErrNoRows {
+		return nil, ErrWorkspaceInviteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	workspace.CreatedAt = parseDateTime(createdAtStr)
+
+	if _, err := tx.Exec("DELETE FROM workspace_invites WHERE token_hash = ?", tokenHash); err != nil {
+		return nil, err
+	}
+
+	var current string
+	err = tx.QueryRow(
+		"SELECT role FROM workspace_users WHERE workspace_id = ? AND user_id = ?",
+		workspace.ID, userID,
+	).Scan(&current)
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = tx.Exec(
+			"INSERT INTO workspace_users (workspace_id, user_id, role, created_at) VALUES (?, ?, ?, ?)",
+			workspace.ID, userID, workspace.Role, now.UTC().Format("2006-01-02 15:04:05"),
+		)
+	case err == nil && models.RoleAtLeast(current, workspace.Role):
+		workspace.Role = current
+		fallthrough
+	case err == nil:
+		// An accepted invite grants the role by hand
+		_, err = tx.Exec(
+			"UPDATE workspace_users SET role = ?, sso_granted = ? WHERE workspace_id = ? AND user_id = ?",
+			workspace.Role, false, workspace.ID, userID,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"database/sql"
+	"shiftplanner/backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestCreateUser_CreatesPersonalWorkspace(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, userID int) {
+		workspaces, err := store.GetUserWorkspaces(userID)
+		if err != nil {
+			t.Fatalf("Failed to get workspaces: %v", err)
+		}
+		if len(workspaces) != 1 || workspaces[0].Name != "testuser" || workspaces[0].Role != models.RoleOwner {
+			t.Errorf("Unexpected personal workspace: %+v", workspaces)
+		}
+	})
+}
+
+func TestWorkspaceUsers(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, ownerID int) {
+		team, err := store.CreateWorkspace(ownerID, "Team")
+		if err != nil {
+			t.Fatalf("Failed to create workspace: %v", err)
+		}
+		other, _ := store.CreateUser("otheruser", "testpassword")
+		otherWorkspaceID := personalWorkspace(t, store, other.ID)
+
+		if _, err := store.GetWorkspaceRole(team.ID, other.ID); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows before joining, got %v", err)
+		}
+
+		if err := store.SetWorkspaceUserRole(team.ID, other.ID, models.RoleViewer); err != nil {
+			t.Fatalf("Failed to add user: %v", err)
+		}
+		if err := store.SetWorkspaceUserRole(team.ID, other.ID, models.RoleScheduler); err != nil {
+			t.Fatalf("Failed to change role: %v", err)
+		}
+		if role, _ := store.GetWorkspaceRole(team.ID, other.ID); role != models.RoleScheduler {
+			t.Errorf("Role mismatch: got %q, want scheduler", role)
+		}
+
+		users, err := store.GetWorkspaceUsers(team.ID)
+		if err != nil {
+			t.Fatalf("Failed to get workspace users: %v", err)
+		}
+		if len(users) != 2 || users[0].Username != "otheruser" || users[1].Role != models.RoleOwner {
+			t.Errorf("Unexpected workspace users: %+v", users)
+		}
+
+		// Data of the team is separate from the personal workspaces
+		store.CreateMember(team.ID, "Alice")
+		if members, _ := store.GetAllMembers(otherWorkspaceID); len(members) != 0 {
+			t.Errorf("Personal workspace should not see team members, got %d", len(members))
+		}
+
+		if err := store.SetWorkspaceUserRole(team.ID, ownerID, models.RoleMember); err != ErrLastOwner {
+			t.Errorf("Expected ErrLastOwner when demoting the last owner, got %v", err)
+		}
+		if err := store.RemoveWorkspaceUser(team.ID, ownerID); err != ErrLastOwner {
+			t.Errorf("Expected ErrLastOwner when removing the last owner, got %v", err)
+		}
+
+		// With a second owner the first may leave
+		store.SetWorkspaceUserRole(team.ID, other.ID, models.RoleOwner)
+		if err := store.RemoveWorkspaceUser(team.ID, ownerID); err != nil {
+			t.Fatalf("Failed to remove owner: %v", err)
+		}
+		if err := store.RemoveWorkspaceUser(team.ID, ownerID); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for a removed user, got %v", err)
+		}
+	})
+}
+
+func TestSSOWorkspaceRoles(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, ownerID int) {
+		team, _ := store.CreateWorkspace(ownerID, "Team")
+		other, _ := store.CreateUser("otheruser", "testpassword")
+
+		if err := store.SetSSOWorkspaceRole(team.ID, other.ID, models.RoleScheduler); err != nil {
+			t.Fatalf("Failed to grant role: %v", err)
+		}
+		if err := store.SetSSOWorkspaceRole(team.ID, other.ID, models.RoleViewer); err != nil {
+			t.Fatalf("Failed to change granted role: %v", err)
+		}
+		roles, err := store.GetSSOWorkspaceRoles(other.ID)
+		if err != nil {
+			t.Fatalf("Failed to get granted roles: %v", err)
+		}
+		if len(roles) != 1 || roles[team.ID] != models.RoleViewer {
+			t.Errorf("Unexpected granted roles: %v", roles)
+		}
+
+		// Single sign-on never changes a role granted by hand
+		if err := store.SetSSOWorkspaceRole(team.ID, ownerID, models.RoleMember); err != nil {
+			t.Fatalf("Failed to skip role granted by hand: %v", err)
+		}
+		if err := store.RemoveSSOWorkspaceRole(team.ID, ownerID); err != nil {
+			t.Fatalf("Failed to skip role granted by hand: %v", err)
+		}
+		if role, _ := store.GetWorkspaceRole(team.ID, ownerID); role != models.RoleOwner {
+			t.Errorf("Role granted by hand should stay owner, got %q", role)
+		}
+
+		// Setting a role by hand takes it out of single sign-on's hands
+		store.SetWorkspaceUserRole(team.ID, other.ID, models.RoleOwner)
+		if roles, _ := store.GetSSOWorkspaceRoles(other.ID); len(roles) != 0 {
+			t.Errorf("Role set by hand should not count as granted, got %v", roles)
+		}
+		store.RemoveWorkspaceUser(team.ID, other.ID)
+
+		// A granted owner can't be demoted or removed while they are the last one
+		store.SetSSOWorkspaceRole(team.ID, other.ID, models.RoleOwner)
+		if err := store.RemoveWorkspaceUser(team.ID, ownerID); err != nil {
+			t.Fatalf("Failed to remove owner: %v", err)
+		}
+		if err := store.SetSSOWorkspaceRole(team.ID, other.ID, models.RoleMember); err != ErrLastOwner {
+			t.Errorf("Expected ErrLastOwner when demoting the last owner, got %v", err)
+		}
+		if err := store.RemoveSSOWorkspaceRole(team.ID, other.ID); err != ErrLastOwner {
+			t.Errorf("Expected ErrLastOwner when removing the last owner, got %v", err)
+		}
+
+		store.SetWorkspaceUserRole(team.ID, ownerID, models.RoleOwner)
+		if err := store.RemoveSSOWorkspaceRole(team.ID, other.ID); err != nil {
+			t.Fatalf("Failed to remove granted role: %v", err)
+		}
+		if _, err := store.GetWorkspaceRole(team.ID, other.ID); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows after removing the granted role, got %v", err)
+		}
+	})
+}
+
+func TestFeedToken_RevokedWithWorkspaceAccess(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, ownerID int) {
+		team, _ := store.CreateWorkspace(ownerID, "Team")
+		other, _ := store.CreateUser("otheruser", "testpassword")
+		otherWorkspaceID := personalWorkspace(t, store, other.ID)
+		store.SetWorkspaceUserRole(team.ID, other.ID, models.RoleViewer)
+
+		feedToken, err := store.CreateFeedToken(team.ID, other.ID, "Phone", "feed", "feed-token-hash")
+		if err != nil {
+			t.Fatalf("Failed to create feed token: %v", err)
+		}
+		if tokens, _ := store.GetFeedTokens(otherWorkspaceID, other.ID); len(tokens) != 0 {
+			t.Errorf("Feed tokens should be listed per workspace, got %d", len(tokens))
+		}
+		if err := store.DeleteFeedToken(otherWorkspaceID, other.ID, feedToken.ID); err != ErrFeedTokenNotFound {
+			t.Errorf("Feed tokens should be revoked per workspace, got %v", err)
+		}
+
+		workspaceID, err := store.GetWorkspaceIDByFeedToken("feed-token-hash")
+		if err != nil || workspaceID != team.ID {
+			t.Fatalf("Feed token should resolve to workspace %d, got %d (%v)", team.ID, workspaceID, err)
+		}
+
+		store.RemoveWorkspaceUser(team.ID, other.ID)
+		if _, err := store.GetWorkspaceIDByFeedToken("feed-token-hash"); err != ErrFeedTokenNotFound {
+			t.Errorf("Expected ErrFeedTokenNotFound after losing access, got %v", err)
+		}
+	})
+}
+
+func TestWorkspaceInvites(t *testing.T) {
+	forEachStoreWithUser(t, func(t *testing.T, store Store, ownerID int) {
+		team, _ := store.CreateWorkspace(ownerID, "Team")
+		other, _ := store.CreateUser("otheruser", "testpassword")
+		now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+		invite, err := store.CreateWorkspaceInvite(team.ID, ownerID, "other@example.com", models.RoleScheduler, "hash-1", now.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to create invite: %v", err)
+		}
+		store.CreateWorkspaceInvite(team.ID, ownerID, "late@example.com", models.RoleViewer, "hash-2", now.Add(-time.Hour))
+
+		invites, err := store.GetWorkspaceInvites(team.ID, now)
+		if err != nil {
+			t.Fatalf("Failed to get invites: %v", err)
+		}
+		if len(invites) != 1 || invites[0].ID != invite.ID || invites[0].Email != "other@example.com" {
+			t.Errorf("Expected only the pending invite, got %+v", invites)
+		}
+
+		if _, err := store.AcceptWorkspaceInvite("hash-2", other.ID, now); err != ErrWorkspaceInviteNotFound {
+			t.Errorf("Expected ErrWorkspaceInviteNotFound for an expired invite, got %v", err)
+		}
+
+		workspace, err := store.AcceptWorkspaceInvite("hash-1", other.ID, now)
+		if err != nil {
+			t.Fatalf("Failed to accept invite: %v", err)
+		}
+		if workspace.ID != team.ID || workspace.Role != models.RoleScheduler {
+			t.Errorf("Unexpected workspace: %+v", workspace)
+		}
+		if role, _ := store.GetWorkspaceRole(team.ID, other.ID); role != models.RoleScheduler {
+			t.Errorf("Role mismatch: got %q, want scheduler", role)
+		}
+
+		// Invites are single-use
+		if _, err := store.AcceptWorkspaceInvite("hash-1", other.ID, now); err != ErrWorkspaceInviteNotFound {
+			t.Errorf("Expected ErrWorkspaceInviteNotFound for a used invite, got %v", err)
+		}
+
+		// A lower role doesn't demote users already in the workspace
+		store.CreateWorkspaceInvite(team.ID, ownerID, "other@example.com", models.RoleViewer, "hash-3", now.Add(time.Hour))
+		if workspace, _ := store.AcceptWorkspaceInvite("hash-3", other.ID, now); workspace.Role != models.RoleScheduler {
+			t.Errorf("Existing role should be kept, got %q", workspace.Role)
+		}
+
+		store.CreateWorkspaceInvite(team.ID, ownerID, "x@example.com", models.RoleViewer, "hash-4", now.Add(time.Hour))
+		invites, _ = store.GetWorkspaceInvites(team.ID, now)
+		if err := store.DeleteWorkspaceInvite(team.ID+1000, invites[0].ID); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for another workspace, got %v", err)
+		}
+		if err := store.DeleteWorkspaceInvite(team.ID, invites[0].ID); err != nil {
+			t.Fatalf("Failed to delete invite: %v", err)
+		}
+		if _, err := store.GetWorkspaceInvite("hash-4", now); err != ErrWorkspaceInviteNotFound {
+			t.Errorf("Expected ErrWorkspaceInviteNotFound for a revoked invite, got %v", err)
+		}
+	})
+}